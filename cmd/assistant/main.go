@@ -12,17 +12,19 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
-	"sync"
-	"sync/atomic"
+	"runtime"
+	"slices"
 	"syscall"
-	"time"
 
 	"github.com/agalue/sherpa-voice-assistant/internal/audio"
 	"github.com/agalue/sherpa-voice-assistant/internal/config"
-	"github.com/agalue/sherpa-voice-assistant/internal/llm"
+	"github.com/agalue/sherpa-voice-assistant/internal/events"
+	"github.com/agalue/sherpa-voice-assistant/internal/pipeline"
 	"github.com/agalue/sherpa-voice-assistant/internal/setup"
 	"github.com/agalue/sherpa-voice-assistant/internal/stt"
 	"github.com/agalue/sherpa-voice-assistant/internal/tts"
@@ -34,6 +36,14 @@ func main() {
 	if err != nil {
 		log.Fatalf("Configuration error: %v", err)
 	}
+	log.SetOutput(logWriter(cfg))
+	if cfg.JSONEvents {
+		events.SetOutput(os.Stdout)
+	}
+	if cfg.Verbose {
+		log.Printf("[Config] CPU cores: %d, Thread counts: VAD=%d, STT=%d, TTS=%d",
+			runtime.NumCPU(), cfg.VADThreads, cfg.STTThreads, cfg.TTSThreads)
+	}
 
 	// Handle informational flags first (no model loading required).
 	ttsProvider, err := tts.NewModelProvider(cfg)
@@ -51,6 +61,23 @@ func main() {
 		os.Exit(0)
 	}
 
+	// --list-input-devices / --list-output-devices: need only the audio devices.
+	if cfg.ListInputDevices || cfg.ListOutputDevices {
+		if cfg.ListInputDevices {
+			printDevices("Input devices (--input-device)", audio.ListCaptureDevices)
+		}
+		if cfg.ListOutputDevices {
+			printDevices("Output devices (--output-device)", audio.ListPlaybackDevices)
+		}
+		os.Exit(0)
+	}
+
+	// --measure-latency: loopback measurement needs only the audio devices.
+	if cfg.MeasureLatency {
+		measureLatency(cfg)
+		os.Exit(0)
+	}
+
 	// Create STT model provider (used by both --setup and pre-flight verification).
 	sttProvider, err := stt.NewModelProvider(cfg)
 	if err != nil {
@@ -70,6 +97,18 @@ func main() {
 		os.Exit(0)
 	}
 
+	// --synthesize-to: speak text into a WAV file; needs only the TTS models.
+	if cfg.SynthesizeTo != "" {
+		synthesizeToFile(cfg, ttsProvider)
+		os.Exit(0)
+	}
+
+	// --transcribe: print the transcript of a WAV file; needs only the STT models.
+	if cfg.Transcribe != "" {
+		transcribeFile(cfg, sttProvider)
+		os.Exit(0)
+	}
+
 	// Verify all model files are present before starting the pipeline.
 	var allMissing []string
 	for _, p := range []setup.ModelProvider{&stt.SileroModelProvider{}, sttProvider, ttsProvider} {
@@ -83,154 +122,188 @@ func main() {
 		log.Fatalf("%d model file(s) missing; run with --setup first", len(allMissing))
 	}
 
+	// --replay-fixtures: check recorded turns against the current models, then exit.
+	if cfg.ReplayFixtures != "" {
+		replayFixtures(cfg)
+	}
+
 	log.Println("🎤 Voice Assistant starting...")
 	log.Printf("⚡ STT acceleration: %s, TTS acceleration: %s", cfg.STTProvider, cfg.TTSProvider)
 	log.Printf("🔊 TTS voice: %s (speaker %d)", cfg.TTSVoice, cfg.TTSSpeakerID)
 
-	// Create context for graceful shutdown
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	// Create context for graceful shutdown, also when the terminal is closed
+	// (SIGHUP), so a push-to-talk terminal is restored
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 	defer stop()
 
-	// Create LLM client and verify connection
-	llmClient, err := llm.NewClient(&llm.Config{
-		Host:         cfg.OllamaURL,
-		Model:        cfg.OllamaModel,
-		SystemPrompt: cfg.SystemPrompt,
-		Verbose:      cfg.Verbose,
-		MaxHistory:   cfg.MaxHistory,
-		Temperature:  cfg.Temperature,
-		SearxngURL:   cfg.SearxngURL,
-	})
+	// Build and run the pipeline until Ctrl+C
+	p, err := pipeline.New(cfg)
 	if err != nil {
-		log.Fatalf("Failed to create LLM client: %v", err)
-	}
-
-	log.Printf("🔗 Checking Ollama connection at %s...", cfg.OllamaURL)
-	if err := llmClient.HealthCheck(ctx); err != nil {
-		log.Fatalf("Ollama connection failed: %v", err)
+		log.Fatalf("%v", err)
 	}
-	log.Printf("✅ Ollama connected (model: %s)", cfg.OllamaModel)
-
-	// Create Silero VAD (voice activity detection)
-	log.Println("🧠 Loading speech recognition models...")
-	vad, err := stt.NewSileroVAD(&stt.SileroConfig{
-		ModelDir:        cfg.ModelDir,
-		Threshold:       cfg.VadThreshold,
-		SilenceDuration: cfg.VADSilenceDuration,
-		SampleRate:      cfg.SampleRate,
-		NumThreads:      cfg.VADThreads,
-		Verbose:         cfg.Verbose,
-	})
+	err = p.Run(ctx)
+	p.Close()
 	if err != nil {
-		log.Fatalf("Failed to create VAD: %v", err)
+		log.Fatalf("%v", err)
 	}
-	defer vad.Close()
+}
+
+// replayFixtures replays the recorded turns at cfg.ReplayFixtures and exits,
+// with status 1 when any transcript or reply differs from the recording.
+func replayFixtures(cfg *config.Config) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Create Whisper transcriber (speech-to-text)
-	recognizer, err := stt.NewTranscriber(cfg)
+	log.Printf("🔁 Replaying fixtures from %s", cfg.ReplayFixtures)
+	mismatches, err := pipeline.ReplayFixtures(ctx, cfg, cfg.ReplayFixtures)
 	if err != nil {
-		log.Fatalf("Failed to create STT transcriber: %v", err)
+		log.Fatalf("Fixture replay failed: %v", err)
+	}
+	if mismatches > 0 {
+		log.Printf("❌ %d fixture(s) differ from the recording", mismatches)
+		stop()
+		os.Exit(1)
 	}
-	defer recognizer.Close()
+	log.Println("✅ All fixtures match the recording")
+	stop()
+	os.Exit(0)
+}
 
-	// Expose via interfaces so the rest of the pipeline is implementation-agnostic.
-	var detector stt.VoiceDetector = vad
-	var transcriber stt.Transcriber = recognizer
-	log.Println("✅ Speech recognition ready")
+// synthesizeToFile speaks cfg.Text, or stdin when it is empty, into the WAV
+// file cfg.SynthesizeTo without opening audio devices or contacting the LLM.
+func synthesizeToFile(cfg *config.Config, provider tts.ModelProvider) {
+	if missing := provider.VerifyModels(cfg.ModelDir); len(missing) > 0 {
+		log.Fatalf("%d TTS model file(s) missing (e.g. %s); run with --setup first", len(missing), missing[0])
+	}
+	text := cfg.Text
+	if text == "" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatalf("Reading text from stdin: %v", err)
+		}
+		text = string(data)
+	}
 
-	// Create TTS synthesizer (implements tts.Synthesizer)
-	log.Println("🔊 Loading text-to-speech models...")
 	synth, err := tts.NewSynthesizer(cfg)
 	if err != nil {
 		log.Fatalf("Failed to create TTS synthesizer: %v", err)
 	}
 	defer synth.Close()
 
-	// Expose via interface so the pipeline is implementation-agnostic.
-	var synthesizer tts.Synthesizer = synth
-	log.Println("✅ Text-to-speech ready")
-
-	// Create interrupt flag for playback
-	var playbackInterrupt atomic.Bool
-
-	// Create audio player
-	player, err := audio.NewPlayer(synthesizer.SampleRate(), cfg.AudioBufferMs, &playbackInterrupt)
+	buf, err := tts.SynthesizeText(context.Background(), synth, text, cfg)
 	if err != nil {
-		log.Fatalf("Failed to create audio player: %v", err)
+		log.Fatalf("Synthesis failed: %v", err)
+	}
+	if err := audio.WriteWAV(cfg.SynthesizeTo, buf); err != nil {
+		log.Fatalf("Writing WAV file: %v", err)
 	}
-	defer player.Close()
+	log.Printf("💾 Wrote %.1fs of speech to %s", float64(len(buf.Samples))/float64(buf.SampleRate), cfg.SynthesizeTo)
+}
 
-	// Channels for pipeline communication
-	transcriptions := make(chan string, 5)
-	responses := make(chan string, 5)
+// transcribeFile splits the WAV file cfg.Transcribe into speech segments as
+// the microphone input would be, and prints the transcript of each with its
+// offset in the file, without opening audio devices or contacting the LLM.
+func transcribeFile(cfg *config.Config, provider stt.ModelProvider) {
+	var missing []string
+	for _, p := range []setup.ModelProvider{&stt.SileroModelProvider{}, provider} {
+		missing = append(missing, p.VerifyModels(cfg.ModelDir)...)
+	}
+	if len(missing) > 0 {
+		log.Fatalf("%d STT model file(s) missing (e.g. %s); run with --setup first", len(missing), missing[0])
+	}
 
-	// Create audio capturer
-	capturer, err := audio.NewCapturer(cfg.SampleRate, func(samples []float32) {
-		detector.AcceptWaveform(samples)
-	})
+	buf, err := audio.ReadWAV(cfg.Transcribe)
 	if err != nil {
-		log.Fatalf("Failed to create audio capturer: %v", err)
+		log.Fatalf("Reading WAV file: %v", err)
+	}
+	samples := buf.Samples
+	if buf.SampleRate != cfg.SampleRate {
+		samples = audio.NewPolyphaseResampler(buf.SampleRate, cfg.SampleRate).Resample(samples)
 	}
-	defer capturer.Close()
-
-	// WaitGroup for goroutines
-	var wg sync.WaitGroup
-
-	// Start STT processing goroutine (interface-based, model-agnostic)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		stt.RunProcessor(ctx, detector, transcriber, transcriptions, &playbackInterrupt, cfg.Verbose)
-	}()
-
-	// Start LLM processing goroutine
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		llmClient.RunProcessor(ctx, transcriptions, responses)
-	}()
 
-	// Start TTS and playback goroutine (interface-based, model-agnostic)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		tts.RunProcessor(ctx, synthesizer, player, responses, &playbackInterrupt, cfg, capturer)
-	}()
+	segments, err := stt.SegmentAudio(&stt.SileroConfig{
+		ModelDir:          cfg.ModelDir,
+		Threshold:         cfg.VadThreshold,
+		SilenceDuration:   cfg.VADSilenceDuration,
+		MinSpeechDuration: cfg.VADMinSpeechDuration,
+		MaxSpeechDuration: cfg.VADMaxSpeechDuration,
+		PreSpeechPadMs:    cfg.VADPreSpeechPadMs,
+		SampleRate:        cfg.SampleRate,
+		NumThreads:        cfg.VADThreads,
+		Verbose:           cfg.Verbose,
+	}, samples)
+	if err != nil {
+		log.Fatalf("Speech detection failed: %v", err)
+	}
+	log.Printf("🎧 Transcribing %s (%.1fs, %d speech segment(s))", cfg.Transcribe, float64(len(samples))/float64(cfg.SampleRate), len(segments))
 
-	// Start audio capture
-	if err := capturer.Start(); err != nil {
-		log.Fatalf("Failed to start audio capture: %v", err)
+	transcriber, err := stt.NewTranscriber(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create STT transcriber: %v", err)
 	}
+	defer transcriber.Close()
 
-	if cfg.WakeWord != "" {
-		log.Printf("🎙️ Listening for wake word: %q", cfg.WakeWord)
-	} else {
-		log.Println("🎙️ Listening... (speak to interact, Ctrl+C to quit)")
+	for _, segment := range segments {
+		if text := transcriber.TranscribeSegment(segment.Samples); text != "" {
+			fmt.Printf("[%7.2fs] %s\n", segment.Start.Seconds(), text)
+		}
 	}
+}
 
-	// Wait for shutdown signal
-	<-ctx.Done()
-	log.Println("🛑 Shutting down...")
+// printDevices prints the devices returned by list under title, with the
+// index and name accepted by the device-selection flags.
+func printDevices(title string, list func() ([]audio.DeviceInfo, error)) {
+	devices, err := list()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	fmt.Printf("%s:\n", title)
+	if len(devices) == 0 {
+		fmt.Println("  (none found)")
+		return
+	}
+	fmt.Printf("  %-5s %-8s %-9s %-7s %s\n", "INDEX", "DEFAULT", "CHANNELS", "RATE", "NAME")
+	for _, d := range devices {
+		isDefault, channels, rate := "", "?", "?"
+		if d.IsDefault {
+			isDefault = "yes"
+		}
+		if d.Channels > 0 {
+			channels = fmt.Sprint(d.Channels)
+		}
+		if d.SampleRate > 0 {
+			rate = fmt.Sprint(d.SampleRate)
+		}
+		fmt.Printf("  %-5d %-8s %-9s %-7s %s\n", d.Index, isDefault, channels, rate, d.Name)
+	}
+	fmt.Println()
+}
 
-	// Stop capture first
-	capturer.Stop()
+// measureLatency plays test clicks and reports the speaker-to-microphone delay.
+// The median is a good starting point for --post-playback-delay-ms.
+func measureLatency(cfg *config.Config) {
+	const trials = 5
+	log.Printf("⏱️ Measuring audio loopback latency (%d trials)...", trials)
+	log.Println("   Use open speakers at normal volume; headsets usually won't leak the click.")
 
-	// Close channels
-	close(transcriptions)
+	delays, err := audio.MeasureLatency(cfg.SampleRate, cfg.AudioBufferMs, trials)
+	if err != nil {
+		log.Fatalf("Latency measurement failed: %v", err)
+	}
 
-	// Wait for goroutines to finish
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
+	slices.Sort(delays)
+	median := delays[len(delays)/2]
+	log.Printf("✅ Loopback latency: median %d ms (min %d ms, max %d ms, %d/%d trials)",
+		median.Milliseconds(), delays[0].Milliseconds(), delays[len(delays)-1].Milliseconds(), len(delays), trials)
+}
 
-	select {
-	case <-done:
-		log.Println("✅ Shutdown complete")
-	case <-time.After(5 * time.Second):
-		log.Println("⚠️ Shutdown timeout, forcing exit")
+// logWriter returns where logs go: stdout, unless the JSON event stream or the
+// audio of --output sink:stdout is written there, in which case stderr.
+func logWriter(cfg *config.Config) io.Writer {
+	if cfg.JSONEvents || cfg.Output == "sink:stdout" {
+		return os.Stderr
 	}
+	return os.Stdout
 }
 
 func init() {
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+)
+
+func TestLogsNeverShareStdoutWithTheSink(t *testing.T) {
+	for _, output := range []string{"sink:stdout", "sink:pipe:/tmp/assistant.pcm"} {
+		cfg := config.DefaultConfig()
+		cfg.Output = output
+		sink, err := audio.ParseSink(cfg.Output, cfg.OutputFormat)
+		if err != nil {
+			t.Fatalf("ParseSink(%q): %v", output, err)
+		}
+		toStdout := sink.String() == "stdout"
+		if toStdout && logWriter(cfg) == os.Stdout {
+			t.Errorf("--output %s: logs are written to the sink's stdout", output)
+		}
+		if !toStdout && logWriter(cfg) != os.Stdout {
+			t.Errorf("--output %s: logs moved off stdout for no reason", output)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.JSONEvents = true
+	if logWriter(cfg) == os.Stdout {
+		t.Error("--json-events: logs are written to the event stream's stdout")
+	}
+}
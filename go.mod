@@ -10,6 +10,8 @@ require (
 	github.com/k2-fsa/sherpa-onnx-go-linux v1.13.2
 	github.com/k2-fsa/sherpa-onnx-go-macos v1.13.2
 	github.com/ollama/ollama v0.24.0
+	golang.org/x/sys v0.45.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -19,6 +21,4 @@ require (
 	github.com/mailru/easyjson v0.9.2 // indirect
 	github.com/wk8/go-ordered-map/v2 v2.1.8 // indirect
 	golang.org/x/crypto v0.52.0 // indirect
-	golang.org/x/sys v0.45.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,81 @@
+package audio
+
+import "math"
+
+// AGC tuning. Capture delivers fixed ~32ms chunks, so the per-chunk smoothing
+// factors below amount to time constants of roughly 50ms (attack) and 600ms
+// (release).
+const (
+	// agcAttack is the fraction of the way the gain moves toward its target
+	// per chunk when the input gets louder, so sudden loud speech is tamed
+	// within a chunk or two.
+	agcAttack = 0.5
+
+	// agcRelease is the same fraction when the input gets quieter; the gain
+	// rises slowly so it does not pump up between words.
+	agcRelease = 0.05
+
+	// agcMaxGainDB caps the boost. Chunks too quiet to reach the target with
+	// it are treated as silence, for which the gain is held.
+	agcMaxGainDB = 30.0
+
+	// agcMinGainDB caps the cut for input far louder than the target.
+	agcMinGainDB = -20.0
+)
+
+// AGC is an automatic gain control that scales captured audio toward a target
+// RMS level, so a quiet microphone still gives speech recognition a usable
+// signal. The gain follows the level of each chunk, quickly when it must come
+// down and slowly when it may go up, and is bounded by agcMaxGainDB. It only
+// goes up during speech, and is held for chunks too quiet to reach the target
+// (silence), so room noise is not amplified. It is not safe for concurrent use.
+type AGC struct {
+	targetDB  float64 // Desired RMS level, in dBFS
+	silenceDB float64 // Level below which the gain is held, in dBFS
+	gainDB    float64 // Current gain, in dB
+}
+
+// NewAGC returns an automatic gain control that normalizes audio toward
+// targetDBFS (e.g. -20), starting at unity gain.
+func NewAGC(targetDBFS float64) *AGC {
+	return &AGC{targetDB: targetDBFS, silenceDB: targetDBFS - agcMaxGainDB}
+}
+
+// Process applies the gain to samples in place and returns them. speech tells
+// whether the chunk is part of speech (e.g. from the VAD): without it the gain
+// may come down but is not raised. The gain is ramped from its previous value
+// across the chunk to avoid clicks, and the result is limited to ±1.
+func (a *AGC) Process(samples []float32, speech bool) []float32 {
+	if len(samples) == 0 {
+		return samples
+	}
+	prev := a.gainDB
+	if level := RMSLevel(samples); level >= a.silenceDB {
+		want := min(max(a.targetDB-level, agcMinGainDB), agcMaxGainDB)
+		rate := agcRelease
+		if want < a.gainDB {
+			rate = agcAttack
+		}
+		if want < a.gainDB || speech {
+			a.gainDB += (want - a.gainDB) * rate
+		}
+	}
+
+	from, to := dbToGain(prev), dbToGain(a.gainDB)
+	step := (to - from) / float64(len(samples))
+	for i, s := range samples {
+		g := from + step*float64(i+1)
+		samples[i] = float32(min(max(float64(s)*g, -1), 1))
+	}
+	return samples
+}
+
+// GainDB returns the gain currently applied, in dB.
+func (a *AGC) GainDB() float64 {
+	return a.gainDB
+}
+
+// dbToGain converts a gain in dB to a linear amplitude factor.
+func dbToGain(db float64) float64 {
+	return math.Pow(10, db/20)
+}
@@ -0,0 +1,74 @@
+package audio
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// agcChunk returns a 512-sample sine at amplitude.
+func agcChunk(amplitude float64) []float32 {
+	chunk := make([]float32, 512)
+	for i := range chunk {
+		chunk[i] = float32(amplitude * math.Sin(2*math.Pi*float64(i)/32))
+	}
+	return chunk
+}
+
+func TestAGCBoostsQuietSpeechTowardTarget(t *testing.T) {
+	a := NewAGC(-20)
+	quiet := 0.01 * math.Sqrt2 // -40 dBFS RMS
+
+	var out []float32
+	for range 200 {
+		out = a.Process(agcChunk(quiet), true)
+	}
+	if level := RMSLevel(out); math.Abs(level+20) > 0.5 {
+		t.Errorf("output level = %.1f dBFS, want about -20", level)
+	}
+
+	// A sudden loud chunk is brought down within a few chunks, never clipping
+	// past full scale.
+	for range 5 {
+		out = a.Process(agcChunk(0.9), false) // Coming down needs no speech
+		for _, s := range out {
+			if s > 1 || s < -1 {
+				t.Fatalf("sample %v beyond full scale", s)
+			}
+		}
+	}
+	if level := RMSLevel(out); level > -15 {
+		t.Errorf("output level after loud input = %.1f dBFS, want near -20", level)
+	}
+}
+
+func TestAGCHoldsGainDuringSilenceAndNoise(t *testing.T) {
+	a := NewAGC(-20)
+	for range 500 {
+		a.Process(agcChunk(0.001*math.Sqrt2), true) // -60 dBFS: below target minus the cap
+	}
+	if got := a.GainDB(); got != 0 {
+		t.Errorf("gain after silence = %.1f dB, want 0 (held)", got)
+	}
+
+	for range 500 {
+		a.Process(agcChunk(0.01*math.Sqrt2), false) // -40 dBFS room noise, no speech
+	}
+	if got := a.GainDB(); got != 0 {
+		t.Errorf("gain after noise without speech = %.1f dB, want 0 (held)", got)
+	}
+
+	for range 500 {
+		a.Process(agcChunk(0.004*math.Sqrt2), true) // -48 dBFS speech
+	}
+	if got := a.GainDB(); got > agcMaxGainDB || got < 27.9 {
+		t.Errorf("gain = %.1f dB, want about 28 (within the %.0f dB cap)", got, agcMaxGainDB)
+	}
+
+	held := a.GainDB()
+	silence := make([]float32, 512)
+	a.Process(silence, true)
+	if a.GainDB() != held || !slices.Equal(silence, make([]float32, 512)) {
+		t.Error("digital silence changed the gain or came out non-zero")
+	}
+}
@@ -0,0 +1,122 @@
+package audio
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// MemoryBudget accounts for the audio buffers held across the assistant (the
+// playback ring, cached responses, recordings, ...) and, when their total
+// exceeds a limit, asks the owners of reclaimable buffers to free them instead
+// of letting memory grow until the process is killed. Owners report usage
+// through a [MemoryAccount] and free memory at their next safe point when
+// [MemoryAccount.Reclaim] says so, so the budget itself never touches their
+// buffers and never blocks. It is safe for concurrent use, but must not be
+// used from an audio callback.
+type MemoryBudget struct {
+	limit int64 // Bytes (0 = unlimited, usage is only tracked)
+
+	mu       sync.Mutex
+	accounts []*MemoryAccount // Reclaim order: earlier accounts free memory first
+	peak     int64
+	reclaims uint64
+}
+
+// MemoryAccount is one owner's share of a [MemoryBudget]. A nil account is
+// valid and ignores every call, so owners need not check whether accounting
+// is enabled.
+type MemoryAccount struct {
+	budget      *MemoryBudget
+	name        string
+	reclaimable bool
+	used        atomic.Int64
+	reclaim     atomic.Bool // Set when the owner should free what it can
+}
+
+// MemoryStats is a snapshot of a [MemoryBudget].
+type MemoryStats struct {
+	Limit    int64            `json:"limit_bytes"` // 0 = unlimited
+	Used     int64            `json:"used_bytes"`
+	Peak     int64            `json:"peak_bytes"`
+	Reclaims uint64           `json:"reclaims"` // Times an owner was asked to free memory
+	Accounts map[string]int64 `json:"accounts"` // Bytes used per account name
+}
+
+// NewMemoryBudget returns a budget capping audio buffers at limit bytes
+// (0 = no cap).
+func NewMemoryBudget(limit int64) *MemoryBudget {
+	return &MemoryBudget{limit: max(limit, 0)}
+}
+
+// Register adds an account named name. Reclaimable accounts are asked to free
+// memory when the budget is exceeded, in registration order; the others (e.g.
+// fixed-size buffers) only count toward usage. A nil budget returns a nil
+// account.
+func (b *MemoryBudget) Register(name string, reclaimable bool) *MemoryAccount {
+	if b == nil {
+		return nil
+	}
+	a := &MemoryAccount{budget: b, name: name, reclaimable: reclaimable}
+	b.mu.Lock()
+	b.accounts = append(b.accounts, a)
+	b.mu.Unlock()
+	return a
+}
+
+// Stats returns the current usage.
+func (b *MemoryBudget) Stats() MemoryStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := MemoryStats{Limit: b.limit, Peak: b.peak, Reclaims: b.reclaims, Accounts: make(map[string]int64, len(b.accounts))}
+	for _, a := range b.accounts {
+		used := a.used.Load()
+		stats.Used += used
+		stats.Accounts[a.name] += used
+	}
+	return stats
+}
+
+// Set records that the account now holds bytes. If that takes the budget over
+// its limit, reclaimable accounts are flagged, in registration order, until
+// the memory they hold covers the excess.
+func (a *MemoryAccount) Set(bytes int64) {
+	if a == nil {
+		return
+	}
+	a.used.Store(bytes)
+
+	b := a.budget
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var total int64
+	for _, acct := range b.accounts {
+		total += acct.used.Load()
+	}
+	b.peak = max(b.peak, total)
+	if b.limit == 0 || total <= b.limit {
+		return
+	}
+	excess := total - b.limit
+	for _, acct := range b.accounts {
+		if excess <= 0 {
+			break
+		}
+		if used := acct.used.Load(); acct.reclaimable && used > 0 {
+			if !acct.reclaim.Swap(true) {
+				b.reclaims++
+			}
+			excess -= used
+		}
+	}
+}
+
+// Reclaim reports whether the owner should free what it can (and then report
+// its new usage with Set), clearing the request.
+func (a *MemoryAccount) Reclaim() bool {
+	return a != nil && a.reclaim.Swap(false)
+}
+
+// SampleBytes returns the memory held by n float32 samples.
+func SampleBytes(n int) int64 {
+	return int64(n) * 4
+}
@@ -0,0 +1,63 @@
+package audio
+
+import "testing"
+
+func TestMemoryBudgetReclaimsInOrder(t *testing.T) {
+	b := NewMemoryBudget(1000)
+	ring := b.Register("ring", false)
+	cache := b.Register("cache", true)
+	recording := b.Register("recording", true)
+
+	ring.Set(600)
+	cache.Set(300)
+	recording.Set(50)
+	if cache.Reclaim() || recording.Reclaim() {
+		t.Fatal("reclaim requested while within budget")
+	}
+
+	// 1200 bytes: freeing the cache (300) covers the 200 excess.
+	recording.Set(300)
+	if !cache.Reclaim() {
+		t.Error("cache not asked to free memory")
+	}
+	if recording.Reclaim() {
+		t.Error("recording asked to free memory although the cache covers the excess")
+	}
+	if ring.Reclaim() {
+		t.Error("non-reclaimable account asked to free memory")
+	}
+	if cache.Reclaim() {
+		t.Error("reclaim request not cleared")
+	}
+
+	// Still 1200 once the cache is empty: now the recording must go.
+	cache.Set(0)
+	recording.Set(600)
+	if !recording.Reclaim() {
+		t.Error("recording not asked to free memory")
+	}
+
+	stats := b.Stats()
+	if stats.Used != 1200 || stats.Peak != 1200 || stats.Reclaims != 2 || stats.Accounts["cache"] != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestMemoryBudgetUnlimitedAndNil(t *testing.T) {
+	b := NewMemoryBudget(0)
+	a := b.Register("cache", true)
+	a.Set(1 << 40)
+	if a.Reclaim() {
+		t.Error("reclaim requested without a limit")
+	}
+	if got := b.Stats().Used; got != 1<<40 {
+		t.Errorf("Used = %d, want usage tracked without a limit", got)
+	}
+
+	var none *MemoryBudget
+	acct := none.Register("cache", true)
+	acct.Set(100) // Must not panic
+	if acct.Reclaim() {
+		t.Error("nil account requested a reclaim")
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unsafe"
 
 	"github.com/gen2brain/malgo"
 )
@@ -20,11 +22,26 @@ const (
 	// This size balances memory usage with sufficient buffering for VAD processing.
 	ringBufferSize = 128
 
-	// maxSamplesPerChunk is the maximum samples per audio callback chunk.
-	// This limit prevents excessive memory allocation in the audio callback path.
-	maxSamplesPerChunk = 2048
+	// capturePeriodMs is the requested capture callback period in milliseconds.
+	capturePeriodMs = 32
+
+	// minSamplesPerChunk is the lower bound for per-chunk buffer sizes, matching
+	// the historical fixed size (32ms at 48kHz with headroom).
+	minSamplesPerChunk = 2048
+
+	// tapQueueSize is the number of chunks buffered per tap (~1s at 32ms chunks).
+	// A tap that falls further behind loses chunks instead of stalling capture.
+	tapQueueSize = 32
 )
 
+// chunkSamples returns the buffer size (in samples) needed to hold one capture
+// callback at the given device rate and period. Backends may deliver somewhat
+// more frames than requested, so 50% headroom is added on top of the nominal size.
+func chunkSamples(deviceSampleRate, periodMs uint32) int {
+	nominal := int((uint64(deviceSampleRate)*uint64(periodMs) + 999) / 1000)
+	return max(minSamplesPerChunk, nominal+nominal/2)
+}
+
 // audioChunk represents a chunk of audio samples in the ring buffer.
 type audioChunk struct {
 	samples []float32 // Pre-allocated buffer for audio samples
@@ -40,11 +57,11 @@ type ringBuffer struct {
 	dropCount atomic.Uint64              // Number of dropped chunks due to overflow
 }
 
-// newRingBuffer creates a new ring buffer with pre-allocated chunks.
-func newRingBuffer() *ringBuffer {
+// newRingBuffer creates a new ring buffer with chunks pre-allocated to chunkSize samples.
+func newRingBuffer(chunkSize int) *ringBuffer {
 	rb := &ringBuffer{}
 	for i := range rb.chunks {
-		rb.chunks[i].samples = make([]float32, maxSamplesPerChunk)
+		rb.chunks[i].samples = make([]float32, chunkSize)
 	}
 	return rb
 }
@@ -95,18 +112,41 @@ func (rb *ringBuffer) pop() []float32 {
 type Capturer struct {
 	ctx              *malgo.AllocatedContext // Malgo audio context
 	device           *malgo.Device           // Audio input device
+	deviceID         *DeviceID               // Device to open (nil = system default)
 	sampleRate       uint32                  // Target sample rate (e.g., 16kHz for STT)
 	deviceSampleRate uint32                  // Actual device sample rate
+	format           sampleFormat            // Format negotiated with the device
 	onSamples        func(samples []float32) // Callback for processed samples
 	running          atomic.Bool             // Flag for pause/resume (temporary)
+	held             atomic.Bool             // Capture paused until released, regardless of Resume
+	active           atomic.Bool             // The device is open and started (see Active)
 	ringBuf          *ringBuffer             // Lock-free buffer for audio callback
+	pool             *samplePool             // Callback conversion buffers sized for the device
 	stopChan         chan struct{}           // Channel to signal shutdown
+	loopStop         chan struct{}           // Closed to end the current processLoop on Restart
+	loopDone         chan struct{}           // Closed when the current processLoop has exited
+	lifecycle        sync.Mutex              // Serializes Restart and Stop (never taken in the callback)
 	wg               sync.WaitGroup          // Wait group for goroutine cleanup
 	resampler        *PolyphaseResampler     // Resampler for downsampling with anti-aliasing
+	taps             atomic.Pointer[[]*tap]  // Registered taps (copy-on-write, read lock-free)
+	deadMic          *deadMicDetector        // Sustained-silence detection (process loop only)
+	signalLost       atomic.Bool             // Input has been all zeros for the dead-mic window
+	clip             *clipDetector           // Clipping detection (process loop only)
+	clipRatio        atomic.Uint32           // math.Float32bits of the last measured clip ratio
+	gate             *DirectionGate          // Optional direction estimate (fed from the callback)
 }
 
-// NewCapturer creates a new audio capturer with ring buffer for backpressure.
-func NewCapturer(sampleRate int, onSamples func(samples []float32)) (*Capturer, error) {
+// tap is a registered observer of captured audio with its own delivery queue.
+type tap struct {
+	fn      func(samples []float32)
+	queue   chan []float32
+	dropped atomic.Uint64
+}
+
+// NewCapturer creates a new audio capturer with ring buffer for backpressure,
+// recording from device, or the system default microphone when device is nil
+// (see [ListCaptureDevices]).
+func NewCapturer(sampleRate int, device *DeviceID, onSamples func(samples []float32)) (*Capturer, error) {
 	ctx, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize audio context: %w", err)
@@ -115,59 +155,200 @@ func NewCapturer(sampleRate int, onSamples func(samples []float32)) (*Capturer,
 	c := &Capturer{
 		ctx:        ctx,
 		sampleRate: uint32(sampleRate),
+		deviceID:   device,
 		onSamples:  onSamples,
-		ringBuf:    newRingBuffer(),
 		stopChan:   make(chan struct{}),
 	}
 
 	return c, nil
 }
 
-// Start begins audio capture from the default microphone.
+// SetDeadMicWindow enables a warning when the microphone delivers nothing but
+// (near-)zero samples for window, which usually means it is muted at the OS
+// level or disconnected. The warning is logged once and repeated only after the
+// signal has come back. A window <= 0 disables detection (the default). It must
+// be called before [Capturer.Start].
+func (c *Capturer) SetDeadMicWindow(window time.Duration) {
+	c.deadMic = newDeadMicDetector(window, c.sampleRate)
+}
+
+// SetClipThreshold enables a warning, logged at most every 30s, when more than
+// threshold (a fraction, e.g. 0.01) of the samples in a one-second window are
+// at or beyond ±0.99, which means the input gain is too high. A threshold <= 0
+// disables detection (the default). It must be called before Start.
+func (c *Capturer) SetClipThreshold(threshold float32) {
+	c.clip = newClipDetector(threshold, c.sampleRate)
+}
+
+// ClipRatio returns the share of clipped samples in the last measured
+// one-second window, or 0 when clipping detection is disabled.
+func (c *Capturer) ClipRatio() float32 {
+	return math.Float32frombits(c.clipRatio.Load())
+}
+
+// SetDirectionGate feeds captured stereo audio to gate so it can tell whether
+// speech comes from in front of a two-microphone array. The device is then opened
+// in stereo when possible; the rest of the pipeline still receives mono. It must
+// be called before [Capturer.Start].
+func (c *Capturer) SetDirectionGate(gate *DirectionGate) {
+	c.gate = gate
+}
+
+// SignalPresent reports whether the microphone is delivering a signal, i.e. it
+// has not been silent for the whole dead-mic window. It is always true when
+// detection is disabled.
+func (c *Capturer) SignalPresent() bool {
+	return !c.signalLost.Load()
+}
+
+// checkClipping feeds samples, as the device delivered them, to the clipping
+// detector and logs what it finds. Resampled audio would be measured on
+// interpolated samples, which can hide clipping or invent it.
+func (c *Capturer) checkClipping(samples []float32) {
+	if c.clip == nil {
+		return
+	}
+	if ratio, measured, warn := c.clip.observe(samples, time.Now()); measured {
+		c.clipRatio.Store(math.Float32bits(ratio))
+		if warn {
+			log.Printf("⚠️ Microphone input is clipping (%.1f%% of samples), reduce the input gain", ratio*100)
+		}
+	}
+}
+
+// checkSignal feeds samples to the dead-mic detector and logs what it finds.
+func (c *Capturer) checkSignal(samples []float32) {
+	if c.deadMic == nil {
+		return
+	}
+	switch lost, restored := c.deadMic.observe(samples); {
+	case lost:
+		c.signalLost.Store(true)
+		log.Println("⚠️ Microphone appears silent — check it's not muted")
+	case restored:
+		c.signalLost.Store(false)
+		log.Println("🎙️ Microphone signal restored")
+	}
+}
+
+// Start begins audio capture from the microphone.
 // Audio is buffered in a ring buffer and processed by a dedicated goroutine
 // to avoid blocking the audio callback.
 func (c *Capturer) Start() error {
+	if err := c.initDevice(); err != nil {
+		return err
+	}
+	c.running.Store(true)
+
+	// Start the consumer goroutine that drains the ring buffer
+	c.startLoop()
+
+	if err := c.device.Start(); err != nil {
+		return fmt.Errorf("failed to start capture device: %w", err)
+	}
+	c.active.Store(true)
+
+	return nil
+}
+
+// Restart reopens the capture device, e.g. after it was disconnected and
+// reconnected. The device's sample rate is queried again and the resampler
+// rebuilt, since a reconnected device may come back at a different native rate
+// (a Bluetooth headset at 44.1kHz instead of 48kHz). Pause and hold states are
+// kept. It must not be called after [Capturer.Stop].
+func (c *Capturer) Restart() error {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+
+	c.active.Store(false)
+	if c.loopStop != nil {
+		close(c.loopStop)
+		<-c.loopDone
+		c.loopStop = nil
+	}
+	c.closeDevice()
+
+	if err := c.initDevice(); err != nil {
+		return err
+	}
+	c.startLoop()
+	if err := c.device.Start(); err != nil {
+		return fmt.Errorf("failed to start capture device: %w", err)
+	}
+	c.active.Store(true)
+	log.Println("🎙️ Capture device restarted")
+	return nil
+}
+
+// initDevice opens the capture device, adapting the format, buffers and
+// resampler to the rate it actually runs at. The device is not started.
+func (c *Capturer) initDevice() error {
 	deviceConfig := malgo.DefaultDeviceConfig(malgo.Capture)
-	deviceConfig.Capture.Format = malgo.FormatF32
-	deviceConfig.Capture.Channels = 1
+	if c.deviceID != nil {
+		deviceConfig.Capture.DeviceID = devicePointer(c.deviceID.id)
+	}
 
 	// Try to use the target sample rate, but device may use a different rate
 	deviceConfig.SampleRate = c.sampleRate
-	deviceConfig.PeriodSizeInMilliseconds = 32 // Low latency: 32ms chunks
+	deviceConfig.PeriodSizeInMilliseconds = capturePeriodMs // Low latency: 32ms chunks
 
-	// Query actual device sample rate (may differ from requested)
-	tempDevice, err := malgo.InitDevice(c.ctx.Context, deviceConfig, malgo.DeviceCallbacks{})
+	// Query actual device sample rate (may differ from requested) and negotiate
+	// the sample format, preferring mono float32
+	formats := deviceFormats
+	if c.gate != nil {
+		formats = preferredFormats(2) // The direction estimate needs both channels
+	}
+	tempDevice, format, err := openDevice(c.ctx.Context, deviceConfig, malgo.DeviceCallbacks{}, formats)
 	if err != nil {
 		return fmt.Errorf("failed to query capture device: %w", err)
 	}
-	c.deviceSampleRate = tempDevice.SampleRate()
+	deviceRate := tempDevice.SampleRate()
+	c.format = format
 	tempDevice.Uninit()
-
-	// Create resampler if device rate differs from target rate
-	if c.deviceSampleRate != c.sampleRate {
-		if c.deviceSampleRate > c.sampleRate {
-			// Downsampling: use polyphase filter to prevent aliasing
-			c.resampler = NewPolyphaseResampler(int(c.deviceSampleRate), int(c.sampleRate))
-			log.Printf("🔄 Audio resampling: %d Hz -> %d Hz (polyphase anti-aliasing)", c.deviceSampleRate, c.sampleRate)
-		} else {
-			// Upsampling: will use linear interpolation in processLoop
-			log.Printf("🔄 Audio resampling: %d Hz -> %d Hz (linear interpolation)", c.deviceSampleRate, c.sampleRate)
+	deviceConfig.Capture.Format = format.format
+	deviceConfig.Capture.Channels = format.channels
+
+	// Size callback buffers for the actual device rate so high-rate interfaces
+	// (e.g. 96kHz) neither reallocate in the callback nor truncate chunks.
+	bufSize := chunkSamples(deviceRate, capturePeriodMs)
+	c.pool = newSamplePool(bufSize)
+	ringBuf := newRingBuffer(bufSize)
+	if c.ringBuf != nil {
+		ringBuf.dropCount.Store(c.ringBuf.dropCount.Load()) // Keep counting across restarts
+	}
+	c.ringBuf = ringBuf
+	log.Printf("🎙️ Capture device: %d Hz, %d-sample buffers", deviceRate, bufSize)
+
+	c.configureRate(deviceRate)
+	if c.gate != nil {
+		c.gate.prepare(deviceRate, bufSize, format.channels == 2)
+		if format.channels != 2 {
+			log.Println("⚠️ Capture device is not stereo, direction gate disabled")
 		}
 	}
 
 	// Audio callback - runs in audio thread, must be fast and non-blocking
 	onRecvFrames := func(pOutputSample, pInputSamples []byte, framecount uint32) {
-		if !c.running.Load() {
+		if !c.running.Load() || c.held.Load() {
 			return
 		}
+		if c.gate != nil {
+			c.gate.observe(pInputSamples, int(framecount), c.format)
+		}
 
-		// Convert byte buffer to float32 samples (uses pooled buffer)
-		pooledSamples := bytesToFloat32(pInputSamples)
+		// Convert byte buffer to mono float32 samples (uses pooled buffer)
+		var pooledSamples []float32
+		if c.format == monoFloat32 {
+			pooledSamples = bytesToFloat32(pInputSamples, c.pool)
+		} else {
+			pooledSamples = c.pool.get(int(framecount))
+			decodeFrames(pooledSamples, pInputSamples, c.format)
+		}
 		if len(pooledSamples) > 0 {
 			// Push to ring buffer (lock-free, never blocks)
 			c.ringBuf.push(pooledSamples)
 		}
-		returnFloat32Buffer(pooledSamples)
+		c.pool.put(pooledSamples)
 	}
 
 	callbacks := malgo.DeviceCallbacks{
@@ -180,34 +361,65 @@ func (c *Capturer) Start() error {
 	}
 
 	c.device = device
-	c.running.Store(true)
+	return nil
+}
 
-	// Start the consumer goroutine that drains the ring buffer
-	c.wg.Add(1)
-	go c.processLoop()
+// configureRate adopts deviceRate as the capture device's rate and rebuilds the
+// resampler for it, so a device that comes back at a different rate after a
+// restart does not pitch-shift the audio sent to the VAD.
+func (c *Capturer) configureRate(deviceRate uint32) {
+	if c.deviceSampleRate != 0 && c.deviceSampleRate != deviceRate {
+		log.Printf("🔄 Capture device rate changed: %d Hz -> %d Hz", c.deviceSampleRate, deviceRate)
+	}
+	c.deviceSampleRate = deviceRate
+	c.resampler = nil
+	if c.clip != nil {
+		c.clip = newClipDetector(c.clip.threshold, deviceRate) // Windows are counted in device samples
+	}
 
-	if err := device.Start(); err != nil {
-		return fmt.Errorf("failed to start capture device: %w", err)
+	// Create resampler if device rate differs from target rate
+	if deviceRate != c.sampleRate {
+		if deviceRate > c.sampleRate {
+			// Downsampling: use polyphase filter to prevent aliasing
+			c.resampler = NewPolyphaseResampler(int(deviceRate), int(c.sampleRate))
+			log.Printf("🔄 Audio resampling: %d Hz -> %d Hz (polyphase anti-aliasing)", deviceRate, c.sampleRate)
+		} else {
+			// Upsampling: will use linear interpolation in processLoop
+			log.Printf("🔄 Audio resampling: %d Hz -> %d Hz (linear interpolation)", deviceRate, c.sampleRate)
+		}
 	}
+}
 
-	return nil
+// startLoop runs processLoop until the capturer is stopped or restarted.
+func (c *Capturer) startLoop() {
+	stop, done := make(chan struct{}), make(chan struct{})
+	c.loopStop, c.loopDone = stop, done
+	c.wg.Add(1)
+	go func() {
+		defer close(done)
+		c.processLoop()
+	}()
 }
 
 // processLoop drains the ring buffer and calls onSamples.
 // This runs in a dedicated goroutine, separate from the audio callback.
 func (c *Capturer) processLoop() {
 	defer c.wg.Done()
+	restart := c.loopStop // nil (never ready) when the loop is driven directly by tests
 
 	for {
 		select {
 		case <-c.stopChan:
 			return
+		case <-restart:
+			return
 		default:
 			samples := c.ringBuf.pop()
 			if samples != nil && c.onSamples != nil && c.running.Load() {
 				// Make a copy since the ring buffer slot will be reused
 				samplesCopy := make([]float32, len(samples))
 				copy(samplesCopy, samples)
+				c.checkClipping(samplesCopy)
 
 				// Apply resampling if needed
 				if c.resampler != nil {
@@ -217,6 +429,8 @@ func (c *Capturer) processLoop() {
 					samplesCopy = ResampleInPlace(samplesCopy, int(c.deviceSampleRate), int(c.sampleRate))
 				}
 
+				c.checkSignal(samplesCopy)
+				c.dispatchTaps(samplesCopy)
 				c.onSamples(samplesCopy)
 			} else {
 				// No samples available, sleep briefly to avoid busy-spinning
@@ -224,6 +438,8 @@ func (c *Capturer) processLoop() {
 				select {
 				case <-c.stopChan:
 					return
+				case <-restart:
+					return
 				case <-time.After(100 * time.Microsecond):
 					// Continue checking for samples
 				}
@@ -232,9 +448,67 @@ func (c *Capturer) processLoop() {
 	}
 }
 
+// AddTap registers fn to receive a copy of every captured chunk after resampling
+// (i.e. at the target sample rate), alongside the primary onSamples callback.
+// Multiple taps may be registered, before or after [Capturer.Start].
+//
+// Each tap runs on its own goroutine fed by a bounded queue, so a slow tap never
+// stalls capture or the VAD; if it falls more than ~1s behind, chunks are dropped
+// for that tap only. Taps stop when the capturer is stopped.
+func (c *Capturer) AddTap(fn func(samples []float32)) {
+	t := &tap{fn: fn, queue: make(chan []float32, tapQueueSize)}
+
+	for {
+		old := c.taps.Load()
+		var taps []*tap
+		if old != nil {
+			taps = append(taps, *old...)
+		}
+		taps = append(taps, t)
+		if c.taps.CompareAndSwap(old, &taps) {
+			break
+		}
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			select {
+			case <-c.stopChan:
+				if n := t.dropped.Load(); n > 0 {
+					log.Printf("⚠️ Audio tap dropped %d chunk(s) because it was too slow", n)
+				}
+				return
+			case samples := <-t.queue:
+				t.fn(samples)
+			}
+		}
+	}()
+}
+
+// dispatchTaps hands each registered tap its own copy of samples without blocking.
+func (c *Capturer) dispatchTaps(samples []float32) {
+	taps := c.taps.Load()
+	if taps == nil {
+		return
+	}
+	for _, t := range *taps {
+		select {
+		case t.queue <- slices.Clone(samples):
+		default:
+			t.dropped.Add(1)
+		}
+	}
+}
+
 // Stop halts audio capture.
 func (c *Capturer) Stop() {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+
 	c.running.Store(false)
+	c.active.Store(false)
 
 	// Signal the process loop to stop
 	select {
@@ -247,6 +521,11 @@ func (c *Capturer) Stop() {
 	// Wait for process loop to finish
 	c.wg.Wait()
 
+	c.closeDevice()
+}
+
+// closeDevice stops and releases the capture device, if open.
+func (c *Capturer) closeDevice() {
 	if c.device != nil {
 		c.device.Stop()
 		c.device.Uninit()
@@ -264,6 +543,20 @@ func (c *Capturer) Resume() {
 	c.running.Store(true)
 }
 
+// Active reports whether the capture device is open and started: true from a
+// successful [Capturer.Start] or [Capturer.Restart] until [Capturer.Stop] or a
+// failed restart. Pausing and holding capture don't change it.
+func (c *Capturer) Active() bool {
+	return c.active.Load()
+}
+
+// SetHold pauses (or releases) capture independently of [Capturer.Pause] and
+// [Capturer.Resume], so an external hold is not undone when playback resumes
+// the microphone in wait mode.
+func (c *Capturer) SetHold(held bool) {
+	c.held.Store(held)
+}
+
 // Close releases all audio resources.
 func (c *Capturer) Close() {
 	c.Stop()
@@ -274,42 +567,101 @@ func (c *Capturer) Close() {
 	}
 }
 
-// float32Pool reduces allocations in the audio callback hot path.
-// Buffers are sized for typical 32ms audio chunks at various sample rates.
-var float32Pool = sync.Pool{
-	New: func() interface{} {
-		// Pre-allocate for 32ms at 48kHz (1536 samples) with headroom
-		buf := make([]float32, 2048)
-		return &buf
-	},
+// DroppedChunks returns how many captured chunks were dropped because the ring
+// buffer was full, i.e. the process loop could not keep up with the device.
+// The count survives [Capturer.Restart].
+func (c *Capturer) DroppedChunks() uint64 {
+	if c.ringBuf == nil {
+		return 0
+	}
+	return c.ringBuf.dropCount.Load()
 }
 
-// bytesToFloat32 converts raw bytes to float32 samples.
-// The returned slice is only valid until the next call - caller must copy if needed.
-func bytesToFloat32(data []byte) []float32 {
-	numSamples := len(data) / 4
-	pBuf := float32Pool.Get().(*[]float32)
+// BufferSamples returns the per-chunk buffer size (in samples) derived from the
+// device sample rate and capture period. It is zero until [Capturer.Start] runs.
+func (c *Capturer) BufferSamples() int {
+	if c.pool == nil {
+		return 0
+	}
+	return c.pool.size
+}
+
+// samplePool reduces allocations in the audio callback hot path.
+// Buffers are sized for one capture period at the device's actual sample rate.
+type samplePool struct {
+	pool sync.Pool
+	size int // Initial capacity of pooled buffers, in samples
+}
 
-	// Ensure buffer is large enough
-	if cap(*pBuf) < numSamples {
-		*pBuf = make([]float32, numSamples)
+// newSamplePool creates a pool whose buffers hold at least size samples.
+func newSamplePool(size int) *samplePool {
+	p := &samplePool{size: size}
+	p.pool.New = func() any {
+		buf := make([]float32, size)
+		return &buf
 	}
-	samples := (*pBuf)[:numSamples]
+	return p
+}
 
-	for i := range samples {
-		bits := binary.LittleEndian.Uint32(data[i*4:])
-		samples[i] = math.Float32frombits(bits)
+// get returns a buffer of length n, growing the pooled buffer if it is too small.
+func (p *samplePool) get(n int) []float32 {
+	pBuf := p.pool.Get().(*[]float32)
+	if cap(*pBuf) < n {
+		*pBuf = make([]float32, n)
 	}
-	return samples
+	return (*pBuf)[:n]
 }
 
-// returnFloat32Buffer returns a buffer to the pool.
-// Must be called after the samples from bytesToFloat32 are no longer needed.
-func returnFloat32Buffer(samples []float32) {
-	if samples == nil {
+// put returns a buffer obtained from get to the pool.
+// Must be called after the samples are no longer needed. Buffers that grew past
+// the initial size are kept so an oversized callback only reallocates once.
+func (p *samplePool) put(samples []float32) {
+	if samples == nil || cap(samples) < p.size {
 		return
 	}
-	// Get the underlying array and return to pool
 	buf := samples[:cap(samples)]
-	float32Pool.Put(&buf)
+	p.pool.Put(&buf)
+}
+
+// nativeLittleEndian reports whether the host stores multi-byte values little-endian
+// (true on amd64, arm64, and every platform this project targets).
+var nativeLittleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// bytesToFloat32 converts raw bytes to float32 samples using a buffer from pool.
+// The returned slice is only valid until it is handed back with pool.put.
+//
+// On little-endian hosts the device bytes already have the in-memory layout of
+// []float32, so they are block-copied into the (always aligned) pooled buffer.
+// Copying bytes rather than reinterpreting the device buffer in place sidesteps
+// alignment requirements on the source; the per-sample decode is only used on
+// big-endian hosts.
+func bytesToFloat32(data []byte, pool *samplePool) []float32 {
+	numSamples := len(data) / 4
+	samples := pool.get(numSamples)
+	if numSamples == 0 {
+		return samples
+	}
+
+	if nativeLittleEndian {
+		// Safe: samples has exactly numSamples*4 bytes of backing storage and
+		// float32 has no invalid bit patterns.
+		dst := unsafe.Slice((*byte)(unsafe.Pointer(&samples[0])), numSamples*4)
+		copy(dst, data)
+		return samples
+	}
+
+	decodeFloat32LE(samples, data)
+	return samples
+}
+
+// decodeFloat32LE decodes little-endian float32 samples from data into samples,
+// one sample at a time. Portable fallback for [bytesToFloat32].
+func decodeFloat32LE(samples []float32, data []byte) {
+	for i := range samples {
+		bits := binary.LittleEndian.Uint32(data[i*4:])
+		samples[i] = math.Float32frombits(bits)
+	}
 }
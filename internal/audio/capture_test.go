@@ -0,0 +1,350 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestChunkSamplesScalesWithDeviceRate validates pool sizing for common device rates.
+func TestChunkSamplesScalesWithDeviceRate(t *testing.T) {
+	tests := []struct {
+		rate uint32
+		want int
+	}{
+		{16000, minSamplesPerChunk}, // 512 nominal, floor applies
+		{48000, 2304},               // 1536 + 50%
+		{96000, 4608},               // 3072 + 50%
+		{192000, 9216},              // 6144 + 50%
+	}
+	for _, tt := range tests {
+		if got := chunkSamples(tt.rate, capturePeriodMs); got != tt.want {
+			t.Errorf("chunkSamples(%d) = %d, want %d", tt.rate, got, tt.want)
+		}
+	}
+}
+
+// TestSamplePoolGrowsOversizedRequests validates that requests larger than the
+// pool size still succeed and that grown buffers are reused.
+func TestSamplePoolGrowsOversizedRequests(t *testing.T) {
+	p := newSamplePool(128)
+
+	buf := p.get(512)
+	if len(buf) != 512 {
+		t.Fatalf("len = %d, want 512", len(buf))
+	}
+	p.put(buf)
+
+	buf = p.get(64)
+	if len(buf) != 64 {
+		t.Errorf("len = %d, want 64", len(buf))
+	}
+	if cap(buf) < p.size {
+		t.Errorf("cap = %d, want at least pool size %d", cap(buf), p.size)
+	}
+}
+
+// TestSamplePoolDropsUndersizedBuffers ensures foreign small buffers never enter the pool.
+func TestSamplePoolDropsUndersizedBuffers(t *testing.T) {
+	p := newSamplePool(256)
+	p.put(make([]float32, 16))
+	p.put(nil)
+
+	if buf := p.get(10); cap(buf) < 256 {
+		t.Errorf("cap = %d, pool returned an undersized buffer", cap(buf))
+	}
+}
+
+// TestBytesToFloat32DecodesLittleEndian validates sample conversion.
+func TestBytesToFloat32DecodesLittleEndian(t *testing.T) {
+	want := []float32{0, 0.5, -1, 0.25}
+	data := make([]byte, len(want)*4)
+	for i, v := range want {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+
+	got := bytesToFloat32(data, newSamplePool(8))
+	if len(got) != len(want) {
+		t.Fatalf("len = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sample %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestBytesToFloat32MatchesPortableDecode validates the fast path against the
+// per-sample decoder, including an odd trailing byte count.
+func TestBytesToFloat32MatchesPortableDecode(t *testing.T) {
+	data := make([]byte, 4*1000+3)
+	for i := 0; i < 1000; i++ {
+		v := float32(math.Sin(float64(i) * 0.01))
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+
+	got := bytesToFloat32(data, newSamplePool(2048))
+	want := make([]float32, 1000)
+	decodeFloat32LE(want, data)
+
+	if len(got) != len(want) {
+		t.Fatalf("len = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sample %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+// benchmarkCallbackBytes is one 32ms callback at 48kHz.
+var benchmarkCallbackBytes = make([]byte, 1536*4)
+
+func BenchmarkBytesToFloat32(b *testing.B) {
+	pool := newSamplePool(2048)
+	b.SetBytes(int64(len(benchmarkCallbackBytes)))
+	for b.Loop() {
+		pool.put(bytesToFloat32(benchmarkCallbackBytes, pool))
+	}
+}
+
+func BenchmarkDecodeFloat32LE(b *testing.B) {
+	samples := make([]float32, len(benchmarkCallbackBytes)/4)
+	b.SetBytes(int64(len(benchmarkCallbackBytes)))
+	for b.Loop() {
+		decodeFloat32LE(samples, benchmarkCallbackBytes)
+	}
+}
+
+// newTestCapturer returns a capturer with no device whose process loop can be
+// driven by pushing directly into its ring buffer.
+func newTestCapturer(onSamples func([]float32)) *Capturer {
+	c := &Capturer{
+		sampleRate:       16000,
+		deviceSampleRate: 16000,
+		onSamples:        onSamples,
+		ringBuf:          newRingBuffer(minSamplesPerChunk),
+		stopChan:         make(chan struct{}),
+	}
+	c.running.Store(true)
+	return c
+}
+
+// TestCapturerTapsReceiveIndependentCopies validates that every tap sees every
+// chunk and that a tap mutating its slice does not affect others.
+func TestCapturerTapsReceiveIndependentCopies(t *testing.T) {
+	c := newTestCapturer(func([]float32) {})
+
+	first := make(chan []float32, 4)
+	second := make(chan []float32, 4)
+	c.AddTap(func(s []float32) {
+		s[0] = 99
+		first <- s
+	})
+	c.AddTap(func(s []float32) { second <- s })
+
+	c.wg.Add(1)
+	go c.processLoop()
+	defer c.Stop()
+
+	c.ringBuf.push([]float32{0.1, 0.2, 0.3})
+
+	for name, ch := range map[string]chan []float32{"first": first, "second": second} {
+		select {
+		case got := <-ch:
+			if len(got) != 3 {
+				t.Errorf("%s tap got %d samples, want 3", name, len(got))
+			}
+			if name == "second" && got[0] != 0.1 {
+				t.Errorf("second tap saw mutation from first tap: %v", got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s tap received nothing", name)
+		}
+	}
+}
+
+// TestCapturerSlowTapDoesNotStallCapture validates that a blocked tap drops chunks
+// while the primary callback keeps receiving audio.
+func TestCapturerSlowTapDoesNotStallCapture(t *testing.T) {
+	var delivered atomic.Int32
+	c := newTestCapturer(func([]float32) { delivered.Add(1) })
+
+	release := make(chan struct{})
+	c.AddTap(func([]float32) { <-release })
+
+	c.wg.Add(1)
+	go c.processLoop()
+
+	const chunks = tapQueueSize * 3
+	for i := 0; i < chunks; i++ {
+		for !c.ringBuf.push([]float32{float32(i)}) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for delivered.Load() < chunks && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	c.Stop()
+
+	if got := delivered.Load(); got != chunks {
+		t.Errorf("primary callback received %d chunks, want %d", got, chunks)
+	}
+	taps := *c.taps.Load()
+	if taps[0].dropped.Load() == 0 {
+		t.Error("slow tap should have dropped chunks")
+	}
+}
+
+// TestCapturerRebuildsResamplerOnRateChange validates that reopening a device at
+// a different native rate replaces the resampler instead of reusing the old one,
+// which would pitch-shift everything sent to the VAD.
+func TestCapturerRebuildsResamplerOnRateChange(t *testing.T) {
+	c := newTestCapturer(nil)
+
+	c.configureRate(48000)
+	if c.resampler == nil || c.resampler.fromRate != 48000 {
+		t.Fatalf("resampler = %+v, want one from 48000 Hz", c.resampler)
+	}
+
+	c.configureRate(44100) // Reconnected device came back at 44.1kHz
+	if c.deviceSampleRate != 44100 || c.resampler == nil || c.resampler.fromRate != 44100 {
+		t.Fatalf("after rate change: device %d Hz, resampler %+v; want 44100 Hz", c.deviceSampleRate, c.resampler)
+	}
+	// One second at the new device rate must still yield one second at 16kHz.
+	if got := len(c.resampler.Resample(make([]float32, 44100))); got < 15990 || got > 16010 {
+		t.Errorf("resampled 1s to %d samples, want ~16000", got)
+	}
+
+	c.configureRate(16000)
+	if c.resampler != nil {
+		t.Error("resampler kept although the device now runs at the target rate")
+	}
+}
+
+// TestCapturerDetectsDeadMic validates that sustained zero input flips
+// SignalPresent once and that real audio restores it.
+func TestCapturerDetectsDeadMic(t *testing.T) {
+	c := newTestCapturer(nil)
+	c.SetDeadMicWindow(100 * time.Millisecond) // 1600 samples at 16kHz
+
+	silence := make([]float32, 512)
+	for range 3 {
+		c.checkSignal(silence)
+	}
+	if !c.SignalPresent() {
+		t.Fatal("signal lost before the window elapsed")
+	}
+	c.checkSignal(silence)
+	if c.SignalPresent() {
+		t.Fatal("signal still present after the window of silence")
+	}
+
+	speech := make([]float32, 512)
+	speech[100] = 0.01
+	c.checkSignal(speech)
+	if !c.SignalPresent() {
+		t.Error("signal not restored after audio resumed")
+	}
+
+	// Detection starts over after recovery.
+	for range 3 {
+		c.checkSignal(silence)
+	}
+	if !c.SignalPresent() {
+		t.Error("signal lost again before a full window of silence")
+	}
+}
+
+// TestDeadMicDetectorReportsTransitionsOnce validates that lost/restored are
+// each reported only on the transition, so the warning is not repeated.
+func TestDeadMicDetectorReportsTransitionsOnce(t *testing.T) {
+	d := newDeadMicDetector(time.Second, 1000)
+	silence := make([]float32, 600)
+
+	var lostCount int
+	for range 5 {
+		if lost, _ := d.observe(silence); lost {
+			lostCount++
+		}
+	}
+	if lostCount != 1 {
+		t.Errorf("lost reported %d times, want 1", lostCount)
+	}
+	if _, restored := d.observe([]float32{0.5}); !restored {
+		t.Error("restore not reported")
+	}
+	if _, restored := d.observe([]float32{0.5}); restored {
+		t.Error("restore reported twice")
+	}
+
+	if lost, _ := newDeadMicDetector(0, 1000).observe(make([]float32, 1e5)); lost {
+		t.Error("disabled detector reported a dead mic")
+	}
+}
+
+func TestCapturerReportsClipRatio(t *testing.T) {
+	c := newTestCapturer(nil)
+	c.SetClipThreshold(0.01)
+
+	chunk := make([]float32, 1600) // 10 chunks per one-second window at 16kHz
+	for i := range 80 {
+		chunk[i] = 1 // 5% clipped
+	}
+	for range 9 {
+		c.checkClipping(chunk)
+	}
+	if got := c.ClipRatio(); got != 0 {
+		t.Fatalf("ClipRatio = %v before a full window, want 0", got)
+	}
+	c.checkClipping(chunk)
+	if got := c.ClipRatio(); got < 0.049 || got > 0.051 {
+		t.Errorf("ClipRatio = %v, want 0.05", got)
+	}
+
+	// Clipping is measured before resampling, in windows of device samples.
+	c.configureRate(48000)
+	if c.clip.window != 48000 || c.clip.threshold != 0.01 {
+		t.Errorf("clip window at 48kHz = %d samples (threshold %v), want 48000 (0.01)", c.clip.window, c.clip.threshold)
+	}
+}
+
+// TestClipDetectorRateLimitsWarnings validates that a clipping input is reported
+// at most once per clipWarnInterval.
+func TestClipDetectorRateLimitsWarnings(t *testing.T) {
+	d := newClipDetector(0.01, 100) // 100-sample windows
+	window := make([]float32, 100)
+	window[0], window[1], window[2] = 1, -1, 0.995 // 3% clipped
+	t0 := time.Now()
+
+	if _, measured, _ := d.observe(window[:50], t0); measured {
+		t.Fatal("measured before a full window")
+	}
+	if ratio, measured, warn := d.observe(window[50:], t0); !measured || ratio != 0.03 || !warn {
+		t.Errorf("first window = (%v, %v, %v), want (0.03, true, true)", ratio, measured, warn)
+	}
+	if _, _, warn := d.observe(window, t0.Add(clipWarnInterval/2)); warn {
+		t.Error("warned again within the interval")
+	}
+	if _, _, warn := d.observe(window, t0.Add(clipWarnInterval)); !warn {
+		t.Error("no warning once the interval had passed")
+	}
+	if _, measured, _ := newClipDetector(0, 100).observe(window, t0); measured {
+		t.Error("disabled detector measured")
+	}
+}
+
+func TestCapturerCountsDroppedChunks(t *testing.T) {
+	c := newTestCapturer(nil)
+	for range ringBufferSize + 3 {
+		c.ringBuf.push([]float32{0.1})
+	}
+	if got := c.DroppedChunks(); got != 3 {
+		t.Errorf("DroppedChunks = %d, want 3", got)
+	}
+}
@@ -0,0 +1,35 @@
+// Package audio provides the built-in response chime.
+package audio
+
+import "math"
+
+// Response chime shape: two short rising notes, distinct from speech and the
+// latency-probe click.
+const (
+	chimeNoteSeconds = 0.09
+	chimeGapSeconds  = 0.03
+	chimeAmplitude   = 0.35
+)
+
+// chimeNotes are the note frequencies in Hz (E5 then A5).
+var chimeNotes = [...]float64{659.25, 880.0}
+
+// GenerateChime returns the built-in response chime at sampleRate: two
+// Hann-enveloped sine notes separated by a short gap, about 0.2 s in total.
+func GenerateChime(sampleRate int) []float32 {
+	noteLen := int(chimeNoteSeconds * float64(sampleRate))
+	gapLen := int(chimeGapSeconds * float64(sampleRate))
+	chime := make([]float32, 0, len(chimeNotes)*noteLen+gapLen)
+
+	for n, freq := range chimeNotes {
+		if n > 0 {
+			chime = append(chime, make([]float32, gapLen)...)
+		}
+		for i := range noteLen {
+			t := float64(i) / float64(sampleRate)
+			window := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(noteLen-1))
+			chime = append(chime, float32(chimeAmplitude*window*math.Sin(2*math.Pi*freq*t)))
+		}
+	}
+	return chime
+}
@@ -0,0 +1,29 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGenerateChime(t *testing.T) {
+	const rate = 24000
+	chime := GenerateChime(rate)
+
+	want := int((2*chimeNoteSeconds + chimeGapSeconds) * rate)
+	if math.Abs(float64(len(chime)-want)) > 2 {
+		t.Errorf("len = %d, want ~%d", len(chime), want)
+	}
+
+	var peak float32
+	for _, s := range chime {
+		peak = max(peak, float32(math.Abs(float64(s))))
+	}
+	if peak == 0 || peak > chimeAmplitude {
+		t.Errorf("peak = %v, want in (0, %v]", peak, chimeAmplitude)
+	}
+
+	// Enveloped notes start and end silently so the chime doesn't click.
+	if chime[0] != 0 || math.Abs(float64(chime[len(chime)-1])) > 1e-3 {
+		t.Errorf("chime edges = %v, %v, want silence", chime[0], chime[len(chime)-1])
+	}
+}
@@ -0,0 +1,62 @@
+package audio
+
+import "time"
+
+// Clipping detection tuning constants.
+const (
+	// clipLevel is the absolute sample value at or beyond which a sample
+	// counts as clipped.
+	clipLevel = 0.99
+
+	// clipWindow is how much audio each clip ratio is measured over.
+	clipWindow = time.Second
+
+	// clipWarnInterval is the minimum time between two clipping warnings.
+	clipWarnInterval = 30 * time.Second
+)
+
+// clipDetector measures the share of clipped samples per clipWindow. It is
+// only used from the capture process loop, never from the audio callback.
+type clipDetector struct {
+	window    int       // Samples per measurement
+	threshold float32   // Clip ratio above which a warning is due (<= 0 = disabled)
+	seen      int       // Samples seen in the current window
+	clipped   int       // Clipped samples in the current window
+	lastWarn  time.Time // When a warning was last reported
+}
+
+// newClipDetector returns a detector warning when more than threshold of the
+// samples in a window clip. A threshold <= 0 disables it.
+func newClipDetector(threshold float32, sampleRate uint32) *clipDetector {
+	return &clipDetector{
+		window:    max(int(clipWindow.Seconds()*float64(sampleRate)), 1),
+		threshold: threshold,
+	}
+}
+
+// observe accounts for samples received at now. When they complete a window,
+// it returns that window's clip ratio with measured set, and warn set if the
+// ratio exceeds the threshold and no warning was reported in the last
+// clipWarnInterval.
+func (d *clipDetector) observe(samples []float32, now time.Time) (ratio float32, measured, warn bool) {
+	if d.threshold <= 0 {
+		return 0, false, false
+	}
+	for _, s := range samples {
+		if s >= clipLevel || s <= -clipLevel {
+			d.clipped++
+		}
+	}
+	d.seen += len(samples)
+	if d.seen < d.window {
+		return 0, false, false
+	}
+
+	ratio = float32(d.clipped) / float32(d.seen)
+	d.seen, d.clipped = 0, 0
+	if ratio > d.threshold && now.Sub(d.lastWarn) >= clipWarnInterval {
+		d.lastWarn = now
+		return ratio, true, true
+	}
+	return ratio, true, false
+}
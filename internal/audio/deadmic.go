@@ -0,0 +1,46 @@
+package audio
+
+import "time"
+
+// deadMicLevel is the peak amplitude below which a chunk counts as silent
+// (about -100 dBFS). Real microphones always pick up some noise above this,
+// so only muted or dead inputs stay under it for long.
+const deadMicLevel = 1e-5
+
+// deadMicDetector tracks how long captured audio has been (near-)zero. It is
+// only used from the capture process loop, never from the audio callback.
+type deadMicDetector struct {
+	window     int  // Silent samples that mark the input as dead (0 = disabled)
+	silent     int  // Consecutive silent samples seen so far
+	signalLost bool // Whether the window has been exceeded without recovery
+}
+
+// newDeadMicDetector returns a detector that reports a dead input after window
+// of continuous silence at sampleRate. A window <= 0 disables detection.
+func newDeadMicDetector(window time.Duration, sampleRate uint32) *deadMicDetector {
+	return &deadMicDetector{window: int(window.Seconds() * float64(sampleRate))}
+}
+
+// observe accounts for samples and reports whether the input just went silent
+// (lost) or just recovered after having gone silent (restored).
+func (d *deadMicDetector) observe(samples []float32) (lost, restored bool) {
+	if d.window <= 0 || len(samples) == 0 {
+		return false, false
+	}
+	for _, s := range samples {
+		if s > deadMicLevel || s < -deadMicLevel {
+			d.silent = 0
+			if d.signalLost {
+				d.signalLost = false
+				return false, true
+			}
+			return false, false
+		}
+	}
+	d.silent += len(samples)
+	if !d.signalLost && d.silent >= d.window {
+		d.signalLost = true
+		return true, false
+	}
+	return false, false
+}
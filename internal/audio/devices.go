@@ -0,0 +1,190 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/gen2brain/malgo"
+)
+
+// DefaultDeviceName selects the system default device in a device list.
+const DefaultDeviceName = "default"
+
+// DeviceID identifies an audio device for [NewCapturer] and [NewPlayer]. It is opaque and only
+// meaningful on the machine that listed it.
+type DeviceID struct{ id malgo.DeviceID }
+
+// String returns the identifier in hexadecimal, for logs.
+func (d DeviceID) String() string { return d.id.String() }
+
+// devicePointers holds the C copy of each device ID opened so far. malgo's
+// DeviceID.Pointer allocates a new copy that is never freed, so each device
+// gets one for the life of the process rather than one per (re)open.
+var (
+	devicePointersMu sync.Mutex
+	devicePointers   = make(map[malgo.DeviceID]unsafe.Pointer)
+)
+
+// devicePointer returns the C copy of id for a malgo device configuration.
+func devicePointer(id malgo.DeviceID) unsafe.Pointer {
+	devicePointersMu.Lock()
+	defer devicePointersMu.Unlock()
+	ptr, ok := devicePointers[id]
+	if !ok {
+		ptr = id.Pointer()
+		devicePointers[id] = ptr
+	}
+	return ptr
+}
+
+// DeviceInfo describes an audio device as listed by [ListCaptureDevices] or
+// [ListPlaybackDevices].
+type DeviceInfo struct {
+	Index      int // Position in the list, as accepted by SelectDevice
+	Name       string
+	ID         DeviceID
+	Channels   int  // Most channels the device offers (0 if unknown)
+	SampleRate int  // Native sample rate in Hz (0 if unknown)
+	IsDefault  bool // The system default device
+}
+
+// ListCaptureDevices returns the capture devices (microphones), in the order
+// their indices refer to.
+func ListCaptureDevices() ([]DeviceInfo, error) {
+	return listDevices(malgo.Capture)
+}
+
+// ListPlaybackDevices returns the playback devices (speakers), in the order
+// their indices refer to.
+func ListPlaybackDevices() ([]DeviceInfo, error) {
+	return listDevices(malgo.Playback)
+}
+
+// listDevices enumerates the devices of kind with their channel counts and
+// native rates.
+func listDevices(kind malgo.DeviceType) ([]DeviceInfo, error) {
+	ctx, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize audio context: %w", err)
+	}
+	defer func() {
+		_ = ctx.Uninit()
+		ctx.Free()
+	}()
+
+	infos, err := ctx.Devices(kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audio devices: %w", err)
+	}
+	devices := make([]DeviceInfo, len(infos))
+	for i := range infos {
+		devices[i] = DeviceInfo{
+			Index:     i,
+			Name:      infos[i].Name(),
+			ID:        DeviceID{infos[i].ID},
+			IsDefault: infos[i].IsDefault != 0,
+		}
+		channels, rate := nativeFormat(ctx.Context, kind, infos[i].ID)
+		devices[i].Channels, devices[i].SampleRate = channels, int(rate)
+	}
+	return devices, nil
+}
+
+// nativeFormat returns the most channels a device offers and its native sample
+// rate (the first one it reports), or zeros when the device cannot be queried.
+// Enumeration leaves the formats out, so this takes a query per device.
+func nativeFormat(ctx malgo.Context, kind malgo.DeviceType, id malgo.DeviceID) (channels int, rate uint32) {
+	info, err := ctx.DeviceInfo(kind, id, malgo.Shared)
+	if err != nil {
+		return 0, 0
+	}
+	for _, f := range info.Formats {
+		channels = max(channels, int(f.Channels))
+		if rate == 0 {
+			rate = f.SampleRate
+		}
+	}
+	return channels, rate
+}
+
+// SelectDevice returns the device spec names in devices: its index,
+// [DefaultDeviceName] for the system default, or a case-insensitive substring
+// of its name (the first device that matches). When none does, the error lists
+// the devices to choose from.
+func SelectDevice(devices []DeviceInfo, spec string) (DeviceInfo, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.EqualFold(spec, DefaultDeviceName) {
+		for _, d := range devices {
+			if d.IsDefault {
+				return d, nil
+			}
+		}
+	} else if index, err := strconv.Atoi(spec); err == nil {
+		if index >= 0 && index < len(devices) {
+			return devices[index], nil
+		}
+	} else {
+		names := make([]string, len(devices))
+		for i, d := range devices {
+			names[i] = d.Name
+		}
+		if _, device := pickDevice(names, []string{spec}); device >= 0 {
+			return devices[device], nil
+		}
+	}
+
+	if len(devices) == 0 {
+		return DeviceInfo{}, fmt.Errorf("audio device %q not found: no devices available", spec)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "audio device %q not found; available devices:", spec)
+	for _, d := range devices {
+		fmt.Fprintf(&b, "\n  %d: %s", d.Index, d.Name)
+		if d.IsDefault {
+			b.WriteString(" (default)")
+		}
+	}
+	return DeviceInfo{}, errors.New(b.String())
+}
+
+// pickDevice returns the position in wanted of the first entry that matches a
+// device in available, and that device's index. Entries match names
+// case-insensitively by substring, so "bt-speaker" finds "BT-Speaker (A2DP)";
+// [DefaultDeviceName] always matches, with device index -1. When nothing
+// matches, choice is len(wanted) and device is -1.
+func pickDevice(available, wanted []string) (choice, device int) {
+	for i, w := range wanted {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w == DefaultDeviceName {
+			return i, -1
+		}
+		for j, name := range available {
+			if strings.Contains(strings.ToLower(name), w) {
+				return i, j
+			}
+		}
+	}
+	return len(wanted), -1
+}
+
+// findDevice enumerates devices of kind and picks the first of wanted that is
+// present (see pickDevice). info is nil when the system default should be used.
+func findDevice(ctx malgo.Context, kind malgo.DeviceType, wanted []string) (info *malgo.DeviceInfo, choice int, err error) {
+	infos, err := ctx.Devices(kind)
+	if err != nil {
+		return nil, len(wanted), fmt.Errorf("failed to list audio devices: %w", err)
+	}
+	names := make([]string, len(infos))
+	for i := range infos {
+		names[i] = infos[i].Name()
+	}
+	choice, device := pickDevice(names, wanted)
+	if device < 0 {
+		return nil, choice, nil
+	}
+	return &infos[device], choice, nil
+}
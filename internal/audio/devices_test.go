@@ -0,0 +1,54 @@
+package audio
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPickDevice(t *testing.T) {
+	available := []string{"MacBook Pro Speakers", "BT-Speaker (A2DP)"}
+	tests := []struct {
+		name         string
+		wanted       []string
+		choice, want int
+	}{
+		{"first present", []string{"bt-speaker", "macbook"}, 0, 1},
+		{"falls back", []string{"headphones", "MacBook"}, 1, 0},
+		{"explicit default", []string{"headphones", "default", "macbook"}, 1, -1},
+		{"none present", []string{"headphones"}, 1, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			choice, device := pickDevice(available, tt.wanted)
+			if choice != tt.choice || device != tt.want {
+				t.Errorf("pickDevice = (%d, %d), want (%d, %d)", choice, device, tt.choice, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectDevice(t *testing.T) {
+	devices := []DeviceInfo{
+		{Index: 0, Name: "Built-in Microphone", IsDefault: true},
+		{Index: 1, Name: "ReSpeaker 4 Mic Array (UAC1.0)"},
+	}
+	for spec, want := range map[string]int{"1": 1, "0": 0, "respeaker": 1, " Built-in ": 0, "Default": 0} {
+		got, err := SelectDevice(devices, spec)
+		if err != nil || got.Index != want {
+			t.Errorf("SelectDevice(%q) = %d, %v, want device %d", spec, got.Index, err, want)
+		}
+	}
+
+	_, err := SelectDevice(devices, "usb")
+	if err == nil {
+		t.Fatal("SelectDevice(\"usb\") succeeded, want not found")
+	}
+	for _, want := range []string{`"usb" not found`, "0: Built-in Microphone (default)", "1: ReSpeaker"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+	if _, err := SelectDevice(devices, "2"); err == nil {
+		t.Error("SelectDevice(\"2\") succeeded, want index out of range")
+	}
+}
@@ -0,0 +1,148 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/gen2brain/malgo"
+)
+
+// Direction gate tuning constants.
+const (
+	// directionVoicedRMS is the per-channel RMS below which a chunk is treated as
+	// background and does not affect the direction estimate.
+	directionVoicedRMS = 0.01
+
+	// directionSmoothing is the weight of each voiced chunk in the running share
+	// of frontal audio (~1s time constant at 32ms chunks).
+	directionSmoothing = 0.03
+)
+
+// DirectionGate estimates whether sound reaching a two-microphone array comes
+// from in front of it, so speaker bleed arriving from the sides can be kept from
+// triggering barge-in. Sound from straight ahead reaches both microphones at the
+// same time and level; sound from a side reaches the nearer one first and louder.
+//
+// The estimate is updated from the capture callback, so all state is either
+// preallocated or atomic: observe never allocates, locks or blocks.
+type DirectionGate struct {
+	maxDelay     time.Duration // Largest inter-channel delay still considered frontal
+	maxLevelDiff float64       // Largest inter-channel level difference (dB) still frontal
+
+	maxLag      int       // maxDelay in samples at the device rate (set by prepare)
+	left, right []float32 // Per-channel scratch buffers (set by prepare)
+
+	frontal atomic.Uint64 // math.Float64bits of the running share of frontal voiced audio
+	stereo  atomic.Bool   // Whether the device delivers two channels
+}
+
+// NewDirectionGate returns a gate that treats audio as frontal when the delay
+// between the two channels is at most maxDelay and their levels differ by at
+// most maxLevelDiffDB. Until voiced audio has been observed, and whenever the
+// capture device is not stereo, everything counts as frontal.
+func NewDirectionGate(maxDelay time.Duration, maxLevelDiffDB float64) *DirectionGate {
+	g := &DirectionGate{maxDelay: maxDelay, maxLevelDiff: maxLevelDiffDB}
+	g.frontal.Store(math.Float64bits(1))
+	return g
+}
+
+// prepare sizes the gate for a device running at rate with chunks of up to
+// frames frames. It must not run concurrently with observe.
+func (g *DirectionGate) prepare(rate uint32, frames int, stereo bool) {
+	g.maxLag = int(math.Ceil(g.maxDelay.Seconds() * float64(rate)))
+	g.left = make([]float32, frames)
+	g.right = make([]float32, frames)
+	g.stereo.Store(stereo)
+	g.frontal.Store(math.Float64bits(1))
+}
+
+// Allows reports whether recent voiced audio came mostly from in front, i.e.
+// whether speech heard now may interrupt playback. A nil gate allows everything.
+func (g *DirectionGate) Allows() bool {
+	if g == nil || !g.stereo.Load() {
+		return true
+	}
+	return math.Float64frombits(g.frontal.Load()) >= 0.5
+}
+
+// observe decodes one callback's worth of interleaved stereo frames and folds
+// its direction into the running estimate. Called on the audio thread.
+func (g *DirectionGate) observe(data []byte, frames int, f sampleFormat) {
+	if f.channels != 2 {
+		return
+	}
+	frames = min(frames, len(g.left), len(data)/(2*f.bytesPerSample()))
+	step := f.bytesPerSample()
+	for i := range frames {
+		g.left[i] = decodeSample(data[2*i*step:], f)
+		g.right[i] = decodeSample(data[(2*i+1)*step:], f)
+	}
+
+	frontal, voiced := g.classify(g.left[:frames], g.right[:frames])
+	if !voiced {
+		return
+	}
+	target := 0.0
+	if frontal {
+		target = 1
+	}
+	share := math.Float64frombits(g.frontal.Load())
+	g.frontal.Store(math.Float64bits(share + directionSmoothing*(target-share)))
+}
+
+// classify reports whether a voiced chunk arrives from in front: both channels
+// at similar levels, best aligned within maxLag samples of each other.
+func (g *DirectionGate) classify(left, right []float32) (frontal, voiced bool) {
+	l, r := channelRMS(left), channelRMS(right)
+	if l < directionVoicedRMS && r < directionVoicedRMS {
+		return false, false
+	}
+	if l == 0 || r == 0 || math.Abs(20*math.Log10(l/r)) > g.maxLevelDiff {
+		return false, true
+	}
+
+	// Cross-correlate over a slightly wider range than the frontal limit so a
+	// side source has somewhere to peak.
+	search := 2*g.maxLag + 1
+	best, bestLag := math.Inf(-1), 0
+	for lag := -search; lag <= search; lag++ {
+		var sum float64
+		for i := max(0, -lag); i < len(left) && i+lag < len(right); i++ {
+			sum += float64(left[i]) * float64(right[i+lag])
+		}
+		if sum > best {
+			best, bestLag = sum, lag
+		}
+	}
+	return abs(bestLag) <= g.maxLag, true
+}
+
+// decodeSample reads the first sample in data as float32.
+func decodeSample(data []byte, f sampleFormat) float32 {
+	if f.format == malgo.FormatS16 {
+		return float32(int16(binary.LittleEndian.Uint16(data))) / 32768
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(data))
+}
+
+// channelRMS returns the root mean square of samples.
+func channelRMS(samples []float32) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,96 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/gen2brain/malgo"
+)
+
+// stereoChunk interleaves left and right into float32 stereo device bytes.
+func stereoChunk(left, right []float32) []byte {
+	data := make([]byte, len(left)*8)
+	for i := range left {
+		binary.LittleEndian.PutUint32(data[i*8:], math.Float32bits(left[i]))
+		binary.LittleEndian.PutUint32(data[i*8+4:], math.Float32bits(right[i]))
+	}
+	return data
+}
+
+// sourceChunk returns a noise burst as heard by two microphones, the right one
+// delay samples later and scaled by gain.
+func sourceChunk(rng *rand.Rand, n, delay int, gain float32) []byte {
+	src := make([]float32, n+delay)
+	for i := range src {
+		src[i] = float32(rng.NormFloat64() * 0.1)
+	}
+	left := src[delay:]
+	right := make([]float32, n)
+	for i := range right {
+		right[i] = src[i] * gain
+	}
+	return stereoChunk(left, right)
+}
+
+// TestDirectionGateTracksSourceDirection validates that frontal sound allows
+// barge-in and that delayed or lopsided (side) sound blocks it after a while.
+func TestDirectionGateTracksSourceDirection(t *testing.T) {
+	format := sampleFormat{format: malgo.FormatF32, channels: 2}
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	g := NewDirectionGate(60*time.Microsecond, 6)
+	g.prepare(48000, 1536, true) // maxLag = 3 samples
+
+	feed := func(delay int, gain float32) {
+		for range 100 {
+			g.observe(sourceChunk(rng, 1536, delay, gain), 1536, format)
+		}
+	}
+
+	if !g.Allows() {
+		t.Fatal("gate blocks before any audio was heard")
+	}
+	feed(0, 1)
+	if !g.Allows() {
+		t.Error("frontal sound blocked")
+	}
+	feed(8, 1) // ~170µs: from the side
+	if g.Allows() {
+		t.Error("delayed (side) sound allowed")
+	}
+	feed(1, 1)
+	if !g.Allows() {
+		t.Error("frontal sound blocked after a side source")
+	}
+	feed(0, 0.2) // 14 dB quieter on one side
+	if g.Allows() {
+		t.Error("lopsided sound allowed")
+	}
+
+	// Silence does not move the estimate either way.
+	for range 100 {
+		g.observe(stereoChunk(make([]float32, 1536), make([]float32, 1536)), 1536, format)
+	}
+	if g.Allows() {
+		t.Error("silence changed the estimate")
+	}
+}
+
+// TestDirectionGateFailsOpen validates that a nil gate and a gate on a mono
+// device never block barge-in.
+func TestDirectionGateFailsOpen(t *testing.T) {
+	var nilGate *DirectionGate
+	if !nilGate.Allows() {
+		t.Error("nil gate blocks")
+	}
+
+	g := NewDirectionGate(60*time.Microsecond, 6)
+	g.prepare(48000, 1536, false)
+	g.frontal.Store(math.Float64bits(0))
+	if !g.Allows() {
+		t.Error("gate on a mono device blocks")
+	}
+}
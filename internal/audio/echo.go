@@ -0,0 +1,194 @@
+package audio
+
+import (
+	"math"
+	"time"
+)
+
+// Echo canceller tuning constants.
+const (
+	// echoTailMs is the span of the adaptive filter: how much delay and
+	// reverberation between the reference and its echo it can model.
+	echoTailMs = 128
+
+	// echoStep is the NLMS step size (0-2): larger adapts faster, smaller is
+	// more stable once converged.
+	echoStep = 0.3
+
+	// echoGeigel is the ratio of microphone to reference peak above which the
+	// microphone is assumed to hold the user's voice as well (double talk), and
+	// adaptation pauses so the filter does not learn to cancel speech.
+	echoGeigel = 0.7
+
+	// echoHoldMs is how long adaptation stays paused after double talk.
+	echoHoldMs = 100
+
+	// echoMaxLagMs bounds how far the reference may run ahead of the microphone
+	// before ReadEchoReference drops the backlog and starts over from now.
+	echoMaxLagMs = 1000
+)
+
+// EchoCanceller removes the assistant's own voice from the microphone signal
+// with a normalized least-mean-squares (NLMS) adaptive filter. It learns the
+// path from the played-back reference to the microphone (speaker, room and
+// both device buffers) and subtracts the estimated echo, so speech over the
+// reply can be told from the reply itself.
+//
+// It suits a fixed speaker and microphone; moving either makes it adapt again
+// for a moment. It is not safe for concurrent use.
+type EchoCanceller struct {
+	weights []float32 // Estimated echo path, oldest reference sample first
+	history []float32 // Reference samples, stored twice so each window is contiguous
+	pos     int       // Position in history of the next reference sample
+	delay   int       // Reference samples skipped before the filter window
+	energy  float64   // Sum of squares of the current filter window
+	hold    int       // Samples left with adaptation paused (double talk)
+	holdLen int       // echoHoldMs in samples
+	peak    float32   // Largest reference magnitude in the last block
+	rate    int       // Sample rate, for SetDelay
+}
+
+// NewEchoCanceller returns an echo canceller for audio at sampleRate, with a
+// filter covering echoes up to 128ms after the reference.
+func NewEchoCanceller(sampleRate int) *EchoCanceller {
+	taps := sampleRate * echoTailMs / 1000
+	e := &EchoCanceller{
+		weights: make([]float32, taps),
+		holdLen: sampleRate * echoHoldMs / 1000,
+		rate:    sampleRate,
+	}
+	e.history = make([]float32, 2*taps)
+	return e
+}
+
+// SetDelay skips the first delay of the reference before the filter window,
+// for setups where the echo arrives later than the filter covers (e.g. large
+// Bluetooth buffers). It resets the learned echo path.
+func (e *EchoCanceller) SetDelay(delay time.Duration) {
+	e.delay = max(0, int(delay.Seconds()*float64(e.rate)))
+	clear(e.weights)
+	e.history = make([]float32, 2*(len(e.weights)+e.delay))
+	e.pos, e.energy, e.hold = 0, 0, 0
+}
+
+// Process returns mic with the echo of reference removed. reference holds the
+// audio played while mic was recorded, at the same rate; missing reference
+// samples count as silence.
+func (e *EchoCanceller) Process(mic, reference []float32) []float32 {
+	out := make([]float32, len(mic))
+	taps := len(e.weights)
+	n := len(e.history) / 2
+
+	// Double-talk detection compares the microphone to the reference peak of
+	// this block and the one before, which covers most of the echo path.
+	peak := e.peak
+	e.peak = 0
+	for _, r := range reference[:min(len(reference), len(mic))] {
+		e.peak = max(e.peak, float32(math.Abs(float64(r))))
+	}
+	peak = max(peak, e.peak)
+
+	for i, m := range mic {
+		var r float32
+		if i < len(reference) {
+			r = reference[i]
+		}
+
+		// Slide the window: the sample leaving it is the one overwritten now
+		// (n samples back); the one entering is delay samples back.
+		leaving := e.history[e.pos]
+		e.history[e.pos], e.history[e.pos+n] = r, r
+		entering := e.history[e.pos+n-e.delay]
+		e.pos = (e.pos + 1) % n
+		e.energy = max(0, e.energy+float64(entering)*float64(entering)-float64(leaving)*float64(leaving))
+
+		if e.energy < 1e-9 {
+			out[i] = m // Nothing played within the window: no echo to remove
+			continue
+		}
+		window := e.history[e.pos : e.pos+taps]
+
+		var estimate float32
+		for k, x := range window {
+			estimate += e.weights[k] * x
+		}
+		residual := m - estimate
+		out[i] = residual
+
+		// Assumes the echo is quieter than the reference, as it is unless the
+		// speaker is very close to the microphone.
+		if float32(math.Abs(float64(m))) > echoGeigel*peak {
+			e.hold = e.holdLen
+		}
+		if e.hold > 0 {
+			e.hold--
+			continue
+		}
+		g := float32(echoStep * float64(residual) / (e.energy + 1e-6))
+		for k, x := range window {
+			e.weights[k] += g * x
+		}
+	}
+	return out
+}
+
+// echoReference carries the samples a Player sends to its device to the
+// goroutine that runs the EchoCanceller.
+type echoReference struct {
+	rate    int           // Rate ReadEchoReference returns samples at
+	ring    *playbackRing // Played samples at the device rate (device callback -> reader)
+	scratch []float32     // Samples of the last fill call (device callback only)
+
+	// Reader state
+	pending   []float32 // Samples converted to rate, not yet returned
+	started   bool      // pending is aligned with the microphone
+	resampler *PolyphaseResampler
+	fromRate  uint32
+}
+
+// frames returns a buffer of n samples for fill to record the output in.
+func (r *echoReference) frames(n int) []float32 {
+	if cap(r.scratch) < n {
+		r.scratch = make([]float32, n)
+	}
+	r.scratch = r.scratch[:n]
+	return r.scratch
+}
+
+// EnableEchoReference makes the player keep the samples it plays, so that
+// [Player.ReadEchoReference] can hand them to an [EchoCanceller] at rate.
+// It must be called before ReadEchoReference.
+func (p *Player) EnableEchoReference(rate int) {
+	p.echoRef.Store(&echoReference{rate: rate, ring: &playbackRing{}})
+}
+
+// ReadEchoReference fills dst with the next samples played (silence included),
+// converted to the rate given to EnableEchoReference, as the reference for the
+// microphone audio of the same length. Call it once per microphone chunk from
+// a single goroutine. The first call, and any call after the reader fell more
+// than a second behind, starts from the most recently played samples.
+func (p *Player) ReadEchoReference(dst []float32) {
+	r := p.echoRef.Load()
+	if r == nil {
+		clear(dst)
+		return
+	}
+
+	samples := r.ring.take()
+	if rate := p.deviceSampleRate.Load(); rate != uint32(r.rate) {
+		if r.resampler == nil || r.fromRate != rate {
+			r.resampler = NewPolyphaseResampler(int(rate), r.rate)
+			r.fromRate = rate
+		}
+		samples = r.resampler.Resample(samples)
+	}
+	r.pending = append(r.pending, samples...)
+
+	if !r.started || len(r.pending) > r.rate*echoMaxLagMs/1000 {
+		r.pending = r.pending[max(0, len(r.pending)-len(dst)):]
+		r.started = true
+	}
+	n := copy(dst, r.pending)
+	clear(dst[n:])
+	r.pending = append(r.pending[:0], r.pending[n:]...)
+}
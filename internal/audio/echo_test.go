@@ -0,0 +1,125 @@
+package audio
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// energy returns the sum of squares of samples.
+func energy(samples []float32) float64 {
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s) * float64(s)
+	}
+	return sum
+}
+
+// echoOf returns reference as heard through a simple room: attenuated, delayed
+// and with one reflection.
+func echoOf(reference []float32, delay int) []float32 {
+	echo := make([]float32, len(reference))
+	for i := range echo {
+		if j := i - delay; j >= 0 {
+			echo[i] += 0.4 * reference[j]
+		}
+		if j := i - delay - 300; j >= 0 {
+			echo[i] += 0.15 * reference[j]
+		}
+	}
+	return echo
+}
+
+func TestEchoCancellerRemovesEcho(t *testing.T) {
+	const rate = 16000
+	rng := rand.New(rand.NewPCG(1, 2))
+	reference := make([]float32, 4*rate)
+	for i := range reference {
+		reference[i] = float32(rng.NormFloat64() * 0.2)
+	}
+	mic := echoOf(reference, 480) // 30ms
+
+	e := NewEchoCanceller(rate)
+	var out []float32
+	for i := 0; i < len(mic); i += 512 { // Capture-sized chunks
+		end := min(i+512, len(mic))
+		out = append(out, e.Process(mic[i:end], reference[i:end])...)
+	}
+
+	last := len(mic) - rate // The final second, after convergence
+	reduction := 10 * math.Log10(energy(mic[last:])/energy(out[last:]))
+	if reduction < 20 {
+		t.Errorf("echo reduced by %.1f dB, want at least 20 dB", reduction)
+	}
+}
+
+func TestEchoCancellerUsesDelay(t *testing.T) {
+	const rate = 16000
+	rng := rand.New(rand.NewPCG(3, 4))
+	reference := make([]float32, 4*rate)
+	for i := range reference {
+		reference[i] = float32(rng.NormFloat64() * 0.2)
+	}
+	mic := echoOf(reference, 200*rate/1000) // Beyond the filter without a delay
+
+	e := NewEchoCanceller(rate)
+	e.SetDelay(150 * time.Millisecond)
+	out := e.Process(mic, reference)
+
+	last := len(mic) - rate
+	if reduction := 10 * math.Log10(energy(mic[last:])/energy(out[last:])); reduction < 20 {
+		t.Errorf("echo reduced by %.1f dB, want at least 20 dB", reduction)
+	}
+}
+
+func TestEchoCancellerPassesSpeechWithoutPlayback(t *testing.T) {
+	e := NewEchoCanceller(16000)
+	mic := make([]float32, 1024)
+	for i := range mic {
+		mic[i] = float32(math.Sin(float64(i) / 10))
+	}
+	out := e.Process(mic, make([]float32, len(mic)))
+	for i := range mic {
+		if out[i] != mic[i] {
+			t.Fatalf("sample %d changed from %v to %v with a silent reference", i, mic[i], out[i])
+		}
+	}
+}
+
+func TestReadEchoReference(t *testing.T) {
+	p := &Player{ring: &playbackRing{}, completeChan: make(chan struct{}, 1), interrupt: &atomic.Bool{}}
+	p.deviceSampleRate.Store(16000)
+	p.EnableEchoReference(16000)
+
+	// Played before the reader started: skipped, reading starts from now.
+	p.ring.push([]float32{0.9, 0.9, 0.9, 0.9})
+	p.fill(nil, 4, nil)
+	p.ring.push([]float32{0.1, 0.2, 0.3, 0.4})
+	p.fill(nil, 4, nil)
+
+	dst := make([]float32, 4)
+	p.ReadEchoReference(dst)
+	if want := []float32{0.1, 0.2, 0.3, 0.4}; !equalSamples(dst, want) {
+		t.Errorf("first read = %v, want the latest samples %v", dst, want)
+	}
+	p.ring.push([]float32{0.5, 0.6})
+	p.fill(nil, 2, nil)
+	p.ReadEchoReference(dst)
+	if want := []float32{0.5, 0.6, 0, 0}; !equalSamples(dst, want) {
+		t.Errorf("second read = %v, want %v padded with silence", dst, want)
+	}
+}
+
+func equalSamples(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
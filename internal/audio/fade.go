@@ -0,0 +1,33 @@
+package audio
+
+// ApplyFade ramps the first and last fadeMs milliseconds of buf, at
+// sampleRate, linearly from and to silence in place, so playback never starts
+// or stops on a non-zero sample (an audible click). Buffers shorter than two
+// fades are ramped over half their length each way; fadeMs <= 0 does nothing.
+func ApplyFade(buf []float32, sampleRate int, fadeMs int) {
+	n := min(fadeSamples(sampleRate, fadeMs), len(buf)/2)
+	fadeIn(buf, n)
+	fadeOut(buf, n)
+}
+
+// fadeSamples returns the length of a fadeMs fade at sampleRate, in samples.
+func fadeSamples(sampleRate int, fadeMs int) int {
+	if fadeMs <= 0 || sampleRate <= 0 {
+		return 0
+	}
+	return sampleRate * fadeMs / 1000
+}
+
+// fadeIn ramps the first n samples of buf up from silence.
+func fadeIn(buf []float32, n int) {
+	for i := range min(n, len(buf)) {
+		buf[i] *= float32(i) / float32(n)
+	}
+}
+
+// fadeOut ramps the last n samples of buf down to silence.
+func fadeOut(buf []float32, n int) {
+	for i := range min(n, len(buf)) {
+		buf[len(buf)-1-i] *= float32(i) / float32(n)
+	}
+}
@@ -0,0 +1,54 @@
+package audio
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestApplyFadeRampsBothEnds(t *testing.T) {
+	buf := make([]float32, 1000)
+	for i := range buf {
+		buf[i] = 1
+	}
+	ApplyFade(buf, 16000, 5) // 80 samples each way
+
+	if buf[0] != 0 || buf[len(buf)-1] != 0 {
+		t.Errorf("edges = %v, %v, want silence", buf[0], buf[len(buf)-1])
+	}
+	if buf[40] != 0.5 || buf[len(buf)-41] != 0.5 {
+		t.Errorf("mid-ramp = %v, %v, want 0.5", buf[40], buf[len(buf)-41])
+	}
+	for i := 80; i < len(buf)-80; i++ {
+		if buf[i] != 1 {
+			t.Fatalf("sample %d = %v, want the middle untouched", i, buf[i])
+		}
+	}
+}
+
+func TestApplyFadeShortBufferAndDisabled(t *testing.T) {
+	buf := []float32{1, 1, 1, 1}
+	ApplyFade(buf, 16000, 5)
+	if want := []float32{0, 0.5, 0.5, 0}; !slices.Equal(buf, want) {
+		t.Errorf("short buffer = %v, want %v", buf, want)
+	}
+
+	buf = []float32{1, 1, 1, 1}
+	ApplyFade(buf, 16000, 0)
+	if !slices.Equal(buf, []float32{1, 1, 1, 1}) {
+		t.Errorf("fadeMs 0 changed the buffer to %v", buf)
+	}
+}
+
+func TestPlayerFadeLeavesCallerBufferUntouched(t *testing.T) {
+	p := newTestPlayer(16000)
+	p.SetFade(5)
+	samples := []float32{1, 1, 1, 1}
+
+	got := p.withFade(AudioBuffer{Samples: samples, SampleRate: 16000})
+	if got[0] != 0 || got[3] != 0 {
+		t.Errorf("played samples = %v, want faded edges", got)
+	}
+	if !slices.Equal(samples, []float32{1, 1, 1, 1}) {
+		t.Errorf("caller's samples = %v, want them unchanged", samples)
+	}
+}
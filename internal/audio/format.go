@@ -0,0 +1,152 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/gen2brain/malgo"
+)
+
+// sampleFormat is the sample encoding and channel count a device was opened with.
+// The rest of the audio layer works in mono float32; callbacks convert to and
+// from this format when the device rejects that.
+type sampleFormat struct {
+	format   malgo.FormatType
+	channels uint32
+}
+
+// monoFloat32 is the preferred device format; no conversion is needed.
+var monoFloat32 = sampleFormat{format: malgo.FormatF32, channels: 1}
+
+// deviceFormats lists the formats tried, in order, when opening a device.
+var deviceFormats = []sampleFormat{
+	monoFloat32,
+	{format: malgo.FormatF32, channels: 2},
+	{format: malgo.FormatS16, channels: 1},
+	{format: malgo.FormatS16, channels: 2},
+}
+
+// preferredFormats returns deviceFormats reordered so formats with the requested
+// channel count are tried first. Devices that reject them still fall back to the
+// remaining formats.
+func preferredFormats(channels uint32) []sampleFormat {
+	formats := make([]sampleFormat, 0, len(deviceFormats))
+	for _, f := range deviceFormats {
+		if f.channels == channels {
+			formats = append(formats, f)
+		}
+	}
+	for _, f := range deviceFormats {
+		if f.channels != channels {
+			formats = append(formats, f)
+		}
+	}
+	return formats
+}
+
+// String returns a human-readable description, e.g. "int16 stereo".
+func (f sampleFormat) String() string {
+	name := "float32"
+	if f.format == malgo.FormatS16 {
+		name = "int16"
+	}
+	switch f.channels {
+	case 1:
+		return name + " mono"
+	case 2:
+		return name + " stereo"
+	default:
+		return fmt.Sprintf("%s %dch", name, f.channels)
+	}
+}
+
+// bytesPerSample returns the size of a single channel sample.
+func (f sampleFormat) bytesPerSample() int {
+	if f.format == malgo.FormatS16 {
+		return 2
+	}
+	return 4
+}
+
+// supported reports whether the callbacks can convert to and from f.
+func (f sampleFormat) supported() bool {
+	return (f.format == malgo.FormatF32 || f.format == malgo.FormatS16) && f.channels > 0
+}
+
+// openDevice initializes a device of cfg.DeviceType, trying each of formats in
+// order until one is accepted. It returns the format the device actually uses so the
+// data callback can convert; the callback must not run before this returns,
+// which holds because devices only call back after Start.
+func openDevice(ctx malgo.Context, cfg malgo.DeviceConfig, callbacks malgo.DeviceCallbacks, formats []sampleFormat) (*malgo.Device, sampleFormat, error) {
+	var firstErr error
+	for _, want := range formats {
+		sub := &cfg.Playback
+		if cfg.DeviceType == malgo.Capture {
+			sub = &cfg.Capture
+		}
+		sub.Format = want.format
+		sub.Channels = want.channels
+
+		device, err := malgo.InitDevice(ctx, cfg, callbacks)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		got := sampleFormat{format: device.PlaybackFormat(), channels: device.PlaybackChannels()}
+		if cfg.DeviceType == malgo.Capture {
+			got = sampleFormat{format: device.CaptureFormat(), channels: device.CaptureChannels()}
+		}
+		if !got.supported() {
+			device.Uninit()
+			if firstErr == nil {
+				firstErr = fmt.Errorf("unsupported device format %v with %d channel(s)", got.format, got.channels)
+			}
+			continue
+		}
+		if got != formats[0] {
+			log.Printf("⚠️  Audio device rejected %s, using %s with conversion", formats[0], got)
+		}
+		return device, got, nil
+	}
+	return nil, sampleFormat{}, firstErr
+}
+
+// decodeFrames converts interleaved device frames in data to mono float32 samples,
+// averaging channels. samples must hold len(data)/(bytesPerSample*channels) values.
+func decodeFrames(samples []float32, data []byte, f sampleFormat) {
+	ch := int(f.channels)
+	step := f.bytesPerSample()
+	for i := range samples {
+		var sum float32
+		for c := range ch {
+			off := (i*ch + c) * step
+			if f.format == malgo.FormatS16 {
+				sum += float32(int16(binary.LittleEndian.Uint16(data[off:]))) / 32768
+			} else {
+				sum += math.Float32frombits(binary.LittleEndian.Uint32(data[off:]))
+			}
+		}
+		samples[i] = sum / float32(ch)
+	}
+}
+
+// encodeFrame writes a mono sample as frame i of out, duplicating it to every
+// channel and converting to int16 (with clipping) when required.
+func encodeFrame(out []byte, i int, sample float32, f sampleFormat) {
+	ch := int(f.channels)
+	step := f.bytesPerSample()
+	for c := range ch {
+		off := (i*ch + c) * step
+		if f.format == malgo.FormatS16 {
+			v := max(-1, min(1, sample))
+			binary.LittleEndian.PutUint16(out[off:], uint16(int16(v*32767)))
+		} else {
+			binary.LittleEndian.PutUint32(out[off:], math.Float32bits(sample))
+		}
+	}
+}
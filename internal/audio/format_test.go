@@ -0,0 +1,76 @@
+package audio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gen2brain/malgo"
+)
+
+func TestEncodeDecodeFramesRoundTrip(t *testing.T) {
+	in := []float32{0, 0.5, -0.5, 0.25, -1}
+	for _, f := range deviceFormats {
+		t.Run(f.String(), func(t *testing.T) {
+			buf := make([]byte, len(in)*int(f.channels)*f.bytesPerSample())
+			for i, s := range in {
+				encodeFrame(buf, i, s, f)
+			}
+			out := make([]float32, len(in))
+			decodeFrames(out, buf, f)
+
+			tol := 1e-6
+			if f.format == malgo.FormatS16 {
+				tol = 1.0 / 16384
+			}
+			for i := range in {
+				if math.Abs(float64(out[i]-in[i])) > tol {
+					t.Errorf("sample %d: got %v, want %v", i, out[i], in[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeFramesAveragesStereo(t *testing.T) {
+	f := sampleFormat{format: malgo.FormatF32, channels: 2}
+	buf := make([]byte, 8)
+	encodeFrame(buf, 0, 0.5, monoFloat32)
+	encodeFrame(buf[4:], 0, -0.25, monoFloat32)
+
+	out := make([]float32, 1)
+	decodeFrames(out, buf, f)
+	if out[0] != 0.125 {
+		t.Errorf("got %v, want 0.125", out[0])
+	}
+}
+
+func TestEncodeFrameClipsInt16(t *testing.T) {
+	f := sampleFormat{format: malgo.FormatS16, channels: 1}
+	buf := make([]byte, 4)
+	encodeFrame(buf, 0, 2.0, f)
+	encodeFrame(buf, 1, -2.0, f)
+
+	out := make([]float32, 2)
+	decodeFrames(out, buf, f)
+	if out[0] < 0.999 || out[1] > -0.999 {
+		t.Errorf("got %v, want values clipped to ±1", out)
+	}
+}
+
+func TestPreferredFormatsTriesRequestedChannelsFirst(t *testing.T) {
+	got := preferredFormats(2)
+	if len(got) != len(deviceFormats) {
+		t.Fatalf("len = %d, want %d", len(got), len(deviceFormats))
+	}
+	for i, f := range got[:2] {
+		if f.channels != 2 {
+			t.Errorf("format %d = %s, want stereo first", i, f)
+		}
+	}
+	if got[0].format != malgo.FormatF32 {
+		t.Errorf("first format = %s, want float32 stereo", got[0])
+	}
+	if preferredFormats(1)[0] != monoFloat32 {
+		t.Errorf("mono preference = %s, want %s", preferredFormats(1)[0], monoFloat32)
+	}
+}
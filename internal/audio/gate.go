@@ -0,0 +1,63 @@
+package audio
+
+import (
+	"math"
+	"time"
+)
+
+// noiseGateHangover is how long the noise gate stays open after the last chunk
+// above its floor, so the quiet tail of speech (trailing consonants, a soft
+// last word) still reaches the VAD.
+const noiseGateHangover = 300 * time.Millisecond
+
+// RMSLevel returns the root-mean-square level of samples in dBFS: 0 for a
+// full-scale square wave, about -3 for a full-scale sine, and -Inf for
+// silence or no samples.
+func RMSLevel(samples []float32) float64 {
+	if len(samples) == 0 {
+		return math.Inf(-1)
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s) * float64(s)
+	}
+	return 10 * math.Log10(sum/float64(len(samples))) // 20·log10(√mean) without the root
+}
+
+// NoiseGate silences chunks of audio whose RMS level is below a floor, so
+// steady background noise such as HVAC hum never reaches the VAD. Once a chunk
+// is loud enough, the gate stays open for a short hangover. It is not safe for
+// concurrent use.
+type NoiseGate struct {
+	floorDB  float64   // Level below which chunks are silenced, in dBFS
+	hangover int       // Samples passed after the last loud chunk
+	open     int       // Hangover samples left
+	silence  []float32 // Zeros returned for gated chunks
+}
+
+// NewNoiseGate returns a noise gate for audio at sampleRate that silences
+// chunks below floorDB (e.g. -50).
+func NewNoiseGate(floorDB float64, sampleRate int) *NoiseGate {
+	return &NoiseGate{
+		floorDB:  floorDB,
+		hangover: int(noiseGateHangover.Seconds() * float64(sampleRate)),
+	}
+}
+
+// Process returns samples unchanged if they are above the floor or within the
+// hangover of the last chunk that was, and silence of the same length
+// otherwise. samples is never modified; the silence is reused by later calls.
+func (g *NoiseGate) Process(samples []float32) []float32 {
+	if RMSLevel(samples) >= g.floorDB {
+		g.open = g.hangover
+		return samples
+	}
+	if g.open > 0 {
+		g.open -= len(samples)
+		return samples
+	}
+	if cap(g.silence) < len(samples) {
+		g.silence = make([]float32, len(samples))
+	}
+	return g.silence[:len(samples)]
+}
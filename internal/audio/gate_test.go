@@ -0,0 +1,46 @@
+package audio
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestRMSLevel(t *testing.T) {
+	square := []float32{1, -1, 1, -1}
+	if got := RMSLevel(square); math.Abs(got) > 1e-9 {
+		t.Errorf("RMSLevel(full-scale square) = %v, want 0", got)
+	}
+	if got := RMSLevel([]float32{0.1, -0.1}); math.Abs(got+20) > 1e-6 {
+		t.Errorf("RMSLevel(0.1) = %v, want -20", got)
+	}
+	for _, samples := range [][]float32{nil, {0, 0}} {
+		if got := RMSLevel(samples); !math.IsInf(got, -1) {
+			t.Errorf("RMSLevel(%v) = %v, want -Inf", samples, got)
+		}
+	}
+}
+
+func TestNoiseGateSilencesQuietChunksAfterHangover(t *testing.T) {
+	g := NewNoiseGate(-40, 1000) // 300 hangover samples at 1 kHz
+	hum := slices.Repeat([]float32{0.001}, 100)
+	speech := slices.Repeat([]float32{0.5}, 100)
+
+	if out := g.Process(hum); !slices.Equal(out, make([]float32, 100)) {
+		t.Error("quiet chunk before any speech was not silenced")
+	}
+	if out := g.Process(speech); !slices.Equal(out, speech) {
+		t.Error("loud chunk was altered")
+	}
+	for i := range 3 {
+		if out := g.Process(hum); !slices.Equal(out, hum) {
+			t.Errorf("quiet chunk %d within the hangover was silenced", i)
+		}
+	}
+	if out := g.Process(hum); !slices.Equal(out, make([]float32, 100)) {
+		t.Error("quiet chunk after the hangover was not silenced")
+	}
+	if hum[0] != 0.001 {
+		t.Error("Process modified its input")
+	}
+}
@@ -0,0 +1,65 @@
+package audio
+
+import "sync"
+
+// History is a rolling window of the most recent captured samples, addressed by
+// absolute sample position so a range can be extracted after the fact (e.g. the
+// audio around a VAD segment). Feed it from a [Capturer.AddTap]; it is safe for
+// concurrent use but must not be written from the audio callback.
+type History struct {
+	mu    sync.Mutex
+	buf   []float32
+	total uint64 // Samples written since creation
+}
+
+// NewHistory creates a History retaining the last capacity samples.
+func NewHistory(capacity int) *History {
+	return &History{buf: make([]float32, max(capacity, 1))}
+}
+
+// Bytes returns the memory held by the window.
+func (h *History) Bytes() int64 {
+	return SampleBytes(len(h.buf))
+}
+
+// Write appends samples, overwriting the oldest ones once the window is full.
+func (h *History) Write(samples []float32) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	size := uint64(len(h.buf))
+	if uint64(len(samples)) > size {
+		h.total += uint64(len(samples)) - size
+		samples = samples[len(samples)-int(size):]
+	}
+	for _, s := range samples {
+		h.buf[h.total%size] = s
+		h.total++
+	}
+}
+
+// Position returns the absolute position of the next sample to be written.
+func (h *History) Position() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.total
+}
+
+// Range returns a copy of the samples in [from, to), clipped to what has been
+// written and is still retained.
+func (h *History) Range(from, to uint64) []float32 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	size := uint64(len(h.buf))
+	if h.total > size {
+		from = max(from, h.total-size)
+	}
+	to = min(to, h.total)
+	if from >= to {
+		return nil
+	}
+	out := make([]float32, 0, to-from)
+	for pos := from; pos < to; pos++ {
+		out = append(out, h.buf[pos%size])
+	}
+	return out
+}
@@ -0,0 +1,38 @@
+package audio
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHistoryRangeClipsToRetainedSamples(t *testing.T) {
+	h := NewHistory(4)
+	h.Write([]float32{1, 2, 3})
+	h.Write([]float32{4, 5})
+
+	if got := h.Position(); got != 5 {
+		t.Fatalf("Position = %d, want 5", got)
+	}
+	tests := []struct {
+		from, to uint64
+		want     []float32
+	}{
+		{0, 5, []float32{2, 3, 4, 5}}, // Sample 1 was overwritten
+		{2, 4, []float32{3, 4}},
+		{4, 10, []float32{5}}, // Not yet written
+		{5, 6, nil},
+	}
+	for _, tt := range tests {
+		if got := h.Range(tt.from, tt.to); !slices.Equal(got, tt.want) {
+			t.Errorf("Range(%d, %d) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestHistoryWriteLargerThanCapacity(t *testing.T) {
+	h := NewHistory(3)
+	h.Write([]float32{1, 2, 3, 4, 5})
+	if got := h.Range(0, h.Position()); !slices.Equal(got, []float32{3, 4, 5}) {
+		t.Errorf("Range = %v, want the last 3 samples", got)
+	}
+}
@@ -0,0 +1,143 @@
+// Package audio provides acoustic loopback latency measurement.
+package audio
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Latency measurement constants.
+const (
+	// clickDuration is the length of the probe signal.
+	clickDuration = 20 * time.Millisecond
+
+	// maxLoopbackDelay bounds the correlation search window.
+	maxLoopbackDelay = time.Second
+
+	// minCorrelation is the normalized correlation below which the click is
+	// considered not heard (e.g. a headset that doesn't leak into the mic).
+	minCorrelation = 0.3
+)
+
+// GenerateClick returns a short Hann-windowed linear chirp (500 Hz → 4 kHz) at
+// sampleRate. A chirp has a sharp autocorrelation peak, unlike a pure tone, so it
+// can be located unambiguously in a captured signal.
+func GenerateClick(sampleRate int) []float32 {
+	n := int(clickDuration.Seconds() * float64(sampleRate))
+	click := make([]float32, n)
+
+	const f0, f1 = 500.0, 4000.0
+	duration := float64(n) / float64(sampleRate)
+	for i := range click {
+		t := float64(i) / float64(sampleRate)
+		phase := 2 * math.Pi * (f0*t + (f1-f0)*t*t/(2*duration))
+		window := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+		click[i] = float32(0.8 * window * math.Sin(phase))
+	}
+	return click
+}
+
+// EstimateDelay locates reference within captured using normalized cross-correlation
+// over lags 0..maxLag. It returns the lag (in samples) with the highest correlation
+// and that correlation in [0, 1]. A low correlation means the reference was not found.
+func EstimateDelay(reference, captured []float32, maxLag int) (lag int, correlation float64) {
+	var refEnergy float64
+	for _, r := range reference {
+		refEnergy += float64(r) * float64(r)
+	}
+	if refEnergy == 0 || len(captured) < len(reference) {
+		return 0, 0
+	}
+
+	maxLag = min(maxLag, len(captured)-len(reference))
+	for l := 0; l <= maxLag; l++ {
+		var dot, capEnergy float64
+		for i, r := range reference {
+			c := float64(captured[l+i])
+			dot += float64(r) * c
+			capEnergy += c * c
+		}
+		if capEnergy == 0 {
+			continue
+		}
+		if corr := math.Abs(dot) / math.Sqrt(refEnergy*capEnergy); corr > correlation {
+			lag, correlation = l, corr
+		}
+	}
+	return lag, correlation
+}
+
+// MeasureLatency plays a click through the default output device and listens for it
+// on the default microphone, returning the speaker-to-microphone delay of each trial.
+// The delay covers the full playback and capture paths (device buffers included),
+// which is the window in which the assistant can hear its own voice.
+//
+// Trials in which the click is not detected are skipped; an error is returned if
+// none succeed.
+func MeasureLatency(sampleRate int, bufferMs uint32, trials int) ([]time.Duration, error) {
+	var (
+		mu       sync.Mutex
+		captured []float32
+	)
+	capturer, err := NewCapturer(sampleRate, nil, func(samples []float32) {
+		mu.Lock()
+		captured = append(captured, samples...)
+		mu.Unlock()
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer capturer.Close()
+
+	var noInterrupt atomic.Bool
+	player, err := NewPlayer(sampleRate, bufferMs, 1, nil, nil, &noInterrupt)
+	if err != nil {
+		return nil, err
+	}
+	defer player.Close()
+
+	if err := capturer.Start(); err != nil {
+		return nil, err
+	}
+
+	click := GenerateClick(sampleRate)
+	maxLag := int(maxLoopbackDelay.Seconds() * float64(sampleRate))
+	var delays []time.Duration
+
+	for trial := 1; trial <= trials; trial++ {
+		// Let the previous click and any device start-up transients die down.
+		time.Sleep(500 * time.Millisecond)
+
+		mu.Lock()
+		start := len(captured)
+		mu.Unlock()
+
+		if err := player.Play(AudioBuffer{Samples: click, SampleRate: sampleRate}); err != nil {
+			return nil, fmt.Errorf("playing click: %w", err)
+		}
+		time.Sleep(maxLoopbackDelay)
+
+		mu.Lock()
+		window := slices.Clone(captured[start:])
+		mu.Unlock()
+
+		lag, corr := EstimateDelay(click, window, maxLag)
+		if corr < minCorrelation {
+			log.Printf("⚠️ Trial %d: click not detected (correlation %.2f)", trial, corr)
+			continue
+		}
+		delay := time.Duration(float64(lag) / float64(sampleRate) * float64(time.Second))
+		log.Printf("⏱️ Trial %d: %d ms (correlation %.2f)", trial, delay.Milliseconds(), corr)
+		delays = append(delays, delay)
+	}
+
+	if len(delays) == 0 {
+		return nil, fmt.Errorf("click was not detected in any of %d trial(s); check speaker volume and that the mic can hear the speaker", trials)
+	}
+	return delays, nil
+}
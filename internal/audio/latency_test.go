@@ -0,0 +1,61 @@
+package audio
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestEstimateDelayFindsDelayedClick validates detection of a click buried in noise.
+func TestEstimateDelayFindsDelayedClick(t *testing.T) {
+	const sampleRate = 16000
+	click := GenerateClick(sampleRate)
+
+	rng := rand.New(rand.NewSource(1))
+	captured := make([]float32, sampleRate)
+	for i := range captured {
+		captured[i] = float32(rng.NormFloat64() * 0.02)
+	}
+
+	// Attenuated copy at 2400 samples (150ms), as if heard across a room.
+	const delay = 2400
+	for i, s := range click {
+		captured[delay+i] += 0.3 * s
+	}
+
+	lag, corr := EstimateDelay(click, captured, sampleRate)
+	if lag != delay {
+		t.Errorf("lag = %d, want %d", lag, delay)
+	}
+	if corr < minCorrelation {
+		t.Errorf("correlation = %.2f, want >= %.2f", corr, minCorrelation)
+	}
+}
+
+// TestEstimateDelayRejectsSilence ensures no false detection without the click.
+func TestEstimateDelayRejectsSilence(t *testing.T) {
+	click := GenerateClick(16000)
+
+	if _, corr := EstimateDelay(click, make([]float32, 8000), 4000); corr != 0 {
+		t.Errorf("correlation on silence = %.2f, want 0", corr)
+	}
+	if _, corr := EstimateDelay(click, click[:10], 100); corr != 0 {
+		t.Errorf("correlation on short capture = %.2f, want 0", corr)
+	}
+}
+
+// TestGenerateClickIsBoundedAndTapered validates the probe signal shape.
+func TestGenerateClickIsBoundedAndTapered(t *testing.T) {
+	click := GenerateClick(16000)
+
+	if len(click) != 320 {
+		t.Fatalf("len = %d, want 320 (20ms at 16kHz)", len(click))
+	}
+	for i, s := range click {
+		if s > 1 || s < -1 {
+			t.Fatalf("sample %d = %v out of range", i, s)
+		}
+	}
+	if click[0] != 0 || click[len(click)-1] > 1e-6 || click[len(click)-1] < -1e-6 {
+		t.Errorf("click should start and end at zero, got %v ... %v", click[0], click[len(click)-1])
+	}
+}
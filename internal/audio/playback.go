@@ -2,10 +2,10 @@
 package audio
 
 import (
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"log"
-	"math"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -21,6 +21,11 @@ const (
 	playbackRingSize = 524288
 )
 
+// ErrInterrupted is returned by [Player.Play] when playback was stopped by
+// [Player.Interrupt]. Interruptions via the external flag return nil, since
+// callers inspect that flag themselves.
+var ErrInterrupted = errors.New("playback interrupted")
+
 // AudioBuffer holds audio samples with metadata.
 type AudioBuffer struct {
 	Samples    []float32 // Audio sample data (mono, floating point)
@@ -29,9 +34,10 @@ type AudioBuffer struct {
 
 // playbackRing is a lock-free single-producer single-consumer ring buffer for audio playback.
 type playbackRing struct {
-	samples [playbackRingSize]float32
-	head    atomic.Uint64 // Write position (producer)
-	tail    atomic.Uint64 // Read position (consumer)
+	samples   [playbackRingSize]float32
+	head      atomic.Uint64 // Write position (producer)
+	tail      atomic.Uint64 // Read position (consumer)
+	overflows atomic.Uint64 // Number of pushes that did not fit and were truncated
 }
 
 // push adds samples to the ring buffer. Returns number of samples written.
@@ -43,6 +49,7 @@ func (rb *playbackRing) push(samples []float32) int {
 	toWrite := len(samples)
 	if toWrite > available {
 		toWrite = available
+		rb.overflows.Add(1)
 	}
 
 	for i := 0; i < toWrite; i++ {
@@ -80,22 +87,48 @@ func (rb *playbackRing) clear() {
 // Player handles audio playback with a persistent device and lock-free ring buffer.
 // Supports interrupt-driven playback for responsive voice interaction.
 type Player struct {
-	ctx              *malgo.AllocatedContext // Malgo audio context
-	device           *malgo.Device           // Audio output device
-	sampleRate       uint32                  // Input sample rate (e.g., TTS output rate)
-	deviceSampleRate uint32                  // Device's native sample rate
-	bufferMs         uint32                  // Buffer size in milliseconds
-	interrupt        *atomic.Bool            // Internal interrupt flag
-	externalIntr     *atomic.Bool            // External interrupt flag (e.g., when user speaks)
-	playing          atomic.Bool             // Flag indicating active playback
-	ring             *playbackRing           // Lock-free ring buffer for samples
-	mu               sync.Mutex              // Protects ring buffer writes (not callback)
-	completeChan     chan struct{}           // Channel to signal playback completion
+	ctx              *malgo.AllocatedContext       // Malgo audio context
+	device           *malgo.Device                 // Audio output device
+	sampleRate       uint32                        // Input sample rate (e.g., TTS output rate)
+	deviceSampleRate atomic.Uint32                 // Device's native sample rate (changes on Restart)
+	bufferMs         uint32                        // Buffer size in milliseconds
+	channels         uint32                        // Requested device channels (mono samples are duplicated)
+	format           sampleFormat                  // Format negotiated with the device
+	interrupt        *atomic.Bool                  // Internal interrupt flag
+	externalIntr     *atomic.Bool                  // External interrupt flag (e.g., when user speaks)
+	playing          atomic.Bool                   // Flag indicating active playback
+	muted            atomic.Bool                   // Output silence while still consuming samples
+	lastPlayedAt     atomic.Int64                  // Unix nanoseconds when the last Play call finished
+	playBegan        atomic.Int64                  // Unix nanoseconds when the current Play call queued its samples
+	bargeInGrace     atomic.Int64                  // Duration at the start of each Play that ignores externalIntr
+	fadeMs           atomic.Int32                  // Fade-in/out applied to each Play's buffer (0 = none)
+	callbacks        atomic.Uint64                 // Number of device callbacks served (consumer progress)
+	consumed         atomic.Uint64                 // Samples actually played; unlike ring.tail, not advanced by clear
+	playStart        atomic.Uint64                 // consumed value at which the current Play's samples begin
+	playLen          atomic.Uint64                 // Number of samples queued by the current Play
+	ring             *playbackRing                 // Lock-free ring buffer for samples
+	mu               sync.Mutex                    // Protects ring buffer writes (not callback)
+	prioMu           sync.Mutex                    // Protects current (taken before mu)
+	current          *playback                     // Playback owning the ring (nil when idle)
+	resamplers       map[int]*PolyphaseResampler   // Per-source-rate resamplers to resampleTo (guarded by resampleMu)
+	resampleTo       uint32                        // Device rate the cached resamplers convert to
+	resampleMu       sync.Mutex                    // Protects resamplers (never taken in the callback)
+	completeChan     chan struct{}                 // Channel to signal playback completion
+	deviceID         *DeviceID                     // Selected output device (nil = devices or system default)
+	devices          []string                      // Output device fallback chain (empty = system default)
+	deviceChoice     atomic.Int32                  // Position in devices of the device in use (len(devices) = default)
+	sink             atomic.Pointer[sinkWriter]    // Stream the output is also written to (nil = none)
+	echoRef          atomic.Pointer[echoReference] // Copy of the output for echo cancellation (nil = none)
 }
 
 // NewPlayer creates a new audio player with a persistent playback device.
 // bufferMs: audio buffer size in milliseconds (20ms for wired, 100ms for Bluetooth, 0 for default 100ms)
-func NewPlayer(sampleRate int, bufferMs uint32, externalInterrupt *atomic.Bool) (*Player, error) {
+// channels: device channel count; 2 opens the device as stereo and duplicates each
+// mono sample to both channels (0 or 1 for mono)
+// device: output device to open (see [ListPlaybackDevices]); when set, devices is ignored
+// devices: output devices to try in order when device is nil, matched by name (see
+// [Player.PreferredDeviceAvailable]); the system default is used when empty or when none is present
+func NewPlayer(sampleRate int, bufferMs uint32, channels int, device *DeviceID, devices []string, externalInterrupt *atomic.Bool) (*Player, error) {
 	ctx, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize audio context: %w", err)
@@ -105,22 +138,28 @@ func NewPlayer(sampleRate int, bufferMs uint32, externalInterrupt *atomic.Bool)
 	if bufferMs == 0 {
 		bufferMs = 100
 	}
-
-	// Query device's native sample rate once during initialization
-	deviceSampleRate := getDeviceNativeSampleRate()
-	log.Printf("🔊 Audio device sample rate: %d Hz (input: %d Hz), buffer: %d ms", deviceSampleRate, sampleRate, bufferMs)
+	if channels <= 0 {
+		channels = 1
+	}
 
 	p := &Player{
-		ctx:              ctx,
-		sampleRate:       uint32(sampleRate),
-		deviceSampleRate: deviceSampleRate,
-		bufferMs:         bufferMs,
-		externalIntr:     externalInterrupt,
-		interrupt:        &atomic.Bool{},
-		ring:             &playbackRing{},
-		completeChan:     make(chan struct{}, 1), // Buffered to prevent blocking
+		ctx:          ctx,
+		sampleRate:   uint32(sampleRate),
+		bufferMs:     bufferMs,
+		channels:     uint32(channels),
+		externalIntr: externalInterrupt,
+		interrupt:    &atomic.Bool{},
+		ring:         &playbackRing{},
+		completeChan: make(chan struct{}, 1), // Buffered to prevent blocking
+		deviceID:     device,
+		devices:      devices,
 	}
 
+	// Query device's native sample rate once during initialization
+	deviceSampleRate := p.nativeSampleRate()
+	log.Printf("🔊 Audio device sample rate: %d Hz (input: %d Hz), buffer: %d ms", deviceSampleRate, sampleRate, bufferMs)
+	p.deviceSampleRate.Store(deviceSampleRate)
+
 	// Initialize the persistent playback device
 	if err := p.initDevice(); err != nil {
 		ctx.Uninit()
@@ -131,51 +170,73 @@ func NewPlayer(sampleRate int, bufferMs uint32, externalInterrupt *atomic.Bool)
 	return p, nil
 }
 
+// NewSinkPlayer creates an audio player with no output device that streams its
+// output to sink at 48kHz instead, e.g. to feed a video call or ffmpeg. It plays,
+// resamples and interrupts exactly like a device-backed player.
+func NewSinkPlayer(sampleRate int, bufferMs uint32, sink Sink, externalInterrupt *atomic.Bool) *Player {
+	if bufferMs == 0 {
+		bufferMs = 100
+	}
+	p := &Player{
+		sampleRate:   uint32(sampleRate),
+		bufferMs:     bufferMs,
+		channels:     1,
+		externalIntr: externalInterrupt,
+		interrupt:    &atomic.Bool{},
+		ring:         &playbackRing{},
+		completeChan: make(chan struct{}, 1),
+	}
+	p.deviceSampleRate.Store(sinkSampleRate)
+	p.startSink(sink, false)
+	return p
+}
+
+// StreamTo additionally streams everything the output device plays to sink,
+// at the device's current sample rate.
+func (p *Player) StreamTo(sink Sink) {
+	p.startSink(sink, true)
+}
+
 // initDevice initializes and starts the persistent playback device.
 func (p *Player) initDevice() error {
 	deviceConfig := malgo.DefaultDeviceConfig(malgo.Playback)
-	deviceConfig.Playback.Format = malgo.FormatF32
-	deviceConfig.Playback.Channels = 1
-	deviceConfig.SampleRate = p.deviceSampleRate
+	deviceConfig.SampleRate = p.deviceSampleRate.Load()
 	deviceConfig.PeriodSizeInMilliseconds = p.bufferMs
 
-	// Lock-free audio callback
-	onSendFrames := func(pOutputSample, pInputSamples []byte, framecount uint32) {
-		// Check for interrupts (lock-free)
-		interrupted := p.interrupt.Load() || (p.externalIntr != nil && p.externalIntr.Load())
-
-		for i := 0; i < int(framecount); i++ {
-			var sample float32
-			if !interrupted {
-				if s, ok := p.ring.pop(); ok {
-					sample = s
-				}
-			}
-			binary.LittleEndian.PutUint32(pOutputSample[i*4:], math.Float32bits(sample))
+	if p.deviceID != nil {
+		deviceConfig.Playback.DeviceID = devicePointer(p.deviceID.id)
+	} else if len(p.devices) > 0 {
+		info, choice, err := findDevice(p.ctx.Context, malgo.Playback, p.devices)
+		if err != nil {
+			return err
 		}
-
-		// Mark playback as done if buffer is empty or interrupted
-		if p.ring.isEmpty() || interrupted {
-			p.playing.Store(false)
-			// Non-blocking send to completion channel
-			select {
-			case p.completeChan <- struct{}{}:
-			default:
-				// Channel already has a signal, no need to send another
-			}
+		switch {
+		case info != nil:
+			deviceConfig.Playback.DeviceID = devicePointer(info.ID)
+			log.Printf("🔊 Output device: %s", info.Name())
+		case choice < len(p.devices):
+			log.Println("🔊 Output device: system default")
+		default:
+			log.Printf("⚠️ None of the output devices %q are available, using the system default", p.devices)
 		}
+		p.deviceChoice.Store(int32(choice))
 	}
 
 	callbacks := malgo.DeviceCallbacks{
-		Data: onSendFrames,
+		Data: func(pOutputSample, _ []byte, framecount uint32) {
+			p.fillOutput(pOutputSample, framecount)
+		},
 	}
 
-	device, err := malgo.InitDevice(p.ctx.Context, deviceConfig, callbacks)
+	// Prefer float32 with the requested channels; fall back to whatever the device accepts
+	device, format, err := openDevice(p.ctx.Context, deviceConfig, callbacks, preferredFormats(p.channels))
 	if err != nil {
 		return fmt.Errorf("failed to initialize playback device: %w", err)
 	}
 
 	p.device = device
+	p.format = format
+	p.adoptDeviceRate(device.SampleRate())
 
 	// Start the device immediately (it will output silence until samples are queued)
 	if err := device.Start(); err != nil {
@@ -187,6 +248,82 @@ func (p *Player) initDevice() error {
 	return nil
 }
 
+// fillOutput is the lock-free device callback body: it writes framecount samples
+// from the ring (or silence) into out, in the device's format, and wakes any
+// waiting Play call.
+func (p *Player) fillOutput(out []byte, framecount uint32) {
+	p.fill(out, framecount, p.sink.Load())
+}
+
+// fill is fillOutput for a given sink (nil = none), which gets a copy of the
+// samples written. out may be nil when only the sink consumes the output.
+func (p *Player) fill(out []byte, framecount uint32, sink *sinkWriter) {
+	var copied, played []float32
+	if sink != nil {
+		copied = sink.frames(int(framecount))
+	}
+	ref := p.echoRef.Load()
+	if ref != nil {
+		played = ref.frames(int(framecount))
+	}
+
+	// Check for interrupts (lock-free)
+	interrupted := p.interrupt.Load() || p.externallyInterrupted()
+	muted := p.muted.Load()
+
+	popped := 0
+	for i := 0; i < int(framecount); i++ {
+		var sample float32
+		if !interrupted {
+			if s, ok := p.ring.pop(); ok {
+				popped++
+				if !muted {
+					sample = s
+				}
+			}
+		}
+		if out != nil {
+			encodeFrame(out, i, sample, p.format)
+		}
+		if copied != nil {
+			copied[i] = sample
+		}
+		if played != nil {
+			played[i] = sample
+		}
+	}
+	if sink != nil && sink.queue != nil {
+		sink.queue.push(copied)
+	}
+	if ref != nil {
+		ref.ring.push(played)
+	}
+	p.consumed.Add(uint64(popped))
+	p.callbacks.Add(1)
+
+	// Wake Play once the buffer has drained or playback was interrupted. Play
+	// itself decides whether its samples have actually been consumed.
+	if p.ring.isEmpty() || interrupted {
+		// Non-blocking send to completion channel
+		select {
+		case p.completeChan <- struct{}{}:
+		default:
+			// Channel already has a signal, no need to send another
+		}
+	}
+}
+
+// nativeSampleRate returns the native rate of the selected output device, or
+// of the default device when none was selected or it cannot be queried.
+func (p *Player) nativeSampleRate() uint32 {
+	if p.deviceID != nil {
+		if _, rate := nativeFormat(p.ctx.Context, malgo.Playback, p.deviceID.id); rate > 0 {
+			return rate
+		}
+	}
+	return getDeviceNativeSampleRate()
+}
+
 // getDeviceNativeSampleRate queries the device's preferred sample rate.
 // Falls back to 48000 Hz if unable to determine.
 func getDeviceNativeSampleRate() uint32 {
@@ -197,62 +334,102 @@ func getDeviceNativeSampleRate() uint32 {
 	return 48000
 }
 
-// Play plays the audio buffer, blocking until complete or interrupted.
+// Play plays the audio buffer, blocking until complete or interrupted. It
+// plays at [PriorityNormal] (see [Player.PlayPriority]).
 func (p *Player) Play(buffer AudioBuffer) error {
-	// Resample if device sample rate differs from input
-	playbackSamples := buffer.Samples
-	if buffer.SampleRate != int(p.deviceSampleRate) {
-		log.Printf("🔄 Resampling audio: %d Hz -> %d Hz (%d samples -> %d samples)",
-			buffer.SampleRate, p.deviceSampleRate, len(buffer.Samples),
-			int(float64(len(buffer.Samples))*float64(p.deviceSampleRate)/float64(buffer.SampleRate)))
-		playbackSamples = ResampleInPlace(buffer.Samples, buffer.SampleRate, int(p.deviceSampleRate))
-	}
+	return p.PlayPriority(buffer, PriorityNormal)
+}
 
-	// Reset interrupt flag
-	p.interrupt.Store(false)
+// RingBytes returns the memory held by the playback ring buffer.
+func (p *Player) RingBytes() int64 {
+	return SampleBytes(playbackRingSize)
+}
 
-	// Queue samples to ring buffer
-	p.mu.Lock()
-	written := p.ring.push(playbackSamples)
-	if written < len(playbackSamples) {
-		log.Printf("⚠️  Playback buffer overflow, dropped %d samples", len(playbackSamples)-written)
+// BufferOverflows returns how many times audio queued for playback did not fit
+// in the ring buffer and was cut short.
+func (p *Player) BufferOverflows() uint64 {
+	return p.ring.overflows.Load()
+}
+
+// IsPlaying reports whether audio is currently being played.
+func (p *Player) IsPlaying() bool {
+	return p.playing.Load()
+}
+
+// Position returns how much of the current (or most recent) Play buffer has been
+// played. After an interruption it reports where playback stopped.
+func (p *Player) Position() time.Duration {
+	start, consumed := p.playStart.Load(), p.consumed.Load()
+	if consumed <= start {
+		return 0
 	}
-	p.mu.Unlock()
+	played := min(consumed-start, p.playLen.Load())
+	return time.Duration(played) * time.Second / time.Duration(p.deviceSampleRate.Load())
+}
 
-	// Mark as playing
-	p.playing.Store(true)
+// LastPlayedAt returns when the most recent Play call finished (zero if none has).
+func (p *Player) LastPlayedAt() time.Time {
+	ns := p.lastPlayedAt.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
 
-	// Wait for playback to complete or be interrupted
-	timeout := time.Duration(len(playbackSamples)/int(p.deviceSampleRate)+2) * time.Second
+// SetMuted silences (or restores) the output. Queued audio keeps playing out
+// silently, so Play timing and completion are unaffected.
+func (p *Player) SetMuted(muted bool) {
+	p.muted.Store(muted)
+}
 
-	// Use channel-based waiting (more idiomatic in Go than sync.Cond)
-	for p.playing.Load() {
-		if p.interrupt.Load() || (p.externalIntr != nil && p.externalIntr.Load()) {
-			p.ring.clear()
-			p.playing.Store(false)
-			return nil
-		}
+// SetBargeInGrace makes the external interrupt flag be ignored for grace after
+// a Play call queues its samples, so the first syllable of a sentence leaking
+// into the microphone cannot stop it (0 disables). The flag is left set, so
+// an interruption raised within the grace period takes effect once it ends.
+func (p *Player) SetBargeInGrace(grace time.Duration) {
+	p.bargeInGrace.Store(int64(max(grace, 0)))
+}
 
-		select {
-		case <-p.completeChan:
-			// Playback completed normally
-		case <-time.After(50 * time.Millisecond):
-			// Timeout to periodically check interrupt flags
-		case <-time.After(timeout):
-			log.Println("⚠️  Playback timeout exceeded")
-			p.ring.clear()
-			p.playing.Store(false)
-			return nil
-		}
+// SetFade ramps the start and end of every buffer played over fadeMs
+// milliseconds (see [ApplyFade]), so sentences do not begin or end with a
+// click (0 disables).
+func (p *Player) SetFade(fadeMs int) {
+	p.fadeMs.Store(int32(max(fadeMs, 0)))
+}
+
+// withFade returns buffer's samples at the device's current rate with the
+// fade set by SetFade applied, leaving buffer itself untouched.
+func (p *Player) withFade(buffer AudioBuffer) []float32 {
+	samples := p.toDeviceRate(buffer)
+	fadeMs := int(p.fadeMs.Load())
+	if fadeMs == 0 {
+		return samples
+	}
+	rate := int(p.deviceSampleRate.Load())
+	if len(samples) > 0 && len(buffer.Samples) > 0 && &samples[0] == &buffer.Samples[0] {
+		samples = slices.Clone(samples) // Not resampled: still the caller's buffer
 	}
+	ApplyFade(samples, rate, fadeMs)
+	return samples
+}
 
-	return nil
+// externallyInterrupted reports whether the external interrupt flag stops the
+// current playback, which it does not during the barge-in grace period. It is
+// called from the device callback, so it must not block.
+func (p *Player) externallyInterrupted() bool {
+	if p.externalIntr == nil || !p.externalIntr.Load() {
+		return false
+	}
+	grace := time.Duration(p.bargeInGrace.Load())
+	return grace == 0 || time.Since(time.Unix(0, p.playBegan.Load())) >= grace
 }
 
-// Interrupt stops current playback.
+// Interrupt stops current playback; the interrupted Play call returns
+// [ErrInterrupted]. It has no effect on a Play call that starts afterwards.
 func (p *Player) Interrupt() {
 	p.interrupt.Store(true)
 	p.ring.clear()
+	p.resetResamplers()
 	p.playing.Store(false)
 	// Non-blocking send to completion channel
 	select {
@@ -262,14 +439,115 @@ func (p *Player) Interrupt() {
 	}
 }
 
-// Close releases all resources.
-func (p *Player) Close() {
-	p.Interrupt()
+// toDeviceRate returns buffer's samples resampled to the device's current rate.
+//
+// One resampler is kept per source rate, so consecutive buffers from the same
+// source are converted as a single stream: filter history and fractional
+// position carry over and no seam is introduced between chunks. The cache is
+// dropped when the device rate changes or playback is cut short.
+func (p *Player) toDeviceRate(buffer AudioBuffer) []float32 {
+	deviceRate := p.deviceSampleRate.Load()
+	if buffer.SampleRate == int(deviceRate) {
+		return buffer.Samples
+	}
+
+	p.resampleMu.Lock()
+	defer p.resampleMu.Unlock()
+	if p.resampleTo != deviceRate {
+		p.resamplers = nil
+		p.resampleTo = deviceRate
+	}
+	r := p.resamplers[buffer.SampleRate]
+	if r == nil {
+		if p.resamplers == nil {
+			p.resamplers = make(map[int]*PolyphaseResampler)
+		}
+		r = NewPolyphaseResampler(buffer.SampleRate, int(deviceRate))
+		p.resamplers[buffer.SampleRate] = r
+		log.Printf("🔄 Resampling audio: %d Hz -> %d Hz", buffer.SampleRate, deviceRate)
+	}
+	return r.Resample(buffer.Samples)
+}
+
+// resetResamplers drops the cached resamplers so audio queued after a cut does
+// not start with filter history from the audio that was discarded.
+func (p *Player) resetResamplers() {
+	p.resampleMu.Lock()
+	p.resamplers = nil
+	p.resampleMu.Unlock()
+}
+
+// adoptDeviceRate records the rate the device actually opened at, which may
+// differ from the one requested (or from before a restart).
+func (p *Player) adoptDeviceRate(rate uint32) {
+	if rate == 0 {
+		return
+	}
+	if old := p.deviceSampleRate.Swap(rate); old != rate {
+		log.Printf("🔊 Playback device rate changed: %d Hz -> %d Hz", old, rate)
+	}
+}
+
+// Restart reopens the playback device, e.g. after it was disconnected and
+// reconnected, picking the first available device of the fallback chain again.
+// Its native sample rate is queried again so later audio is resampled for the
+// device as it is now; audio queued before the restart is dropped.
+func (p *Player) Restart() error {
+	if p.ctx == nil {
+		return nil // Streaming to a sink only
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closeDevice()
+	p.ring.clear()
+	p.adoptDeviceRate(p.nativeSampleRate())
+	if err := p.initDevice(); err != nil {
+		return err
+	}
+	log.Println("🔊 Playback device restarted")
+	return nil
+}
+
+// RestartIfIdle restarts the device like [Player.Restart] unless audio is
+// playing, reporting whether it did. Playback that starts meanwhile waits for
+// the restart rather than being cut off by it.
+func (p *Player) RestartIfIdle() (bool, error) {
+	p.prioMu.Lock()
+	defer p.prioMu.Unlock()
+	if p.current != nil {
+		return false, nil
+	}
+	return true, p.Restart()
+}
+
+// PreferredDeviceAvailable reports whether an output device earlier in the
+// fallback chain than the one in use has appeared, e.g. a Bluetooth speaker
+// that connected after startup. [Player.Restart] switches to it.
+func (p *Player) PreferredDeviceAvailable() bool {
+	if int(p.deviceChoice.Load()) == 0 {
+		return false
+	}
+	_, choice, err := findDevice(p.ctx.Context, malgo.Playback, p.devices)
+	return err == nil && choice < int(p.deviceChoice.Load())
+}
+
+// closeDevice stops and releases the playback device, if open.
+func (p *Player) closeDevice() {
 	if p.device != nil {
 		p.device.Stop()
 		p.device.Uninit()
 		p.device = nil
 	}
+}
+
+// Close releases all resources.
+func (p *Player) Close() {
+	p.Interrupt()
+	p.stopSink()
+	p.mu.Lock()
+	p.closeDevice()
+	p.mu.Unlock()
 	if p.ctx != nil {
 		_ = p.ctx.Uninit()
 		p.ctx.Free()
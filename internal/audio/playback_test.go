@@ -0,0 +1,296 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gen2brain/malgo"
+)
+
+// newTestPlayer returns a Player with no device attached; tests drive the
+// consumer side by calling fillOutput directly.
+func newTestPlayer(rate uint32) *Player {
+	p := &Player{
+		sampleRate:   rate,
+		bufferMs:     10,
+		format:       monoFloat32,
+		interrupt:    &atomic.Bool{},
+		ring:         &playbackRing{},
+		completeChan: make(chan struct{}, 1),
+	}
+	p.deviceSampleRate.Store(rate)
+	return p
+}
+
+func TestPlayTinyBufferWaitsForConsumption(t *testing.T) {
+	p := newTestPlayer(16000)
+	// A stale completion signal from an earlier Play must not end this one.
+	p.completeChan <- struct{}{}
+
+	samples := []float32{0.1, 0.2, 0.3, 0.4, 0.5}
+	done := make(chan struct{})
+	go func() {
+		_ = p.Play(AudioBuffer{Samples: samples, SampleRate: 16000})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Play returned before any samples were consumed")
+	case <-time.After(120 * time.Millisecond):
+	}
+
+	const period = 160 // one 10ms period at 16kHz, far larger than the buffer
+	out := make([]byte, period*4)
+	p.fillOutput(out, period)
+	for i, want := range samples {
+		if got := math.Float32frombits(binary.LittleEndian.Uint32(out[i*4:])); got != want {
+			t.Fatalf("sample %d = %v, want %v", i, got, want)
+		}
+	}
+
+	// Play only finishes after a further callback has handed the last period off.
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case <-done:
+			if n := p.callbacks.Load(); n < 2 {
+				t.Fatalf("Play returned after %d callback(s), want at least 2", n)
+			}
+			if p.IsPlaying() {
+				t.Error("IsPlaying() = true after Play returned")
+			}
+			return
+		case <-deadline:
+			t.Fatal("Play did not return after samples were consumed")
+		case <-time.After(10 * time.Millisecond):
+			p.fillOutput(out, period)
+		}
+	}
+}
+
+func TestPlayInterrupted(t *testing.T) {
+	p := newTestPlayer(16000)
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Play(AudioBuffer{Samples: make([]float32, 16000), SampleRate: 16000})
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	p.Interrupt()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrInterrupted) {
+			t.Errorf("Play() = %v, want ErrInterrupted", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Play did not return after Interrupt")
+	}
+	if !p.ring.isEmpty() {
+		t.Error("ring not cleared after interrupt")
+	}
+}
+
+func TestRestartIfIdleSkipsPlayback(t *testing.T) {
+	p := newTestPlayer(16000)
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Play(AudioBuffer{Samples: make([]float32, 16000), SampleRate: 16000})
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	if restarted, err := p.RestartIfIdle(); restarted || err != nil {
+		t.Errorf("RestartIfIdle() during playback = %v, %v; want false, nil", restarted, err)
+	}
+	p.Interrupt()
+	<-done
+
+	if restarted, err := p.RestartIfIdle(); !restarted || err != nil {
+		t.Errorf("RestartIfIdle() when idle = %v, %v; want true, nil", restarted, err)
+	}
+}
+
+func TestPlayMutedConsumesSilently(t *testing.T) {
+	p := newTestPlayer(16000)
+	p.SetMuted(true)
+	p.ring.push([]float32{0.5, 0.5})
+
+	out := make([]byte, 4*4)
+	p.fillOutput(out, 4)
+	for i := range 4 {
+		if got := math.Float32frombits(binary.LittleEndian.Uint32(out[i*4:])); got != 0 {
+			t.Errorf("sample %d = %v, want silence", i, got)
+		}
+	}
+	if !p.ring.isEmpty() {
+		t.Error("muted output should still consume queued samples")
+	}
+}
+
+func TestFillOutputDuplicatesToStereo(t *testing.T) {
+	p := newTestPlayer(16000)
+	p.format = sampleFormat{format: malgo.FormatF32, channels: 2}
+	p.ring.push([]float32{0.5, -0.25})
+
+	out := make([]byte, 2*2*4)
+	p.fillOutput(out, 2)
+
+	want := []float32{0.5, 0.5, -0.25, -0.25}
+	for i, w := range want {
+		if got := math.Float32frombits(binary.LittleEndian.Uint32(out[i*4:])); got != w {
+			t.Errorf("value %d = %v, want %v", i, got, w)
+		}
+	}
+}
+
+func TestPositionReportsWhereInterruptedPlaybackStopped(t *testing.T) {
+	p := newTestPlayer(16000)
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Play(AudioBuffer{Samples: make([]float32, 16000), SampleRate: 16000})
+	}()
+
+	// Play 0.25 s of the 1 s buffer, then interrupt.
+	for p.ring.isEmpty() {
+		time.Sleep(time.Millisecond)
+	}
+	out := make([]byte, 4000*4)
+	p.fillOutput(out, 4000)
+	p.Interrupt()
+	<-done
+
+	if got, want := p.Position(), 250*time.Millisecond; got != want {
+		t.Errorf("Position() = %v, want %v", got, want)
+	}
+}
+
+// TestPlayerFollowsDeviceRateAcrossRestart validates that audio is resampled for
+// the rate a device reports after being reopened, not the rate it had before.
+func TestPlayerFollowsDeviceRateAcrossRestart(t *testing.T) {
+	p := newTestPlayer(48000)
+	buf := AudioBuffer{Samples: make([]float32, 24000), SampleRate: 24000} // 1s
+
+	if got := len(p.toDeviceRate(buf)); got != 48000 {
+		t.Fatalf("before restart: %d samples, want 48000", got)
+	}
+
+	p.adoptDeviceRate(44100) // Reconnected device came back at 44.1kHz
+	if got := len(p.toDeviceRate(buf)); got != 44100 {
+		t.Errorf("after restart: %d samples, want 44100", got)
+	}
+
+	p.adoptDeviceRate(0) // An unknown rate keeps the current one
+	if got := p.deviceSampleRate.Load(); got != 44100 {
+		t.Errorf("deviceSampleRate = %d, want 44100", got)
+	}
+}
+
+// TestPlayerResamplesConsecutiveChunksAsOneStream validates that a buffer split
+// into chunks comes out the same as if it had been resampled in one piece, i.e.
+// the resampler for a source rate is reused and no seams appear between chunks.
+func TestPlayerResamplesConsecutiveChunksAsOneStream(t *testing.T) {
+	const from = 48000
+	signal := make([]float32, 4800)
+	for i := range signal {
+		signal[i] = float32(math.Sin(2 * math.Pi * 440 * float64(i) / from))
+	}
+	want := NewPolyphaseResampler(from, 24000).Resample(signal)
+
+	p := newTestPlayer(24000)
+	var got []float32
+	for chunk := range slices.Chunk(signal, 1000) {
+		got = append(got, p.toDeviceRate(AudioBuffer{Samples: chunk, SampleRate: from})...)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("chunked output has %d samples, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if math.Abs(float64(got[i]-want[i])) > 1e-6 {
+			t.Fatalf("sample %d = %v, want %v (seam between chunks)", i, got[i], want[i])
+		}
+	}
+}
+
+// TestPlayerResamplerCacheResets validates that each source rate gets its own
+// resampler and that cached state is dropped on interrupt and rate change.
+func TestPlayerResamplerCacheResets(t *testing.T) {
+	p := newTestPlayer(48000)
+	p.toDeviceRate(AudioBuffer{Samples: make([]float32, 240), SampleRate: 24000})
+	p.toDeviceRate(AudioBuffer{Samples: make([]float32, 220), SampleRate: 22050})
+	if len(p.resamplers) != 2 {
+		t.Fatalf("cached %d resamplers, want one per source rate", len(p.resamplers))
+	}
+	first := p.resamplers[24000]
+	p.toDeviceRate(AudioBuffer{Samples: make([]float32, 240), SampleRate: 24000})
+	if p.resamplers[24000] != first {
+		t.Error("resampler for 24000 Hz recreated between consecutive chunks")
+	}
+
+	p.Interrupt()
+	if len(p.resamplers) != 0 {
+		t.Error("resamplers kept across an interrupt")
+	}
+
+	p.toDeviceRate(AudioBuffer{Samples: make([]float32, 240), SampleRate: 24000})
+	p.adoptDeviceRate(44100)
+	p.toDeviceRate(AudioBuffer{Samples: make([]float32, 240), SampleRate: 24000})
+	if r := p.resamplers[24000]; r == nil || r.toRate != 44100 {
+		t.Errorf("resampler after rate change = %+v, want one to 44100 Hz", r)
+	}
+}
+
+func TestBargeInGraceIgnoresEarlyInterrupts(t *testing.T) {
+	p := newTestPlayer(16000)
+	external := &atomic.Bool{}
+	p.externalIntr = external
+	p.SetBargeInGrace(time.Hour)
+	p.playBegan.Store(time.Now().UnixNano())
+
+	external.Store(true)
+	p.ring.push([]float32{0.5})
+	out := make([]byte, 4)
+	p.fillOutput(out, 1)
+	if got := math.Float32frombits(binary.LittleEndian.Uint32(out)); got != 0.5 {
+		t.Errorf("sample = %v, want playback to go on within the grace period", got)
+	}
+	if !external.Load() {
+		t.Error("interrupt raised within the grace period was cleared")
+	}
+
+	// Once the grace period is over, the flag still set stops playback.
+	p.playBegan.Store(time.Now().Add(-2 * time.Hour).UnixNano())
+	p.ring.push([]float32{0.5})
+	p.fillOutput(out, 1)
+	if got := math.Float32frombits(binary.LittleEndian.Uint32(out)); got != 0 {
+		t.Errorf("sample = %v, want playback stopped after the grace period", got)
+	}
+
+	p.SetBargeInGrace(0)
+	p.playBegan.Store(time.Now().UnixNano())
+	external.Store(true)
+	p.ring.push([]float32{0.5})
+	p.fillOutput(out, 1)
+	if got := math.Float32frombits(binary.LittleEndian.Uint32(out)); got != 0 || !external.Load() {
+		t.Errorf("sample = %v, interrupt = %v; want playback stopped without a grace period", got, external.Load())
+	}
+}
+
+func TestPlaybackRingCountsOverflows(t *testing.T) {
+	p := newTestPlayer(16000)
+	p.ring.push(make([]float32, playbackRingSize-10))
+	if got := p.BufferOverflows(); got != 0 {
+		t.Fatalf("BufferOverflows = %d after a push that fit, want 0", got)
+	}
+	if n := p.ring.push(make([]float32, 20)); n != 10 {
+		t.Fatalf("push wrote %d samples, want 10", n)
+	}
+	if got := p.BufferOverflows(); got != 1 {
+		t.Errorf("BufferOverflows = %d, want 1", got)
+	}
+}
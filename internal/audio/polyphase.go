@@ -3,48 +3,74 @@ package audio
 
 import "math"
 
+// Anti-aliasing filter design constants.
+const (
+	// upsampleFilterLen is the filter length kept for upsampling, which uses
+	// linear interpolation and never applies the filter.
+	upsampleFilterLen = 64
+
+	// transitionFraction is the width of the filter transition band as a
+	// fraction of the output Nyquist frequency. The stopband starts exactly at
+	// the output Nyquist, so the passband extends to 80% of it (6.4 kHz at 16 kHz).
+	transitionFraction = 0.2
+
+	// blackmanTransitionWidth is the normalized transition width × tap count of
+	// a Blackman-windowed sinc (≈74 dB stopband attenuation).
+	blackmanTransitionWidth = 5.5
+)
+
 // PolyphaseResampler implements a polyphase filter for high-quality downsampling.
 // Prevents aliasing artifacts when downsampling (e.g., 48kHz -> 16kHz for STT).
-// Uses a 64-tap sinc filter with Hamming window for optimal quality/performance.
+// Uses a Blackman-windowed sinc filter whose length scales with the decimation
+// ratio, so the stopband always begins at the output Nyquist frequency.
 type PolyphaseResampler struct {
 	fromRate   int       // Source sample rate
 	toRate     int       // Target sample rate
 	ratio      float64   // Conversion ratio
-	filterLen  int       // FIR filter length (64 taps)
+	filterLen  int       // FIR filter length (165 taps for 48kHz -> 16kHz)
 	filter     []float32 // Low-pass filter coefficients
 	history    []float32 // Sample history for filter
+	phase      float64   // Position of the next output sample relative to the next input chunk
 	lastSample float32   // Last sample for continuity
 }
 
+// downsampleFilterLen returns the number of taps needed for the transition band
+// to fit between transitionFraction below the output Nyquist and the Nyquist itself.
+// The result is odd so the filter has an integer group delay.
+func downsampleFilterLen(ratio float64) int {
+	transition := transitionFraction * ratio * 0.5
+	n := int(math.Ceil(blackmanTransitionWidth / transition))
+	return n | 1
+}
+
 // NewPolyphaseResampler creates a new polyphase resampler with anti-aliasing filter.
 // Use this for downsampling (e.g., 48kHz -> 16kHz). For upsampling, linear interpolation is sufficient.
 //
-// The resampler uses a 64-tap sinc filter with Hamming window to prevent aliasing.
-// Filter cutoff is set to the output Nyquist frequency for downsampling.
+// The filter cutoff sits in the middle of the transition band, just below the
+// output Nyquist, so content above Nyquist is attenuated by the full stopband
+// instead of folding back into the speech band.
 func NewPolyphaseResampler(fromRate, toRate int) *PolyphaseResampler {
 	ratio := float64(toRate) / float64(fromRate)
 
-	// Filter length: use 64 taps for good quality/performance balance
-	filterLen := 64
-
-	// Design a low-pass sinc filter with Hamming window
-	// Cutoff frequency is at the lower of the two Nyquist frequencies
+	filterLen := upsampleFilterLen
 	cutoff := 0.5
 	if ratio < 1.0 {
-		// Downsampling: filter at output Nyquist
-		cutoff = ratio * 0.5
+		// Downsampling: stopband starts at output Nyquist
+		filterLen = downsampleFilterLen(ratio)
+		cutoff = ratio * 0.5 * (1 - transitionFraction/2)
 	}
 
 	filter := make([]float32, filterLen)
 	for i := 0; i < filterLen; i++ {
 		n := float64(i) - float64(filterLen-1)/2.0
+		// Blackman window
+		x := 2.0 * math.Pi * float64(i) / float64(filterLen-1)
+		window := 0.42 - 0.5*math.Cos(x) + 0.08*math.Cos(2*x)
 		if n == 0 {
-			filter[i] = float32(2.0 * cutoff)
+			filter[i] = float32(2.0 * cutoff * window)
 		} else {
 			// Sinc function
 			sinc := math.Sin(2.0*math.Pi*cutoff*n) / (math.Pi * n)
-			// Hamming window
-			window := 0.54 - 0.46*math.Cos(2.0*math.Pi*float64(i)/float64(filterLen-1))
 			filter[i] = float32(sinc * window)
 		}
 	}
@@ -122,40 +148,34 @@ func (r *PolyphaseResampler) upsample(input []float32) []float32 {
 	return output
 }
 
-// downsample uses polyphase filtering to prevent aliasing
+// downsample uses polyphase filtering to prevent aliasing.
+//
+// The FIR is applied causally over the history and the new input, so each
+// output depends only on samples already received: no filter taps fall off the
+// end of a chunk, and the fractional output position carries over between calls.
 func (r *PolyphaseResampler) downsample(input []float32) []float32 {
 	inputLen := len(input)
-	outputLen := int(float64(inputLen) * r.ratio)
-	output := make([]float32, outputLen)
+	step := 1.0 / r.ratio
+	output := make([]float32, 0, int(float64(inputLen)*r.ratio)+1)
 
-	// Combine history with new input
-	combined := append(r.history, input...)
+	// Combine history with new input (full slice expression forces a copy)
+	combined := append(r.history[:len(r.history):len(r.history)], input...)
 
-	for i := 0; i < outputLen; i++ {
-		// Calculate source position in combined buffer
-		srcPos := float64(i) / r.ratio
-		srcIdx := int(srcPos) + len(r.history)
-
-		// Apply FIR filter centered at srcIdx
+	pos := r.phase
+	for ; pos < float64(inputLen); pos += step {
+		// Apply FIR filter over the filterLen samples ending at pos
+		end := int(pos) + len(r.history) + 1
+		taps := combined[end-r.filterLen : end]
 		sample := float32(0.0)
-		for j := 0; j < r.filterLen; j++ {
-			idx := srcIdx - r.filterLen/2 + j
-			if idx >= 0 && idx < len(combined) {
-				sample += combined[idx] * r.filter[j]
-			}
+		for j, h := range r.filter {
+			sample += taps[j] * h
 		}
-		output[i] = sample
+		output = append(output, sample)
 	}
+	r.phase = pos - float64(inputLen)
 
-	// Update history with last filterLen samples from input
-	if inputLen >= r.filterLen {
-		copy(r.history, input[inputLen-r.filterLen:])
-	} else {
-		// Shift existing history and append new samples
-		shift := r.filterLen - inputLen
-		copy(r.history, r.history[inputLen:])
-		copy(r.history[shift:], input)
-	}
+	// Keep the last filterLen samples as history
+	copy(r.history, combined[len(combined)-r.filterLen:])
 
 	return output
 }
@@ -1,6 +1,7 @@
 package audio
 
 import (
+	"fmt"
 	"math"
 	"testing"
 )
@@ -18,9 +19,12 @@ func TestPolyphaseResamplerConstruction(t *testing.T) {
 		t.Errorf("Filter coefficients sum = %v, want 1.0", sum)
 	}
 
-	// Verify filter length is 64 taps
-	if r.filterLen != 64 {
-		t.Errorf("Filter length = %d, want 64", r.filterLen)
+	// Verify filter length scales with the decimation ratio (165 taps at 3:1)
+	if r.filterLen != 165 {
+		t.Errorf("Filter length = %d, want 165", r.filterLen)
+	}
+	if got := NewPolyphaseResampler(48000, 8000).filterLen; got != 331 {
+		t.Errorf("Filter length at 6:1 = %d, want 331", got)
 	}
 
 	// Verify ratio is correct (16000/48000 = 0.333...)
@@ -120,9 +124,9 @@ func TestPolyphaseHistoryBuffer(t *testing.T) {
 		t.Error("History buffer should allow continuous processing")
 	}
 
-	// Verify history buffer is populated (64 samples)
-	if len(r.history) != 64 {
-		t.Errorf("History buffer length = %d, want 64", len(r.history))
+	// Verify history buffer holds one filter length of samples
+	if len(r.history) != r.filterLen {
+		t.Errorf("History buffer length = %d, want %d", len(r.history), r.filterLen)
 	}
 
 	// Verify history contains non-zero values after first chunk
@@ -166,7 +170,7 @@ func TestPolyphaseEdgeCases(t *testing.T) {
 	}
 
 	// Input shorter than filter length but enough to produce output
-	shortInput := make([]float32, 32) // Less than 64-tap filter
+	shortInput := make([]float32, 32) // Less than the filter length
 	for i := range shortInput {
 		shortInput[i] = float32(i)
 	}
@@ -212,3 +216,70 @@ func TestResamplePolyphaseConvenience(t *testing.T) {
 		t.Errorf("Upsampling: got %d samples, want ~%d", len(output), expectedLen)
 	}
 }
+
+// chirp returns a linear sine sweep from f0 to f1 Hz.
+func chirp(rate int, seconds, f0, f1 float64) []float32 {
+	n := int(float64(rate) * seconds)
+	out := make([]float32, n)
+	k := (f1 - f0) / seconds
+	for i := range out {
+		t := float64(i) / float64(rate)
+		out[i] = float32(0.5 * math.Sin(2*math.Pi*(f0*t+0.5*k*t*t)))
+	}
+	return out
+}
+
+func rms(s []float32) float64 {
+	var sum float64
+	for _, v := range s {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum / float64(len(s)))
+}
+
+// resampleChunked runs input through r in capture-sized chunks.
+func resampleChunked(r *PolyphaseResampler, input []float32, chunk int) []float32 {
+	var out []float32
+	for i := 0; i < len(input); i += chunk {
+		out = append(out, r.Resample(input[i:min(i+chunk, len(input))])...)
+	}
+	return out
+}
+
+// TestPolyphaseDownsampleRejectsAliases sweeps a sine across every frequency
+// the output cannot represent and checks that almost none of it folds back.
+func TestPolyphaseDownsampleRejectsAliases(t *testing.T) {
+	for _, tc := range []struct{ from, to int }{{48000, 16000}, {48000, 8000}, {44100, 16000}, {96000, 16000}} {
+		t.Run(fmt.Sprintf("%d-%d", tc.from, tc.to), func(t *testing.T) {
+			// Sweep everything between the output and input Nyquist frequencies.
+			input := chirp(tc.from, 1.0, float64(tc.to)/2, 0.95*float64(tc.from)/2)
+			r := NewPolyphaseResampler(tc.from, tc.to)
+			out := resampleChunked(r, input, chunkSamples(uint32(tc.from), capturePeriodMs))
+
+			// Skip the filter warm-up at the start.
+			aliasDB := 20 * math.Log10(rms(out[len(out)/20:])/rms(input))
+			if aliasDB > -50 {
+				t.Errorf("aliased energy = %.1f dB relative to input, want below -50 dB", aliasDB)
+			}
+		})
+	}
+}
+
+// TestPolyphaseDownsamplePreservesPassband checks that speech-band content is
+// not attenuated by the anti-aliasing filter.
+func TestPolyphaseDownsamplePreservesPassband(t *testing.T) {
+	for _, tc := range []struct{ from, to int }{{48000, 16000}, {48000, 8000}, {44100, 16000}} {
+		t.Run(fmt.Sprintf("%d-%d", tc.from, tc.to), func(t *testing.T) {
+			// A tone at 60% of the output Nyquist must pass essentially unchanged.
+			freq := 0.3 * float64(tc.to)
+			input := chirp(tc.from, 0.5, freq, freq)
+			r := NewPolyphaseResampler(tc.from, tc.to)
+			out := resampleChunked(r, input, chunkSamples(uint32(tc.from), capturePeriodMs))
+
+			gainDB := 20 * math.Log10(rms(out[len(out)/10:])/rms(input))
+			if math.Abs(gainDB) > 0.5 {
+				t.Errorf("passband gain = %.2f dB, want within ±0.5 dB", gainDB)
+			}
+		})
+	}
+}
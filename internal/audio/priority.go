@@ -0,0 +1,248 @@
+package audio
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Playback priorities for [Player.PlayPriority].
+const (
+	PriorityNormal       = 0 // Responses (what [Player.Play] uses)
+	PriorityAnnouncement = 1 // Announcements that pause a response in progress
+)
+
+// playback is one PlayPriority call's claim on the ring. At most one playback
+// owns the ring at a time (Player.current); the ones it paused are chained
+// through prev, most recent first.
+type playback struct {
+	priority int
+	prev     *playback     // Playback paused to make way for this one (nil if none)
+	done     chan struct{} // Closed when the call returns
+	target   atomic.Uint64 // Ring head position once all its samples have been read
+	paused   atomic.Bool   // Set while a higher-priority playback owns the ring
+
+	// Set under Player.mu while paused
+	length uint64    // Samples queued by the call
+	played uint64    // Samples played before the pause
+	saved  []float32 // Samples taken off the ring by the pause, restored on resume
+}
+
+// take removes and returns the samples queued in the ring. The consumer may read
+// a few of them while they are being copied, in which case those are heard twice.
+func (rb *playbackRing) take() []float32 {
+	head, tail := rb.head.Load(), rb.tail.Load()
+	out := make([]float32, head-tail)
+	for i := range out {
+		out[i] = rb.samples[(tail+uint64(i))%playbackRingSize]
+	}
+	rb.tail.Store(head)
+	return out
+}
+
+// PlayPriority plays buffer like [Player.Play], tagged with priority. A call
+// with a higher priority than the playback in progress pauses it: the samples
+// it has not played yet are set aside, buffer plays, and the paused playback
+// then picks up where it stopped, its Play call returning only once it has
+// finished. A call with the same or a lower priority waits until the ring is
+// free instead. If the higher-priority playback is interrupted, the paused one
+// is not resumed. Use [PriorityAnnouncement] for announcements that should not
+// wait for a response to end.
+func (p *Player) PlayPriority(buffer AudioBuffer, priority int) error {
+	return p.play(p.withFade(buffer), nil, priority)
+}
+
+// play queues samples at priority and blocks until they, and the rest of
+// stream when it is not nil, have been played or playback is interrupted.
+func (p *Player) play(samples []float32, stream *chunkStream, priority int) error {
+	defer func() { p.lastPlayedAt.Store(time.Now().UnixNano()) }()
+
+	pb := &playback{priority: priority, done: make(chan struct{})}
+	p.claim(pb, samples)
+	completed := false
+	defer func() { p.release(pb, completed) }()
+
+	// Wait for playback to complete or be interrupted. A stream's deadline is
+	// renewed as its chunks arrive.
+	timeout := p.playTimeout(len(samples))
+	deadline := time.After(timeout)
+	var chunks <-chan AudioBuffer // nil once the stream has ended
+	if stream != nil {
+		chunks = stream.chunks
+	}
+
+	// Completion is tracked by samples consumed rather than by the ring being
+	// empty: a buffer shorter than one device period can be pushed and drained
+	// between two callbacks, and a stale completion signal from a previous Play
+	// must not end this one early. Once the last sample has been read, wait for
+	// one more callback so the final period has been handed to the device.
+	drained := false
+	wasPaused := false
+	var drainedAt uint64
+	for {
+		if p.interrupt.Load() {
+			p.ring.clear()
+			return ErrInterrupted
+		}
+		if p.externallyInterrupted() {
+			p.ring.clear()
+			p.resetResamplers()
+			return nil
+		}
+
+		switch {
+		case pb.paused.Load():
+			wasPaused = true
+		case wasPaused:
+			// Resumed: the time spent paused does not count against the deadline.
+			wasPaused = false
+			deadline = time.After(timeout)
+		case chunks != nil:
+			// More of the stream may follow: not complete even if drained.
+		default:
+			if !drained && p.ring.tail.Load() >= pb.target.Load() {
+				drained = true
+				drainedAt = p.callbacks.Load()
+			}
+			if drained && p.callbacks.Load() > drainedAt {
+				completed = true
+				return nil
+			}
+		}
+
+		select {
+		case chunk, ok := <-chunks:
+			var queued []float32
+			if ok {
+				queued = stream.next(p.toDeviceRate(chunk))
+			} else {
+				queued = stream.end()
+				chunks = nil
+			}
+			timeout = p.playTimeout(p.extend(pb, queued))
+			deadline = time.After(timeout)
+		case <-p.completeChan:
+			// Consumer made progress; re-check completion
+		case <-time.After(50 * time.Millisecond):
+			// Timeout to periodically check interrupt flags
+		case <-deadline:
+			if wasPaused {
+				continue // Waiting for a higher-priority playback to finish
+			}
+			log.Println("⚠️  Playback timeout exceeded")
+			p.ring.clear()
+			p.resetResamplers()
+			return nil
+		}
+	}
+}
+
+// playTimeout returns how long a playback of n samples may take before it is
+// given up on.
+func (p *Player) playTimeout(n int) time.Duration {
+	return time.Duration(n/int(p.deviceSampleRate.Load())+2) * time.Second
+}
+
+// claim waits until pb may own the ring, pausing a lower-priority playback if
+// needed, and queues samples for it.
+func (p *Player) claim(pb *playback, samples []float32) {
+	for {
+		p.prioMu.Lock()
+		cur := p.current
+		if cur != nil && pb.priority <= cur.priority {
+			p.prioMu.Unlock()
+			<-cur.done
+			continue
+		}
+
+		p.mu.Lock()
+		if cur != nil {
+			cur.played = min(p.consumed.Load()-min(p.playStart.Load(), p.consumed.Load()), cur.length)
+			cur.saved = p.ring.take()
+			cur.paused.Store(true)
+			pb.prev = cur
+		}
+		p.current = pb
+		p.interrupt.Store(false)
+
+		// Queue samples to ring buffer. The target is the ring position that the
+		// consumer must reach before every sample of this buffer has been read.
+		// The barge-in grace period starts with them, not while waiting above.
+		p.playBegan.Store(time.Now().UnixNano())
+		p.playStart.Store(p.consumed.Load() + p.ring.head.Load() - p.ring.tail.Load())
+		written := p.ring.push(samples)
+		pb.length = uint64(written)
+		p.playLen.Store(pb.length)
+		if written < len(samples) {
+			log.Printf("⚠️  Playback buffer overflow, dropped %d samples", len(samples)-written)
+		}
+		pb.target.Store(p.ring.head.Load())
+		p.mu.Unlock()
+
+		p.playing.Store(true)
+		p.prioMu.Unlock()
+		return
+	}
+}
+
+// extend queues samples after the ones pb has queued so far, setting them
+// aside with the rest of pb when it is paused, and returns how many samples pb
+// has queued in all.
+func (p *Player) extend(pb *playback, samples []float32) int {
+	p.prioMu.Lock()
+	defer p.prioMu.Unlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case len(samples) == 0:
+	case pb.paused.Load():
+		pb.saved = append(pb.saved, samples...)
+		pb.length += uint64(len(samples))
+	case p.current == pb:
+		written := p.ring.push(samples)
+		if written < len(samples) {
+			log.Printf("⚠️  Playback buffer overflow, dropped %d samples", len(samples)-written)
+		}
+		pb.length += uint64(written)
+		p.playLen.Store(pb.length)
+		pb.target.Store(p.ring.head.Load())
+	}
+	return int(pb.length)
+}
+
+// release gives up pb's claim on the ring. When pb completed and had paused a
+// playback, that one resumes; otherwise the paused playback is left with
+// nothing to play, so it returns as soon as it notices the interruption.
+func (p *Player) release(pb *playback, completed bool) {
+	p.prioMu.Lock()
+	defer close(pb.done)
+	defer p.prioMu.Unlock()
+
+	if p.current != pb {
+		// pb was paused and gave up (e.g. on interruption): unlink it.
+		for q := p.current; q != nil; q = q.prev {
+			if q.prev == pb {
+				q.prev = pb.prev
+				break
+			}
+		}
+		return
+	}
+
+	p.current = pb.prev
+	if prev := pb.prev; prev != nil {
+		p.mu.Lock()
+		if completed && len(prev.saved) > 0 {
+			p.playStart.Store(p.consumed.Load() + p.ring.head.Load() - p.ring.tail.Load() - prev.played)
+			p.playLen.Store(prev.length)
+			p.ring.push(prev.saved)
+		}
+		prev.target.Store(p.ring.head.Load())
+		prev.saved = nil
+		prev.paused.Store(false)
+		p.mu.Unlock()
+		p.resetResamplers()
+	}
+	p.playing.Store(p.current != nil)
+}
@@ -0,0 +1,152 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// pump drives the consumer of p in periods of n samples until done is closed,
+// returning every sample output.
+func pump(t *testing.T, p *Player, n int, done <-chan error) []float32 {
+	t.Helper()
+	var played []float32
+	out := make([]byte, n*4)
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case <-done:
+			return played
+		case <-deadline:
+			t.Fatal("playback did not finish")
+		case <-time.After(5 * time.Millisecond):
+			p.fillOutput(out, uint32(n))
+			for i := range n {
+				played = append(played, math.Float32frombits(binary.LittleEndian.Uint32(out[i*4:])))
+			}
+		}
+	}
+}
+
+func constant(n int, v float32) AudioBuffer {
+	buf := AudioBuffer{Samples: make([]float32, n), SampleRate: 16000}
+	for i := range buf.Samples {
+		buf.Samples[i] = v
+	}
+	return buf
+}
+
+func TestPlayPriorityPausesAndResumesLowerPriority(t *testing.T) {
+	p := newTestPlayer(16000)
+	normal := make(chan error, 1)
+	go func() { normal <- p.Play(constant(1000, 0.1)) }()
+	for p.ring.isEmpty() {
+		time.Sleep(time.Millisecond)
+	}
+	out := make([]byte, 400*4)
+	p.fillOutput(out, 400) // 400 of the 1000 normal samples play
+
+	announcement := make(chan error, 1)
+	go func() { announcement <- p.PlayPriority(constant(200, 0.9), PriorityAnnouncement) }()
+	for {
+		p.prioMu.Lock()
+		claimed := p.current != nil && p.current.priority == PriorityAnnouncement
+		p.prioMu.Unlock()
+		if claimed {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	played := pump(t, p, 100, normal)
+	if err := <-announcement; err != nil {
+		t.Fatalf("announcement: %v", err)
+	}
+	if played[0] != 0.9 {
+		t.Fatalf("first sample after the announcement started = %v, want 0.9", played[0])
+	}
+
+	// The announcement plays whole, then exactly the 600 unplayed normal samples.
+	var counts [2]int
+	last := float32(0.9)
+	for _, s := range played {
+		switch s {
+		case 0.9:
+			if last != 0.9 {
+				t.Fatal("announcement samples after the response resumed")
+			}
+			counts[0]++
+		case 0.1:
+			counts[1]++
+		}
+		if s != 0 {
+			last = s
+		}
+	}
+	if counts != [2]int{200, 600} {
+		t.Errorf("played %d announcement and %d remaining response samples, want 200 and 600", counts[0], counts[1])
+	}
+	if got, want := p.Position(), 1000*time.Second/16000; got != want {
+		t.Errorf("Position() = %v, want %v (the whole response)", got, want)
+	}
+	if p.IsPlaying() {
+		t.Error("IsPlaying() = true after both playbacks finished")
+	}
+}
+
+func TestPlayWaitsForHigherPriority(t *testing.T) {
+	p := newTestPlayer(16000)
+	announcement := make(chan error, 1)
+	go func() { announcement <- p.PlayPriority(constant(300, 0.9), PriorityAnnouncement) }()
+	for p.ring.isEmpty() {
+		time.Sleep(time.Millisecond)
+	}
+
+	normal := make(chan error, 1)
+	go func() { normal <- p.Play(constant(300, 0.1)) }()
+	time.Sleep(20 * time.Millisecond)
+	if queued := p.ring.head.Load() - p.ring.tail.Load(); queued != 300 {
+		t.Fatalf("%d samples queued, want only the announcement's 300", queued)
+	}
+
+	played := pump(t, p, 100, normal)
+	if err := <-announcement; err != nil {
+		t.Fatalf("announcement: %v", err)
+	}
+	if played[0] != 0.9 {
+		t.Fatalf("first sample = %v, want the announcement", played[0])
+	}
+	for i := 300; i < 600; i++ {
+		if played[i] != 0.1 && played[i] != 0 {
+			t.Fatalf("sample %d = %v, want the normal playback after the announcement", i, played[i])
+		}
+	}
+}
+
+func TestPlayPriorityInterruptedDoesNotResume(t *testing.T) {
+	p := newTestPlayer(16000)
+	normal := make(chan error, 1)
+	go func() { normal <- p.Play(constant(1000, 0.1)) }()
+	for p.ring.isEmpty() {
+		time.Sleep(time.Millisecond)
+	}
+
+	announcement := make(chan error, 1)
+	go func() { announcement <- p.PlayPriority(constant(1000, 0.9), PriorityAnnouncement) }()
+	for p.ring.head.Load()-p.ring.tail.Load() != 1000 || p.ring.samples[p.ring.tail.Load()%playbackRingSize] != 0.9 {
+		time.Sleep(time.Millisecond)
+	}
+	p.Interrupt()
+
+	for _, ch := range []chan error{announcement, normal} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatal("Interrupt did not end both playbacks")
+		}
+	}
+	if !p.ring.isEmpty() {
+		t.Error("paused samples were restored after the interruption")
+	}
+}
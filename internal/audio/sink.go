@@ -0,0 +1,242 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// sinkSampleRate is the rate a [Player] without an output device streams at,
+// the usual rate of streaming and VoIP software.
+const sinkSampleRate = 48000
+
+// SinkFormat is the encoding of audio streamed to a [Sink].
+type SinkFormat int
+
+const (
+	// SinkPCM streams raw signed 16-bit little-endian mono samples.
+	SinkPCM SinkFormat = iota
+	// SinkWAV streams a WAV header followed by 16-bit PCM, for readers that
+	// need the format up front. The header gives no length.
+	SinkWAV
+)
+
+// Sink names where a [Player] streams its output besides (or instead of) an
+// output device: a file or named pipe, or stdout.
+type Sink struct {
+	Path   string // File or named pipe to write to; empty for stdout
+	Format SinkFormat
+}
+
+// String returns the sink as it appears in logs.
+func (s Sink) String() string {
+	if s.Path == "" {
+		return "stdout"
+	}
+	return s.Path
+}
+
+// ParseSink parses an output sink specification, "sink:pipe:PATH" or
+// "sink:stdout", and a format, "pcm" or "wav".
+func ParseSink(spec, format string) (Sink, error) {
+	var s Sink
+	switch strings.ToLower(format) {
+	case "pcm":
+		s.Format = SinkPCM
+	case "wav":
+		s.Format = SinkWAV
+	default:
+		return Sink{}, fmt.Errorf("unknown output format %q (want pcm or wav)", format)
+	}
+	switch target, ok := strings.CutPrefix(spec, "sink:"); {
+	case !ok:
+		return Sink{}, fmt.Errorf("invalid output %q (want sink:pipe:PATH or sink:stdout)", spec)
+	case target == "stdout":
+	case strings.HasPrefix(target, "pipe:") && len(target) > len("pipe:"):
+		s.Path = strings.TrimPrefix(target, "pipe:")
+	default:
+		return Sink{}, fmt.Errorf("invalid output %q (want sink:pipe:PATH or sink:stdout)", spec)
+	}
+	return s, nil
+}
+
+// sinkWriter streams a Player's output to a Sink from its own goroutine, so
+// the device callback never blocks on a slow or absent reader.
+type sinkWriter struct {
+	Sink
+	rate  int           // Rate the stream is written at
+	queue *playbackRing // Samples handed over by the device callback (nil without a device)
+	buf   []float32     // Samples of the last fillOutput call (written by the consumer only)
+	w     io.WriteCloser
+	pcm   []byte
+	stop  chan struct{}
+	done  chan struct{}
+
+	resampler *PolyphaseResampler // Converts queued samples when the device rate differs from rate
+	fromRate  uint32              // Source rate of resampler
+}
+
+// frames returns a buffer of n samples for fillOutput to record its output in.
+func (s *sinkWriter) frames(n int) []float32 {
+	if cap(s.buf) < n {
+		s.buf = make([]float32, n)
+	}
+	s.buf = s.buf[:n]
+	return s.buf
+}
+
+// open opens the sink for writing, waiting for a reader when it is a named pipe.
+func (s *sinkWriter) open() error {
+	if s.Path == "" {
+		s.w = nopWriteCloser{os.Stdout}
+	} else {
+		if info, err := os.Stat(s.Path); err == nil && info.Mode()&os.ModeNamedPipe != 0 {
+			log.Printf("🔊 Waiting for a reader on %s", s.Path)
+		}
+		f, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open output sink: %w", err)
+		}
+		s.w = f
+	}
+	if s.Format == SinkWAV {
+		if _, err := s.w.Write(wavHeader(s.rate, math.MaxUint32-36)); err != nil {
+			s.close()
+			return fmt.Errorf("failed to write to output sink: %w", err)
+		}
+	}
+	log.Printf("🔊 Streaming output to %s (%d Hz)", s, s.rate)
+	return nil
+}
+
+// write encodes samples to the sink. When a pipe's reader goes away the sink is
+// closed, to be reopened for the next reader; any other error is returned.
+func (s *sinkWriter) write(samples []float32) error {
+	s.pcm = encodePCM16(s.pcm[:0], samples)
+	_, err := s.w.Write(s.pcm)
+	if errors.Is(err, syscall.EPIPE) && s.Path != "" {
+		log.Printf("🔊 Output sink %s lost its reader", s)
+		s.close()
+		return nil
+	}
+	return err
+}
+
+// close closes the sink, if open.
+func (s *sinkWriter) close() {
+	if s.w != nil {
+		s.w.Close()
+		s.w = nil
+	}
+}
+
+// nopWriteCloser keeps stdout open when the sink is closed.
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// startSink starts streaming the player's output to sink. With tee set the
+// device keeps playing and its callback hands every period to the sink as well;
+// otherwise the sink goroutine consumes the ring in the device's place.
+func (p *Player) startSink(sink Sink, tee bool) {
+	s := &sinkWriter{
+		Sink: sink,
+		rate: int(p.deviceSampleRate.Load()),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if tee {
+		s.queue = &playbackRing{}
+	}
+	p.sink.Store(s)
+	go p.runSink(s)
+}
+
+// runSink writes the player's output to s every buffer period until stopSink.
+func (p *Player) runSink(s *sinkWriter) {
+	defer close(s.done)
+	defer s.close()
+
+	ticker := time.NewTicker(time.Duration(p.bufferMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	failed := false
+	var start time.Time
+	var sent int64
+	for {
+		if s.w == nil && !failed {
+			if err := s.open(); err != nil {
+				log.Printf("⚠️ %v; output is discarded", err)
+				failed = true
+			}
+			start, sent = time.Now(), 0
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		var samples []float32
+		if s.queue != nil {
+			samples = p.sinkResample(s, s.queue.take())
+		} else {
+			// Pace by the clock like a device would, catching up after a slow
+			// write rather than drifting, but never by more than a second.
+			due := int64(time.Since(start).Seconds()*float64(s.rate)) - sent
+			if due > int64(s.rate) {
+				start, sent = time.Now(), 0
+				due = int64(s.rate) * int64(p.bufferMs) / 1000
+			}
+			if due <= 0 {
+				continue
+			}
+			p.fill(nil, uint32(due), s)
+			samples = s.buf
+			sent += due
+		}
+
+		if s.w != nil && len(samples) > 0 {
+			if err := s.write(samples); err != nil {
+				log.Printf("⚠️ Failed to write to output sink %s: %v; output is discarded", s, err)
+				s.close()
+				failed = true
+			}
+		}
+	}
+}
+
+// sinkResample converts samples queued by the device callback to the sink's
+// rate, which stays fixed when a restart changes the device rate.
+func (p *Player) sinkResample(s *sinkWriter, samples []float32) []float32 {
+	rate := p.deviceSampleRate.Load()
+	if rate == uint32(s.rate) {
+		return samples
+	}
+	if s.resampler == nil || s.fromRate != rate {
+		s.resampler = NewPolyphaseResampler(int(rate), s.rate)
+		s.fromRate = rate
+	}
+	return s.resampler.Resample(samples)
+}
+
+// stopSink stops streaming, waiting briefly for the sink to close. A sink still
+// waiting for a pipe reader cannot be interrupted and is left to exit on its own.
+func (p *Player) stopSink() {
+	s := p.sink.Swap(nil)
+	if s == nil {
+		return
+	}
+	close(s.stop)
+	select {
+	case <-s.done:
+	case <-time.After(time.Second):
+	}
+}
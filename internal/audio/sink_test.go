@@ -0,0 +1,66 @@
+package audio
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSink(t *testing.T) {
+	tests := []struct {
+		spec, format string
+		want         Sink
+		wantErr      bool
+	}{
+		{spec: "sink:pipe:/tmp/va.pcm", format: "pcm", want: Sink{Path: "/tmp/va.pcm"}},
+		{spec: "sink:stdout", format: "wav", want: Sink{Format: SinkWAV}},
+		{spec: "sink:pipe:C:/va.wav", format: "WAV", want: Sink{Path: "C:/va.wav", Format: SinkWAV}},
+		{spec: "sink:pipe:", format: "pcm", wantErr: true},
+		{spec: "pipe:/tmp/va.pcm", format: "pcm", wantErr: true},
+		{spec: "sink:speaker", format: "pcm", wantErr: true},
+		{spec: "sink:stdout", format: "mp3", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSink(tt.spec, tt.format)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSink(%q, %q) error = %v, wantErr %v", tt.spec, tt.format, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSink(%q, %q) = %+v, want %+v", tt.spec, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestSinkPlayerStreamsPlayback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	p := NewSinkPlayer(sinkSampleRate, 10, Sink{Path: path, Format: SinkWAV}, nil)
+
+	samples := make([]float32, sinkSampleRate/20) // 50ms
+	for i := range samples {
+		samples[i] = 0.5
+	}
+	if err := p.Play(AudioBuffer{Samples: samples, SampleRate: sinkSampleRate}); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	p.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < 44 || !bytes.Equal(data[:44], wavHeader(sinkSampleRate, math.MaxUint32-36)) {
+		t.Fatalf("stream does not start with a %d Hz WAV header", sinkSampleRate)
+	}
+	var loud int
+	for i := 44; i+1 < len(data); i += 2 {
+		if int16(uint16(data[i])|uint16(data[i+1])<<8) > 16000 {
+			loud++
+		}
+	}
+	if loud != len(samples) {
+		t.Errorf("streamed %d samples of the played audio, want %d", loud, len(samples))
+	}
+}
@@ -0,0 +1,56 @@
+package audio
+
+import "slices"
+
+// chunkStream holds the state of a [Player.PlayStream] call: its chunks, once
+// resampled for the device, are faded in at the start of the stream and out at
+// its end (see [Player.SetFade]), so they play like a single buffer.
+type chunkStream struct {
+	chunks  <-chan AudioBuffer
+	fadeLen int       // Samples faded in and out, at the device rate
+	started bool      // The first chunk has been queued
+	held    []float32 // The stream's last fadeLen samples so far, queued once it is known whether more follow
+}
+
+// next returns the samples of a resampled chunk to queue now, holding back the
+// last fadeLen of them.
+func (s *chunkStream) next(samples []float32) []float32 {
+	out := make([]float32, 0, len(s.held)+len(samples))
+	out = append(append(out, s.held...), samples...)
+	if !s.started {
+		fadeIn(out, s.fadeLen)
+		s.started = true
+	}
+	keep := min(s.fadeLen, len(out))
+	s.held = slices.Clone(out[len(out)-keep:])
+	return out[:len(out)-keep]
+}
+
+// end returns the samples held back at the end of the stream, faded out.
+func (s *chunkStream) end() []float32 {
+	fadeOut(s.held, len(s.held))
+	held := s.held
+	s.held = nil
+	return held
+}
+
+// PlayStream plays audio that arrives in chunks, such as a long sentence a
+// synthesizer delivers piece by piece, starting as soon as the first chunk
+// arrives instead of once all of it is available. Consecutive chunks play as
+// one buffer: they are resampled as a single stream, and faded in and out only
+// at its ends. It blocks like [Player.Play] until chunks is closed and
+// everything has been played, or playback is interrupted; it then stops
+// receiving, so the sender must not block on a full channel (e.g. by also
+// watching a context it cancels on interruption). Underruns, when chunks
+// arrive slower than they play, are heard as gaps.
+func (p *Player) PlayStream(chunks <-chan AudioBuffer) error {
+	first, ok := <-chunks
+	if !ok {
+		return nil
+	}
+	stream := &chunkStream{
+		chunks:  chunks,
+		fadeLen: fadeSamples(int(p.deviceSampleRate.Load()), int(p.fadeMs.Load())),
+	}
+	return p.play(stream.next(p.toDeviceRate(first)), stream, PriorityNormal)
+}
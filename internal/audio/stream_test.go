@@ -0,0 +1,96 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// ones returns n samples of full scale.
+func ones(n int) []float32 {
+	s := make([]float32, n)
+	for i := range s {
+		s[i] = 1
+	}
+	return s
+}
+
+func TestPlayStreamPlaysChunksAsOneBuffer(t *testing.T) {
+	p := newTestPlayer(16000)
+	p.SetFade(1) // 16 samples
+	chunks := make(chan AudioBuffer, 4)
+	done := make(chan error, 1)
+	go func() { done <- p.PlayStream(chunks) }()
+
+	const period = 160
+	out := make([]byte, period*4)
+	var played []float32
+	consume := func() {
+		p.fillOutput(out, period)
+		for i := range period {
+			played = append(played, math.Float32frombits(binary.LittleEndian.Uint32(out[i*4:])))
+		}
+	}
+
+	chunks <- AudioBuffer{Samples: ones(100), SampleRate: 16000}
+	// The first chunk starts playing before the rest has been produced, all
+	// but the samples held back for a possible fade-out.
+	deadline := time.Now().Add(2 * time.Second)
+	for p.ring.isEmpty() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	consume()
+	if played[0] != 0 || played[50] != 1 || played[83] != 1 || played[84] != 0 {
+		t.Fatalf("first period = %v..., want 84 faded-in samples then silence", played[:100])
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("PlayStream returned (%v) before the stream ended", err)
+	default:
+	}
+
+	chunks <- AudioBuffer{Samples: ones(100), SampleRate: 16000}
+	close(chunks)
+	played = played[:0]
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("PlayStream: %v", err)
+			}
+			// 116 samples: the 16 held back and the second chunk, faded out.
+			if played[0] != 1 || played[99] != 1 || played[107] != 0.5 || played[115] != 0 {
+				t.Errorf("rest of stream = %v, want it continued and faded out", played[:116])
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+			consume()
+		}
+		if time.Now().After(deadline.Add(2 * time.Second)) {
+			t.Fatal("PlayStream did not return after the stream ended")
+		}
+	}
+}
+
+func TestPlayStreamInterrupted(t *testing.T) {
+	p := newTestPlayer(16000)
+	chunks := make(chan AudioBuffer, 1)
+	done := make(chan error, 1)
+	go func() { done <- p.PlayStream(chunks) }()
+	chunks <- AudioBuffer{Samples: ones(100), SampleRate: 16000}
+
+	for !p.IsPlaying() {
+		time.Sleep(time.Millisecond)
+	}
+	p.Interrupt()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrInterrupted) {
+			t.Errorf("PlayStream = %v, want ErrInterrupted", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("PlayStream did not return after Interrupt while the stream was open")
+	}
+}
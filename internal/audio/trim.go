@@ -0,0 +1,37 @@
+package audio
+
+// trimPadMs is how much audio TrimSilence keeps around the first and last
+// sample above the threshold, so soft word onsets and endings such as
+// fricatives are not clipped.
+const trimPadMs = 20
+
+// TrimSilence returns samples, at sampleRate, without the leading and trailing
+// runs whose absolute amplitude stays below threshold, keeping a short pad on
+// each side. The result shares samples' backing array. Audio that is silent
+// throughout is returned empty; a threshold <= 0 returns samples unchanged.
+func TrimSilence(samples []float32, sampleRate int, threshold float32) []float32 {
+	if threshold <= 0 {
+		return samples
+	}
+	pad := sampleRate * trimPadMs / 1000
+	loud := func(s float32) bool { return s >= threshold || s <= -threshold }
+
+	first := -1
+	for i, s := range samples {
+		if loud(s) {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return samples[:0]
+	}
+	last := first
+	for i := len(samples) - 1; i > first; i-- {
+		if loud(samples[i]) {
+			last = i
+			break
+		}
+	}
+	return samples[max(first-pad, 0):min(last+1+pad, len(samples))]
+}
@@ -0,0 +1,35 @@
+package audio
+
+import "testing"
+
+func TestTrimSilenceKeepsPad(t *testing.T) {
+	samples := make([]float32, 3000)
+	for i := 1000; i < 2000; i++ {
+		samples[i] = 0.3
+	}
+	samples[10] = 0.001 // Noise below the threshold
+
+	for _, rate := range []int{16000, 24000} {
+		pad := rate * trimPadMs / 1000
+		got := TrimSilence(samples, rate, 0.01)
+		if want := 1000 + 2*pad; len(got) != want {
+			t.Fatalf("len at %d Hz = %d, want %d (speech plus pad on both sides)", rate, len(got), want)
+		}
+		if got[pad] != 0.3 || got[pad-1] != 0 {
+			t.Errorf("speech at %d Hz does not start right after the leading pad", rate)
+		}
+	}
+}
+
+func TestTrimSilenceEdgeCases(t *testing.T) {
+	loud := []float32{0.5, 0, 0, -0.5}
+	if got := TrimSilence(loud, 24000, 0.01); len(got) != len(loud) {
+		t.Errorf("len = %d, want %d (pad clamped to the buffer)", len(got), len(loud))
+	}
+	if got := TrimSilence(make([]float32, 100), 24000, 0.01); len(got) != 0 {
+		t.Errorf("silent input trimmed to %d samples, want 0", len(got))
+	}
+	if got := TrimSilence(make([]float32, 100), 24000, 0); len(got) != 100 {
+		t.Errorf("disabled trim returned %d samples, want 100", len(got))
+	}
+}
@@ -0,0 +1,188 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// WAV format codes.
+const (
+	wavFormatPCM   = 1
+	wavFormatFloat = 3
+)
+
+// DecodeWAV reads a RIFF/WAVE stream containing 16-bit PCM or 32-bit float
+// samples and returns it as mono float32 audio. Multi-channel input is
+// downmixed by averaging; unknown chunks are skipped.
+func DecodeWAV(r io.Reader) (AudioBuffer, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return AudioBuffer{}, fmt.Errorf("reading WAV header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return AudioBuffer{}, errors.New("not a RIFF/WAVE stream")
+	}
+
+	var (
+		haveFmt       bool
+		format        uint16
+		channels      int
+		sampleRate    int
+		bitsPerSample int
+	)
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return AudioBuffer{}, fmt.Errorf("reading WAV chunk: %w", err)
+		}
+		id := string(hdr[0:4])
+		size := int64(binary.LittleEndian.Uint32(hdr[4:8]))
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return AudioBuffer{}, fmt.Errorf("WAV fmt chunk too short (%d bytes)", size)
+			}
+			buf := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return AudioBuffer{}, fmt.Errorf("reading WAV fmt chunk: %w", err)
+			}
+			format = binary.LittleEndian.Uint16(buf[0:2])
+			channels = int(binary.LittleEndian.Uint16(buf[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(buf[4:8]))
+			bitsPerSample = int(binary.LittleEndian.Uint16(buf[14:16]))
+			haveFmt = true
+
+		case "data":
+			if !haveFmt {
+				return AudioBuffer{}, errors.New("WAV data chunk before fmt chunk")
+			}
+			return decodeWAVData(r, size, format, channels, sampleRate, bitsPerSample)
+
+		default:
+			// Chunks are word-aligned
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return AudioBuffer{}, fmt.Errorf("skipping WAV %q chunk: %w", id, err)
+			}
+		}
+	}
+}
+
+// ReadWAV reads the WAV file at path as mono float32 audio (see [DecodeWAV]).
+func ReadWAV(path string) (AudioBuffer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return AudioBuffer{}, err
+	}
+	defer f.Close()
+	buf, err := DecodeWAV(bufio.NewReader(f))
+	if err != nil {
+		return AudioBuffer{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return buf, nil
+}
+
+// decodeWAVData reads a data chunk of size bytes and downmixes it to mono float32.
+func decodeWAVData(r io.Reader, size int64, format uint16, channels, sampleRate, bitsPerSample int) (AudioBuffer, error) {
+	if channels < 1 || sampleRate <= 0 {
+		return AudioBuffer{}, fmt.Errorf("invalid WAV format: %d channel(s) at %d Hz", channels, sampleRate)
+	}
+
+	var decode func([]byte) float32
+	switch {
+	case format == wavFormatPCM && bitsPerSample == 16:
+		decode = func(b []byte) float32 { return float32(int16(binary.LittleEndian.Uint16(b))) / 32768 }
+	case format == wavFormatFloat && bitsPerSample == 32:
+		decode = func(b []byte) float32 { return math.Float32frombits(binary.LittleEndian.Uint32(b)) }
+	default:
+		return AudioBuffer{}, fmt.Errorf("unsupported WAV encoding: format %d, %d bits", format, bitsPerSample)
+	}
+
+	// Streams written before their length is known may report a 0 or
+	// 0xFFFFFFFF data size; read to EOF in that case.
+	var data []byte
+	var err error
+	if size == 0 || size == math.MaxUint32 {
+		data, err = io.ReadAll(r)
+	} else {
+		data = make([]byte, size)
+		_, err = io.ReadFull(r, data)
+	}
+	if err != nil {
+		return AudioBuffer{}, fmt.Errorf("reading WAV data: %w", err)
+	}
+
+	step := bitsPerSample / 8
+	frameSize := step * channels
+	samples := make([]float32, len(data)/frameSize)
+	for i := range samples {
+		var sum float32
+		for c := range channels {
+			sum += decode(data[i*frameSize+c*step:])
+		}
+		samples[i] = sum / float32(channels)
+	}
+	return AudioBuffer{Samples: samples, SampleRate: sampleRate}, nil
+}
+
+// EncodeWAV writes buf as a mono 16-bit PCM WAV stream. Samples are clipped to [-1, 1].
+func EncodeWAV(w io.Writer, buf AudioBuffer) error {
+	if buf.SampleRate <= 0 {
+		return fmt.Errorf("invalid sample rate %d", buf.SampleRate)
+	}
+	if _, err := w.Write(wavHeader(buf.SampleRate, uint32(len(buf.Samples)*2))); err != nil {
+		return err
+	}
+	_, err := w.Write(encodePCM16(nil, buf.Samples))
+	return err
+}
+
+// WriteWAV writes buf to the file at path as a mono 16-bit PCM WAV file (see
+// [EncodeWAV]), replacing any existing file.
+func WriteWAV(path string, buf AudioBuffer) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := EncodeWAV(f, buf); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// wavHeader returns the 44-byte header of a mono 16-bit PCM WAV stream holding
+// dataSize bytes of samples.
+func wavHeader(sampleRate int, dataSize uint32) []byte {
+	const bitsPerSample = 16
+	header := make([]byte, 44)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], 36+dataSize)
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], wavFormatPCM)
+	binary.LittleEndian.PutUint16(header[22:24], 1) // Mono
+	binary.LittleEndian.PutUint32(header[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(sampleRate*bitsPerSample/8))
+	binary.LittleEndian.PutUint16(header[32:34], bitsPerSample/8)
+	binary.LittleEndian.PutUint16(header[34:36], bitsPerSample)
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], dataSize)
+	return header
+}
+
+// encodePCM16 appends samples to dst as 16-bit little-endian PCM, clipped to
+// [-1, 1], and returns the extended slice.
+func encodePCM16(dst []byte, samples []float32) []byte {
+	for _, s := range samples {
+		s = min(max(s, -1), 1)
+		dst = binary.LittleEndian.AppendUint16(dst, uint16(int16(math.Round(float64(s)*math.MaxInt16))))
+	}
+	return dst
+}
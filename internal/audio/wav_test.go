@@ -0,0 +1,128 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildWAV assembles a WAV stream with an optional extra chunk before the data.
+func buildWAV(format uint16, channels, rate, bits int, data []byte, extra bool) []byte {
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(0))
+	b.WriteString("WAVE")
+	b.WriteString("fmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(16))
+	binary.Write(&b, binary.LittleEndian, format)
+	binary.Write(&b, binary.LittleEndian, uint16(channels))
+	binary.Write(&b, binary.LittleEndian, uint32(rate))
+	binary.Write(&b, binary.LittleEndian, uint32(rate*channels*bits/8))
+	binary.Write(&b, binary.LittleEndian, uint16(channels*bits/8))
+	binary.Write(&b, binary.LittleEndian, uint16(bits))
+	if extra {
+		b.WriteString("LIST")
+		binary.Write(&b, binary.LittleEndian, uint32(3))
+		b.Write([]byte{1, 2, 3, 0}) // odd size plus pad byte
+	}
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(len(data)))
+	b.Write(data)
+	return b.Bytes()
+}
+
+func TestDecodeWAVPCM16Stereo(t *testing.T) {
+	var data bytes.Buffer
+	for _, v := range []int16{16384, 0, -16384, -16384} { // L,R frames
+		binary.Write(&data, binary.LittleEndian, v)
+	}
+	buf, err := DecodeWAV(bytes.NewReader(buildWAV(wavFormatPCM, 2, 24000, 16, data.Bytes(), true)))
+	if err != nil {
+		t.Fatalf("DecodeWAV: %v", err)
+	}
+	if buf.SampleRate != 24000 {
+		t.Errorf("SampleRate = %d, want 24000", buf.SampleRate)
+	}
+	want := []float32{0.25, -0.5}
+	if len(buf.Samples) != len(want) {
+		t.Fatalf("got %d samples, want %d", len(buf.Samples), len(want))
+	}
+	for i := range want {
+		if buf.Samples[i] != want[i] {
+			t.Errorf("sample %d = %v, want %v", i, buf.Samples[i], want[i])
+		}
+	}
+}
+
+func TestDecodeWAVFloat32(t *testing.T) {
+	var data bytes.Buffer
+	for _, v := range []float32{0.1, -0.7} {
+		binary.Write(&data, binary.LittleEndian, math.Float32bits(v))
+	}
+	buf, err := DecodeWAV(bytes.NewReader(buildWAV(wavFormatFloat, 1, 16000, 32, data.Bytes(), false)))
+	if err != nil {
+		t.Fatalf("DecodeWAV: %v", err)
+	}
+	if len(buf.Samples) != 2 || buf.Samples[0] != 0.1 || buf.Samples[1] != -0.7 {
+		t.Errorf("got %v", buf.Samples)
+	}
+}
+
+func TestDecodeWAVRejectsInvalid(t *testing.T) {
+	if _, err := DecodeWAV(bytes.NewReader([]byte("not a wav file"))); err == nil {
+		t.Error("expected error for non-WAV input")
+	}
+	if _, err := DecodeWAV(bytes.NewReader(buildWAV(wavFormatPCM, 1, 16000, 8, []byte{1, 2}, false))); err == nil {
+		t.Error("expected error for 8-bit PCM")
+	}
+}
+
+func TestEncodeWAVRoundTrip(t *testing.T) {
+	in := AudioBuffer{Samples: []float32{0, 0.5, -0.5, 1.5, -1.5}, SampleRate: 16000}
+	var b bytes.Buffer
+	if err := EncodeWAV(&b, in); err != nil {
+		t.Fatalf("EncodeWAV: %v", err)
+	}
+	if b.Len() != 44+2*len(in.Samples) {
+		t.Errorf("encoded %d bytes, want %d", b.Len(), 44+2*len(in.Samples))
+	}
+
+	out, err := DecodeWAV(&b)
+	if err != nil {
+		t.Fatalf("DecodeWAV: %v", err)
+	}
+	if out.SampleRate != in.SampleRate {
+		t.Errorf("SampleRate = %d, want %d", out.SampleRate, in.SampleRate)
+	}
+	want := []float32{0, 0.5, -0.5, 1, -1} // Out-of-range samples are clipped
+	for i, w := range want {
+		if math.Abs(float64(out.Samples[i]-w)) > 1e-3 {
+			t.Errorf("sample %d = %v, want %v", i, out.Samples[i], w)
+		}
+	}
+}
+
+func TestWriteWAVReadWAV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	if err := WriteWAV(path, AudioBuffer{Samples: []float32{0.25, -0.25}, SampleRate: 24000}); err != nil {
+		t.Fatalf("WriteWAV: %v", err)
+	}
+	out, err := ReadWAV(path)
+	if err != nil {
+		t.Fatalf("ReadWAV: %v", err)
+	}
+	if out.SampleRate != 24000 || len(out.Samples) != 2 || math.Abs(float64(out.Samples[0]-0.25)) > 1e-3 {
+		t.Errorf("read back %+v, want the written buffer", out)
+	}
+
+	if err := WriteWAV(path, AudioBuffer{}); err == nil {
+		t.Error("WriteWAV accepted a buffer without a sample rate")
+	}
+	if _, err := ReadWAV(filepath.Join(t.TempDir(), "missing.wav")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadWAV(missing) = %v, want a not-exist error", err)
+	}
+}
@@ -7,9 +7,13 @@ package config
 import (
 	"flag"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/agalue/sherpa-voice-assistant/internal/sherpa"
 )
@@ -22,6 +26,9 @@ const (
 	InterruptAlways InterruptMode = iota
 	// InterruptWait pauses microphone during playback (best for open speakers).
 	InterruptWait
+	// InterruptSentence lets speech stop playback, but only once the current
+	// sentence has finished (a gentler barge-in for headsets and speakers).
+	InterruptSentence
 )
 
 // String returns the string representation of the interrupt mode.
@@ -31,6 +38,8 @@ func (m InterruptMode) String() string {
 		return "always"
 	case InterruptWait:
 		return "wait"
+	case InterruptSentence:
+		return "sentence"
 	default:
 		return "unknown"
 	}
@@ -43,11 +52,72 @@ func ParseInterruptMode(s string) (InterruptMode, error) {
 		return InterruptAlways, nil
 	case "wait":
 		return InterruptWait, nil
+	case "sentence":
+		return InterruptSentence, nil
 	default:
-		return InterruptWait, fmt.Errorf("invalid interrupt mode: %s (must be 'always' or 'wait')", s)
+		return InterruptWait, fmt.Errorf("invalid interrupt mode: %s (must be 'always', 'wait' or 'sentence')", s)
 	}
 }
 
+// AllowsBargeIn reports whether speech during playback stops the response,
+// immediately (InterruptAlways) or at the next sentence boundary (InterruptSentence).
+func (m InterruptMode) AllowsBargeIn() bool {
+	return m == InterruptAlways || m == InterruptSentence
+}
+
+// PipelineMode defines whether the LLM may work on a new turn while the previous
+// reply is still being spoken.
+type PipelineMode int
+
+const (
+	// PipelineOverlapped lets the LLM answer the next prompt while the previous
+	// reply plays, so quick follow-ups are answered sooner.
+	PipelineOverlapped PipelineMode = iota
+	// PipelineSequential starts the next LLM turn only once the previous reply
+	// has finished playing, was interrupted or was discarded.
+	PipelineSequential
+)
+
+// String returns the string representation of the pipeline mode.
+func (m PipelineMode) String() string {
+	switch m {
+	case PipelineOverlapped:
+		return "overlapped"
+	case PipelineSequential:
+		return "sequential"
+	default:
+		return "unknown"
+	}
+}
+
+// ParsePipelineMode converts a string to PipelineMode.
+func ParsePipelineMode(s string) (PipelineMode, error) {
+	switch s {
+	case "overlapped":
+		return PipelineOverlapped, nil
+	case "sequential":
+		return PipelineSequential, nil
+	default:
+		return PipelineOverlapped, fmt.Errorf("invalid pipeline mode: %s (must be 'overlapped' or 'sequential')", s)
+	}
+}
+
+// AllowsBargeIn reports whether speech during playback stops the response: in
+// 'always' and 'sentence' modes, and in 'wait' mode while listening during
+// playback (see WaitModeListenDuringPlayback).
+func (c *Config) AllowsBargeIn() bool {
+	return c.InterruptMode.AllowsBargeIn() || c.ListensDuringPlayback()
+}
+
+// ListensDuringPlayback reports whether 'wait' mode keeps the microphone on at
+// reduced sensitivity during playback instead of pausing it.
+func (c *Config) ListensDuringPlayback() bool {
+	return c.InterruptMode == InterruptWait && c.WaitModeListenDuringPlayback
+}
+
+// ChimeTone selects the built-in generated tone for [Config.ResponseChime].
+const ChimeTone = "tone"
+
 // Config holds all configuration for the voice assistant.
 // Populated from CLI flags, environment variables, or defaults.
 //
@@ -58,33 +128,192 @@ type Config struct {
 	// Model directory (base for all model files)
 	ModelDir string
 
+	// Maximum time to load the VAD, STT and TTS models at startup (0 = no limit)
+	ModelLoadTimeout time.Duration
+
 	// Backend selection (which STT/TTS implementation to use)
 	STTBackend string // STT backend (e.g. "whisper"); selects the Transcriber implementation
-	TTSBackend string // TTS backend (e.g. "kokoro"); selects the Synthesizer implementation
+	TTSBackend string // TTS backend (e.g. "kokoro", "http"); selects the Synthesizer implementation
+	TTSURL     string // Remote TTS endpoint for the "http" backend
 
 	// STT settings (generic — implementations interpret STTModel in their own way)
 	STTModel    string // STT model identifier (e.g. "tiny", "base", "small")
 	STTLanguage string // Language code for speech recognition (e.g., "en", "es", "auto")
 
+	// With STTLanguage "auto", reply in the language detected in each transcript:
+	// the LLM is asked to answer in it and the TTS voice switches to one that
+	// speaks it (the default voice and language are used when none does)
+	MatchResponseLanguage bool
+
+	// Capitalize transcripts and add a missing period or question mark
+	// (question detection uses English question words)
+	AutoPunctuate bool
+
+	// Send and store transcripts as recognized, wake word kept and not punctuated;
+	// commands are still stripped (and punctuated with AutoPunctuate) for matching
+	HistoryRawTranscript bool
+
+	// Phrases loaded from HotwordsFile (one per line) that transcripts are biased
+	// toward: a transcript that nearly matches one is replaced by it
+	HotwordsFile string
+	Hotwords     []string
+
+	// Drop transcripts whose estimated no-speech probability (derived from the
+	// token probabilities, when the model reports them) exceeds this (0 disables)
+	MaxNoSpeechProb float32
+
+	// Decode a speech segment a second time when it transcribes to nothing,
+	// recovering turns lost to occasional engine hiccups at the cost of a second
+	// decode for segments that really contain no speech
+	RetryEmptyTranscript bool
+
 	// LLM settings
-	OllamaURL    string
+	LLMBackend   string // LLM backend ("ollama", or "openai" for OpenAI-compatible servers)
+	LLMAPIKey    string // Bearer token for the openai backend (empty = none)
+	OllamaURL    string // Server URL (also used by the openai backend)
 	OllamaModel  string
 	SystemPrompt string
 	MaxHistory   int     // Maximum conversation history length
 	Temperature  float32 // LLM temperature (0.0-2.0, lower=deterministic, higher=creative)
 	SearxngURL   string  // Optional SearXNG URL for web search (empty uses DuckDuckGo)
+	KeepAlive    string  // How long Ollama keeps the model loaded ("10m", "-1" = forever, empty = server default)
+	HistoryFile  string  // Conversation history loaded at startup and saved on shutdown (empty = none)
+
+	// Retries of an LLM request that failed transiently (connection error,
+	// timeout, 5xx) and the wait before the first retry, doubled for each further one
+	LLMMaxRetries     int
+	LLMRetryBackoffMs int
+
+	// Maximum tokens per LLM reply (num_predict) and the model's context
+	// window in tokens (num_ctx)
+	LLMNumPredict int
+	LLMNumCtx     int
+
+	// Phrase spoken when the LLM returns an empty reply twice in a row (empty = stay silent)
+	EmptyResponseFallback string
+
+	// Phrase spoken when a reply has nothing speakable once split into sentences,
+	// e.g. only emoji or markdown symbols (empty = stay silent)
+	EmptyAfterFilterFallback string
+
+	// Phrases spoken in turn when a tool call (e.g. a web search) is still running
+	// after ToolProgressDelay, so slow lookups don't sound like a hang
+	// (0 delay or no phrases, the default, disables)
+	ToolProgressDelay   time.Duration
+	ToolProgressPhrases []string
+
+	// Pause a response being spoken for an announcement (e.g. a timer) and resume
+	// it afterwards, instead of cutting it off
+	PauseForAnnouncements bool
+
+	// Personas loaded from PersonasFile (JSON mapping names to prompt + temperature),
+	// the one selected at startup (empty = SystemPrompt), and the phrases that switch
+	// persona when followed by its name ("be my tutor")
+	PersonasFile   string
+	Personas       map[string]Persona
+	Persona        string
+	PersonaPhrases []string
+
+	// Spoken names for Ollama models ("smart" -> "qwen2.5:7b") and the phrases that
+	// switch to one when followed by its name and "model" ("switch to the smart model")
+	ModelAliases map[string]string
+	ModelPhrases []string
 
 	// Voice assistant settings
-	WakeWord     string
-	TTSVoice     string // TTS voice name (e.g., "af_bella" for American female Bella)
-	TTSSpeakerID int    // Speaker ID for multi-speaker models (af_bella=2 in v1.0)
+	WakeWord     []string // Wake words, any of which activates the assistant (empty = always listening)
+	TTSVoice     string   // TTS voice name (e.g., "af_bella" for American female Bella)
+	TTSSpeakerID int      // Speaker ID for multi-speaker models (af_bella=2 in v1.0)
 	TTSSpeed     float32
 	SampleRate   int
+
+	// Maximum sentences a sherpa-onnx TTS engine synthesizes per internal batch
+	// (>= 1). Kokoro only supports 1; engines that batch may synthesize faster with more.
+	TTSMaxNumSentences int
+
+	// How many sentences synthesis may run ahead of playback (>= 1). Higher values
+	// absorb slow synthesis; lower ones waste less work when a reply is interrupted.
+	MaxSynthLookahead int
+
+	// Start playing a sentence while it is still being synthesized, chunk by
+	// chunk as the TTS engine produces it, instead of once it is complete.
+	StreamSynthesis bool
+
+	// Splitting of responses into pieces for synthesis (see tts.SentenceSplitConfig):
+	// sentences shorter than SentenceMinChars are joined with the next, and any
+	// of SentenceSoftBoundaries (e.g. ",;:") also ends a piece once it has
+	// SentenceSoftMinChars characters (empty = sentence boundaries only)
+	SentenceMinChars       int
+	SentenceSoftBoundaries string
+	SentenceSoftMinChars   int
+
+	// Sentences longer than this are cut at word boundaries before synthesis so
+	// run-ons stay interruptible (0 = no limit)
+	MaxSentenceChars int
+
+	// Trim leading and trailing audio quieter than this amplitude from each
+	// synthesized sentence, keeping a short pad (0 disables)
+	TrimSilence float32
+
+	// After the wake word is heard on its own, the next segment is accepted without
+	// it if it starts within this window (0 = reply to the bare wake word right away)
+	WakeWordGrace time.Duration
+
+	// Edit distance (in characters, ignoring case, punctuation and spacing)
+	// within which the first transcribed words still count as a wake word, so
+	// "hey sharpa" activates "hey sherpa"; capped at a quarter of the wake
+	// word's length (0 = exact match only)
+	WakeWordTolerance int
+
+	// Only listen while toggled on with the space bar on the terminal, for noisy
+	// rooms where always-on VAD triggers falsely
+	PushToTalk bool
+
+	// Supplemental pronunciation lexicon merged with the voice's built-in one
+	// (Kokoro English and Mandarin voices only; empty disables)
+	UserLexicon  string
 	VadThreshold float32
 
+	// Honor inline [voice:NAME] directives in spoken text, switching the TTS
+	// voice from that sentence on (e.g. narrator and characters in a story);
+	// the voice in use before is restored after each response
+	VoiceDirectives bool
+
+	// Honor inline [phon:PHONEMES|fallback text] markup in spoken text, passing
+	// PHONEMES straight to backends that take phoneme input and speaking the
+	// fallback text with the others
+	PhonemeMarkup bool
+
+	// Honor <break time="500ms"/> and <emphasis>...</emphasis> tags in spoken
+	// text, inserting silence and slowing emphasized words; other tags are
+	// stripped
+	SSMLMarkup bool
+
 	// VAD silence duration in seconds (how long to wait before considering speech ended)
 	VADSilenceDuration float32
 
+	// Shortest speech the VAD delivers as a segment (shorter bursts, e.g. a
+	// cough or a door, are ignored) and longest continuous speech before it is
+	// split into another segment, in seconds
+	VADMinSpeechDuration float32
+	VADMaxSpeechDuration float32
+
+	// VAD audio buffer depth in seconds. Memory use is seconds × sample rate × 4 bytes
+	// (60s at 16kHz ≈ 3.8 MB); must be at least VADMaxSpeechDuration
+	VADBufferSeconds float32
+
+	// Milliseconds of audio from before the VAD's detected speech onset prepended
+	// to each segment, so a late trigger does not clip the first phoneme (0 disables)
+	VADPreSpeechPadMs int
+
+	// Maximum seconds of speech accepted across the segments of a single turn
+	// (0 = unlimited). Longer turns are dropped and the user is asked to be briefer.
+	MaxTurnAudioSeconds float32
+
+	// Seconds of raw audio saved before and after each transcribed segment to a WAV
+	// file in ContextDumpDir, for debugging transcription errors (0 disables)
+	ContextDumpSeconds float32
+	ContextDumpDir     string
+
 	// Hardware acceleration provider (cpu, cuda, coreml)
 	// Auto-detected based on platform if empty
 	Provider string
@@ -95,33 +324,209 @@ type Config struct {
 	// TTS-specific provider (overrides Provider for speech synthesis)
 	TTSProvider string
 
-	// Interrupt mode: InterruptAlways (headsets) or InterruptWait (open speakers)
+	// Interrupt mode: InterruptAlways (headsets), InterruptWait (open speakers) or
+	// InterruptSentence (stop at the next sentence boundary)
 	InterruptMode InterruptMode
 
+	// Pipeline mode: PipelineOverlapped (the LLM may answer the next prompt while
+	// a reply plays) or PipelineSequential (one turn at a time)
+	PipelineMode PipelineMode
+
 	// Delay in milliseconds before resuming microphone after playback ends (only for InterruptWait mode)
 	PostPlaybackDelayMs int
 
+	// Milliseconds at the start of each sentence's playback during which speech
+	// does not interrupt it, so the assistant's first syllable leaking into the
+	// microphone cannot cut it off (0 disables). Only applies where speech stops
+	// a sentence midway: InterruptAlways, or InterruptWait listening during playback
+	BargeInGraceMs int
+
+	// In InterruptWait mode, keep listening during playback with the VAD threshold
+	// raised to VADThresholdDuringPlayback instead of pausing the microphone, so a
+	// loud, close barge-in still stops the reply but the assistant's own voice doesn't
+	WaitModeListenDuringPlayback bool
+	VADThresholdDuringPlayback   float32
+
+	// Transcripts heard within this long after playback that closely match the
+	// assistant's last reply are treated as its own echo and ignored (0 disables)
+	SelfEchoSuppression time.Duration
+
 	// Thread counts for models (0 = auto-detect based on CPU cores)
 	NumThreads int // Global default for all models
 	VADThreads int // VAD-specific (overrides NumThreads if > 0)
 	STTThreads int // STT-specific (overrides NumThreads if > 0)
 	TTSThreads int // TTS-specific (overrides NumThreads if > 0)
 
+	// Optional greeting spoken once at startup (empty = no greeting)
+	Greeting string
+
+	// Keep the microphone off until the greeting (plus PostPlaybackDelayMs) has
+	// finished, regardless of InterruptMode, so it cannot trigger an interrupt
+	MuteDuringGreeting bool
+
+	// Re-engagement: during an active conversation, speak ReengagePrompt once if the
+	// user stays silent for ReengageAfter after a response (0 disables)
+	ReengagePrompt string
+	ReengageAfter  time.Duration
+
+	// Phrases that replay the last response from cache instead of querying the LLM
+	// (matched case-insensitively against the whole utterance; empty disables replay)
+	ReplayPhrases []string
+
+	// Phrases that continue an interrupted response from the sentence where it was
+	// cut off (matched like ReplayPhrases; empty, the default, disables resume)
+	ResumePhrases []string
+
+	// Phrases that lower (more sensitive) or raise (less sensitive) the VAD
+	// threshold by one step at runtime (matched like ReplayPhrases; empty disables)
+	MoreSensitivePhrases []string
+	LessSensitivePhrases []string
+
+	// Phrases that make the assistant speak faster or slower by one step at
+	// runtime (matched like ReplayPhrases; empty disables)
+	FasterPhrases []string
+	SlowerPhrases []string
+
+	// Sound played right before each new response so listeners know speech is
+	// starting: "tone" for the built-in chime, a WAV file path, or empty to disable
+	ResponseChime string
+
 	// Audio buffer size in milliseconds (0 = default 100ms for Bluetooth)
 	// Use 20ms for wired/built-in audio (lower latency)
 	// Use 100ms for Bluetooth devices (prevents distortion)
 	AudioBufferMs uint32
 
+	// Milliseconds over which each sentence fades in and out during playback, so
+	// it never starts or stops with a click (0 disables)
+	FadeMs int
+
+	// Subtract the assistant's own playback from the microphone with an
+	// adaptive filter, so open speakers don't trigger barge-in
+	EchoCancel bool
+
+	// Delay of the echo beyond what the echo canceller's filter covers (128ms),
+	// e.g. with large Bluetooth buffers
+	EchoCancelDelay time.Duration
+
+	// Capture device, by its index in the device list or a case-insensitive
+	// substring of its name (empty = system default)
+	InputDevice string
+
+	// Playback channels: 1 (mono) or 2 to open the output as stereo with every
+	// sample duplicated to both channels (fixes audio in only one ear on some headsets)
+	OutputChannels int
+
+	// Playback device, by its index in the device list or a case-insensitive
+	// substring of its name (empty = OutputDevices or the system default)
+	OutputDevice string
+
+	// Output devices to try in order, matched case-insensitively against device
+	// names ("default" = system default); the first one present is used. Empty
+	// uses the system default.
+	OutputDevices []string
+
+	// How often to check whether an output device earlier in OutputDevices has
+	// appeared and switch playback to it between replies (0 = never)
+	OutputDeviceMigrate time.Duration
+
+	// Stream playback to a named pipe or file ("sink:pipe:PATH") or stdout
+	// ("sink:stdout", which moves logs to stderr) instead of the output device,
+	// e.g. for OBS, ffmpeg or a VoIP bridge (empty = output device only)
+	Output string
+
+	// Encoding of the Output stream: "pcm" (raw 16-bit mono) or "wav"
+	OutputFormat string
+
+	// Keep playing on the output device while streaming to Output
+	OutputTee bool
+
+	// Warn when the microphone delivers only (near-)zero samples for this long,
+	// which usually means it is muted at the OS level (0 disables)
+	DeadMicWindow time.Duration
+
+	// Warn when more than this fraction of captured samples in a second clip
+	// (reach ±0.99), meaning the input gain is too high (0 disables)
+	ClipThreshold float32
+
+	// Silence captured chunks quieter than this RMS level in dBFS (e.g. -50)
+	// before the VAD hears them, so steady background noise such as HVAC hum
+	// cannot trigger it (0 disables)
+	NoiseFloorDB float32
+
+	// Normalize captured audio toward AGCTargetDB (RMS level in dBFS) with
+	// automatic gain control, for quiet microphones
+	AGC         bool
+	AGCTargetDB float32
+
+	// With a two-microphone array, only speech arriving from in front may
+	// interrupt playback: the channels must line up within DirectionMaxDelay and
+	// differ in level by at most DirectionMaxLevelDiff dB (speaker bleed from the
+	// sides reaches one microphone first and louder)
+	DirectionGate         bool
+	DirectionMaxDelay     time.Duration
+	DirectionMaxLevelDiff float32
+
+	// Optional HTTP status server listen address (e.g. ":8080"; empty disables)
+	HTTPAddr string
+
+	// Maximum simultaneous HTTP inference requests per engine (POST /transcribe
+	// and POST /speak are limited separately); requests beyond the limit are
+	// rejected with 429
+	MaxConcurrentRequests int
+
+	// Optional Unix socket accepting control commands (interrupt, mute, unmute,
+	// reset, pause, resume) from external processes; empty disables
+	ControlSocket string
+
+	// Optional conversation transcript file (empty disables) and its format:
+	// "jsonl" (machine-readable), "text" (human-readable) or "markdown" (for sharing)
+	TranscriptLog    string
+	TranscriptFormat string
+
+	// Save every turn (input audio, transcript, reply and reply audio) as a
+	// fixture bundle under this directory (empty disables)
+	RecordFixtures string
+
+	// Replay the fixture bundle(s) at this path through STT and the LLM, report
+	// differences from the recording and exit (empty = run normally)
+	ReplayFixtures string
+
+	// Budget in MB for audio buffers (playback ring, VAD buffer, replay cache,
+	// recordings). When exceeded, the replay cache and then the reply audio of
+	// fixture recordings are dropped (0 = unlimited)
+	AudioMemoryBudgetMB int
+
 	// Debug
 	Verbose bool
 
+	// Log every request sent to Ollama (model, options and the full conversation
+	// history); off by default since it writes conversation content to the log
+	LogRequests bool
+
+	// Log how long each turn spent in VAD, STT, LLM and TTS, and a p50/p95
+	// summary of each on shutdown
+	Metrics bool
+
+	// Write newline-delimited JSON events to stdout; logs move to stderr
+	JSONEvents bool
+
 	// Setup flags (not persistent at runtime; used during --setup invocation)
 	Setup bool // Download model files and exit
 	Force bool // Re-download even if model files already exist
 
 	// Informational flags (handled in main, not here)
-	ListVoices bool   // List all available TTS voices and exit
-	VoiceInfo  string // Show details for a specific voice and exit
+	ListVoices        bool   // List all available TTS voices and exit
+	VoiceInfo         string // Show details for a specific voice and exit
+	ListInputDevices  bool   // List capture devices (microphones) and exit
+	ListOutputDevices bool   // List playback devices (speakers) and exit
+
+	// Diagnostics (handled in main, not here)
+	MeasureLatency bool // Measure speaker-to-microphone loopback latency and exit
+
+	// Offline synthesis and transcription (handled in main, not here)
+	SynthesizeTo string // Write the speech for Text (or stdin) to this WAV file and exit
+	Text         string // Text to speak with SynthesizeTo (empty reads stdin)
+	Transcribe   string // Print the transcript of each speech segment in this WAV file and exit
 }
 
 // DefaultConfig returns a configuration with sensible defaults.
@@ -131,11 +536,19 @@ func DefaultConfig() *Config {
 
 	return &Config{
 		ModelDir:           defaultModelDir,
+		ModelLoadTimeout:   2 * time.Minute,
 		SampleRate:         16000,
 		VadThreshold:       0.5,
 		VADSilenceDuration: 0.8, // Allow 800ms pauses in natural speech
+		VADBufferSeconds:   60,
+
+		VADMinSpeechDuration: 0.1,
+		VADMaxSpeechDuration: 30,
+		VADPreSpeechPadMs:    200,
+		ContextDumpDir:       "context-dumps",
 
 		// LLM defaults
+		LLMBackend:   "ollama",
 		OllamaURL:    "http://localhost:11434",
 		OllamaModel:  "qwen2.5:1.5b",
 		SystemPrompt: "You are a helpful voice assistant. Keep responses brief and concise, maximum 2-3 short sentences. Be conversational and natural for speech output. IMPORTANT: Your responses will be read aloud, so you must NEVER use markdown, asterisks, underscores, backticks, brackets, code blocks, bullet points, numbered lists, special characters, or any formatting. Use only plain text with normal punctuation. Speak naturally as if having a conversation.",
@@ -143,11 +556,27 @@ func DefaultConfig() *Config {
 		Temperature:  0.7, // Default creativity level
 		SearxngURL:   "",  // Empty = use DuckDuckGo fallback
 
+		LLMMaxRetries:     2,
+		LLMRetryBackoffMs: 500,
+
+		LLMNumPredict: 150,
+		LLMNumCtx:     1024,
+
+		EmptyResponseFallback:    "I didn't catch that, could you rephrase?",
+		EmptyAfterFilterFallback: "Sorry, I can't say that reply out loud.",
+		ToolProgressDelay:        2 * time.Second, // No ToolProgressPhrases: progress is opt-in
+
 		// TTS defaults (voice name and speaker ID are generic TTS concepts)
 		TTSVoice:     "af_bella", // Default voice
 		TTSSpeakerID: 2,          // Default speaker ID
 		TTSSpeed:     0.93,
 
+		TTSMaxNumSentences: 1, // Kokoro only supports 1
+		MaxSynthLookahead:  2,
+
+		SentenceSoftMinChars: 80,
+		MaxSentenceChars:     250,
+
 		// STT defaults
 		STTBackend:  "whisper", // Default STT backend
 		TTSBackend:  "kokoro",  // Default TTS backend
@@ -155,8 +584,9 @@ func DefaultConfig() *Config {
 		STTLanguage: "en",      // Default to English for STT
 
 		// No wake word by default (always listening)
-		WakeWord: "",
-		Verbose:  false,
+		WakeWord:      nil,
+		WakeWordGrace: 4 * time.Second,
+		Verbose:       false,
 		// Auto-detect provider (empty = auto)
 		Provider:    "",
 		STTProvider: "",
@@ -165,6 +595,10 @@ func DefaultConfig() *Config {
 		// Interrupt mode defaults
 		InterruptMode:       InterruptWait,
 		PostPlaybackDelayMs: 300,
+		BargeInGraceMs:      200,
+		SelfEchoSuppression: 3 * time.Second,
+
+		VADThresholdDuringPlayback: 0.85,
 
 		// Thread count defaults (0 = auto-detect)
 		NumThreads: 0,
@@ -173,85 +607,311 @@ func DefaultConfig() *Config {
 		TTSThreads: 0,
 
 		// Audio buffer defaults (0 = 100ms, optimized for Bluetooth)
-		AudioBufferMs: 0,
+		AudioBufferMs:  0,
+		FadeMs:         5,
+		OutputChannels: 1,
+		OutputFormat:   "pcm",
+		DeadMicWindow:  10 * time.Second,
+		ClipThreshold:  0.01,
+		AGCTargetDB:    -20,
+
+		DirectionMaxDelay:     60 * time.Microsecond,
+		DirectionMaxLevelDiff: 6,
+
+		// Greeting defaults (no greeting; mic muted while one plays)
+		Greeting:           "",
+		MuteDuringGreeting: true,
+
+		// Re-engagement defaults (disabled)
+		ReengagePrompt: "Are you still there?",
+		ReengageAfter:  0,
+
+		// Replay defaults (resume is opt-in: "go on" may be meant for the LLM)
+		ReplayPhrases: []string{"say that again", "repeat that", "repeat"},
+
+		// Runtime VAD sensitivity defaults
+		PersonaPhrases:       []string{"be my", "switch to"},
+		ModelPhrases:         []string{"switch to", "use"},
+		MoreSensitivePhrases: []string{"be more sensitive"},
+		LessSensitivePhrases: []string{"be less sensitive"},
+
+		// Runtime speech speed defaults
+		FasterPhrases: []string{"speak faster", "talk faster"},
+		SlowerPhrases: []string{"speak slower", "talk slower", "slow down"},
+
+		// Transcript defaults (disabled)
+		TranscriptLog:    "",
+		TranscriptFormat: "jsonl",
+
+		// One request per engine at a time: each engine serializes inference anyway
+		MaxConcurrentRequests: 1,
 	}
 }
 
-// ParseFlags parses command-line flags and returns a Config.
+// ParseFlags parses command-line flags and returns a Config. Settings are
+// taken from, in increasing precedence: [DefaultConfig], VA_* environment
+// variables (see [ApplyEnv]), the file named by --config (see [LoadFromFile])
+// and flags given on the command line.
 func ParseFlags() (*Config, error) {
 	cfg := DefaultConfig()
+	if err := ApplyEnv(cfg); err != nil {
+		return nil, err
+	}
+	return parse(flag.CommandLine, cfg, os.Args[1:], "")
+}
+
+// LoadFromFile returns the Config described by the YAML (.yaml, .yml) or JSON
+// (.json) file at path. Its keys are flag names without the dashes, e.g.
+//
+//	tts-voice: ef_dora
+//	vad-threshold: 0.4
+//	output-device-fallback: [bt-speaker, built-in]
+//
+// and values are validated as if given as flags. Lists may be written as
+// sequences and model-aliases as a mapping. Unknown keys are an error.
+//
+// Precedence is defaults < file < flags: the file overrides [DefaultConfig],
+// and when it is loaded through --config, flags given on the command line
+// override the file.
+func LoadFromFile(path string) (*Config, error) {
+	return parse(flag.NewFlagSet("config", flag.ContinueOnError), DefaultConfig(), nil, path)
+}
+
+// parse defines the flags on fs with cfg's values as defaults, parses args and,
+// when file (or --config in args) names a config file, applies its settings to
+// the flags args did not set, then validates the result.
+func parse(fs *flag.FlagSet, cfg *Config, args []string, file string) (*Config, error) {
+	fs.StringVar(&file, "config", file, "YAML or JSON file with settings keyed by flag name; flags on the command line override it")
 
 	// Informational flags (handled by the caller after ParseFlags returns)
-	flag.BoolVar(&cfg.ListVoices, "list-voices", false, "List all available TTS voices and exit")
-	flag.StringVar(&cfg.VoiceInfo, "voice-info", "", "Show detailed information about a specific voice and exit")
+	fs.BoolVar(&cfg.ListVoices, "list-voices", cfg.ListVoices, "List all available TTS voices and exit")
+	fs.StringVar(&cfg.VoiceInfo, "voice-info", cfg.VoiceInfo, "Show detailed information about a specific voice and exit")
+	fs.BoolVar(&cfg.ListInputDevices, "list-input-devices", cfg.ListInputDevices, "List microphones (index, name, channels, sample rate) for --input-device and exit")
+	fs.BoolVar(&cfg.ListOutputDevices, "list-output-devices", cfg.ListOutputDevices, "List speakers (index, name, channels, sample rate) for --output-device and exit")
+	fs.BoolVar(&cfg.MeasureLatency, "measure-latency", cfg.MeasureLatency, "Play test clicks and measure speaker-to-microphone latency, then exit")
+	fs.StringVar(&cfg.SynthesizeTo, "synthesize-to", cfg.SynthesizeTo, "Speak --text (or stdin) into this WAV file and exit, without audio devices, STT or the LLM")
+	fs.StringVar(&cfg.Text, "text", cfg.Text, "Text to speak with --synthesize-to (default: read stdin)")
+	fs.StringVar(&cfg.Transcribe, "transcribe", cfg.Transcribe, "Split this WAV file into speech segments, print the transcript of each and exit, without audio devices or the LLM")
 
 	// Setup flags
-	flag.BoolVar(&cfg.Setup, "setup", false, "Download required model files then exit (idempotent, safe to re-run)")
-	flag.BoolVar(&cfg.Force, "force", false, "Force re-download of model files even if they already exist (use with --setup)")
+	fs.BoolVar(&cfg.Setup, "setup", cfg.Setup, "Download required model files then exit (idempotent, safe to re-run)")
+	fs.BoolVar(&cfg.Force, "force", cfg.Force, "Force re-download of model files even if they already exist (use with --setup)")
 
 	// Model directory
-	flag.StringVar(&cfg.ModelDir, "model-dir", cfg.ModelDir, "Base directory for all model files")
+	fs.StringVar(&cfg.ModelDir, "model-dir", cfg.ModelDir, "Base directory for all model files")
+	fs.DurationVar(&cfg.ModelLoadTimeout, "model-load-timeout", cfg.ModelLoadTimeout, "Give up if loading the speech models takes longer than this (0 = no limit)")
 
 	// Audio settings
-	flag.IntVar(&cfg.SampleRate, "sample-rate", cfg.SampleRate, "Audio sample rate for speech recognition")
+	fs.IntVar(&cfg.SampleRate, "sample-rate", cfg.SampleRate, "Audio sample rate for speech recognition")
 	vadThreshold := float64(cfg.VadThreshold)
-	flag.Float64Var(&vadThreshold, "vad-threshold", vadThreshold, "Voice activity detection threshold (0.0-1.0)")
+	fs.Float64Var(&vadThreshold, "vad-threshold", vadThreshold, "Voice activity detection threshold (0.0-1.0)")
 	vadSilenceDuration := float64(cfg.VADSilenceDuration)
-	flag.Float64Var(&vadSilenceDuration, "vad-silence-duration", vadSilenceDuration, "VAD silence duration in seconds (how long to wait before speech is considered ended)")
+	fs.Float64Var(&vadSilenceDuration, "vad-silence-duration", vadSilenceDuration, "VAD silence duration in seconds (how long to wait before speech is considered ended)")
+	vadMinSpeechDuration := float64(cfg.VADMinSpeechDuration)
+	fs.Float64Var(&vadMinSpeechDuration, "vad-min-speech-duration", vadMinSpeechDuration, "Ignore speech shorter than this many seconds (raise to reject noise bursts)")
+	vadMaxSpeechDuration := float64(cfg.VADMaxSpeechDuration)
+	fs.Float64Var(&vadMaxSpeechDuration, "vad-max-speech-duration", vadMaxSpeechDuration, "Split continuous speech into segments of at most this many seconds")
+	vadBufferSeconds := float64(cfg.VADBufferSeconds)
+	fs.Float64Var(&vadBufferSeconds, "vad-buffer-seconds", vadBufferSeconds, "VAD audio buffer depth in seconds (memory = seconds x sample-rate x 4 bytes; at least vad-max-speech-duration)")
+	fs.IntVar(&cfg.VADPreSpeechPadMs, "vad-pre-speech-pad-ms", cfg.VADPreSpeechPadMs, "Milliseconds of audio before detected speech to prepend to each segment (0 disables)")
+	maxTurnAudioSeconds := float64(cfg.MaxTurnAudioSeconds)
+	fs.Float64Var(&maxTurnAudioSeconds, "max-turn-audio-seconds", maxTurnAudioSeconds, "Maximum seconds of speech per turn across segments (0 = unlimited)")
+	contextDumpSeconds := float64(cfg.ContextDumpSeconds)
+	fs.Float64Var(&contextDumpSeconds, "context-dump-seconds", contextDumpSeconds, "Save each transcribed turn to a WAV with this many seconds of audio before and after it (0 disables)")
+	fs.StringVar(&cfg.ContextDumpDir, "context-dump-dir", cfg.ContextDumpDir, "Directory for --context-dump-seconds WAV files")
 
 	// LLM settings
-	flag.StringVar(&cfg.OllamaURL, "ollama-url", cfg.OllamaURL, "Ollama API URL")
-	flag.StringVar(&cfg.OllamaModel, "ollama-model", cfg.OllamaModel, "Ollama model name (must support tool calling, e.g., qwen2.5:1.5b, qwen2.5:3b)")
-	flag.StringVar(&cfg.SystemPrompt, "system-prompt", cfg.SystemPrompt, "System prompt for the LLM")
-	flag.IntVar(&cfg.MaxHistory, "max-history", cfg.MaxHistory, "Maximum conversation history length")
+	fs.StringVar(&cfg.LLMBackend, "llm-backend", cfg.LLMBackend, "LLM backend ('ollama' or 'openai' for OpenAI-compatible servers such as vLLM or LM Studio)")
+	fs.StringVar(&cfg.LLMAPIKey, "llm-api-key", cfg.LLMAPIKey, "API key sent as a bearer token by the openai LLM backend (empty = none)")
+	fs.StringVar(&cfg.OllamaURL, "ollama-url", cfg.OllamaURL, "Ollama API URL, or the server URL for --llm-backend openai (e.g. http://localhost:8000/v1)")
+	fs.StringVar(&cfg.OllamaModel, "ollama-model", cfg.OllamaModel, "Ollama model name, or the model ID for --llm-backend openai (must support tool calling, e.g., qwen2.5:1.5b, qwen2.5:3b)")
+	fs.StringVar(&cfg.SystemPrompt, "system-prompt", cfg.SystemPrompt, "System prompt for the LLM (supports {{.Time}}, {{.Date}}, {{.Weekday}}, {{.Year}}, {{.Timezone}})")
+	fs.IntVar(&cfg.MaxHistory, "max-history", cfg.MaxHistory, "Maximum conversation history length")
+	fs.StringVar(&cfg.HistoryFile, "history-file", cfg.HistoryFile, "Load conversation history from this file at startup and save it there on shutdown (empty disables)")
 	temperature := float64(cfg.Temperature)
-	flag.Float64Var(&temperature, "temperature", temperature, "LLM temperature (0.0-2.0). Lower values (0.1-0.3) for translation/factual tasks, higher (0.7-1.0) for creative responses")
-	flag.StringVar(&cfg.SearxngURL, "searxng-url", cfg.SearxngURL, "Optional SearXNG URL for web search (empty uses DuckDuckGo fallback)")
+	fs.Float64Var(&temperature, "temperature", temperature, "LLM temperature (0.0-2.0). Lower values (0.1-0.3) for translation/factual tasks, higher (0.7-1.0) for creative responses")
+	fs.StringVar(&cfg.SearxngURL, "searxng-url", cfg.SearxngURL, "Optional SearXNG URL for web search (empty uses DuckDuckGo fallback)")
+	fs.StringVar(&cfg.KeepAlive, "ollama-keep-alive", cfg.KeepAlive, "How long Ollama keeps the model loaded after a request (e.g. 10m, -1 = forever, empty = server default)")
+	fs.StringVar(&cfg.PersonasFile, "personas-file", cfg.PersonasFile, "JSON file mapping persona names to {\"prompt\": ..., \"temperature\": ...}")
+	fs.StringVar(&cfg.Persona, "persona", cfg.Persona, "Persona from --personas-file to start with (empty = --system-prompt)")
+	modelAliases := fs.String("model-aliases", joinModelAliases(cfg.ModelAliases), "Comma-separated name=model pairs for switching models by voice, e.g. 'fast=qwen2.5:1.5b,smart=qwen2.5:7b'")
+	modelPhrases := fs.String("model-phrases", strings.Join(cfg.ModelPhrases, ","), "Comma-separated phrases that switch model when followed by an alias and 'model', e.g. 'switch to' (empty disables)")
+	personaPhrases := fs.String("persona-phrases", strings.Join(cfg.PersonaPhrases, ","), "Comma-separated phrases that switch persona when followed by its name, e.g. 'be my' (empty disables)")
+	fs.IntVar(&cfg.LLMMaxRetries, "llm-max-retries", cfg.LLMMaxRetries, "Retries of an LLM request that failed with a connection error, timeout or server error (0 disables)")
+	fs.IntVar(&cfg.LLMRetryBackoffMs, "llm-retry-backoff-ms", cfg.LLMRetryBackoffMs, "Milliseconds to wait before the first LLM retry, doubled for each further one")
+	fs.IntVar(&cfg.LLMNumPredict, "llm-num-predict", cfg.LLMNumPredict, "Maximum tokens per LLM reply; longer replies are cut off")
+	fs.IntVar(&cfg.LLMNumCtx, "llm-num-ctx", cfg.LLMNumCtx, "LLM context window in tokens (larger keeps more conversation in view but uses more GPU memory; Ollama only)")
+	fs.StringVar(&cfg.EmptyResponseFallback, "empty-response-fallback", cfg.EmptyResponseFallback, "Phrase spoken when the LLM returns an empty reply after one retry (empty = stay silent)")
+	fs.StringVar(&cfg.EmptyAfterFilterFallback, "empty-after-filter-fallback", cfg.EmptyAfterFilterFallback, "Phrase spoken when a reply has nothing speakable, e.g. only emoji or symbols (empty = stay silent)")
+	fs.DurationVar(&cfg.ToolProgressDelay, "tool-progress-delay", cfg.ToolProgressDelay, "Speak a progress phrase when a tool call runs longer than this (0 disables)")
+	toolProgressPhrases := fs.String("tool-progress-phrases", strings.Join(cfg.ToolProgressPhrases, ","), "Comma-separated phrases spoken in turn while a slow tool call runs (empty disables)")
+	fs.BoolVar(&cfg.PauseForAnnouncements, "pause-for-announcements", cfg.PauseForAnnouncements, "Pause a response being spoken for an announcement (e.g. a timer) and resume it afterwards, instead of cutting it off")
 
 	// TTS settings
 	ttsSpeed := float64(cfg.TTSSpeed)
-	flag.Float64Var(&ttsSpeed, "tts-speed", ttsSpeed, "Text-to-speech speed multiplier")
-	flag.StringVar(&cfg.TTSVoice, "tts-voice", cfg.TTSVoice, "TTS voice name (e.g., 'bf_emma', 'af_bella')")
-	flag.IntVar(&cfg.TTSSpeakerID, "tts-speaker-id", cfg.TTSSpeakerID, "TTS speaker ID (bf_emma=21, af_bella=2)")
+	fs.Float64Var(&ttsSpeed, "tts-speed", ttsSpeed, "Text-to-speech speed multiplier")
+	fs.StringVar(&cfg.TTSVoice, "tts-voice", cfg.TTSVoice, "TTS voice name (e.g., 'bf_emma', 'af_bella')")
+	fs.IntVar(&cfg.TTSSpeakerID, "tts-speaker-id", cfg.TTSSpeakerID, "TTS speaker ID (bf_emma=21, af_bella=2)")
+	fs.IntVar(&cfg.SentenceMinChars, "sentence-min-chars", cfg.SentenceMinChars, "Join sentences shorter than this many characters with the next one before synthesis (0 = never)")
+	fs.StringVar(&cfg.SentenceSoftBoundaries, "sentence-soft-boundaries", cfg.SentenceSoftBoundaries, "Extra characters that split long sentences for faster playback, e.g. \",;:\" (empty = sentence boundaries only)")
+	fs.IntVar(&cfg.SentenceSoftMinChars, "sentence-soft-min-chars", cfg.SentenceSoftMinChars, "Minimum characters before a soft boundary splits a sentence")
+	fs.IntVar(&cfg.MaxSentenceChars, "max-sentence-chars", cfg.MaxSentenceChars, "Cut longer sentences at word boundaries before synthesis so they stay interruptible (0 = no limit)")
+	trimSilence := float64(cfg.TrimSilence)
+	fs.Float64Var(&trimSilence, "trim-silence", trimSilence, "Trim leading/trailing synthesized audio quieter than this amplitude, e.g. 0.01 (0 disables)")
+	fs.IntVar(&cfg.MaxSynthLookahead, "max-synth-lookahead", cfg.MaxSynthLookahead, "Maximum sentences synthesized ahead of playback (lower wastes less work on interruption)")
+	fs.BoolVar(&cfg.StreamSynthesis, "stream-synthesis", cfg.StreamSynthesis, "Start playing each sentence as its first audio is synthesized, for long sentences (not with --trim-silence, <emphasis> or [phon:...] markup)")
+	fs.IntVar(&cfg.TTSMaxNumSentences, "tts-max-sentences", cfg.TTSMaxNumSentences, "Maximum sentences per TTS engine batch (Kokoro only supports 1)")
+	fs.BoolVar(&cfg.VoiceDirectives, "voice-directives", cfg.VoiceDirectives, "Honor inline [voice:NAME] directives in spoken text, switching voices between sentences (e.g. for dialogue; [voice:default] switches back)")
+	fs.BoolVar(&cfg.PhonemeMarkup, "phoneme-markup", cfg.PhonemeMarkup, "Honor inline [phon:PHONEMES|fallback] markup in spoken text (fallback text is spoken if the TTS model can't take phonemes)")
+	fs.BoolVar(&cfg.SSMLMarkup, "ssml", cfg.SSMLMarkup, "Honor <break time=\"500ms\"/> and <emphasis>...</emphasis> in spoken text (other tags are stripped)")
+	fs.StringVar(&cfg.UserLexicon, "user-lexicon", cfg.UserLexicon, "Supplemental lexicon file with pronunciation overrides (word followed by phonemes, one per line)")
 
 	// Backend selection
-	flag.StringVar(&cfg.STTBackend, "stt-backend", cfg.STTBackend, "STT backend implementation (e.g. 'whisper')")
-	flag.StringVar(&cfg.TTSBackend, "tts-backend", cfg.TTSBackend, "TTS backend implementation (e.g. 'kokoro')")
+	fs.StringVar(&cfg.STTBackend, "stt-backend", cfg.STTBackend, "STT backend implementation (e.g. 'whisper')")
+	fs.StringVar(&cfg.TTSBackend, "tts-backend", cfg.TTSBackend, "TTS backend implementation ('kokoro' or 'http')")
+	fs.StringVar(&cfg.TTSURL, "tts-url", cfg.TTSURL, "Remote TTS endpoint for --tts-backend http (POST JSON text, returns WAV)")
 
 	// STT settings
-	flag.StringVar(&cfg.STTModel, "stt-model", cfg.STTModel, "STT model identifier (e.g. tiny, base, small)")
-	flag.StringVar(&cfg.STTLanguage, "stt-language", cfg.STTLanguage, "STT language code (e.g., 'en', 'es', 'fr', 'auto' for detection)")
+	fs.StringVar(&cfg.STTModel, "stt-model", cfg.STTModel, "STT model identifier (e.g. tiny, base, small)")
+	fs.StringVar(&cfg.STTLanguage, "stt-language", cfg.STTLanguage, "STT language code (e.g., 'en', 'es', 'fr', 'auto' for detection)")
+	fs.BoolVar(&cfg.MatchResponseLanguage, "match-response-language", cfg.MatchResponseLanguage, "Reply in the language detected in each transcript, switching TTS voice to match (requires --stt-language auto)")
+	fs.BoolVar(&cfg.AutoPunctuate, "auto-punctuate", cfg.AutoPunctuate, "Capitalize transcripts and add missing terminal punctuation (question detection is English-only)")
+	fs.BoolVar(&cfg.HistoryRawTranscript, "history-raw-transcript", cfg.HistoryRawTranscript, "Send and store transcripts verbatim (wake word kept, not punctuated) instead of cleaned up")
+	fs.StringVar(&cfg.HotwordsFile, "hotwords-file", cfg.HotwordsFile, "File of expected phrases, one per line; transcripts that nearly match one are replaced by it")
+	maxNoSpeechProb := float64(cfg.MaxNoSpeechProb)
+	fs.Float64Var(&maxNoSpeechProb, "max-no-speech-prob", maxNoSpeechProb, "Drop transcripts more likely than this to be decoded noise, e.g. 0.6 (0 disables; needs a model reporting token probabilities)")
+	fs.BoolVar(&cfg.RetryEmptyTranscript, "retry-empty-transcript", cfg.RetryEmptyTranscript, "Decode a speech segment once more when it transcribes to nothing")
 
 	// Hardware acceleration
-	flag.StringVar(&cfg.Provider, "provider", cfg.Provider, "Hardware acceleration provider (cpu, cuda, coreml). Auto-detected if not specified")
-	flag.StringVar(&cfg.STTProvider, "stt-provider", cfg.STTProvider, "Provider for STT (overrides --provider for speech recognition)")
-	flag.StringVar(&cfg.TTSProvider, "tts-provider", cfg.TTSProvider, "Provider for TTS (overrides --provider for speech synthesis)")
+	fs.StringVar(&cfg.Provider, "provider", cfg.Provider, "Hardware acceleration provider (cpu, cuda, coreml). Auto-detected if not specified")
+	fs.StringVar(&cfg.STTProvider, "stt-provider", cfg.STTProvider, "Provider for STT (overrides --provider for speech recognition)")
+	fs.StringVar(&cfg.TTSProvider, "tts-provider", cfg.TTSProvider, "Provider for TTS (overrides --provider for speech synthesis)")
 
 	// Thread count settings
-	flag.IntVar(&cfg.NumThreads, "num-threads", cfg.NumThreads, "Number of threads for all models (0 = auto-detect based on CPU cores)")
-	flag.IntVar(&cfg.VADThreads, "vad-threads", cfg.VADThreads, "VAD threads (0 = use num-threads, typically 1)")
-	flag.IntVar(&cfg.STTThreads, "stt-threads", cfg.STTThreads, "STT threads (0 = use num-threads, typically cores/2)")
-	flag.IntVar(&cfg.TTSThreads, "tts-threads", cfg.TTSThreads, "TTS threads (0 = use num-threads, typically cores/2)")
+	fs.IntVar(&cfg.NumThreads, "num-threads", cfg.NumThreads, "Number of threads for all models (0 = auto-detect based on CPU cores)")
+	fs.IntVar(&cfg.VADThreads, "vad-threads", cfg.VADThreads, "VAD threads (0 = use num-threads, typically 1)")
+	fs.IntVar(&cfg.STTThreads, "stt-threads", cfg.STTThreads, "STT threads (0 = use num-threads, typically cores/2)")
+	fs.IntVar(&cfg.TTSThreads, "tts-threads", cfg.TTSThreads, "TTS threads (0 = use num-threads, typically cores/2)")
 
 	// Audio settings
-	audioBufferMs := flag.Uint("audio-buffer-ms", uint(cfg.AudioBufferMs), "Audio buffer size in ms (0=auto 100ms for Bluetooth, 20ms for wired/built-in)")
+	fs.BoolVar(&cfg.EchoCancel, "echo-cancel", cfg.EchoCancel, "Remove the assistant's own voice from the microphone so open speakers can be used with --interrupt-mode always")
+	fs.DurationVar(&cfg.EchoCancelDelay, "echo-cancel-delay", cfg.EchoCancelDelay, "Skip this much of the playback reference before the echo canceller's 128ms filter, for echoes that arrive later (e.g. Bluetooth)")
+	fs.StringVar(&cfg.InputDevice, "input-device", cfg.InputDevice, "Microphone to capture from, by index or name substring, e.g. \"respeaker\" (empty = system default)")
+	fs.IntVar(&cfg.OutputChannels, "output-channels", cfg.OutputChannels, "Playback channels: 1 (mono) or 2 (stereo, mono audio duplicated to both channels)")
+	fs.StringVar(&cfg.OutputDevice, "output-device", cfg.OutputDevice, "Speaker to play through, by index or name substring, e.g. \"usb\" (empty = system default)")
+	outputDevices := fs.String("output-device-fallback", strings.Join(cfg.OutputDevices, ","), "Comma-separated output devices to try in order, matched by name (e.g. \"bt-speaker,built-in\"; empty = system default)")
+	fs.DurationVar(&cfg.OutputDeviceMigrate, "output-device-migrate", cfg.OutputDeviceMigrate, "Check this often for a preferred --output-device-fallback device and switch to it when it appears (0 = never)")
+	fs.StringVar(&cfg.Output, "output", cfg.Output, "Stream playback to 'sink:pipe:PATH' (named pipe or file) or 'sink:stdout' instead of the output device (empty = output device)")
+	fs.StringVar(&cfg.OutputFormat, "output-format", cfg.OutputFormat, "Encoding of the --output stream: pcm (raw 16-bit mono) or wav")
+	fs.BoolVar(&cfg.OutputTee, "output-tee", cfg.OutputTee, "Keep playing on the output device while streaming to --output")
+	fs.BoolVar(&cfg.DirectionGate, "direction-gate", cfg.DirectionGate, "Only let speech from in front of a stereo microphone array interrupt playback")
+	fs.DurationVar(&cfg.DirectionMaxDelay, "direction-max-delay", cfg.DirectionMaxDelay, "Largest delay between the two microphones still counted as in front (with --direction-gate)")
+	directionMaxLevelDiff := float64(cfg.DirectionMaxLevelDiff)
+	fs.Float64Var(&directionMaxLevelDiff, "direction-max-level-diff", directionMaxLevelDiff, "Largest level difference in dB between the two microphones still counted as in front (with --direction-gate)")
+	clipThreshold := float64(cfg.ClipThreshold)
+	fs.Float64Var(&clipThreshold, "clip-threshold", clipThreshold, "Warn when more than this fraction of input samples clip in a second, e.g. 0.01 = 1% (0 disables)")
+	noiseFloorDB := float64(cfg.NoiseFloorDB)
+	fs.Float64Var(&noiseFloorDB, "noise-floor-db", noiseFloorDB, "Silence input quieter than this RMS level in dBFS before the VAD, e.g. -50 against HVAC hum (0 disables)")
+	fs.BoolVar(&cfg.AGC, "agc", cfg.AGC, "Apply automatic gain control to the microphone, boosting quiet input toward --agc-target-db")
+	agcTargetDB := float64(cfg.AGCTargetDB)
+	fs.Float64Var(&agcTargetDB, "agc-target-db", agcTargetDB, "RMS level in dBFS that --agc normalizes speech toward")
+	fs.DurationVar(&cfg.DeadMicWindow, "dead-mic-window", cfg.DeadMicWindow, "Warn when the microphone has been completely silent (e.g. muted) for this long (0 disables)")
+	fs.IntVar(&cfg.AudioMemoryBudgetMB, "audio-memory-budget-mb", cfg.AudioMemoryBudgetMB, "Cap audio buffer memory at this many MB, dropping the replay cache and then recorded reply audio when exceeded (0 = unlimited)")
+	audioBufferMs := fs.Uint("audio-buffer-ms", uint(cfg.AudioBufferMs), "Audio buffer size in ms (0=auto 100ms for Bluetooth, 20ms for wired/built-in)")
+	fs.IntVar(&cfg.FadeMs, "fade-ms", cfg.FadeMs, "Fade each sentence in and out over this many ms during playback to avoid clicks (0 disables)")
 
 	// Other settings
-	flag.StringVar(&cfg.WakeWord, "wake-word", cfg.WakeWord, "Wake word to activate the assistant (optional)")
-	flag.BoolVar(&cfg.Verbose, "verbose", cfg.Verbose, "Enable verbose logging")
+	wakeWords := fs.String("wake-word", strings.Join(cfg.WakeWord, ","), "Wake word to activate the assistant, or a comma-separated list of them, e.g. \"hey sherpa,computer\" (optional)")
+	fs.IntVar(&cfg.WakeWordTolerance, "wake-word-tolerance", cfg.WakeWordTolerance, "Accept a wake word misheard by up to this many characters, e.g. 2 for \"hey sharpa\" (0 = exact match)")
+	fs.DurationVar(&cfg.WakeWordGrace, "wake-word-grace", cfg.WakeWordGrace, "After the wake word alone, accept a command without it if spoken within this long (0 = reply to the bare wake word)")
+	fs.BoolVar(&cfg.PushToTalk, "push-to-talk", cfg.PushToTalk, "Only listen while toggled on with the space bar (stdin must be a terminal)")
+	fs.BoolVar(&cfg.Verbose, "verbose", cfg.Verbose, "Enable verbose logging")
+	fs.BoolVar(&cfg.LogRequests, "log-requests", cfg.LogRequests, "Log each request sent to Ollama, including the full conversation history (for debugging prompts)")
+	fs.BoolVar(&cfg.Metrics, "metrics", cfg.Metrics, "Log how long each turn spent in VAD, STT, LLM and TTS, and a p50/p95 summary on shutdown")
+	fs.BoolVar(&cfg.JSONEvents, "json-events", cfg.JSONEvents, "Write transcripts, responses and interrupts to stdout as JSON lines (logs go to stderr)")
+	fs.StringVar(&cfg.HTTPAddr, "http-addr", cfg.HTTPAddr, "Listen address for the HTTP status server with /status, /healthz, /metrics, /transcribe and /speak (e.g. ':8080'; empty disables)")
+	fs.IntVar(&cfg.MaxConcurrentRequests, "max-concurrent-requests", cfg.MaxConcurrentRequests, "Maximum simultaneous HTTP /transcribe or /speak requests per engine; extra requests get 429")
+
+	fs.StringVar(&cfg.ControlSocket, "control-socket", cfg.ControlSocket, "Unix socket path for external control commands: interrupt, mute, unmute, reset, pause, resume (empty disables)")
+
+	// Transcript settings
+	fs.StringVar(&cfg.TranscriptLog, "transcript-log", cfg.TranscriptLog, "Append every conversation turn to this file (empty disables)")
+	fs.StringVar(&cfg.RecordFixtures, "record-fixtures", cfg.RecordFixtures, "Save every turn as a replayable fixture bundle under this directory (empty disables)")
+	fs.StringVar(&cfg.ReplayFixtures, "replay-fixtures", cfg.ReplayFixtures, "Replay the fixture bundle(s) at this path through STT and the LLM, report differences and exit")
+	fs.StringVar(&cfg.TranscriptFormat, "transcript-format", cfg.TranscriptFormat, "Transcript format: 'jsonl', 'text' or 'markdown'")
 
 	// Interrupt mode settings
 	var interruptModeStr string
-	flag.StringVar(&interruptModeStr, "interrupt-mode", cfg.InterruptMode.String(), "Interrupt mode: 'always' (headsets) or 'wait' (open speakers, pauses mic during playback)")
-	flag.IntVar(&cfg.PostPlaybackDelayMs, "post-playback-delay-ms", cfg.PostPlaybackDelayMs, "Delay in milliseconds before resuming mic after playback (only for 'wait' mode)")
+	fs.StringVar(&interruptModeStr, "interrupt-mode", cfg.InterruptMode.String(), "Interrupt mode: 'always' (headsets), 'wait' (open speakers, pauses mic during playback) or 'sentence' (stop after the current sentence)")
+	var pipelineModeStr string
+	fs.StringVar(&pipelineModeStr, "pipeline-mode", cfg.PipelineMode.String(), "Pipeline mode: 'overlapped' (LLM may answer the next prompt while a reply plays) or 'sequential' (next LLM turn starts after playback)")
+	fs.DurationVar(&cfg.SelfEchoSuppression, "self-echo-suppression", cfg.SelfEchoSuppression, "Ignore transcripts that repeat the assistant's last reply within this long after playback (0 disables)")
+	fs.BoolVar(&cfg.WaitModeListenDuringPlayback, "wait-mode-listen-during-playback", cfg.WaitModeListenDuringPlayback, "In 'wait' mode, keep listening during playback at --vad-threshold-during-playback instead of pausing the mic")
+	vadThresholdDuringPlayback := float64(cfg.VADThresholdDuringPlayback)
+	fs.Float64Var(&vadThresholdDuringPlayback, "vad-threshold-during-playback", vadThresholdDuringPlayback, "VAD threshold while the assistant speaks with --wait-mode-listen-during-playback (0.0-1.0, above --vad-threshold)")
+	fs.IntVar(&cfg.PostPlaybackDelayMs, "post-playback-delay-ms", cfg.PostPlaybackDelayMs, "Delay in milliseconds before resuming mic after playback (only for 'wait' mode)")
+	fs.IntVar(&cfg.BargeInGraceMs, "barge-in-grace-ms", cfg.BargeInGraceMs, "Milliseconds at the start of each sentence during which speech does not interrupt it (0 disables; not used in 'sentence' mode)")
+
+	// Greeting settings
+	fs.StringVar(&cfg.Greeting, "greeting", cfg.Greeting, "Text spoken once at startup (empty disables)")
+	fs.BoolVar(&cfg.MuteDuringGreeting, "mute-during-greeting", cfg.MuteDuringGreeting, "Keep the microphone off until the startup greeting finishes playing")
+
+	// Re-engagement settings
+	fs.StringVar(&cfg.ReengagePrompt, "reengage-prompt", cfg.ReengagePrompt, "Prompt spoken once when the user goes quiet during a conversation")
+	fs.DurationVar(&cfg.ReengageAfter, "reengage-after", cfg.ReengageAfter, "Silence after a response before speaking --reengage-prompt, e.g. 20s (0 disables)")
+
+	// Replay and resume settings
+	replayPhrases := fs.String("replay-phrases", strings.Join(cfg.ReplayPhrases, ","), "Comma-separated phrases that replay the last response without querying the LLM (empty disables)")
+	resumePhrases := fs.String("resume-phrases", strings.Join(cfg.ResumePhrases, ","), "Comma-separated phrases that continue an interrupted response where it stopped (empty disables)")
 
-	flag.Parse()
+	// Runtime VAD sensitivity
+	moreSensitivePhrases := fs.String("more-sensitive-phrases", strings.Join(cfg.MoreSensitivePhrases, ","), "Comma-separated phrases that lower the VAD threshold while running (empty disables)")
+	lessSensitivePhrases := fs.String("less-sensitive-phrases", strings.Join(cfg.LessSensitivePhrases, ","), "Comma-separated phrases that raise the VAD threshold while running (empty disables)")
+
+	// Runtime speech speed
+	fasterPhrases := fs.String("faster-phrases", strings.Join(cfg.FasterPhrases, ","), "Comma-separated phrases that make speech faster while running (empty disables)")
+	slowerPhrases := fs.String("slower-phrases", strings.Join(cfg.SlowerPhrases, ","), "Comma-separated phrases that make speech slower while running (empty disables)")
+
+	// Response chime
+	fs.StringVar(&cfg.ResponseChime, "response-chime", cfg.ResponseChime, "Sound played before each new response: 'tone' for the built-in chime or a WAV file path (empty disables)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if file != "" {
+		if err := applyConfigFile(fs, file); err != nil {
+			return nil, err
+		}
+	}
 
 	cfg.TTSSpeed = float32(ttsSpeed)
+	cfg.TrimSilence = float32(trimSilence)
+	cfg.MaxNoSpeechProb = float32(maxNoSpeechProb)
 	cfg.VadThreshold = float32(vadThreshold)
+	cfg.VADThresholdDuringPlayback = float32(vadThresholdDuringPlayback)
 	cfg.VADSilenceDuration = float32(vadSilenceDuration)
+	cfg.VADMinSpeechDuration = float32(vadMinSpeechDuration)
+	cfg.VADMaxSpeechDuration = float32(vadMaxSpeechDuration)
+	cfg.VADBufferSeconds = float32(vadBufferSeconds)
+	cfg.MaxTurnAudioSeconds = float32(maxTurnAudioSeconds)
+	cfg.ContextDumpSeconds = float32(contextDumpSeconds)
 	cfg.AudioBufferMs = uint32(*audioBufferMs)
+	cfg.DirectionMaxLevelDiff = float32(directionMaxLevelDiff)
+	cfg.ClipThreshold = float32(clipThreshold)
+	cfg.NoiseFloorDB = float32(noiseFloorDB)
+	cfg.AGCTargetDB = float32(agcTargetDB)
 	cfg.Temperature = float32(temperature)
+	cfg.ReplayPhrases = splitList(*replayPhrases)
+	cfg.OutputDevices = splitList(*outputDevices)
+	cfg.WakeWord = splitList(*wakeWords)
+	cfg.ToolProgressPhrases = splitList(*toolProgressPhrases)
+	cfg.ResumePhrases = splitList(*resumePhrases)
+	cfg.PersonaPhrases = splitList(*personaPhrases)
+	cfg.ModelPhrases = splitList(*modelPhrases)
+	cfg.MoreSensitivePhrases = splitList(*moreSensitivePhrases)
+	cfg.LessSensitivePhrases = splitList(*lessSensitivePhrases)
+	cfg.FasterPhrases = splitList(*fasterPhrases)
+	cfg.SlowerPhrases = splitList(*slowerPhrases)
 
 	// Validate numeric ranges
 	if cfg.Temperature < 0.0 || cfg.Temperature > 2.0 {
@@ -261,17 +921,207 @@ func ParseFlags() (*Config, error) {
 	if cfg.VadThreshold < 0.0 || cfg.VadThreshold > 1.0 {
 		return nil, fmt.Errorf("vad-threshold must be between 0.0 and 1.0, got %.2f", cfg.VadThreshold)
 	}
+	if cfg.AudioMemoryBudgetMB < 0 {
+		return nil, fmt.Errorf("audio-memory-budget-mb must be >= 0, got %d", cfg.AudioMemoryBudgetMB)
+	}
+	if cfg.MaxNoSpeechProb < 0 || cfg.MaxNoSpeechProb > 1 {
+		return nil, fmt.Errorf("max-no-speech-prob must be between 0 and 1, got %g", cfg.MaxNoSpeechProb)
+	}
+	if cfg.TrimSilence < 0 || cfg.TrimSilence >= 1 {
+		return nil, fmt.Errorf("trim-silence must be between 0 and 1, got %g", cfg.TrimSilence)
+	}
+	if cfg.WaitModeListenDuringPlayback && (cfg.VADThresholdDuringPlayback <= 0 || cfg.VADThresholdDuringPlayback >= 1) {
+		return nil, fmt.Errorf("vad-threshold-during-playback must be between 0.0 and 1.0, got %.2f", cfg.VADThresholdDuringPlayback)
+	}
+	if cfg.WaitModeListenDuringPlayback && cfg.VADThresholdDuringPlayback <= cfg.VadThreshold {
+		return nil, fmt.Errorf("vad-threshold-during-playback (%.2f) must be above vad-threshold (%.2f)", cfg.VADThresholdDuringPlayback, cfg.VadThreshold)
+	}
+
+	if cfg.VADMinSpeechDuration <= 0 || cfg.VADMaxSpeechDuration <= 0 {
+		return nil, fmt.Errorf("vad-min-speech-duration and vad-max-speech-duration must be positive, got %.2f and %.2f", cfg.VADMinSpeechDuration, cfg.VADMaxSpeechDuration)
+	}
+	if cfg.VADMinSpeechDuration >= cfg.VADMaxSpeechDuration {
+		return nil, fmt.Errorf("vad-min-speech-duration (%.2f) must be less than vad-max-speech-duration (%.2f)", cfg.VADMinSpeechDuration, cfg.VADMaxSpeechDuration)
+	}
+
+	if cfg.VADPreSpeechPadMs < 0 {
+		return nil, fmt.Errorf("vad-pre-speech-pad-ms must not be negative, got %d", cfg.VADPreSpeechPadMs)
+	}
+
+	if cfg.MaxTurnAudioSeconds < 0 {
+		return nil, fmt.Errorf("max-turn-audio-seconds must not be negative, got %.2f", cfg.MaxTurnAudioSeconds)
+	}
+
+	if cfg.ContextDumpSeconds < 0 {
+		return nil, fmt.Errorf("context-dump-seconds must not be negative, got %.2f", cfg.ContextDumpSeconds)
+	}
+
+	if cfg.MatchResponseLanguage && !strings.EqualFold(cfg.STTLanguage, "auto") {
+		return nil, fmt.Errorf("match-response-language requires stt-language auto, got %q", cfg.STTLanguage)
+	}
+
+	if cfg.OutputChannels != 1 && cfg.OutputChannels != 2 {
+		return nil, fmt.Errorf("output-channels must be 1 or 2, got %d", cfg.OutputChannels)
+	}
+
+	if cfg.MaxConcurrentRequests < 1 {
+		return nil, fmt.Errorf("max-concurrent-requests must be at least 1, got %d", cfg.MaxConcurrentRequests)
+	}
+
+	if cfg.ModelLoadTimeout < 0 {
+		return nil, fmt.Errorf("model-load-timeout must not be negative, got %s", cfg.ModelLoadTimeout)
+	}
+
+	if cfg.SelfEchoSuppression < 0 {
+		return nil, fmt.Errorf("self-echo-suppression must not be negative, got %s", cfg.SelfEchoSuppression)
+	}
+
+	if cfg.DirectionMaxDelay < 0 || cfg.DirectionMaxLevelDiff < 0 {
+		return nil, fmt.Errorf("direction-max-delay and direction-max-level-diff must not be negative")
+	}
+
+	if cfg.LLMMaxRetries < 0 || cfg.LLMRetryBackoffMs < 0 {
+		return nil, fmt.Errorf("llm-max-retries and llm-retry-backoff-ms must not be negative")
+	}
+	if cfg.LLMNumPredict < 1 || cfg.LLMNumCtx < 1 {
+		return nil, fmt.Errorf("llm-num-predict and llm-num-ctx must be at least 1, got %d and %d", cfg.LLMNumPredict, cfg.LLMNumCtx)
+	}
+	if cfg.FadeMs < 0 || cfg.FadeMs > 100 {
+		return nil, fmt.Errorf("fade-ms must be between 0 and 100, got %d", cfg.FadeMs)
+	}
+	if cfg.BargeInGraceMs < 0 {
+		return nil, fmt.Errorf("barge-in-grace-ms must not be negative, got %d", cfg.BargeInGraceMs)
+	}
+	if cfg.ToolProgressDelay < 0 {
+		return nil, fmt.Errorf("tool-progress-delay must not be negative, got %s", cfg.ToolProgressDelay)
+	}
+	if cfg.OutputDeviceMigrate < 0 {
+		return nil, fmt.Errorf("output-device-migrate must not be negative, got %s", cfg.OutputDeviceMigrate)
+	}
+	if cfg.OutputFormat != "pcm" && cfg.OutputFormat != "wav" {
+		return nil, fmt.Errorf("output-format must be 'pcm' or 'wav', got %q", cfg.OutputFormat)
+	}
+	if cfg.Output == "sink:stdout" && cfg.JSONEvents {
+		return nil, fmt.Errorf("output sink:stdout cannot be combined with json-events, which also writes to stdout")
+	}
+	if cfg.OutputDevice != "" && len(cfg.OutputDevices) > 0 {
+		return nil, fmt.Errorf("output-device cannot be combined with output-device-fallback")
+	}
+	if cfg.OutputTee && cfg.Output == "" {
+		return nil, fmt.Errorf("output-tee requires output")
+	}
+	if cfg.Text != "" && cfg.SynthesizeTo == "" {
+		return nil, fmt.Errorf("text requires synthesize-to")
+	}
+	if cfg.Transcribe != "" && cfg.SynthesizeTo != "" {
+		return nil, fmt.Errorf("transcribe cannot be combined with synthesize-to")
+	}
+	if cfg.ClipThreshold < 0 || cfg.ClipThreshold > 1 {
+		return nil, fmt.Errorf("clip-threshold must be between 0 and 1, got %g", cfg.ClipThreshold)
+	}
+	if cfg.EchoCancelDelay < 0 {
+		return nil, fmt.Errorf("echo-cancel-delay must not be negative, got %s", cfg.EchoCancelDelay)
+	}
+	if cfg.NoiseFloorDB > 0 {
+		return nil, fmt.Errorf("noise-floor-db must not be positive (dBFS), got %g", cfg.NoiseFloorDB)
+	}
+	if cfg.AGCTargetDB >= 0 {
+		return nil, fmt.Errorf("agc-target-db must be negative (dBFS), got %g", cfg.AGCTargetDB)
+	}
+	if cfg.DeadMicWindow < 0 {
+		return nil, fmt.Errorf("dead-mic-window must not be negative, got %s", cfg.DeadMicWindow)
+	}
+
+	if cfg.WakeWordTolerance < 0 {
+		return nil, fmt.Errorf("wake-word-tolerance must not be negative, got %d", cfg.WakeWordTolerance)
+	}
+	if cfg.WakeWordGrace < 0 {
+		return nil, fmt.Errorf("wake-word-grace must not be negative, got %s", cfg.WakeWordGrace)
+	}
+
+	if cfg.ReengageAfter < 0 {
+		return nil, fmt.Errorf("reengage-after must not be negative, got %s", cfg.ReengageAfter)
+	}
+
+	if cfg.SentenceMinChars < 0 {
+		return nil, fmt.Errorf("sentence-min-chars must not be negative, got %d", cfg.SentenceMinChars)
+	}
+	if cfg.SentenceSoftMinChars < 0 {
+		return nil, fmt.Errorf("sentence-soft-min-chars must not be negative, got %d", cfg.SentenceSoftMinChars)
+	}
+	if cfg.MaxSentenceChars < 0 {
+		return nil, fmt.Errorf("max-sentence-chars must not be negative, got %d", cfg.MaxSentenceChars)
+	}
+
+	if cfg.MaxSynthLookahead < 1 {
+		return nil, fmt.Errorf("max-synth-lookahead must be at least 1, got %d", cfg.MaxSynthLookahead)
+	}
+
+	if cfg.TTSMaxNumSentences < 1 {
+		return nil, fmt.Errorf("tts-max-sentences must be at least 1, got %d", cfg.TTSMaxNumSentences)
+	}
 
 	if cfg.TTSSpeed <= 0.0 {
 		return nil, fmt.Errorf("tts-speed must be positive, got %.2f", cfg.TTSSpeed)
 	}
 
+	if cfg.UserLexicon != "" {
+		if _, err := os.Stat(cfg.UserLexicon); err != nil {
+			return nil, fmt.Errorf("user-lexicon must be a readable file: %w", err)
+		}
+	}
+
+	if cfg.HotwordsFile != "" {
+		hotwords, err := LoadHotwords(cfg.HotwordsFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Hotwords = hotwords
+	}
+
+	if cfg.PersonasFile != "" {
+		personas, err := LoadPersonas(cfg.PersonasFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Personas = personas
+	}
+	if cfg.Persona != "" {
+		cfg.Persona = strings.ToLower(cfg.Persona)
+		if _, ok := cfg.Personas[cfg.Persona]; !ok {
+			return nil, fmt.Errorf("persona %q not found (define it in --personas-file)", cfg.Persona)
+		}
+	}
+
+	aliases, err := parseModelAliases(*modelAliases)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ModelAliases = aliases
+
+	if cfg.ResponseChime != "" && cfg.ResponseChime != ChimeTone {
+		if _, err := os.Stat(cfg.ResponseChime); err != nil {
+			return nil, fmt.Errorf("response-chime must be %q or a readable WAV file: %w", ChimeTone, err)
+		}
+	}
+
+	switch cfg.TranscriptFormat {
+	case "jsonl", "text", "markdown":
+	default:
+		return nil, fmt.Errorf("transcript-format must be 'jsonl', 'text' or 'markdown', got %q", cfg.TranscriptFormat)
+	}
+
 	// Parse interrupt mode
 	if mode, err := ParseInterruptMode(interruptModeStr); err != nil {
 		return nil, err
 	} else {
 		cfg.InterruptMode = mode
 	}
+	if mode, err := ParsePipelineMode(pipelineModeStr); err != nil {
+		return nil, err
+	} else {
+		cfg.PipelineMode = mode
+	}
 
 	// Auto-detect provider if not specified
 	if cfg.Provider == "" {
@@ -323,12 +1173,40 @@ func (c *Config) normalizeThreadCounts() {
 	if c.TTSThreads == 0 {
 		c.TTSThreads = c.NumThreads
 	}
+}
 
-	// Log thread configuration in verbose mode
-	if c.Verbose {
-		fmt.Printf("[Config] CPU cores: %d, Thread counts: VAD=%d, STT=%d, TTS=%d\n",
-			cpuCores, c.VADThreads, c.STTThreads, c.TTSThreads)
+// parseModelAliases parses comma-separated name=model pairs; names are lowercased.
+func parseModelAliases(s string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	for _, pair := range splitList(s) {
+		name, model, ok := strings.Cut(pair, "=")
+		name, model = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(model)
+		if !ok || name == "" || model == "" {
+			return nil, fmt.Errorf("model-aliases entries must look like name=model, got %q", pair)
+		}
+		aliases[name] = model
+	}
+	return aliases, nil
+}
+
+// joinModelAliases formats aliases as parseModelAliases reads them.
+func joinModelAliases(aliases map[string]string) string {
+	pairs := make([]string, 0, len(aliases))
+	for _, name := range slices.Sorted(maps.Keys(aliases)) {
+		pairs = append(pairs, name+"="+aliases[name])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// splitList splits a comma-separated flag value into trimmed, non-empty items.
+func splitList(s string) []string {
+	var items []string
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
 
 // detectProvider auto-detects the best hardware acceleration provider for the current platform.
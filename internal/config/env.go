@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// envPrefix starts the name of every environment variable read by ApplyEnv.
+const envPrefix = "VA_"
+
+// ApplyEnv sets the fields of cfg named by VA_* environment variables, for
+// deployments (e.g. containers) where passing flags is awkward.
+//
+// A field's variable is VA_ followed by its Go name in upper snake case, with
+// acronyms kept together: OllamaURL is VA_OLLAMA_URL, TTSVoice is VA_TTS_VOICE,
+// VADSilenceDuration is VA_VAD_SILENCE_DURATION and AudioMemoryBudgetMB is
+// VA_AUDIO_MEMORY_BUDGET_MB. Names follow the Config fields, not the flags, so
+// --output-device-fallback is VA_OUTPUT_DEVICES.
+//
+// Values are written as on the command line: numbers, booleans (true, false,
+// 1, 0), durations (e.g. 10s), modes by name (e.g. VA_INTERRUPT_MODE=wait),
+// lists comma-separated and VA_MODEL_ALIASES as name=model pairs. A variable
+// that is set but empty clears a text or list field. Personas can only be
+// loaded from --personas-file.
+//
+// A value that does not parse is an error naming the variable. Range checks
+// happen later, in ParseFlags, where flags override the environment.
+func ApplyEnv(cfg *Config) error {
+	v := reflect.ValueOf(cfg).Elem()
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		name := envName(field.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setField(v.Field(i), value); err != nil {
+			if numErr, ok := err.(*strconv.NumError); ok {
+				err = numErr.Err // The value is already in the message
+			}
+			return fmt.Errorf("invalid value %q for %s: %w", value, name, err)
+		}
+	}
+	return nil
+}
+
+// envName returns the environment variable for a Config field name.
+func envName(field string) string {
+	r := []rune(field)
+	var b strings.Builder
+	b.WriteString(envPrefix)
+	for i, c := range r {
+		// A word starts at an upper-case letter after a lower-case one
+		// (OllamaURL) or at the last capital of an acronym (TTSVoice).
+		if i > 0 && unicode.IsUpper(c) &&
+			(!unicode.IsUpper(r[i-1]) || i+1 < len(r) && unicode.IsLower(r[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(c))
+	}
+	return b.String()
+}
+
+// setField parses value into the Config field f according to its type.
+func setField(f reflect.Value, value string) error {
+	switch p := f.Addr().Interface().(type) {
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*p = d
+		return nil
+	case *InterruptMode:
+		mode, err := ParseInterruptMode(value)
+		if err != nil {
+			return err
+		}
+		*p = mode
+		return nil
+	case *PipelineMode:
+		mode, err := ParsePipelineMode(value)
+		if err != nil {
+			return err
+		}
+		*p = mode
+		return nil
+	case *[]string:
+		*p = splitList(value)
+		return nil
+	case *map[string]string:
+		m, err := parseModelAliases(value)
+		if err != nil {
+			return err
+		}
+		*p = m
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(value, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(x)
+	default:
+		return fmt.Errorf("cannot be set from the environment")
+	}
+	return nil
+}
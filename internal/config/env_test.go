@@ -0,0 +1,99 @@
+package config
+
+import (
+	"flag"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"OllamaURL":           "VA_OLLAMA_URL",
+		"TTSVoice":            "VA_TTS_VOICE",
+		"Temperature":         "VA_TEMPERATURE",
+		"VADPreSpeechPadMs":   "VA_VAD_PRE_SPEECH_PAD_MS",
+		"AudioMemoryBudgetMB": "VA_AUDIO_MEMORY_BUDGET_MB",
+		"JSONEvents":          "VA_JSON_EVENTS",
+	}
+	for field, want := range tests {
+		if got := envName(field); got != want {
+			t.Errorf("envName(%q) = %q, want %q", field, got, want)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("VA_OLLAMA_URL", "http://ollama:11434")
+	t.Setenv("VA_TEMPERATURE", "0.3")
+	t.Setenv("VA_MAX_HISTORY", "6")
+	t.Setenv("VA_AUDIO_BUFFER_MS", "40")
+	t.Setenv("VA_VERBOSE", "true")
+	t.Setenv("VA_WAKE_WORD_GRACE", "2s")
+	t.Setenv("VA_INTERRUPT_MODE", "wait")
+	t.Setenv("VA_OUTPUT_DEVICES", "bt-speaker, built-in")
+	t.Setenv("VA_MODEL_ALIASES", "Fast=qwen2.5:1.5b")
+
+	cfg := DefaultConfig()
+	if err := ApplyEnv(cfg); err != nil {
+		t.Fatalf("ApplyEnv: %v", err)
+	}
+	if cfg.OllamaURL != "http://ollama:11434" || cfg.Temperature != 0.3 || cfg.MaxHistory != 6 || cfg.AudioBufferMs != 40 || !cfg.Verbose {
+		t.Errorf("scalar fields not applied: url %q, temperature %v, history %d, buffer %d, verbose %v",
+			cfg.OllamaURL, cfg.Temperature, cfg.MaxHistory, cfg.AudioBufferMs, cfg.Verbose)
+	}
+	if cfg.WakeWordGrace != 2*time.Second || cfg.InterruptMode != InterruptWait {
+		t.Errorf("WakeWordGrace = %s, InterruptMode = %s", cfg.WakeWordGrace, cfg.InterruptMode)
+	}
+	if want := []string{"bt-speaker", "built-in"}; !slices.Equal(cfg.OutputDevices, want) {
+		t.Errorf("OutputDevices = %q, want %q", cfg.OutputDevices, want)
+	}
+	if cfg.ModelAliases["fast"] != "qwen2.5:1.5b" {
+		t.Errorf("ModelAliases = %v", cfg.ModelAliases)
+	}
+	if cfg.TTSVoice != DefaultConfig().TTSVoice {
+		t.Errorf("TTSVoice = %q, want the default", cfg.TTSVoice)
+	}
+}
+
+func TestApplyEnvRejectsInvalidValues(t *testing.T) {
+	tests := map[string]string{
+		"VA_TEMPERATURE":     "warm",
+		"VA_MAX_HISTORY":     "1.5",
+		"VA_AUDIO_BUFFER_MS": "-20",
+		"VA_VERBOSE":         "yes please",
+		"VA_REENGAGE_AFTER":  "30",
+		"VA_PIPELINE_MODE":   "parallel",
+	}
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			err := ApplyEnv(DefaultConfig())
+			if err == nil || !strings.Contains(err.Error(), name) {
+				t.Errorf("ApplyEnv with %s=%q: error = %v, want one naming the variable", name, value, err)
+			}
+		})
+	}
+}
+
+func TestFlagsOverrideEnv(t *testing.T) {
+	t.Setenv("VA_TTS_VOICE", "ef_dora")
+	t.Setenv("VA_TEMPERATURE", "0.3")
+	t.Setenv("VA_SETUP", "true")
+
+	cfg := DefaultConfig()
+	if err := ApplyEnv(cfg); err != nil {
+		t.Fatalf("ApplyEnv: %v", err)
+	}
+	cfg, err := parse(flag.NewFlagSet("test", flag.ContinueOnError), cfg, []string{"-tts-voice", "bf_emma"}, "")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cfg.TTSVoice != "bf_emma" {
+		t.Errorf("TTSVoice = %q, want the flag's bf_emma", cfg.TTSVoice)
+	}
+	if cfg.Temperature != 0.3 || !cfg.Setup {
+		t.Errorf("Temperature = %v, Setup = %v, want the environment's 0.3 and true", cfg.Temperature, cfg.Setup)
+	}
+}
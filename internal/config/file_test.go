@@ -0,0 +1,99 @@
+package config
+
+import (
+	"flag"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFromFile(t *testing.T) {
+	files := map[string]string{
+		"va.yaml": `
+tts-voice: ef_dora
+vad-threshold: 0.4
+output-device-migrate: 10s
+auto-punctuate: true
+output-device-fallback: [bt-speaker, built-in]
+model-aliases:
+  fast: qwen2.5:1.5b
+  smart: qwen2.5:7b
+`,
+		"va.json": `{
+  "tts-voice": "ef_dora",
+  "vad-threshold": 0.4,
+  "output-device-migrate": "10s",
+  "auto-punctuate": true,
+  "output-device-fallback": ["bt-speaker", "built-in"],
+  "model-aliases": {"fast": "qwen2.5:1.5b", "smart": "qwen2.5:7b"}
+}`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := LoadFromFile(writeConfigFile(t, name, content))
+			if err != nil {
+				t.Fatalf("LoadFromFile: %v", err)
+			}
+			if cfg.TTSVoice != "ef_dora" || cfg.VadThreshold != 0.4 || cfg.OutputDeviceMigrate != 10*time.Second || !cfg.AutoPunctuate {
+				t.Errorf("scalar settings not applied: voice %q, threshold %v, migrate %s, punctuate %v",
+					cfg.TTSVoice, cfg.VadThreshold, cfg.OutputDeviceMigrate, cfg.AutoPunctuate)
+			}
+			if want := []string{"bt-speaker", "built-in"}; !slices.Equal(cfg.OutputDevices, want) {
+				t.Errorf("OutputDevices = %q, want %q", cfg.OutputDevices, want)
+			}
+			if want := map[string]string{"fast": "qwen2.5:1.5b", "smart": "qwen2.5:7b"}; !maps.Equal(cfg.ModelAliases, want) {
+				t.Errorf("ModelAliases = %v, want %v", cfg.ModelAliases, want)
+			}
+			if def := DefaultConfig(); cfg.OllamaModel != def.OllamaModel {
+				t.Errorf("OllamaModel = %q, want the default %q", cfg.OllamaModel, def.OllamaModel)
+			}
+		})
+	}
+}
+
+func TestLoadFromFileRejectsBadFiles(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"unknown.yaml", "tts-voic: ef_dora\n", `unknown key "tts-voic"`},
+		{"nested.yaml", "config: other.yaml\n", `unknown key "config"`},
+		{"invalid.yaml", "vad-threshold: 1.5\n", "vad-threshold must be between"},
+		{"type.json", `{"sample-rate": "fast"}`, "sample-rate"},
+		{"va.toml", "tts-voice = 'ef_dora'\n", "unsupported extension"},
+		{"devices.yaml", "output-device: '2'\noutput-device-fallback: [bt-speaker]\n", "cannot be combined"},
+	}
+	for _, tt := range tests {
+		_, err := LoadFromFile(writeConfigFile(t, tt.name, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want one mentioning %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestFlagsOverrideConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "va.yaml", "tts-voice: ef_dora\nvad-threshold: 0.4\n")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg, err := parse(fs, DefaultConfig(), []string{"-tts-voice", "bf_emma", "-config", path}, "")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cfg.TTSVoice != "bf_emma" {
+		t.Errorf("TTSVoice = %q, want the flag's bf_emma", cfg.TTSVoice)
+	}
+	if cfg.VadThreshold != 0.4 {
+		t.Errorf("VadThreshold = %v, want the file's 0.4", cfg.VadThreshold)
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadHotwords reads a file of expected phrases, one per line. Blank lines and
+// lines starting with # are skipped.
+func LoadHotwords(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hotwords file: %w", err)
+	}
+	var phrases []string
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		phrases = append(phrases, line)
+	}
+	if len(phrases) == 0 {
+		return nil, fmt.Errorf("hotwords file %s has no phrases", path)
+	}
+	return phrases, nil
+}
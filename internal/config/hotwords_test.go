@@ -0,0 +1,30 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadHotwords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hotwords.txt")
+	content := "# Home automation\nlights on\n\n  lights off  \nplay music"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadHotwords(path)
+	if err != nil {
+		t.Fatalf("LoadHotwords: %v", err)
+	}
+	if want := []string{"lights on", "lights off", "play music"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte("# nothing yet\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHotwords(path); err == nil {
+		t.Error("expected an error for a file without phrases")
+	}
+}
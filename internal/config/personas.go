@@ -0,0 +1,49 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Persona is a named system prompt (and optional temperature) the assistant can
+// switch to at runtime.
+type Persona struct {
+	Prompt      string   `json:"prompt"`
+	Temperature *float32 `json:"temperature,omitempty"` // nil keeps --temperature
+}
+
+// LoadPersonas reads a JSON file mapping persona names to personas:
+//
+//	{"tutor": {"prompt": "You are a patient tutor...", "temperature": 0.3}}
+//
+// Names are lowercased so voice commands match them case-insensitively.
+func LoadPersonas(path string) (map[string]Persona, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read personas file: %w", err)
+	}
+	var raw map[string]Persona
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid personas file %s: %w", path, err)
+	}
+
+	personas := make(map[string]Persona, len(raw))
+	for name, p := range raw {
+		key := strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case key == "":
+			return nil, fmt.Errorf("personas file %s: empty persona name", path)
+		case strings.TrimSpace(p.Prompt) == "":
+			return nil, fmt.Errorf("personas file %s: persona %q has no prompt", path, name)
+		case p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2):
+			return nil, fmt.Errorf("personas file %s: persona %q temperature must be between 0.0 and 2.0", path, name)
+		}
+		if _, dup := personas[key]; dup {
+			return nil, fmt.Errorf("personas file %s: duplicate persona %q", path, name)
+		}
+		personas[key] = p
+	}
+	return personas, nil
+}
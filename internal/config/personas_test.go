@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePersonas(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "personas.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPersonasNormalizesNames(t *testing.T) {
+	path := writePersonas(t, `{
+		"Tutor": {"prompt": "You are a patient tutor.", "temperature": 0.3},
+		"comedian": {"prompt": "You are a comedian."}
+	}`)
+	personas, err := LoadPersonas(path)
+	if err != nil {
+		t.Fatalf("LoadPersonas: %v", err)
+	}
+	tutor, ok := personas["tutor"]
+	if !ok || tutor.Temperature == nil || *tutor.Temperature != 0.3 {
+		t.Errorf("tutor = %+v, want lowercased name with temperature 0.3", tutor)
+	}
+	if c := personas["comedian"]; c.Temperature != nil {
+		t.Errorf("comedian temperature = %v, want nil (keep the default)", *c.Temperature)
+	}
+}
+
+func TestLoadPersonasRejectsInvalidEntries(t *testing.T) {
+	tests := map[string]string{
+		"no prompt":   `{"tutor": {"prompt": "  "}}`,
+		"temperature": `{"tutor": {"prompt": "x", "temperature": 3}}`,
+		"duplicate":   `{"Tutor": {"prompt": "x"}, "tutor": {"prompt": "y"}}`,
+		"empty name":  `{" ": {"prompt": "x"}}`,
+		"malformed":   `{"tutor": `,
+	}
+	for name, content := range tests {
+		if _, err := LoadPersonas(writePersonas(t, content)); err == nil {
+			t.Errorf("%s: LoadPersonas succeeded, want an error", name)
+		} else if !strings.Contains(err.Error(), "personas file") {
+			t.Errorf("%s: error %q does not name the file", name, err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Package control accepts line-based commands on a Unix domain socket so external
+// processes (hardware buttons, home automation) can drive the assistant.
+//
+// Each connection may send any number of newline-terminated commands. Every
+// command gets a one-line reply: "ok" on success or "error: <reason>". Some
+// commands take the rest of the line as an argument:
+//
+//	echo interrupt | nc -U /tmp/voice-assistant.sock
+//	echo "say The timer is done" | nc -U /tmp/voice-assistant.sock
+package control
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Handler executes a command. A returned error is reported to the client.
+type Handler func() error
+
+// ArgHandler executes a command that takes an argument: the rest of the line
+// after the command name, with its case preserved.
+type ArgHandler func(arg string) error
+
+// Server listens on a Unix socket and dispatches commands to handlers.
+type Server struct {
+	path        string
+	ln          net.Listener
+	handlers    map[string]Handler
+	argHandlers map[string]ArgHandler
+	wg          sync.WaitGroup
+	mu          sync.Mutex
+	conns       map[net.Conn]struct{}
+}
+
+// Listen creates the socket at path and starts serving commands, dispatched by
+// (case-insensitive) name to handlers, or to argHandlers for commands followed
+// by an argument. A stale socket file left by a previous run is removed first.
+func Listen(path string, handlers map[string]Handler, argHandlers map[string]ArgHandler) (*Server, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		_ = os.Remove(path)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+
+	s := &Server{
+		path:        path,
+		ln:          ln,
+		handlers:    make(map[string]Handler, len(handlers)),
+		argHandlers: make(map[string]ArgHandler, len(argHandlers)),
+		conns:       make(map[net.Conn]struct{}),
+	}
+	for name, h := range handlers {
+		s.handlers[strings.ToLower(name)] = h
+	}
+	for name, h := range argHandlers {
+		s.argHandlers[strings.ToLower(name)] = h
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Commands returns the supported command names, sorted. Commands taking an
+// argument are listed as "name <arg>".
+func (s *Server) Commands() []string {
+	names := make([]string, 0, len(s.handlers)+len(s.argHandlers))
+	for name := range s.handlers {
+		names = append(names, name)
+	}
+	for name := range s.argHandlers {
+		names = append(names, name+" <arg>")
+	}
+	slices.Sort(names)
+	return names
+}
+
+// serve accepts connections until the listener is closed.
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("⚠️ Control socket accept failed: %v", err)
+			}
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+// handle reads commands from conn until it is closed.
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		cmd := strings.TrimSpace(scanner.Text())
+		if cmd == "" {
+			continue
+		}
+		if _, err := io.WriteString(conn, s.execute(cmd)+"\n"); err != nil {
+			return
+		}
+	}
+}
+
+// execute runs line and returns the reply line.
+func (s *Server) execute(line string) string {
+	name, arg, hasArg := strings.Cut(line, " ")
+	name = strings.ToLower(name)
+	arg = strings.TrimSpace(arg)
+
+	var run func() error
+	if h, ok := s.argHandlers[name]; ok {
+		if !hasArg || arg == "" {
+			return fmt.Sprintf("error: %s needs an argument", name)
+		}
+		run = func() error { return h(arg) }
+	} else if h, ok := s.handlers[name]; ok && !hasArg {
+		run = h
+	} else {
+		return fmt.Sprintf("error: unknown command %q (available: %s)", strings.ToLower(line), strings.Join(s.Commands(), ", "))
+	}
+	log.Printf("🎛️ Control command: %s", line)
+	if err := run(); err != nil {
+		return "error: " + err.Error()
+	}
+	return "ok"
+}
+
+// Close stops accepting commands, closes open connections, and removes the socket file.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	_ = os.Remove(s.path)
+	return err
+}
@@ -0,0 +1,112 @@
+package control
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func startServer(t *testing.T, handlers map[string]Handler) (*Server, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "control.sock")
+	s, err := Listen(path, handlers, nil)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, path
+}
+
+func send(t *testing.T, path string, cmds ...string) []string {
+	t.Helper()
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	var replies []string
+	for _, cmd := range cmds {
+		if _, err := conn.Write([]byte(cmd + "\n")); err != nil {
+			t.Fatal(err)
+		}
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		replies = append(replies, strings.TrimSpace(line))
+	}
+	return replies
+}
+
+func TestServerDispatchesCommands(t *testing.T) {
+	var interrupts int
+	_, path := startServer(t, map[string]Handler{
+		"interrupt": func() error { interrupts++; return nil },
+		"reset":     func() error { return errors.New("not now") },
+	})
+
+	got := send(t, path, "interrupt", "  INTERRUPT ", "reset", "explode")
+	if got[0] != "ok" || got[1] != "ok" {
+		t.Errorf("interrupt replies = %q, want ok", got[:2])
+	}
+	if interrupts != 2 {
+		t.Errorf("interrupt handler ran %d times, want 2", interrupts)
+	}
+	if got[2] != "error: not now" {
+		t.Errorf("reset reply = %q", got[2])
+	}
+	if !strings.HasPrefix(got[3], "error: unknown command") || !strings.Contains(got[3], "interrupt, reset") {
+		t.Errorf("unknown command reply = %q", got[3])
+	}
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Simulate a crash: the file stays behind after the listener is gone.
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+
+	s, err := Listen(path, nil, nil)
+	if err != nil {
+		t.Fatalf("Listen over stale socket: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket file not removed on Close: %v", err)
+	}
+}
+
+func TestServerPassesArguments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+	var said []string
+	s, err := Listen(path, map[string]Handler{"interrupt": func() error { return nil }}, map[string]ArgHandler{
+		"say": func(text string) error { said = append(said, text); return nil },
+	})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	got := send(t, path, "SAY The timer is done.", "say", "interrupt now")
+	if got[0] != "ok" || len(said) != 1 || said[0] != "The timer is done." {
+		t.Errorf("say reply = %q, said = %q; want the argument with its case kept", got[0], said)
+	}
+	if got[1] != "error: say needs an argument" {
+		t.Errorf("bare say reply = %q", got[1])
+	}
+	if !strings.HasPrefix(got[2], "error: unknown command") || !strings.Contains(got[2], "say <arg>") {
+		t.Errorf("argument to a plain command reply = %q", got[2])
+	}
+}
@@ -0,0 +1,64 @@
+// Package events writes machine-readable pipeline events as newline-delimited JSON.
+//
+// Emission is disabled until [SetOutput] is called, so callers can emit
+// unconditionally. Events are written from pipeline goroutines, never from the
+// real-time audio callbacks.
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// Type identifies the kind of event.
+type Type string
+
+// Event types.
+const (
+	Ready      Type = "ready"      // The assistant is listening
+	Transcript Type = "transcript" // User speech was transcribed
+	Response   Type = "response"   // A response is about to be spoken
+	Replay     Type = "replay"     // The last response is being replayed
+	Resume     Type = "resume"     // An interrupted response is being resumed
+	Interrupt  Type = "interrupt"  // Playback was interrupted by the user
+)
+
+// Event is a single line of the event stream.
+type Event struct {
+	Type Type      `json:"type"`
+	Text string    `json:"text,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+var (
+	mu  sync.Mutex
+	enc *json.Encoder
+)
+
+// SetOutput directs events to w (typically os.Stdout). A nil w disables emission.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	if w == nil {
+		enc = nil
+		return
+	}
+	enc = json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+}
+
+// Emit writes an event of type t with optional text. It is a no-op when
+// emission is disabled and is safe for concurrent use.
+func Emit(t Type, text string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if enc == nil {
+		return
+	}
+	if err := enc.Encode(Event{Type: t, Text: text, Time: time.Now()}); err != nil {
+		log.Printf("⚠️ Failed to write %s event: %v", t, err)
+	}
+}
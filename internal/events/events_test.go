@@ -0,0 +1,47 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEmitWritesJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(nil)
+
+	Emit(Transcript, "what's <the> weather")
+	Emit(Interrupt, "")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+
+	var ev Event
+	if err := json.Unmarshal([]byte(lines[0]), &ev); err != nil {
+		t.Fatalf("invalid JSON %q: %v", lines[0], err)
+	}
+	if ev.Type != Transcript || ev.Text != "what's <the> weather" || ev.Time.IsZero() {
+		t.Errorf("got %+v", ev)
+	}
+	if strings.Contains(lines[0], `\u003c`) {
+		t.Errorf("text should not be HTML-escaped: %s", lines[0])
+	}
+	if strings.Contains(lines[1], `"text"`) {
+		t.Errorf("empty text should be omitted: %s", lines[1])
+	}
+}
+
+func TestEmitDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	SetOutput(nil)
+
+	Emit(Response, "hello")
+	if buf.Len() != 0 {
+		t.Errorf("expected no output when disabled, got %q", buf.String())
+	}
+}
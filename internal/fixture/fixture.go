@@ -0,0 +1,141 @@
+// Package fixture saves conversation turns as self-contained bundles (the
+// spoken input, its transcript, the assistant's reply and the reply's audio) and
+// compares replays against them, for end-to-end regression testing of speech
+// recognition and prompting.
+package fixture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+)
+
+// Files making up a fixture bundle directory.
+const (
+	metaFile   = "fixture.json" // Transcript, response and timestamp
+	inputFile  = "input.wav"    // Speech segment that was transcribed
+	outputFile = "output.wav"   // Synthesized reply (for listening; not compared)
+)
+
+// Turn is one recorded interaction.
+type Turn struct {
+	Time       time.Time         `json:"time"`
+	Transcript string            `json:"transcript"` // Transcriber output for Input
+	Response   string            `json:"response"`   // Reply spoken for Transcript (empty if none)
+	Input      audio.AudioBuffer `json:"-"`
+	Output     audio.AudioBuffer `json:"-"`
+}
+
+// Save writes t as a bundle in dir, creating it if needed. The output audio is
+// omitted when none was recorded.
+func Save(dir string, t *Turn) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	meta, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, metaFile), append(meta, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	if err := writeWAV(filepath.Join(dir, inputFile), t.Input); err != nil {
+		return err
+	}
+	if len(t.Output.Samples) > 0 {
+		return writeWAV(filepath.Join(dir, outputFile), t.Output)
+	}
+	return nil
+}
+
+// Load reads the bundle in dir. The output audio is optional.
+func Load(dir string) (*Turn, error) {
+	meta, err := os.ReadFile(filepath.Join(dir, metaFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	var t Turn
+	if err := json.Unmarshal(meta, &t); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", dir, err)
+	}
+	if t.Input, err = readWAV(filepath.Join(dir, inputFile)); err != nil {
+		return nil, err
+	}
+	if t.Output, err = readWAV(filepath.Join(dir, outputFile)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// List returns the bundles to replay for path: path itself when it is a
+// bundle, otherwise its immediate subdirectories that are, in name order
+// (recording order for directories written by [Recorder]).
+func List(path string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(path, metaFile)); err == nil {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var dirs []string
+	for _, e := range entries {
+		dir := filepath.Join(path, e.Name())
+		if _, err := os.Stat(filepath.Join(dir, metaFile)); e.IsDir() && err == nil {
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no fixtures found in %s", path)
+	}
+	slices.Sort(dirs)
+	return dirs, nil
+}
+
+// Diff compares a replayed turn with the recorded one and returns one line per
+// field that differs. Whitespace at either end is ignored.
+func Diff(recorded, replayed *Turn) []string {
+	var diffs []string
+	compare := func(field, want, got string) {
+		if strings.TrimSpace(want) != strings.TrimSpace(got) {
+			diffs = append(diffs, fmt.Sprintf("%s: recorded %q, replayed %q", field, want, got))
+		}
+	}
+	compare("transcript", recorded.Transcript, replayed.Transcript)
+	compare("response", recorded.Response, replayed.Response)
+	return diffs
+}
+
+// writeWAV encodes buf to path.
+func writeWAV(path string, buf audio.AudioBuffer) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write fixture audio: %w", err)
+	}
+	if err := audio.EncodeWAV(f, buf); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write fixture audio: %w", err)
+	}
+	return f.Close()
+}
+
+// readWAV decodes the WAV file at path. A missing file is reported with an
+// error satisfying os.IsNotExist.
+func readWAV(path string) (audio.AudioBuffer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return audio.AudioBuffer{}, err
+	}
+	defer f.Close()
+	buf, err := audio.DecodeWAV(f)
+	if err != nil {
+		return audio.AudioBuffer{}, fmt.Errorf("invalid fixture audio %s: %w", path, err)
+	}
+	return buf, nil
+}
@@ -0,0 +1,73 @@
+package fixture
+
+import (
+	"math"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+)
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "turn")
+	want := &Turn{
+		Time:       time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+		Transcript: "What's the weather?",
+		Response:   "Sunny and warm.",
+		Input:      audio.AudioBuffer{Samples: []float32{0, 0.5, -0.5}, SampleRate: 16000},
+	}
+	if err := Save(dir, want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !got.Time.Equal(want.Time) || got.Transcript != want.Transcript || got.Response != want.Response {
+		t.Errorf("Load = %+v, want %+v", got, want)
+	}
+	if got.Input.SampleRate != 16000 || len(got.Input.Samples) != 3 || math.Abs(float64(got.Input.Samples[1]-0.5)) > 1e-3 {
+		t.Errorf("input audio = %+v, want the recorded samples", got.Input)
+	}
+	if len(got.Output.Samples) != 0 {
+		t.Errorf("output audio = %d samples, want none", len(got.Output.Samples))
+	}
+}
+
+func TestListFindsBundlesInOrder(t *testing.T) {
+	root := t.TempDir()
+	turn := &Turn{Input: audio.AudioBuffer{Samples: []float32{0}, SampleRate: 16000}}
+	for _, name := range []string{"b-turn-002", "a-turn-001"} {
+		if err := Save(filepath.Join(root, name), turn); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	dirs, err := List(root)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	want := []string{filepath.Join(root, "a-turn-001"), filepath.Join(root, "b-turn-002")}
+	if !slices.Equal(dirs, want) {
+		t.Errorf("List(root) = %q, want %q", dirs, want)
+	}
+	if dirs, _ := List(want[0]); !slices.Equal(dirs, want[:1]) {
+		t.Errorf("List(bundle) = %q, want the bundle itself", dirs)
+	}
+	if _, err := List(t.TempDir()); err == nil {
+		t.Error("List of an empty directory succeeded")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	recorded := &Turn{Transcript: "Hello.", Response: "Hi there!"}
+	if diffs := Diff(recorded, &Turn{Transcript: " Hello.", Response: "Hi there!\n"}); len(diffs) != 0 {
+		t.Errorf("Diff = %q, want none for whitespace-only changes", diffs)
+	}
+	if diffs := Diff(recorded, &Turn{Transcript: "Hello.", Response: "Hey!"}); len(diffs) != 1 {
+		t.Errorf("Diff = %q, want one response difference", diffs)
+	}
+}
@@ -0,0 +1,125 @@
+package fixture
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+)
+
+// Recorder saves each turn of a live session as a bundle under one directory.
+// A turn starts with a transcribed speech segment and collects the reply and
+// the audio synthesized for it until the next turn starts, so turns should not
+// overlap (e.g. record in wait interrupt mode). Its methods may be called from
+// different goroutines, but never from an audio callback.
+type Recorder struct {
+	dir     string // Session directory
+	session string // Bundle name prefix, unique per Recorder
+
+	mu      sync.Mutex
+	turn    *Turn                // Turn being recorded (nil before the first transcript)
+	count   int                  // Turns started so far
+	memory  *audio.MemoryAccount // Audio held by the turn (nil = not accounted)
+	trimmed bool                 // The turn's output audio was dropped to save memory
+}
+
+// NewRecorder returns a Recorder writing bundles to dir, created if needed.
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	return &Recorder{dir: dir, session: time.Now().Format("20060102-150405")}, nil
+}
+
+// SetMemoryAccount reports the audio held by the turn being recorded to a,
+// which may ask for it back: the turn's reply audio is then dropped (its
+// transcript and response are still saved).
+func (r *Recorder) SetMemoryAccount(a *audio.MemoryAccount) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.memory = a
+}
+
+// Input starts a new turn for the speech segment that was transcribed as
+// transcript, saving the previous turn.
+func (r *Recorder) Input(samples []float32, sampleRate int, transcript string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.saveLocked()
+	r.count++
+	r.turn = &Turn{
+		Time:       time.Now(),
+		Transcript: transcript,
+		Input:      audio.AudioBuffer{Samples: append([]float32(nil), samples...), SampleRate: sampleRate},
+	}
+	r.trimmed = false
+	r.accountLocked()
+}
+
+// Response records text as the current turn's reply, unless it already has one.
+func (r *Recorder) Response(text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.turn != nil && r.turn.Response == "" {
+		r.turn.Response = text
+	}
+}
+
+// Output appends audio synthesized for text to the current turn when text is
+// part of its reply, so greetings and other announcements are left out.
+func (r *Recorder) Output(text string, out audio.AudioBuffer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.turn == nil || r.trimmed || r.turn.Response == "" || !strings.Contains(r.turn.Response, strings.TrimSpace(text)) {
+		return
+	}
+	if r.turn.Output.SampleRate == 0 {
+		r.turn.Output.SampleRate = out.SampleRate
+	}
+	r.turn.Output.Samples = append(r.turn.Output.Samples, out.Samples...)
+	r.accountLocked()
+}
+
+// accountLocked reports the current turn's audio to the memory account and
+// drops the output audio if the account asks for memory back.
+func (r *Recorder) accountLocked() {
+	if r.turn == nil {
+		r.memory.Set(0)
+		return
+	}
+	r.memory.Set(audio.SampleBytes(len(r.turn.Input.Samples) + len(r.turn.Output.Samples)))
+	if r.memory.Reclaim() && len(r.turn.Output.Samples) > 0 {
+		r.turn.Output = audio.AudioBuffer{}
+		r.trimmed = true
+		log.Println("⚠️ Audio memory budget exceeded, the fixture being recorded keeps no reply audio")
+		r.memory.Set(audio.SampleBytes(len(r.turn.Input.Samples)))
+	}
+}
+
+// Close saves the turn in progress.
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.saveLocked()
+}
+
+// saveLocked writes the current turn, if any, and clears it. Errors are logged
+// since recording must not disturb the conversation.
+func (r *Recorder) saveLocked() {
+	if r.turn == nil {
+		return
+	}
+	dir := filepath.Join(r.dir, fmt.Sprintf("%s-turn-%03d", r.session, r.count))
+	if err := Save(dir, r.turn); err != nil {
+		log.Printf("⚠️ Fixture recording: %v", err)
+	} else {
+		log.Printf("💾 Saved fixture %s", dir)
+	}
+	r.turn = nil
+	r.accountLocked()
+}
@@ -0,0 +1,86 @@
+package fixture
+
+import (
+	"testing"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+)
+
+func TestRecorderSavesTurnsWithTheirReplyAudio(t *testing.T) {
+	root := t.TempDir()
+	r, err := NewRecorder(root)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	speech := audio.AudioBuffer{Samples: []float32{0.1, 0.2}, SampleRate: 24000}
+
+	r.Output("Hello, I'm ready.", speech) // Greeting before any turn: not recorded
+	r.Input([]float32{0.1}, 16000, "What's the weather?")
+	r.Response("Sunny. Warm, too.")
+	r.Output("Sunny.", speech)
+	r.Output("Still working on it.", speech) // Progress phrase: not part of the reply
+	r.Output("Warm, too.", speech)
+	r.Input([]float32{0.2}, 16000, "Thanks")
+	r.Close()
+
+	dirs, err := List(root)
+	if err != nil || len(dirs) != 2 {
+		t.Fatalf("List = %q, %v; want two bundles", dirs, err)
+	}
+	first, err := Load(dirs[0])
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if first.Transcript != "What's the weather?" || first.Response != "Sunny. Warm, too." {
+		t.Errorf("first turn = %q / %q", first.Transcript, first.Response)
+	}
+	if len(first.Output.Samples) != 4 || first.Output.SampleRate != 24000 {
+		t.Errorf("first turn output = %d samples at %d Hz, want the two reply sentences", len(first.Output.Samples), first.Output.SampleRate)
+	}
+
+	second, err := Load(dirs[1])
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if second.Transcript != "Thanks" || second.Response != "" {
+		t.Errorf("second turn = %q / %q, want an unanswered turn", second.Transcript, second.Response)
+	}
+}
+
+func TestRecorderDropsReplyAudioOverMemoryBudget(t *testing.T) {
+	root := t.TempDir()
+	r, err := NewRecorder(root)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	budget := audio.NewMemoryBudget(audio.SampleBytes(4))
+	r.SetMemoryAccount(budget.Register("fixture recording", true))
+
+	r.Input([]float32{0.1}, 16000, "Tell me a story")
+	r.Response("Once upon a time. The end.")
+	r.Output("Once upon a time.", audio.AudioBuffer{Samples: []float32{0.1, 0.2}, SampleRate: 24000})
+	if got := budget.Stats().Used; got != audio.SampleBytes(3) {
+		t.Errorf("Used = %d, want the input and reply audio", got)
+	}
+	r.Output("The end.", audio.AudioBuffer{Samples: []float32{0.3, 0.4}, SampleRate: 24000})
+	if got := budget.Stats().Used; got != audio.SampleBytes(1) {
+		t.Errorf("Used = %d, want only the input audio once over budget", got)
+	}
+	r.Close()
+	if got := budget.Stats().Used; got != 0 {
+		t.Errorf("Used = %d after saving, want 0", got)
+	}
+
+	dirs, err := List(root)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	turn, err := Load(dirs[0])
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if turn.Response != "Once upon a time. The end." || len(turn.Output.Samples) != 0 || len(turn.Input.Samples) != 1 {
+		t.Errorf("turn = %q with %d input and %d output samples, want the response and input without reply audio",
+			turn.Response, len(turn.Input.Samples), len(turn.Output.Samples))
+	}
+}
@@ -0,0 +1,143 @@
+// Package intent matches transcripts against rule-based command patterns so
+// commands such as "turn on the lights" can be handled directly, without a
+// round trip to the LLM.
+//
+// Patterns are matched against the whole utterance, ignoring case and
+// punctuation. A word in braces is a slot that captures one or more words, and
+// "*" matches any (possibly empty) run of words:
+//
+//	m := intent.NewMatcher()
+//	m.RegisterIntent([]string{"* turn on the {device}", "* switch on the {device}"}, func(args intent.Args) string {
+//		return "Turning on the " + args["device"] + "."
+//	})
+//	reply, ok := m.Match("Please turn on the kitchen lights.") // "Turning on the kitchen lights.", true
+package intent
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Args holds the words captured by a pattern's slots, keyed by slot name.
+type Args map[string]string
+
+// Handler produces the spoken response for a matched intent. An empty response
+// means the command was handled silently.
+type Handler func(args Args) string
+
+// rule is one registered pattern and the handler it triggers.
+type rule struct {
+	re      *regexp.Regexp
+	handler Handler
+}
+
+// Matcher holds registered intents. It is safe for concurrent use.
+type Matcher struct {
+	mu    sync.RWMutex
+	rules []rule
+}
+
+// NewMatcher creates an empty Matcher.
+func NewMatcher() *Matcher {
+	return &Matcher{}
+}
+
+// slotRe matches a {slot} placeholder whose name is usable as a capture group.
+var slotRe = regexp.MustCompile(`^\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+// RegisterIntent adds handler for each of patterns. Patterns are tried in
+// registration order and the first match wins.
+func (m *Matcher) RegisterIntent(patterns []string, handler Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range patterns {
+		if re := compilePattern(p); re != nil {
+			m.rules = append(m.rules, rule{re: re, handler: handler})
+		}
+	}
+}
+
+// Match runs the handler of the first intent matching text and returns its
+// response. ok is false when no intent matches and text should go to the LLM.
+// A nil Matcher matches nothing.
+func (m *Matcher) Match(text string) (response string, ok bool) {
+	if m == nil {
+		return "", false
+	}
+	normalized := normalize(text)
+	if normalized == "" {
+		return "", false
+	}
+
+	m.mu.RLock()
+	var (
+		handler Handler
+		args    Args
+	)
+	for _, r := range m.rules {
+		groups := r.re.FindStringSubmatch(normalized + " ")
+		if groups == nil {
+			continue
+		}
+		handler, args = r.handler, Args{}
+		for i, name := range r.re.SubexpNames() {
+			if name != "" {
+				args[name] = groups[i]
+			}
+		}
+		break
+	}
+	m.mu.RUnlock()
+
+	if handler == nil {
+		return "", false
+	}
+	// Called outside the lock so handlers may register further intents.
+	return handler(args), true
+}
+
+// compilePattern turns a pattern into an anchored regular expression over
+// normalized text followed by a single space, or returns nil for an empty
+// pattern. Every token consumes its trailing space, so an empty "*" leaves
+// no gap behind.
+func compilePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	for word := range strings.FieldsSeq(pattern) {
+		switch m := slotRe.FindStringSubmatch(word); {
+		case m != nil:
+			b.WriteString(`(?P<` + m[1] + `>\S+(?: \S+)*?) `)
+		case word == "*":
+			b.WriteString(`(?:\S+ )*?`)
+		default:
+			for w := range strings.FieldsSeq(normalize(word)) {
+				b.WriteString(regexp.QuoteMeta(w) + ` `)
+			}
+		}
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`^` + b.String() + `$`)
+}
+
+// normalize lowercases text, drops punctuation other than apostrophes inside
+// words, and collapses whitespace, so "Turn ON the lights!" and "turn on the
+// lights" compare equal.
+func normalize(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '\'':
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	words := strings.Fields(b.String())
+	for i, w := range words {
+		words[i] = strings.Trim(w, "'")
+	}
+	return strings.Join(strings.Fields(strings.Join(words, " ")), " ")
+}
@@ -0,0 +1,55 @@
+package intent
+
+import "testing"
+
+func TestMatchCapturesSlots(t *testing.T) {
+	m := NewMatcher()
+	m.RegisterIntent([]string{"* turn {state} the {device}"}, func(args Args) string {
+		return args["state"] + ":" + args["device"]
+	})
+
+	tests := []struct {
+		text   string
+		want   string
+		wantOK bool
+	}{
+		{"Turn on the lights.", "on:lights", true},
+		{"Please, turn OFF the kitchen lights!", "off:kitchen lights", true},
+		{"turn on the", "", false},
+		{"turnon the lights", "", false},
+		{"What's the weather like?", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := m.Match(tt.text)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Match(%q) = %q, %v; want %q, %v", tt.text, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestMatchFirstRegisteredWins(t *testing.T) {
+	m := NewMatcher()
+	m.RegisterIntent([]string{"stop the music"}, func(Args) string { return "specific" })
+	m.RegisterIntent([]string{"stop *"}, func(Args) string { return "generic" })
+
+	if got, _ := m.Match("Stop the music."); got != "specific" {
+		t.Errorf("Match = %q, want the first registered intent", got)
+	}
+	if got, _ := m.Match("stop"); got != "generic" {
+		t.Errorf("Match(\"stop\") = %q, want an empty wildcard to match", got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if got, want := normalize("  What's   UP,  doc?! 'quoted' "), "what's up doc quoted"; got != want {
+		t.Errorf("normalize = %q, want %q", got, want)
+	}
+}
+
+func TestNilMatcherMatchesNothing(t *testing.T) {
+	var m *Matcher
+	if _, ok := m.Match("turn on the lights"); ok {
+		t.Error("nil Matcher reported a match")
+	}
+}
@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Backend sends chat requests to an LLM server. Requests and responses use the
+// Ollama API types, which the conversation history is kept in, so the history
+// and the agentic loop in [Client] are shared by all backends; each backend
+// translates them to its own wire format.
+type Backend interface {
+	// Chat sends req and calls fn with the response: once when req.Stream is
+	// false, or once per piece of the reply when it is true, the last call
+	// with Done set. Streamed tool calls may be delivered in any of the calls.
+	// An error from fn aborts the request and is returned.
+	Chat(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error
+
+	// HealthCheck verifies the server is reachable.
+	HealthCheck(ctx context.Context) error
+}
+
+// modelChecker is implemented by backends that can tell whether a model is
+// available, so [Client.SetModel] can refuse unknown models.
+type modelChecker interface {
+	CheckModel(ctx context.Context, name string) error
+}
+
+// modelLoader is implemented by backends that can load a model ahead of the
+// first request, so [Client.SetModel] can hide the load time.
+type modelLoader interface {
+	LoadModel(ctx context.Context, name string, keepAlive *api.Duration) error
+}
+
+// newBackend creates the backend named by cfg.Backend ("ollama" when empty)
+// for the server at cfg.Host.
+func newBackend(cfg *Config) (Backend, error) {
+	host, err := url.Parse(strings.TrimSuffix(cfg.Host, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid host URL: %w", err)
+	}
+	switch strings.ToLower(cfg.Backend) {
+	case "", "ollama":
+		return &ollamaBackend{client: api.NewClient(host, newHTTPClient())}, nil
+	case "openai":
+		return newOpenAIBackend(host, cfg.APIKey), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM backend %q (available: ollama, openai)", cfg.Backend)
+	}
+}
+
+// newHTTPClient returns an http.Client with connection pooling configured to
+// reduce latency on repeated requests to a local LLM.
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: 60 * time.Second,
+		Transport: &http.Transport{
+			MaxIdleConns:        10,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     90 * time.Second,
+			DisableCompression:  false,
+		},
+	}
+}
+
+// ollamaBackend is the [Backend] for an Ollama server, through the official
+// Ollama Go client.
+type ollamaBackend struct {
+	client *api.Client
+}
+
+func (b *ollamaBackend) Chat(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+	return b.client.Chat(ctx, req, fn)
+}
+
+func (b *ollamaBackend) HealthCheck(ctx context.Context) error {
+	if err := b.client.Heartbeat(ctx); err != nil {
+		return fmt.Errorf("cannot reach Ollama: %w", err)
+	}
+	return nil
+}
+
+func (b *ollamaBackend) CheckModel(ctx context.Context, name string) error {
+	if _, err := b.client.Show(ctx, &api.ShowRequest{Model: name}); err != nil {
+		var status api.StatusError
+		if errors.As(err, &status) && status.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("model %q is not available in Ollama (pull it with: ollama pull %s)", name, name)
+		}
+		return fmt.Errorf("cannot check model %q: %w", name, err)
+	}
+	return nil
+}
+
+// LoadModel asks Ollama to load the model without generating anything.
+func (b *ollamaBackend) LoadModel(ctx context.Context, name string, keepAlive *api.Duration) error {
+	return b.client.Chat(ctx, &api.ChatRequest{
+		Model:     name,
+		Stream:    new(false),
+		KeepAlive: keepAlive,
+	}, func(api.ChatResponse) error { return nil })
+}
@@ -1,73 +1,169 @@
-// Package llm provides LLM integration via Ollama API.
+// Package llm provides LLM integration via the Ollama API or an OpenAI-compatible
+// chat completions API.
 package llm
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"net/http"
-	"net/url"
+	"log"
+	"maps"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
 
+	"github.com/agalue/sherpa-voice-assistant/internal/metrics"
+	"github.com/agalue/sherpa-voice-assistant/internal/state"
 	"github.com/ollama/ollama/api"
 )
 
-// Client is an Ollama API client for LLM interactions with agentic tool support.
+// Client is an LLM client with agentic tool support. It keeps the conversation
+// history and runs the tool-calling loop; a [Backend] talks to the server.
 type Client struct {
-	client      *api.Client   // Official Ollama Go client
-	model       string        // LLM model name (e.g., "qwen2.5:3b")
-	history     []api.Message // Conversation history (system prompt at index 0)
-	verbose     bool          // Enable verbose logging
-	maxHistory  int           // Maximum conversation history length
-	temperature float32       // LLM temperature
-	tools       []api.Tool    // Available tools for the agent
-	registry    ToolRegistry  // Tool execution registry
+	backend     Backend            // Server the requests are sent to
+	model       string             // LLM model name (e.g., "qwen2.5:3b")
+	history     []api.Message      // Conversation history (unrendered system prompt at index 0)
+	injected    []bool             // Parallel to history: user messages that were made up (see Prompt.Injected)
+	promptTmpl  *template.Template // System prompt template (nil when the prompt has no variables)
+	verbose     bool               // Enable verbose logging
+	logRequests bool               // Log every chat request sent to Ollama
+	maxHistory  int                // Maximum conversation history length
+	temperature float32            // LLM temperature
+	tools       []api.Tool         // Available tools for the agent
+	registry    ToolRegistry       // Tool execution registry
+	fallback    string             // Reply used when the model keeps answering with nothing
+	keepAlive   *api.Duration      // How long Ollama keeps the model loaded (nil = server default)
+	baseTemp    float32            // Temperature for personas that don't set their own
+	personas    map[string]Persona // Selectable personas by lowercase name
+	replyLang   string             // Language replies must be in (empty = as the prompt says)
+	mu          sync.Mutex         // Guards the history and the settings that change at runtime; not held while a turn waits on the server
+	metrics     *metrics.Recorder  // Records how long replies take (nil = not recorded)
+	states      *state.Manager     // Left Thinking when a prompt gets no reply (nil = not tracked)
+	generation  uint64             // Incremented when the history is cleared, so turns begun before are dropped
+	unreachable atomic.Bool        // The last request failed as if the server were down (see Reachable)
+
+	maxRetries   int           // Retries of a request that failed transiently
+	retryBackoff time.Duration // Wait before the first retry, doubled for each further one
+
+	numPredict int // Maximum tokens per reply (num_predict)
+	numCtx     int // Context window in tokens (num_ctx)
+
+	progress        func(phrase string) // Reports that a tool call is taking a while (nil = silent)
+	progressDelay   time.Duration       // How long a tool runs before progress is reported
+	progressPhrases []string            // Phrases reported in turn
+	progressNext    int                 // Index of the next phrase (only used by Chat)
 }
 
 // Config holds LLM client configuration.
 type Config struct {
+	Backend      string // "ollama" (default) or "openai" for OpenAI-compatible servers
 	Host         string
+	APIKey       string // Bearer token for the openai backend (empty = none)
 	Model        string
 	SystemPrompt string
 	Verbose      bool
+	LogRequests  bool // Log each chat request (full history included) before sending it
 	MaxHistory   int
 	Temperature  float32 // LLM temperature for controlling randomness
 	SearxngURL   string  // Optional SearXNG URL for web search
+
+	// MaxRetries is how many times a request that failed transiently (connection
+	// error, timeout, 5xx) is repeated before the turn fails; RetryBackoff is the
+	// wait before the first retry, doubled for each further one.
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	// NumPredict caps the tokens of each reply and NumCtx sets the model's
+	// context window (0 = 150 and 1024, short replies for voice and little GPU
+	// memory). The openai backend only sends NumPredict, as max_tokens.
+	NumPredict int
+	NumCtx     int
+
+	// EmptyResponseFallback is returned when the model replies with empty text
+	// twice in a row (e.g., it emitted only a stop token). Empty returns "".
+	EmptyResponseFallback string
+
+	// KeepAlive is how long Ollama keeps the model loaded after a request, as a
+	// duration ("10m"), a number of seconds, or "-1" to keep it loaded forever.
+	// Empty uses the server default (5 minutes).
+	KeepAlive string
+
+	// Metrics, when set, records how long each reply from RunProcessor takes.
+	Metrics *metrics.Recorder
+
+	// States, when set, goes from [state.Thinking] back to [state.Idle] when
+	// RunProcessor has no reply to send for a prompt.
+	States *state.Manager
+
+	// Personas maps lowercase names to prompts selectable with [Client.SetPersona].
+	Personas map[string]Persona
+
+	// ToolProgress, when set, is called with the next of ToolProgressPhrases
+	// when a tool call is still running after ToolProgressDelay, so the user
+	// hears something during slow lookups. It must not block. A zero delay or
+	// no phrases disables it.
+	ToolProgress        func(phrase string)
+	ToolProgressDelay   time.Duration
+	ToolProgressPhrases []string
 }
 
-// NewClient creates a new Ollama client with optimized connection pooling and agentic tool support.
-// The HTTP client is configured for low-latency repeated requests to local LLM.
+// Persona is a named system prompt with an optional temperature override.
+type Persona struct {
+	Prompt      string
+	Temperature *float32 // nil uses Config.Temperature
+}
+
+// Reply length and context window defaults.
+const (
+	defaultNumPredict = 150  // Enough for the 2-3 sentences a spoken reply should have
+	defaultNumCtx     = 1024 // Small enough to save GPU memory on edge devices
+
+	// A context of at least largeNumCtx with replies capped below
+	// smallNumPredict tokens is warned about: the context was raised for longer
+	// conversations, but the replies stay clipped.
+	largeNumCtx     = 4096
+	smallNumPredict = 100
+)
+
+// NewClient creates a client for the backend selected by cfg.Backend, with
+// optimized connection pooling and agentic tool support. The HTTP client is
+// configured for low-latency repeated requests to local LLM.
 func NewClient(cfg *Config) (*Client, error) {
 	maxHistory := cfg.MaxHistory
 	if maxHistory <= 0 {
 		maxHistory = 10 // Default to 10 message pairs
 	}
+	numPredict, numCtx := cfg.NumPredict, cfg.NumCtx
+	if numPredict <= 0 {
+		numPredict = defaultNumPredict
+	}
+	if numCtx <= 0 {
+		numCtx = defaultNumCtx
+	}
+	if numCtx >= largeNumCtx && numPredict < smallNumPredict {
+		log.Printf("⚠️ Replies are capped at %d tokens despite a %d-token context; raise --llm-num-predict if they get cut short", numPredict, numCtx)
+	}
 
-	// Parse host URL
-	host := strings.TrimSuffix(cfg.Host, "/")
-	parsedURL, err := url.Parse(host)
+	backend, err := newBackend(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("invalid host URL: %w", err)
+		return nil, err
 	}
 
-	// Create official Ollama client with optimized http.Client
-	// Configure connection pooling to reduce latency on repeated requests
-	httpClient := &http.Client{
-		Timeout: 60 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:        10,
-			MaxIdleConnsPerHost: 10,
-			IdleConnTimeout:     90 * time.Second,
-			DisableCompression:  false,
-		},
+	keepAlive, err := parseKeepAlive(cfg.KeepAlive)
+	if err != nil {
+		return nil, err
 	}
-	client := api.NewClient(parsedURL, httpClient)
 
-	// Build system prompt with tool usage instructions
-	systemPrompt := cfg.SystemPrompt + " CRITICAL: You have two tools available: get_weather and search_web. " +
-		"When asked about current events, news, facts, sports results, or anything you don't know: " +
-		"IMMEDIATELY use search_web tool - DO NOT say you lack information or capabilities. " +
-		"For weather queries: use get_weather tool. Always use tools proactively."
+	// The template is kept in history and rendered per request (see PromptVars).
+	systemPrompt := withToolInstructions(cfg.SystemPrompt)
+	promptTmpl, err := parseSystemPrompt(systemPrompt)
+	if err != nil {
+		return nil, err
+	}
 
 	// Initialize history with enhanced system prompt at index 0
 	history := make([]api.Message, 0, 1)
@@ -81,116 +177,477 @@ func NewClient(cfg *Config) (*Client, error) {
 	tools := GetToolDefinitions()
 
 	return &Client{
-		client:      client,
+		backend:     backend,
 		model:       cfg.Model,
 		history:     history,
+		injected:    make([]bool, len(history)),
+		promptTmpl:  promptTmpl,
 		verbose:     cfg.Verbose,
+		logRequests: cfg.LogRequests,
 		maxHistory:  maxHistory,
 		temperature: cfg.Temperature,
 		tools:       tools,
 		registry:    registry,
+		fallback:    cfg.EmptyResponseFallback,
+		keepAlive:   keepAlive,
+		baseTemp:    cfg.Temperature,
+		personas:    cfg.Personas,
+		metrics:     cfg.Metrics,
+		states:      cfg.States,
+
+		maxRetries:   max(0, cfg.MaxRetries),
+		retryBackoff: cfg.RetryBackoff,
+
+		numPredict: numPredict,
+		numCtx:     numCtx,
+
+		progress:        cfg.ToolProgress,
+		progressDelay:   cfg.ToolProgressDelay,
+		progressPhrases: cfg.ToolProgressPhrases,
 	}, nil
 }
 
+// withToolInstructions appends the tool usage instructions to a system prompt.
+func withToolInstructions(prompt string) string {
+	return prompt + " CRITICAL: You have two tools available: get_weather and search_web. " +
+		"When asked about current events, news, facts, sports results, or anything you don't know: " +
+		"IMMEDIATELY use search_web tool - DO NOT say you lack information or capabilities. " +
+		"For weather queries: use get_weather tool. Always use tools proactively."
+}
+
+// SetSystemPrompt replaces the system prompt (tool instructions are appended as
+// at startup). The conversation history is kept.
+func (c *Client) SetSystemPrompt(prompt string) error {
+	systemPrompt := withToolInstructions(prompt)
+	promptTmpl, err := parseSystemPrompt(systemPrompt)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.history[0].Content = systemPrompt
+	c.promptTmpl = promptTmpl
+	return nil
+}
+
+// SetReplyLanguage asks for subsequent replies in language (e.g. "Spanish"),
+// whatever the system prompt says; empty leaves it to the system prompt.
+func (c *Client) SetReplyLanguage(language string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.replyLang = language
+}
+
+// SetTemperature changes the sampling temperature for subsequent requests.
+func (c *Client) SetTemperature(temperature float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.temperature = temperature
+}
+
+// AvailablePersonas returns the names of the configured personas, sorted.
+func (c *Client) AvailablePersonas() []string {
+	return slices.Sorted(maps.Keys(c.personas))
+}
+
+// SetPersona switches to the named persona (case-insensitive): its system prompt
+// and temperature replace the current ones and the history is cleared, since
+// earlier answers were given in a different role. A turn in progress finishes
+// with the old persona, but its exchange is not added to the new history.
+func (c *Client) SetPersona(name string) error {
+	persona, ok := c.personas[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unknown persona %q", name)
+	}
+	systemPrompt := withToolInstructions(persona.Prompt)
+	promptTmpl, err := parseSystemPrompt(systemPrompt)
+	if err != nil {
+		return err
+	}
+	temperature := c.baseTemp
+	if persona.Temperature != nil {
+		temperature = *persona.Temperature
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.history[0].Content = systemPrompt
+	c.promptTmpl = promptTmpl
+	c.temperature = temperature
+	c.clearHistory()
+	return nil
+}
+
+// Model switching timeouts.
+const (
+	// modelCheckTimeout bounds the check that a model exists before switching to it.
+	modelCheckTimeout = 10 * time.Second
+
+	// modelWarmupTimeout bounds loading a newly selected model in the background.
+	modelWarmupTimeout = 2 * time.Minute
+)
+
+// Model returns the name of the model used for requests.
+func (c *Client) Model() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.model
+}
+
+// SetModel switches subsequent requests to the named model, e.g. from a fast
+// small model to a larger one for harder questions. It fails if the server does
+// not have the model (when the backend can tell). The history is kept. With
+// Ollama, the model is loaded in the background right away, so the first reply
+// from it is not slowed down by the load.
+func (c *Client) SetModel(name string) error {
+	if checker, ok := c.backend.(modelChecker); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), modelCheckTimeout)
+		defer cancel()
+		if err := checker.CheckModel(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	c.model = name
+	c.mu.Unlock()
+
+	if loader, ok := c.backend.(modelLoader); ok {
+		go c.warmUp(loader, name)
+	}
+	return nil
+}
+
+// warmUp asks the backend to load model without generating anything.
+func (c *Client) warmUp(loader modelLoader, model string) {
+	ctx, cancel := context.WithTimeout(context.Background(), modelWarmupTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := loader.LoadModel(ctx, model, c.keepAlive); err != nil {
+		log.Printf("⚠️ Could not preload model %s: %v", model, err)
+		return
+	}
+	log.Printf("🧠 Model %s loaded in %s", model, time.Since(start).Round(time.Millisecond))
+}
+
+// parseKeepAlive converts a [Config.KeepAlive] value into Ollama's keep_alive
+// field. Negative values mean "forever" and are sent as -1.
+func parseKeepAlive(s string) (*api.Duration, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		if secs < 0 {
+			return &api.Duration{Duration: -1}, nil
+		}
+		return &api.Duration{Duration: time.Duration(secs * float64(time.Second))}, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keep-alive %q: use a duration like 10m, seconds, or -1 for forever", s)
+	}
+	if d < 0 {
+		d = -1
+	}
+	return &api.Duration{Duration: d}, nil
+}
+
 // Chat sends a message and returns the response using agentic loop with tool calling.
 // This method implements the agentic loop: LLM → Tool Calls → Tool Results → LLM → Final Answer
+// The turn is added to the history once it completes, unless the history was
+// cleared meanwhile; a failed turn leaves the history as it was.
 func (c *Client) Chat(ctx context.Context, userMessage string) (string, error) {
-	// Append user message to history (system prompt already at index 0)
-	c.history = append(c.history, api.Message{
+	return c.chat(ctx, userMessage, false, nil)
+}
+
+// ChatStream is like [Client.Chat] but streams the reply: onToken is called
+// with each piece of text as the model generates it, so speech can start before the
+// reply is complete. The assembled reply is added to the history at the end. If
+// ctx is cancelled or onToken returns an error, the turn is abandoned, the
+// history is left as it was before the call, and the error is returned.
+func (c *Client) ChatStream(ctx context.Context, userMessage string, onToken func(string) error) error {
+	_, err := c.chat(ctx, userMessage, false, onToken)
+	return err
+}
+
+// chat runs one agentic turn for Chat and ChatStream. With onToken set the
+// responses are streamed to it; the fallback reply, when used, is passed to it
+// as well. An injected user message is marked as such in the history.
+func (c *Client) chat(ctx context.Context, userMessage string, injected bool, onToken func(string) error) (string, error) {
+	c.mu.Lock()
+	// Render the system prompt once per turn so time-based variables are current.
+	// The persona or model may change mid-turn, so they are read once.
+	systemPrompt := c.renderSystemPrompt()
+	if c.replyLang != "" {
+		systemPrompt += " Always reply in " + c.replyLang + ", the language the user is speaking."
+	}
+	model, temperature := c.model, c.temperature
+	generation := c.generation
+	c.mu.Unlock()
+
+	// Messages of this turn, added to the history once it completes
+	turn := []api.Message{{
 		Role:    "user",
 		Content: userMessage,
-	})
+	}}
 
 	// Agentic loop: keep calling LLM until no more tools are needed
 	maxIterations := 5 // Prevent infinite loops
+	retriedEmpty := false
 	for iteration := 0; iteration < maxIterations; iteration++ {
-		var response api.ChatResponse
-		err := c.client.Chat(ctx, &api.ChatRequest{
-			Model:    c.model,
-			Messages: c.history, // Pass history directly (includes system prompt)
-			Tools:    c.tools,   // Provide available tools
-			Stream:   new(false),
-			Think:    &api.ThinkValue{Value: false},
+		req := &api.ChatRequest{
+			Model:     model,
+			Messages:  c.requestMessages(systemPrompt, turn), // History with the rendered system prompt
+			Tools:     c.tools,                               // Provide available tools
+			Stream:    new(onToken != nil),
+			Think:     &api.ThinkValue{Value: false},
+			KeepAlive: c.keepAlive,
 			Options: map[string]any{
-				"temperature": c.temperature,
-				"num_predict": 150,  // Limit response length for voice output
-				"num_ctx":     1024, // Reduced context window to save GPU memory
+				"temperature": temperature,
+				"num_predict": c.numPredict, // Limit response length for voice output
+				"num_ctx":     c.numCtx,     // Reduced context window to save GPU memory
 			},
-		}, func(resp api.ChatResponse) error {
-			response = resp
-			return nil
-		})
+		}
+		if c.logRequests {
+			logRequest(req)
+		}
+		message, err := c.send(ctx, req, onToken)
 		if err != nil {
 			return "", fmt.Errorf("chat request failed: %w", err)
 		}
 
 		// Check if LLM wants to use tools
-		if len(response.Message.ToolCalls) == 0 {
+		if len(message.ToolCalls) == 0 {
 			// No tools needed, we have the final answer
-			finalResponse := strings.TrimSpace(response.Message.Content)
+			finalResponse := strings.TrimSpace(message.Content)
 
-			// Append assistant response to history
-			c.history = append(c.history, api.Message{
-				Role:    "assistant",
-				Content: finalResponse,
-			})
-
-			// Trim history if too long
-			c.trimHistory()
+			// An empty reply would leave the user in silence; ask once more,
+			// then fall back to a canned phrase.
+			if finalResponse == "" {
+				if !retriedEmpty {
+					retriedEmpty = true
+					log.Println("⚠️ LLM returned an empty response, retrying")
+					continue
+				}
+				log.Println("⚠️ LLM returned an empty response again, using fallback")
+				finalResponse = c.fallback
+				if onToken != nil && finalResponse != "" {
+					if err := onToken(finalResponse); err != nil {
+						return "", err
+					}
+				}
+			}
 
+			// Append the turn and the assistant response to history
+			c.commitTurn(generation, turn, injected, finalResponse)
 			return finalResponse, nil
 		}
 
 		// Execute tools and collect results
-		// First, append the assistant's message with tool calls to history
-		c.history = append(c.history, response.Message)
+		// First, append the assistant's message with tool calls to the turn
+		turn = append(turn, message)
 
 		// Execute each tool call
-		for _, toolCall := range response.Message.ToolCalls {
+		for _, toolCall := range message.ToolCalls {
 			toolFunc, exists := c.registry[toolCall.Function.Name]
 			if !exists {
 				// Unknown tool, add error message
-				c.history = append(c.history, api.Message{
-					Role:    "tool",
-					Content: fmt.Sprintf("Error: Unknown tool '%s'", toolCall.Function.Name),
+				turn = append(turn, api.Message{
+					Role:       "tool",
+					Content:    fmt.Sprintf("Error: Unknown tool '%s'", toolCall.Function.Name),
+					ToolName:   toolCall.Function.Name,
+					ToolCallID: toolCall.ID,
 				})
 				continue
 			}
 
 			// Execute the tool (convert Arguments to JSON string)
 			argJSON := toolCall.Function.Arguments.String()
+			stopProgress := c.startToolProgress(toolCall.Function.Name)
 			result, err := toolFunc(ctx, argJSON)
+			stopProgress()
 			if err != nil {
 				// Tool execution failed, add error message
 				result = fmt.Sprintf("Error executing tool: %v", err)
 			}
 
-			// Add tool result to history
-			c.history = append(c.history, api.Message{
-				Role:    "tool",
-				Content: result,
+			// Add tool result to the turn
+			turn = append(turn, api.Message{
+				Role:       "tool",
+				Content:    result,
+				ToolName:   toolCall.Function.Name,
+				ToolCallID: toolCall.ID,
 			})
 		}
-
-		// Trim history after each iteration to prevent unbounded growth,
-		// including tool messages added in this iteration.
-		c.trimHistory()
 		// Loop continues: LLM will see tool results and generate final response
 	}
 
 	// If we hit max iterations, append a final assistant message and return an error
 	finalMsg := "I apologize, but I couldn't complete the task within the allowed time."
+	c.commitTurn(generation, turn, injected, finalMsg)
+	return finalMsg, fmt.Errorf("max agentic iterations (%d) exceeded", maxIterations)
+}
+
+// sendOnce sends req and returns the assistant message. With onToken set the
+// response is streamed: each piece of text is passed to onToken and the pieces
+// and tool calls are assembled into the returned message. An error from onToken
+// aborts the request and is returned, as is a stream cut short by ctx.
+func (c *Client) sendOnce(ctx context.Context, req *api.ChatRequest, onToken func(string) error) (api.Message, error) {
+	var message api.Message
+	var content strings.Builder
+	done := false
+	err := c.backend.Chat(ctx, req, func(resp api.ChatResponse) error {
+		if onToken == nil {
+			message = resp.Message
+			return nil
+		}
+		done = resp.Done
+		message.Role = resp.Message.Role
+		message.ToolCalls = append(message.ToolCalls, resp.Message.ToolCalls...)
+		if resp.Message.Content == "" {
+			return nil
+		}
+		content.WriteString(resp.Message.Content)
+		return onToken(resp.Message.Content)
+	})
+	if err != nil || onToken == nil {
+		return message, err
+	}
+	if !done {
+		if err := ctx.Err(); err != nil {
+			return message, err
+		}
+		return message, errIncompleteStream
+	}
+	message.Content = content.String()
+	return message, nil
+}
+
+// commitTurn appends the messages of a completed turn and its reply to the
+// history and trims it, marking its user message if it was injected. A turn
+// begun at an earlier generation, before the history was cleared (e.g. by a
+// persona switch), is dropped instead.
+func (c *Client) commitTurn(generation uint64, turn []api.Message, injected bool, reply string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		if c.verbose {
+			log.Println("[LLM] History was cleared during the turn; not keeping it")
+		}
+		return
+	}
+	c.history = append(c.history, turn...)
 	c.history = append(c.history, api.Message{
 		Role:    "assistant",
-		Content: finalMsg,
+		Content: reply,
 	})
+	marks := make([]bool, len(turn)+1)
+	marks[0] = injected
+	c.injected = append(c.injected, marks...)
 	c.trimHistory()
-	return finalMsg, fmt.Errorf("max agentic iterations (%d) exceeded", maxIterations)
+}
+
+// logRequest logs req as the JSON sent to Ollama (other backends translate it),
+// for debugging prompts and history trimming.
+func logRequest(req *api.ChatRequest) {
+	data, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		log.Printf("[LLM] Failed to serialize request: %v", err)
+		return
+	}
+	log.Printf("[LLM] Request: %s", data)
+}
+
+// startToolProgress arranges for the next progress phrase to be reported if
+// the tool named tool is still running after the progress delay. The returned
+// function cancels the report and must be called when the tool returns.
+func (c *Client) startToolProgress(tool string) (stop func()) {
+	if c.progress == nil || c.progressDelay <= 0 || len(c.progressPhrases) == 0 {
+		return func() {}
+	}
+	phrase := c.progressPhrases[c.progressNext%len(c.progressPhrases)]
+	timer := time.AfterFunc(c.progressDelay, func() {
+		if c.verbose {
+			log.Printf("[LLM] Tool %s still running after %s", tool, c.progressDelay)
+		}
+		c.progress(phrase)
+	})
+	return func() {
+		if !timer.Stop() {
+			c.progressNext++ // Rotate only through phrases that were reported
+		}
+	}
+}
+
+// renderSystemPrompt returns the system prompt with template variables expanded.
+// On a rendering error the raw template is used so the turn can still proceed.
+func (c *Client) renderSystemPrompt() string {
+	if c.promptTmpl == nil {
+		return c.history[0].Content
+	}
+	rendered, err := renderSystemPrompt(c.promptTmpl, time.Now())
+	if err != nil {
+		log.Printf("⚠️ System prompt template error: %v", err)
+		return c.history[0].Content
+	}
+	return rendered
+}
+
+// requestMessages returns a copy of the history with systemPrompt as its system
+// message, followed by turn, the messages of the turn in progress.
+func (c *Client) requestMessages(systemPrompt string, turn []api.Message) []api.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	messages := make([]api.Message, 0, len(c.history)+len(turn))
+	messages = append(messages, c.history...)
+	messages[0].Content = systemPrompt
+	return append(messages, turn...)
+}
+
+// interruptedNote marks an assistant message that the user cut off, so the model
+// doesn't assume the rest of its answer was heard.
+const interruptedNote = "[interrupted by the user]"
+
+// ReviseResponse replaces the assistant reply full in the history with heard, the
+// part the user actually heard before interrupting (empty if none of it), marked
+// as interrupted. This keeps follow-up answers from referring to unheard text.
+// The most recent matching reply is revised, even if later turns have been
+// added since. Returns false when full is no longer in the history.
+func (c *Client) ReviseResponse(full, heard string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := len(c.history) - 1; i > 0; i-- {
+		msg := &c.history[i]
+		if msg.Role != "assistant" || msg.Content != full {
+			continue
+		}
+		if heard = strings.TrimSpace(heard); heard != "" {
+			msg.Content = heard + " " + interruptedNote
+		} else {
+			msg.Content = interruptedNote
+		}
+		return true
+	}
+	return false
 }
 
 // ClearHistory clears the conversation history (preserves system prompt).
+// It does not wait for a Chat in progress: that turn's exchange is dropped
+// when it completes, rather than added to the cleared history.
 func (c *Client) ClearHistory() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clearHistory()
+}
+
+// clearHistory empties the history for ClearHistory and SetPersona. c.mu must
+// be held.
+func (c *Client) clearHistory() {
 	c.history = c.history[:1] // Keep only system prompt at index 0
+	c.injected = c.injected[:1]
+	c.generation++
 }
 
 // trimHistory keeps only the last N message pairs (preserves system prompt).
@@ -200,14 +657,18 @@ func (c *Client) trimHistory() {
 		// Keep system prompt (index 0) and last N pairs
 		systemMsg := c.history[0]
 		c.history = append([]api.Message{systemMsg}, c.history[len(c.history)-c.maxHistory*2:]...)
+		c.injected = append([]bool{false}, c.injected[len(c.injected)-c.maxHistory*2:]...)
 	}
 }
 
-// HealthCheck verifies the Ollama server is reachable.
+// Reachable reports whether the LLM server answered the last request, or
+// failed it with an error it would not have sent if it were down (e.g. 4xx).
+// It is true until the first request; cancelled requests don't change it.
+func (c *Client) Reachable() bool {
+	return !c.unreachable.Load()
+}
+
+// HealthCheck verifies the LLM server is reachable.
 func (c *Client) HealthCheck(ctx context.Context) error {
-	// Use the Heartbeat method to check connectivity
-	if err := c.client.Heartbeat(ctx); err != nil {
-		return fmt.Errorf("cannot reach Ollama: %w", err)
-	}
-	return nil
+	return c.backend.HealthCheck(ctx)
 }
@@ -0,0 +1,480 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a Client talking to a fake Ollama server that answers
+// each chat request with the next entry of replies.
+func newTestClient(t *testing.T, fallback string, replies ...string) (*Client, *int) {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reply := replies[min(calls, len(replies)-1)]
+		calls++
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"model":   "test",
+			"message": map[string]string{"role": "assistant", "content": reply},
+			"done":    true,
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient(&Config{Host: srv.URL, Model: "test", EmptyResponseFallback: fallback})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return c, &calls
+}
+
+func TestChatRetriesEmptyResponseOnce(t *testing.T) {
+	c, calls := newTestClient(t, "fallback", " \n", "Hello there.")
+
+	got, err := c.Chat(context.Background(), "hi")
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if got != "Hello there." {
+		t.Errorf("Chat = %q, want the retried reply", got)
+	}
+	if *calls != 2 {
+		t.Errorf("requests = %d, want 2", *calls)
+	}
+}
+
+func TestChatUsesFallbackAfterSecondEmptyResponse(t *testing.T) {
+	c, calls := newTestClient(t, "Could you rephrase?", "", "")
+
+	got, err := c.Chat(context.Background(), "hi")
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if got != "Could you rephrase?" {
+		t.Errorf("Chat = %q, want the fallback phrase", got)
+	}
+	if *calls != 2 {
+		t.Errorf("requests = %d, want 2", *calls)
+	}
+	if last := c.history[len(c.history)-1]; last.Role != "assistant" || last.Content != got {
+		t.Errorf("last history message = %+v, want the fallback as assistant reply", last)
+	}
+}
+
+func TestReviseResponseKeepsOnlyWhatWasHeard(t *testing.T) {
+	c, _ := newTestClient(t, "", "First part. Second part.", "Next answer.")
+	full, _ := c.Chat(context.Background(), "hi")
+	if _, err := c.Chat(context.Background(), "and then?"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A later turn was added; the earlier reply is still found and revised.
+	if !c.ReviseResponse(full, "First part.") {
+		t.Fatal("ReviseResponse did not find the reply")
+	}
+	if got, want := c.history[2].Content, "First part. "+interruptedNote; got != want {
+		t.Errorf("revised reply = %q, want %q", got, want)
+	}
+	if got := c.history[4].Content; got != "Next answer." {
+		t.Errorf("later reply = %q, want it untouched", got)
+	}
+
+	if !c.ReviseResponse("Next answer.", "") || c.history[4].Content != interruptedNote {
+		t.Errorf("unheard reply = %q, want only the interruption note", c.history[4].Content)
+	}
+	if c.ReviseResponse("never said", "") {
+		t.Error("ReviseResponse should report a missing reply")
+	}
+}
+
+func TestParseKeepAlive(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // JSON sent to Ollama; "" means the field is omitted
+	}{
+		{"", ""},
+		{"10m", `"10m0s"`},
+		{"90", `"1m30s"`},
+		{"-1", "-1"},
+		{"-1m", "-1"},
+	}
+	for _, tt := range tests {
+		d, err := parseKeepAlive(tt.in)
+		if err != nil {
+			t.Fatalf("parseKeepAlive(%q): %v", tt.in, err)
+		}
+		got := ""
+		if d != nil {
+			b, _ := json.Marshal(d)
+			got = string(b)
+		}
+		if got != tt.want {
+			t.Errorf("parseKeepAlive(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := parseKeepAlive("forever"); err == nil {
+		t.Error("parseKeepAlive(\"forever\") succeeded, want an error")
+	}
+}
+
+func TestSetPersonaSwitchesPromptAndClearsHistory(t *testing.T) {
+	c, _ := newTestClient(t, "", "Hi.")
+	c.baseTemp, c.temperature = 0.7, 0.7
+	low := float32(0.2)
+	c.personas = map[string]Persona{
+		"tutor":    {Prompt: "You are a tutor.", Temperature: &low},
+		"comedian": {Prompt: "You are a comedian."},
+	}
+	if _, err := c.Chat(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.SetPersona("Tutor"); err != nil {
+		t.Fatalf("SetPersona: %v", err)
+	}
+	if len(c.history) != 1 || !strings.HasPrefix(c.history[0].Content, "You are a tutor.") {
+		t.Errorf("history = %+v, want only the tutor system prompt", c.history)
+	}
+	if c.temperature != 0.2 {
+		t.Errorf("temperature = %v, want the persona's 0.2", c.temperature)
+	}
+
+	// A persona without a temperature restores the configured one.
+	if err := c.SetPersona("comedian"); err != nil {
+		t.Fatal(err)
+	}
+	if c.temperature != 0.7 {
+		t.Errorf("temperature = %v, want the base 0.7", c.temperature)
+	}
+
+	if err := c.SetPersona("pirate"); err == nil {
+		t.Error("SetPersona(\"pirate\") succeeded, want an error")
+	}
+	if got := c.AvailablePersonas(); !slices.Equal(got, []string{"comedian", "tutor"}) {
+		t.Errorf("AvailablePersonas = %v", got)
+	}
+}
+
+func TestSetPersonaDropsTurnInProgress(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"model":   "test",
+			"message": map[string]string{"role": "assistant", "content": "Arr."},
+			"done":    true,
+		})
+	}))
+	defer srv.Close()
+	c, err := NewClient(&Config{Host: srv.URL, Model: "test"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	c.personas = map[string]Persona{"tutor": {Prompt: "You are a tutor."}}
+
+	done := make(chan error)
+	go func() {
+		_, err := c.Chat(context.Background(), "talk like a pirate")
+		done <- err
+	}()
+	<-entered
+	if err := c.SetPersona("tutor"); err != nil {
+		t.Fatalf("SetPersona: %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.history) != 1 {
+		t.Errorf("history = %+v, want only the tutor system prompt", c.history)
+	}
+}
+
+func TestSetModelChecksAvailability(t *testing.T) {
+	var mu sync.Mutex
+	var chatModels []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/api/show":
+			if req.Model != "big" {
+				w.WriteHeader(http.StatusNotFound)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "model not found"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{})
+		case "/api/chat":
+			mu.Lock()
+			chatModels = append(chatModels, req.Model)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"model":   req.Model,
+				"message": map[string]string{"role": "assistant", "content": "Hi."},
+				"done":    true,
+			})
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient(&Config{Host: srv.URL, Model: "small"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.SetModel("missing"); err == nil || !strings.Contains(err.Error(), "ollama pull missing") {
+		t.Errorf("SetModel(missing) = %v, want a not-available error", err)
+	}
+	if c.Model() != "small" {
+		t.Errorf("model changed to %q after a failed switch", c.Model())
+	}
+
+	if err := c.SetModel("big"); err != nil {
+		t.Fatalf("SetModel(big): %v", err)
+	}
+	if _, err := c.Chat(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Contains(chatModels, "big") || slices.Contains(chatModels, "small") {
+		t.Errorf("chat requests used models %v, want only big", chatModels)
+	}
+}
+
+func TestToolProgressReportsSlowToolsOnly(t *testing.T) {
+	var mu sync.Mutex
+	var reported []string
+	c := &Client{
+		progress: func(phrase string) {
+			mu.Lock()
+			defer mu.Unlock()
+			reported = append(reported, phrase)
+		},
+		progressDelay:   10 * time.Millisecond,
+		progressPhrases: []string{"Looking that up...", "Still working on it..."},
+	}
+
+	stop := c.startToolProgress("fast")
+	stop()
+	for range 2 {
+		stop = c.startToolProgress("slow")
+		time.Sleep(50 * time.Millisecond)
+		stop()
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"Looking that up...", "Still working on it..."}
+	if !slices.Equal(reported, want) {
+		t.Errorf("reported = %q, want %q", reported, want)
+	}
+}
+
+func TestChatLogsRequestsWhenEnabled(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	c, _ := newTestClient(t, "", "Hello there.")
+	if _, err := c.Chat(context.Background(), "what is the secret word"); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if strings.Contains(buf.String(), "[LLM] Request") {
+		t.Fatal("request logged although LogRequests is off")
+	}
+
+	c.logRequests = true
+	if _, err := c.Chat(context.Background(), "and the other one"); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	for _, want := range []string{"[LLM] Request", `"model": "test"`, "what is the secret word", "and the other one", `"num_ctx"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestChatSendsConfiguredReplyLengthAndContext(t *testing.T) {
+	var options map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Options map[string]any `json:"options"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		options = req.Options
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"model":   "test",
+			"message": map[string]string{"role": "assistant", "content": "Sure."},
+			"done":    true,
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	for _, tc := range []struct {
+		predict, ctx         int
+		wantPredict, wantCtx float64
+		warn                 bool
+	}{
+		{0, 0, 150, 1024, false},
+		{400, 8192, 400, 8192, false},
+		{50, 8192, 50, 8192, true},
+	} {
+		buf.Reset()
+		c, err := NewClient(&Config{Host: srv.URL, Model: "test", NumPredict: tc.predict, NumCtx: tc.ctx})
+		if err != nil {
+			t.Fatalf("NewClient: %v", err)
+		}
+		if _, err := c.Chat(context.Background(), "hi"); err != nil {
+			t.Fatalf("Chat: %v", err)
+		}
+		if options["num_predict"] != tc.wantPredict || options["num_ctx"] != tc.wantCtx {
+			t.Errorf("NumPredict %d, NumCtx %d: options = %v", tc.predict, tc.ctx, options)
+		}
+		if warned := strings.Contains(buf.String(), "capped at"); warned != tc.warn {
+			t.Errorf("NumPredict %d, NumCtx %d: warned = %v, want %v", tc.predict, tc.ctx, warned, tc.warn)
+		}
+	}
+}
+
+func TestSetReplyLanguageAddsInstructionPerRequest(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	c, _ := newTestClient(t, "", "Hola.")
+	c.logRequests = true
+	prompt := c.history[0].Content
+
+	c.SetReplyLanguage("Spanish")
+	if _, err := c.Chat(context.Background(), "hola"); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Always reply in Spanish") {
+		t.Errorf("request does not ask for Spanish:\n%s", buf.String())
+	}
+	if c.history[0].Content != prompt {
+		t.Errorf("stored system prompt changed to %q", c.history[0].Content)
+	}
+
+	buf.Reset()
+	c.SetReplyLanguage("")
+	if _, err := c.Chat(context.Background(), "hello"); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if strings.Contains(buf.String(), "Always reply in") {
+		t.Errorf("request still asks for a reply language:\n%s", buf.String())
+	}
+}
+
+// newStreamingClient returns a Client talking to a fake Ollama server that
+// streams chunks as separate response lines, then waits for block (if non-nil)
+// to be closed or the request to be cancelled before finishing.
+func newStreamingClient(t *testing.T, block chan struct{}, chunks ...string) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Stream *bool }
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Stream == nil || !*req.Stream {
+			t.Error("request is not streamed")
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		for _, chunk := range chunks {
+			_ = enc.Encode(map[string]any{
+				"model":   "test",
+				"message": map[string]string{"role": "assistant", "content": chunk},
+			})
+			w.(http.Flusher).Flush()
+		}
+		if block != nil {
+			select {
+			case <-block:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		_ = enc.Encode(map[string]any{"model": "test", "message": map[string]string{"role": "assistant"}, "done": true})
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient(&Config{Host: srv.URL, Model: "test"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return c
+}
+
+func TestChatStreamDeliversTokensAndRecordsReply(t *testing.T) {
+	c := newStreamingClient(t, nil, "Hello", " there", ".")
+
+	var tokens []string
+	err := c.ChatStream(context.Background(), "hi", func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if want := []string{"Hello", " there", "."}; !slices.Equal(tokens, want) {
+		t.Errorf("tokens = %q, want %q", tokens, want)
+	}
+	if len(c.history) != 3 || c.history[1].Content != "hi" || c.history[2].Content != "Hello there." {
+		t.Errorf("history = %+v, want the user message and the assembled reply", c.history[1:])
+	}
+}
+
+func TestChatStreamAbortLeavesHistoryUntouched(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	t.Run("cancelled", func(t *testing.T) {
+		c := newStreamingClient(t, block, "Partial")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		err := c.ChatStream(ctx, "hi", func(string) error {
+			cancel() // The user interrupted after the first token
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("ChatStream error = %v, want context.Canceled", err)
+		}
+		if len(c.history) != 1 {
+			t.Errorf("history = %+v, want only the system prompt", c.history)
+		}
+	})
+
+	t.Run("callback error", func(t *testing.T) {
+		c := newStreamingClient(t, block, "Partial")
+		stop := errors.New("stop")
+		if err := c.ChatStream(context.Background(), "hi", func(string) error { return stop }); !errors.Is(err, stop) {
+			t.Fatalf("ChatStream error = %v, want %v", err, stop)
+		}
+		if len(c.history) != 1 {
+			t.Errorf("history = %+v, want only the system prompt", c.history)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/ollama/ollama/api"
+)
+
+// savedMessage is a history message as written by [Client.SaveHistory].
+type savedMessage struct {
+	api.Message
+	Injected bool `json:"injected,omitempty"` // Made up rather than said by the user (see Prompt.Injected)
+}
+
+// SaveHistory writes the conversation history to path as a JSON array of
+// messages. The system prompt is included at index 0, as the unrendered
+// template, so the file records what the conversation was held under; it is
+// not restored by [Client.LoadHistory]. Injected user messages are marked with
+// "injected": true. The file is replaced atomically.
+func (c *Client) SaveHistory(path string) error {
+	c.mu.Lock()
+	messages := make([]savedMessage, len(c.history))
+	for i, msg := range c.history {
+		messages[i] = savedMessage{Message: msg, Injected: c.injected[i]}
+	}
+	c.mu.Unlock()
+	data, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode history: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	return nil
+}
+
+// LoadHistory replaces the conversation history with the one saved at path by
+// [Client.SaveHistory]. The configured system prompt is kept: a system message
+// at the start of the file is dropped, even when it differs (e.g. the prompt or
+// persona changed since). The result is trimmed to MaxHistory exchanges.
+func (c *Client) LoadHistory(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to load history: %w", err)
+	}
+	var messages []api.Message
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return fmt.Errorf("invalid history file %s: %w", path, err)
+	}
+	// Decoded apart, since api.Message decodes itself and would hide the flag
+	var marks []struct {
+		Injected bool `json:"injected"`
+	}
+	if err := json.Unmarshal(data, &marks); err != nil {
+		return fmt.Errorf("invalid history file %s: %w", path, err)
+	}
+	injected := make([]bool, len(messages))
+	for i, mark := range marks {
+		injected[i] = mark.Injected
+	}
+	if len(messages) > 0 && messages[0].Role == "system" {
+		if messages[0].Content != c.systemPromptTemplate() && c.verbose {
+			log.Println("[LLM] Saved history has a different system prompt, keeping the configured one")
+		}
+		messages, injected = messages[1:], injected[1:]
+	}
+	for i, msg := range messages {
+		switch msg.Role {
+		case "user", "assistant", "tool":
+		default:
+			return fmt.Errorf("invalid history file %s: message %d has role %q", path, i, msg.Role)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.history = append(c.history[:1], messages...)
+	c.injected = append(c.injected[:1], injected...)
+	c.trimHistory()
+	return nil
+}
+
+// systemPromptTemplate returns the unrendered system prompt.
+func (c *Client) systemPromptTemplate() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.history[0].Content
+}
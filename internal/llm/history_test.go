@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSaveAndLoadHistoryKeepsConfiguredPrompt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+
+	saved, _ := newTestClient(t, "", "Hi there.")
+	if _, err := saved.Chat(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := saved.SaveHistory(path); err != nil {
+		t.Fatalf("SaveHistory: %v", err)
+	}
+
+	loaded, _ := newTestClient(t, "", "unused")
+	if err := loaded.SetSystemPrompt("You are a pirate."); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.LoadHistory(path); err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	if len(loaded.history) != 3 {
+		t.Fatalf("history = %+v, want the system prompt and one exchange", loaded.history)
+	}
+	if !strings.HasPrefix(loaded.history[0].Content, "You are a pirate.") {
+		t.Errorf("system prompt = %q, want the configured one", loaded.history[0].Content)
+	}
+	if loaded.history[1].Content != "hello" || loaded.history[2].Content != "Hi there." {
+		t.Errorf("exchange = %q / %q, want the saved one", loaded.history[1].Content, loaded.history[2].Content)
+	}
+}
+
+func TestLoadHistoryRejectsInvalidFiles(t *testing.T) {
+	c, _ := newTestClient(t, "", "unused")
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"garbage.json": "not json",
+		"role.json":    `[{"role": "system", "content": "a"}, {"role": "system", "content": "b"}]`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.LoadHistory(path); err == nil {
+			t.Errorf("LoadHistory(%s) succeeded, want an error", name)
+		}
+	}
+	if len(c.history) != 1 {
+		t.Errorf("history = %+v, want it untouched by failed loads", c.history)
+	}
+}
+
+func TestSaveAndLoadHistoryKeepInjectedMarks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+
+	saved, _ := newTestClient(t, "", "Hi there.")
+	if _, err := saved.chat(context.Background(), "Hello", true, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := saved.Chat(context.Background(), "how are you"); err != nil {
+		t.Fatal(err)
+	}
+	if err := saved.SaveHistory(path); err != nil {
+		t.Fatalf("SaveHistory: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(data), `"injected": true`); got != 1 {
+		t.Errorf("saved history marks %d messages as injected, want 1:\n%s", got, data)
+	}
+
+	loaded, _ := newTestClient(t, "", "unused")
+	if err := loaded.LoadHistory(path); err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	want := []bool{false, true, false, false, false}
+	if !slices.Equal(loaded.injected, want) {
+		t.Errorf("injected = %v, want %v", loaded.injected, want)
+	}
+	if loaded.history[1].Content != "Hello" {
+		t.Errorf("injected message = %q, want it sent as is", loaded.history[1].Content)
+	}
+}
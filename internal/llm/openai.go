@@ -0,0 +1,309 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Compile-time interface compliance checks.
+var (
+	_ Backend      = (*openAIBackend)(nil)
+	_ modelChecker = (*openAIBackend)(nil)
+)
+
+// openAIBackend is the [Backend] for servers with an OpenAI-compatible chat
+// completions API (vLLM, LM Studio, llama.cpp server, Ollama's /v1 endpoint).
+// Ollama-only request fields (keep_alive, think, num_ctx) are not sent.
+type openAIBackend struct {
+	baseURL string // Server URL up to and including /v1
+	apiKey  string // Sent as a bearer token when set
+	client  *http.Client
+}
+
+// newOpenAIBackend returns an openAIBackend for the server at host, which may
+// be given with or without the /v1 suffix.
+func newOpenAIBackend(host *url.URL, apiKey string) *openAIBackend {
+	base := host.String()
+	if !strings.HasSuffix(base, "/v1") {
+		base += "/v1"
+	}
+	return &openAIBackend{baseURL: base, apiKey: apiKey, client: newHTTPClient()}
+}
+
+// openAIRequest is the body of a chat completions request.
+type openAIRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Tools       []api.Tool      `json:"tools,omitempty"` // Same JSON shape as OpenAI's
+	Stream      bool            `json:"stream"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	MaxTokens   *int            `json:"max_tokens,omitempty"`
+}
+
+// openAIMessage is a chat message, or the delta of one in a streamed response.
+type openAIMessage struct {
+	Role       string           `json:"role,omitempty"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// openAIToolCall is a tool call, or a piece of one in a streamed response.
+type openAIToolCall struct {
+	Index    int    `json:"index"` // Position among the message's calls (streamed pieces only)
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"` // JSON object, as a string
+	} `json:"function"`
+}
+
+// openAIResponse is a chat completion, or one chunk of a streamed one.
+type openAIResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		Delta        openAIMessage `json:"delta"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+func (b *openAIBackend) Chat(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+	body, err := openAIRequestFor(req)
+	if err != nil {
+		return err
+	}
+	resp, err := b.do(ctx, http.MethodPost, "/chat/completions", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !body.Stream {
+		var completion openAIResponse
+		if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+			return fmt.Errorf("invalid chat completion: %w", err)
+		}
+		if len(completion.Choices) == 0 {
+			return fmt.Errorf("chat completion has no choices")
+		}
+		message, err := completion.Choices[0].Message.ollamaMessage()
+		if err != nil {
+			return err
+		}
+		return fn(api.ChatResponse{Model: req.Model, Message: message, Done: true})
+	}
+	return readOpenAIStream(resp.Body, req.Model, fn)
+}
+
+// readOpenAIStream passes the text of a server-sent event stream of chat
+// completion chunks to fn as it arrives. Tool calls arrive in pieces; they are
+// assembled and passed in the final call, with Done set, once the stream
+// completes. A stream that ends early returns without the final call.
+func readOpenAIStream(r io.Reader, model string, fn api.ChatResponseFunc) error {
+	var calls []openAIToolCall
+	finished := false
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue // Blank separators, comments and other event fields
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			finished = true
+			break
+		}
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("invalid chat completion chunk: %w", err)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		choice := chunk.Choices[0]
+		for _, piece := range choice.Delta.ToolCalls {
+			if piece.Index >= len(calls) {
+				calls = slices.Grow(calls, piece.Index+1-len(calls))[:piece.Index+1]
+			}
+			call := &calls[piece.Index]
+			call.ID += piece.ID
+			call.Function.Name += piece.Function.Name
+			call.Function.Arguments += piece.Function.Arguments
+		}
+		if choice.FinishReason != "" {
+			finished = true
+		}
+		if choice.Delta.Content != "" {
+			err := fn(api.ChatResponse{
+				Model:   model,
+				Message: api.Message{Role: "assistant", Content: choice.Delta.Content},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil || !finished {
+		return err
+	}
+
+	message, err := openAIMessage{Role: "assistant", ToolCalls: calls}.ollamaMessage()
+	if err != nil {
+		return err
+	}
+	return fn(api.ChatResponse{Model: model, Message: message, Done: true})
+}
+
+func (b *openAIBackend) HealthCheck(ctx context.Context) error {
+	if _, err := b.models(ctx); err != nil {
+		return fmt.Errorf("cannot reach %s: %w", b.baseURL, err)
+	}
+	return nil
+}
+
+func (b *openAIBackend) CheckModel(ctx context.Context, name string) error {
+	models, err := b.models(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot check model %q: %w", name, err)
+	}
+	if !slices.Contains(models, name) {
+		return fmt.Errorf("model %q is not available on the server (available: %s)", name, strings.Join(models, ", "))
+	}
+	return nil
+}
+
+// models returns the IDs of the models the server offers.
+func (b *openAIBackend) models(ctx context.Context) ([]string, error) {
+	resp, err := b.do(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("invalid model list: %w", err)
+	}
+	ids := make([]string, len(list.Data))
+	for i, m := range list.Data {
+		ids[i] = m.ID
+	}
+	return ids, nil
+}
+
+// do sends a request with body (nil for none) as JSON to path under the base
+// URL. A response other than 200 OK is returned as an [api.StatusError], as the
+// Ollama client does, so errors are classified the same for both backends.
+func (b *openAIBackend) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.apiKey)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, api.StatusError{
+			StatusCode:   resp.StatusCode,
+			Status:       resp.Status,
+			ErrorMessage: strings.TrimSpace(string(msg)),
+		}
+	}
+	return resp, nil
+}
+
+// openAIRequestFor translates an Ollama chat request. Of the options, only
+// temperature and num_predict (as max_tokens) have an OpenAI equivalent.
+func openAIRequestFor(req *api.ChatRequest) (*openAIRequest, error) {
+	out := &openAIRequest{
+		Model:    req.Model,
+		Messages: make([]openAIMessage, len(req.Messages)),
+		Tools:    req.Tools,
+		Stream:   req.Stream != nil && *req.Stream,
+	}
+	for i, msg := range req.Messages {
+		out.Messages[i] = openAIMessageFor(msg)
+	}
+
+	// A round trip through JSON accepts the options in any numeric type.
+	data, err := json.Marshal(req.Options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode options: %w", err)
+	}
+	var options struct {
+		Temperature *float64 `json:"temperature"`
+		NumPredict  *int     `json:"num_predict"`
+	}
+	if err := json.Unmarshal(data, &options); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	out.Temperature, out.MaxTokens = options.Temperature, options.NumPredict
+	return out, nil
+}
+
+// openAIMessageFor translates an Ollama message.
+func openAIMessageFor(msg api.Message) openAIMessage {
+	out := openAIMessage{Role: msg.Role, Content: msg.Content, ToolCallID: msg.ToolCallID}
+	for i, call := range msg.ToolCalls {
+		c := openAIToolCall{Index: i, ID: call.ID, Type: "function"}
+		c.Function.Name = call.Function.Name
+		c.Function.Arguments = call.Function.Arguments.String()
+		out.ToolCalls = append(out.ToolCalls, c)
+	}
+	return out
+}
+
+// ollamaMessage translates m to an Ollama message.
+func (m openAIMessage) ollamaMessage() (api.Message, error) {
+	out := api.Message{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+	for i, call := range m.ToolCalls {
+		args := api.NewToolCallFunctionArguments()
+		if strings.TrimSpace(call.Function.Arguments) != "" {
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+				return api.Message{}, fmt.Errorf("invalid arguments for tool %s: %w", call.Function.Name, err)
+			}
+		}
+		out.ToolCalls = append(out.ToolCalls, api.ToolCall{
+			ID: call.ID,
+			Function: api.ToolCallFunction{
+				Index:     i,
+				Name:      call.Function.Name,
+				Arguments: args,
+			},
+		})
+	}
+	return out, nil
+}
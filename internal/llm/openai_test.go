@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeOpenAIServer is an OpenAI-compatible server that answers each chat
+// request with the next of its responses and records the requests.
+type fakeOpenAIServer struct {
+	mu        sync.Mutex
+	responses []string // Chat completion bodies, or SSE streams when streaming
+	requests  []openAIRequest
+	auth      []string // Authorization header of each chat request
+}
+
+func (s *fakeOpenAIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.URL.Path {
+	case "/v1/models":
+		fmt.Fprint(w, `{"object": "list", "data": [{"id": "small"}, {"id": "big"}]}`)
+	case "/v1/chat/completions":
+		var req openAIRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		s.requests = append(s.requests, req)
+		s.auth = append(s.auth, r.Header.Get("Authorization"))
+		fmt.Fprint(w, s.responses[min(len(s.requests), len(s.responses))-1])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newOpenAIClient(t *testing.T, srv *fakeOpenAIServer) *Client {
+	t.Helper()
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	c, err := NewClient(&Config{Backend: "openai", Host: ts.URL, APIKey: "secret", Model: "small"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return c
+}
+
+func TestOpenAIBackendRunsToolCalls(t *testing.T) {
+	srv := &fakeOpenAIServer{responses: []string{
+		`{"choices": [{"message": {"role": "assistant", "content": null, "tool_calls": [
+			{"id": "call_1", "type": "function", "function": {"name": "lookup", "arguments": "{\"q\": \"x\"}"}}
+		]}, "finish_reason": "tool_calls"}]}`,
+		`{"choices": [{"message": {"role": "assistant", "content": "Done."}, "finish_reason": "stop"}]}`,
+	}}
+	c := newOpenAIClient(t, srv)
+
+	got, err := c.Chat(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if got != "Done." {
+		t.Errorf("Chat = %q, want the final reply", got)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.requests) != 2 {
+		t.Fatalf("chat requests = %d, want 2", len(srv.requests))
+	}
+	first := srv.requests[0]
+	if first.Model != "small" || first.Stream || first.MaxTokens == nil || *first.MaxTokens != 150 || len(first.Tools) == 0 {
+		t.Errorf("first request = %+v, want model, max_tokens and tools translated", first)
+	}
+	if first.Messages[0].Role != "system" || first.Messages[1].Content != "hello" {
+		t.Errorf("first request messages = %+v, want the system prompt then the user message", first.Messages)
+	}
+	if srv.auth[0] != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the API key as bearer token", srv.auth[0])
+	}
+
+	msgs := srv.requests[1].Messages
+	call, result := msgs[len(msgs)-2], msgs[len(msgs)-1]
+	if len(call.ToolCalls) != 1 || call.ToolCalls[0].ID != "call_1" || call.ToolCalls[0].Function.Arguments != `{"q":"x"}` {
+		t.Errorf("tool call message = %+v, want the call sent back", call)
+	}
+	if result.Role != "tool" || result.ToolCallID != "call_1" {
+		t.Errorf("tool result message = %+v, want it tied to call_1", result)
+	}
+}
+
+func TestOpenAIBackendStreams(t *testing.T) {
+	srv := &fakeOpenAIServer{responses: []string{
+		"data: {\"choices\": [{\"delta\": {\"role\": \"assistant\", \"tool_calls\": [{\"index\": 0, \"id\": \"call_1\", \"function\": {\"name\": \"lookup\", \"arguments\": \"{\\\"q\\\"\"}}]}}]}\n\n" +
+			"data: {\"choices\": [{\"delta\": {\"tool_calls\": [{\"index\": 0, \"function\": {\"arguments\": \": \\\"x\\\"}\"}}]}, \"finish_reason\": \"tool_calls\"}]}\n\n" +
+			"data: [DONE]\n\n",
+		"data: {\"choices\": [{\"delta\": {\"role\": \"assistant\", \"content\": \"Hello \"}}]}\n\n" +
+			": keep-alive\n\n" +
+			"data: {\"choices\": [{\"delta\": {\"content\": \"there.\"}, \"finish_reason\": \"stop\"}]}\n\n" +
+			"data: [DONE]\n\n",
+	}}
+	c := newOpenAIClient(t, srv)
+
+	var tokens []string
+	err := c.ChatStream(context.Background(), "hi", func(tok string) error {
+		tokens = append(tokens, tok)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if strings.Join(tokens, "|") != "Hello |there." {
+		t.Errorf("tokens = %q, want the streamed pieces", tokens)
+	}
+	if last := c.history[len(c.history)-1]; last.Content != "Hello there." {
+		t.Errorf("last history message = %+v, want the assembled reply", last)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	msgs := srv.requests[1].Messages
+	call := msgs[len(msgs)-2]
+	if !srv.requests[0].Stream || len(call.ToolCalls) != 1 || call.ToolCalls[0].Function.Arguments != `{"q":"x"}` {
+		t.Errorf("tool call message = %+v, want the streamed pieces assembled", call)
+	}
+}
+
+func TestOpenAIBackendStreamCutShort(t *testing.T) {
+	srv := &fakeOpenAIServer{responses: []string{
+		"data: {\"choices\": [{\"delta\": {\"role\": \"assistant\", \"content\": \"Hello \"}}]}\n\n",
+	}}
+	c := newOpenAIClient(t, srv)
+
+	err := c.ChatStream(context.Background(), "hi", func(string) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "ended before") {
+		t.Errorf("ChatStream = %v, want an incomplete stream error", err)
+	}
+	if len(c.history) != 1 {
+		t.Errorf("history = %+v, want it untouched", c.history)
+	}
+}
+
+func TestOpenAIBackendChecksModelsAndHealth(t *testing.T) {
+	c := newOpenAIClient(t, &fakeOpenAIServer{})
+
+	if err := c.HealthCheck(context.Background()); err != nil {
+		t.Errorf("HealthCheck: %v", err)
+	}
+	if err := c.SetModel("big"); err != nil || c.Model() != "big" {
+		t.Errorf("SetModel(big) = %v, model %q", err, c.Model())
+	}
+	if err := c.SetModel("missing"); err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Errorf("SetModel(missing) = %v, want a not-available error", err)
+	}
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": "invalid api key"}`, http.StatusUnauthorized)
+	}))
+	defer down.Close()
+	c, err := NewClient(&Config{Backend: "openai", Host: down.URL + "/v1/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.HealthCheck(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("HealthCheck = %v, want the server's rejection", err)
+	}
+}
+
+func TestNewClientRejectsUnknownBackend(t *testing.T) {
+	if _, err := NewClient(&Config{Backend: "kobold", Host: "http://localhost"}); err == nil {
+		t.Error("NewClient accepted an unknown backend")
+	}
+}
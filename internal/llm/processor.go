@@ -3,41 +3,121 @@ package llm
 import (
 	"context"
 	"log"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/intent"
+	"github.com/agalue/sherpa-voice-assistant/internal/metrics"
+	"github.com/agalue/sherpa-voice-assistant/internal/session"
+	"github.com/agalue/sherpa-voice-assistant/internal/state"
 )
 
+// TurnLogger records conversation turns, e.g. a [session.Logger].
+type TurnLogger interface {
+	Log(t session.Turn) error
+}
+
+// Prompt is a user transcript for [Client.RunProcessor].
+type Prompt struct {
+	Text     string // The command, matched against intents
+	Verbatim string // The prompt as spoken, sent to the LLM and logged instead of Text when set
+	Language string // Language it was spoken in, recorded in the transcript ("" = unknown)
+	Injected bool   // Made up rather than heard (e.g. for a bare wake word), marked as such in the history and transcript
+}
+
 // RunProcessor reads user transcriptions from in, generates LLM responses via Chat,
-// and sends them to out. It is intended to be run as a goroutine and returns when
-// ctx is cancelled or in is closed.
-func (c *Client) RunProcessor(ctx context.Context, in <-chan string, out chan<- string) {
+// and sends them to out. Transcriptions matching one of intents (which may be nil)
+// are answered by the intent's handler instead, without calling the LLM or
+// touching its history. Empty responses are not sent (see [Config.States]).
+// When transcript is non-nil, each successful exchange is logged to it. When
+// spoken is non-nil, the next prompt is only read after a value arrives on it,
+// which the caller sends once the response just sent to out has been played
+// (or dropped); signals already pending when a response is sent are discarded
+// as stale. It is intended to be run as a goroutine and returns when ctx is
+// cancelled or in is closed.
+func (c *Client) RunProcessor(ctx context.Context, in <-chan Prompt, out chan<- string, intents *intent.Matcher, transcript TurnLogger, spoken <-chan struct{}) {
+	// reply sends response to out and, when pacing by spoken, waits for it to
+	// be played. It reports false when ctx ended first.
+	reply := func(response string) bool {
+		if spoken != nil {
+			drainSignals(spoken)
+		}
+		select {
+		case out <- response:
+		case <-ctx.Done():
+			return false
+		}
+		if spoken == nil {
+			return true
+		}
+		select {
+		case <-spoken:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case text, ok := <-in:
+		case prompt, ok := <-in:
 			if !ok {
 				return
 			}
+			text := prompt.Text
+			spoken := text
+			if prompt.Verbatim != "" {
+				spoken = prompt.Verbatim
+			}
 
-			log.Printf("🧠 Processing: %q", text)
-
-			response, err := c.Chat(ctx, text)
+			var (
+				response string
+				err      error
+			)
+			if reply, ok := intents.Match(text); ok {
+				log.Printf("🎯 Intent matched: %q", text)
+				response = reply
+			} else {
+				log.Printf("🧠 Processing: %q", spoken)
+				start := time.Now()
+				response, err = c.chat(ctx, spoken, prompt.Injected, nil)
+				c.metrics.Since(metrics.LLM, start)
+			}
 			if err != nil {
 				log.Printf("❌ LLM error: %v", err)
-				select {
-				case out <- "I'm sorry, I encountered an error.":
-				case <-ctx.Done():
+				if !reply("I'm sorry, I encountered an error.") {
 					return
 				}
 				continue
 			}
 
+			if response == "" {
+				c.states.Transition(state.Thinking, state.Idle)
+				continue // Nothing to say (e.g., an intent handled silently)
+			}
 			log.Printf("🤖 Assistant: %s", response)
 
-			select {
-			case out <- response:
-			case <-ctx.Done():
+			if transcript != nil {
+				if err := transcript.Log(session.Turn{Time: time.Now(), User: spoken, Assistant: response, Language: prompt.Language, Injected: prompt.Injected}); err != nil {
+					log.Printf("⚠️ Transcript log: %v", err)
+				}
+			}
+
+			if !reply(response) {
 				return
 			}
 		}
 	}
 }
+
+// drainSignals discards any values pending on ch.
+func drainSignals(ch <-chan struct{}) {
+	for {
+		select {
+		case <-ch:
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/intent"
+	"github.com/agalue/sherpa-voice-assistant/internal/state"
+)
+
+func TestRunProcessorWaitsForSpokenInSequentialMode(t *testing.T) {
+	c, calls := newTestClient(t, "", "Sure.")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := make(chan Prompt, 2)
+	out := make(chan string)
+	spoken := make(chan struct{}, 1)
+	spoken <- struct{}{} // Stale signal, e.g. from the greeting
+	go c.RunProcessor(ctx, in, out, nil, nil, spoken)
+
+	in <- Prompt{Text: "first"}
+	in <- Prompt{Text: "second"}
+	<-out
+	time.Sleep(50 * time.Millisecond)
+	if *calls != 1 || len(in) != 1 {
+		t.Fatalf("requests = %d, queued prompts = %d; want the second turn held until spoken", *calls, len(in))
+	}
+
+	spoken <- struct{}{}
+	select {
+	case <-out:
+	case <-time.After(time.Second):
+		t.Fatal("second turn not answered after the first reply was spoken")
+	}
+}
+
+func TestRunProcessorEndsThinkingWithoutReply(t *testing.T) {
+	c, _ := newTestClient(t, "", "Sure.")
+	c.states = state.NewManager(false)
+	c.states.Set(state.Thinking)
+	intents := intent.NewMatcher()
+	intents.RegisterIntent([]string{"lights off"}, func(intent.Args) string { return "" })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := make(chan Prompt)
+	go c.RunProcessor(ctx, in, make(chan string), intents, nil, nil)
+	in <- Prompt{Text: "lights off"}
+	in <- Prompt{Text: "lights off"} // Taken once the first has been handled
+
+	if got := c.states.Current(); got != state.Idle {
+		t.Errorf("state after a silent intent = %s, want idle", got)
+	}
+}
@@ -0,0 +1,56 @@
+// Package llm provides LLM integration via Ollama API.
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// PromptVars are the variables available to system prompt templates.
+//
+// A system prompt may reference them with Go template syntax, e.g.
+// "Today is {{.Date}} and the local time is {{.Time}}." The prompt is rendered
+// fresh for every turn, so the values are always current.
+type PromptVars struct {
+	Time     string // Local time, e.g. "3:04 PM"
+	Date     string // Local date, e.g. "Monday, January 2, 2006"
+	Weekday  string // Day of the week, e.g. "Monday"
+	Year     int    // Four-digit year
+	Timezone string // Local time zone abbreviation, e.g. "CET"
+}
+
+// newPromptVars returns the prompt variables for the instant now.
+func newPromptVars(now time.Time) PromptVars {
+	zone, _ := now.Zone()
+	return PromptVars{
+		Time:     now.Format("3:04 PM"),
+		Date:     now.Format("Monday, January 2, 2006"),
+		Weekday:  now.Weekday().String(),
+		Year:     now.Year(),
+		Timezone: zone,
+	}
+}
+
+// parseSystemPrompt compiles a system prompt template. Prompts without template
+// actions return a nil template, so rendering is skipped entirely for them.
+func parseSystemPrompt(prompt string) (*template.Template, error) {
+	if !strings.Contains(prompt, "{{") {
+		return nil, nil
+	}
+	tmpl, err := template.New("system").Option("missingkey=error").Parse(prompt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid system prompt template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderSystemPrompt expands tmpl with the variables for now.
+func renderSystemPrompt(tmpl *template.Template, now time.Time) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, newPromptVars(now)); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderSystemPromptExpandsBuiltinVariables(t *testing.T) {
+	tmpl, err := parseSystemPrompt("It is {{.Time}} on {{.Date}} ({{.Weekday}}, {{.Year}}, {{.Timezone}}).")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	now := time.Date(2026, time.March, 9, 14, 5, 0, 0, time.UTC)
+	got, err := renderSystemPrompt(tmpl, now)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	want := "It is 2:05 PM on Monday, March 9, 2026 (Monday, 2026, UTC)."
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseSystemPromptPlainTextNeedsNoTemplate(t *testing.T) {
+	tmpl, err := parseSystemPrompt("You are a helpful voice assistant.")
+	if err != nil || tmpl != nil {
+		t.Errorf("plain prompt: tmpl=%v err=%v, want nil/nil", tmpl, err)
+	}
+}
+
+func TestParseSystemPromptRejectsMalformedTemplate(t *testing.T) {
+	if _, err := parseSystemPrompt("It is {{.Time"); err == nil {
+		t.Error("expected error for unterminated action")
+	}
+}
+
+func TestRenderSystemPromptRejectsUnknownVariable(t *testing.T) {
+	tmpl, err := parseSystemPrompt("Hello from {{.City}}")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if _, err := renderSystemPrompt(tmpl, time.Now()); err == nil || !strings.Contains(err.Error(), "City") {
+		t.Errorf("expected error naming the unknown field, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// errIncompleteStream reports a streamed response that ended before the reply
+// was complete, typically because the connection dropped.
+var errIncompleteStream = errors.New("response stream ended before the reply was complete")
+
+// send sends req like [Client.sendOnce], retrying transient failures (see
+// retriable) up to the configured number of times, waiting the retry backoff
+// before the first retry and doubling it for each further one. A streamed
+// response is only retried while none of it has reached onToken, so no part of
+// a reply is spoken twice. Nothing is added to the history here, so a retry
+// sends exactly the same messages.
+func (c *Client) send(ctx context.Context, req *api.ChatRequest, onToken func(string) error) (api.Message, error) {
+	streamed := false
+	track := onToken
+	if onToken != nil {
+		track = func(text string) error {
+			streamed = true
+			return onToken(text)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		message, err := c.sendOnce(ctx, req, track)
+		if err == nil || attempt >= c.maxRetries || streamed || !retriable(ctx, err) {
+			c.noteReachable(ctx, err)
+			return message, err
+		}
+		delay := c.retryBackoff << attempt
+		log.Printf("⚠️ LLM request failed (%v), retrying in %s (%d/%d)", err, delay, attempt+1, c.maxRetries)
+		select {
+		case <-ctx.Done():
+			return message, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// noteReachable records whether a request made with ctx that ended with err
+// reached the server (see [Client.Reachable]). A cancelled request tells nothing.
+func (c *Client) noteReachable(ctx context.Context, err error) {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	c.unreachable.Store(err != nil && retriable(ctx, err))
+}
+
+// retriable reports whether err, returned by a request made with ctx, may go
+// away if the request is repeated: connection failures, timeouts, server
+// errors (5xx, e.g. while a model is loading) and responses cut short. Client
+// errors (4xx) and cancellation of ctx are final.
+func retriable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var status api.StatusError
+	if errors.As(err, &status) {
+		return status.StatusCode >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, errIncompleteStream)
+}
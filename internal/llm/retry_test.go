@@ -0,0 +1,163 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// newFlakyClient returns a Client whose server fails the first failures chat
+// requests with status and then replies "Hi.", and a counter of requests.
+func newFlakyClient(t *testing.T, status, failures int) (*Client, *int) {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= failures {
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "model is loading"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"model":   "test",
+			"message": map[string]string{"role": "assistant", "content": "Hi."},
+			"done":    true,
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient(&Config{Host: srv.URL, Model: "test", MaxRetries: 2, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return c, &calls
+}
+
+func TestChatRetriesServerErrors(t *testing.T) {
+	c, calls := newFlakyClient(t, http.StatusServiceUnavailable, 2)
+
+	got, err := c.Chat(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if got != "Hi." || *calls != 3 {
+		t.Errorf("Chat = %q after %d requests, want the reply after 3", got, *calls)
+	}
+	if len(c.history) != 3 || c.history[1].Content != "hello" {
+		t.Errorf("history = %+v, want the user message once", c.history)
+	}
+}
+
+func TestChatGivesUpAfterMaxRetries(t *testing.T) {
+	c, calls := newFlakyClient(t, http.StatusInternalServerError, 5)
+
+	if _, err := c.Chat(context.Background(), "hello"); err == nil {
+		t.Fatal("Chat succeeded, want the server error")
+	}
+	if *calls != 3 {
+		t.Errorf("requests = %d, want 1 + 2 retries", *calls)
+	}
+	if len(c.history) != 1 {
+		t.Errorf("history = %+v, want it untouched", c.history)
+	}
+}
+
+func TestReachableFollowsLastRequest(t *testing.T) {
+	c, _ := newFlakyClient(t, http.StatusServiceUnavailable, 5)
+	if !c.Reachable() {
+		t.Error("unreachable before any request")
+	}
+
+	if _, err := c.Chat(context.Background(), "hello"); err == nil {
+		t.Fatal("Chat succeeded, want the server error")
+	}
+	if c.Reachable() {
+		t.Error("reachable after every attempt failed with 503")
+	}
+
+	if _, err := c.Chat(context.Background(), "hello"); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if !c.Reachable() {
+		t.Error("still unreachable after the server replied")
+	}
+}
+
+func TestChatDoesNotRetryClientErrors(t *testing.T) {
+	c, calls := newFlakyClient(t, http.StatusNotFound, 1)
+
+	if _, err := c.Chat(context.Background(), "hello"); err == nil {
+		t.Fatal("Chat succeeded, want the client error")
+	}
+	if *calls != 1 {
+		t.Errorf("requests = %d, want no retry", *calls)
+	}
+}
+
+func TestChatStreamDoesNotRetryAfterSpeaking(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		// A piece of the reply, then the connection closes before done
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"model":   "test",
+			"message": map[string]string{"role": "assistant", "content": "Hello "},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	c, err := NewClient(&Config{Host: srv.URL, Model: "test", MaxRetries: 2, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.ChatStream(context.Background(), "hi", func(string) error { return nil })
+	if !errors.Is(err, errIncompleteStream) {
+		t.Errorf("ChatStream = %v, want an incomplete stream error", err)
+	}
+	if calls != 1 {
+		t.Errorf("requests = %d, want no retry once text was streamed", calls)
+	}
+}
+
+func TestRetriable(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"server error", context.Background(), api.StatusError{StatusCode: http.StatusBadGateway}, true},
+		{"client error", context.Background(), api.StatusError{StatusCode: http.StatusBadRequest}, false},
+		{"connection refused", context.Background(), fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true},
+		{"connection reset", context.Background(), fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"cut short", context.Background(), io.ErrUnexpectedEOF, true},
+		{"timeout", context.Background(), timeoutError{}, true},
+		{"cancelled", cancelled, fmt.Errorf("dial: %w", syscall.ECONNREFUSED), false},
+		{"cancel error", context.Background(), context.Canceled, false},
+		{"other", context.Background(), errors.New("invalid tool call"), false},
+	}
+	for _, tt := range tests {
+		if got := retriable(tt.ctx, tt.err); got != tt.want {
+			t.Errorf("retriable(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// timeoutError is a net.Error that timed out.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
@@ -0,0 +1,160 @@
+// Package metrics records how long each stage of a conversational turn takes,
+// for latency profiling (--metrics).
+//
+// A nil *Recorder is valid and records nothing, so callers can time their
+// stages unconditionally.
+package metrics
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stage is a step of a turn whose duration is recorded.
+type Stage int
+
+// Stages, in the order a turn goes through them.
+const (
+	VAD Stage = iota // From the end of speech until the segment reaches speech recognition
+	STT              // Transcribing the segment
+	LLM              // Generating the reply, tool calls included
+	TTS              // Synthesizing the reply, all sentences together
+	numStages
+)
+
+// String returns the stage's name as used in log lines ("stt").
+func (s Stage) String() string {
+	switch s {
+	case VAD:
+		return "vad"
+	case STT:
+		return "stt"
+	case LLM:
+		return "llm"
+	case TTS:
+		return "tts"
+	default:
+		return fmt.Sprintf("stage(%d)", int(s))
+	}
+}
+
+// maxTurns bounds the turns kept for [Recorder.Snapshot]; older turns are
+// dropped so a long session does not grow without limit.
+const maxTurns = 1000
+
+// Recorder accumulates the time spent in each stage of the current turn and
+// keeps the totals of past turns for aggregate statistics. It is safe for
+// concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	current [numStages]time.Duration // Time in each stage during the current turn
+	seen    [numStages]bool          // Stages observed during the current turn
+	turns   [numStages][]time.Duration
+}
+
+// New returns an empty Recorder.
+func New() *Recorder {
+	return &Recorder{}
+}
+
+// Observe adds d to the time spent in stage during the current turn. Stages
+// that run several times per turn (e.g. TTS, once per sentence) add up.
+func (r *Recorder) Observe(stage Stage, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current[stage] += d
+	r.seen[stage] = true
+}
+
+// Since is shorthand for Observe(stage, time.Since(start)).
+func (r *Recorder) Since(stage Stage, start time.Time) {
+	r.Observe(stage, time.Since(start))
+}
+
+// EndTurn logs the stage times of the current turn as one line, e.g.
+// "📊 Latency: stt=320ms llm=1.1s tts=450ms", keeps them for Snapshot and
+// starts a new turn. A turn with nothing observed is not logged.
+func (r *Recorder) EndTurn() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	var parts []string
+	for stage := range numStages {
+		if !r.seen[stage] {
+			continue
+		}
+		parts = append(parts, stage.String()+"="+formatDuration(r.current[stage]))
+		if len(r.turns[stage]) == maxTurns {
+			r.turns[stage] = r.turns[stage][1:]
+		}
+		r.turns[stage] = append(r.turns[stage], r.current[stage])
+	}
+	r.current, r.seen = [numStages]time.Duration{}, [numStages]bool{}
+	r.mu.Unlock()
+
+	if len(parts) > 0 {
+		log.Printf("📊 Latency: %s", strings.Join(parts, " "))
+	}
+}
+
+// Summary holds the aggregate time of one stage over the recorded turns.
+type Summary struct {
+	Stage    Stage
+	Turns    int // Turns the stage was observed in
+	P50, P95 time.Duration
+}
+
+// Snapshot returns the median and 95th percentile time of each stage over the
+// turns ended so far (at most the last 1000), in stage order. Stages never
+// observed are left out.
+func (r *Recorder) Snapshot() []Summary {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []Summary
+	for stage := range numStages {
+		if len(r.turns[stage]) == 0 {
+			continue
+		}
+		sorted := slices.Sorted(slices.Values(r.turns[stage]))
+		out = append(out, Summary{
+			Stage: stage,
+			Turns: len(sorted),
+			P50:   percentile(sorted, 0.50),
+			P95:   percentile(sorted, 0.95),
+		})
+	}
+	return out
+}
+
+// String formats s for the shutdown report, e.g. "llm p50=1.1s p95=2.4s (12 turns)".
+func (s Summary) String() string {
+	return fmt.Sprintf("%s p50=%s p95=%s (%d turns)", s.Stage, formatDuration(s.P50), formatDuration(s.P95), s.Turns)
+}
+
+// percentile returns the nearest-rank p-th percentile (0-1] of sorted, which
+// must not be empty.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[max(0, rank-1)]
+}
+
+// formatDuration rounds d for log lines: to the millisecond below a second
+// ("320ms") and to a tenth of a second above ("1.1s").
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
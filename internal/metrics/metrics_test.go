@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEndTurnLogsObservedStages(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	r := New()
+	r.Observe(STT, 320*time.Millisecond)
+	r.Observe(TTS, 200*time.Millisecond)
+	r.Observe(TTS, 250*time.Millisecond) // A second sentence adds up
+	r.Observe(LLM, 1140*time.Millisecond)
+	r.EndTurn()
+
+	if got := buf.String(); !strings.Contains(got, "Latency: stt=320ms llm=1.1s tts=450ms\n") {
+		t.Errorf("log = %q, want the stages of the turn in order", got)
+	}
+
+	buf.Reset()
+	r.EndTurn()
+	if buf.Len() != 0 {
+		t.Errorf("empty turn logged %q", buf.String())
+	}
+}
+
+func TestSnapshotPercentiles(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	r := New()
+	for i := 1; i <= 20; i++ {
+		r.Observe(LLM, time.Duration(i)*100*time.Millisecond)
+		if i%2 == 0 {
+			r.Observe(STT, 300*time.Millisecond)
+		}
+		r.EndTurn()
+	}
+
+	got := r.Snapshot()
+	if len(got) != 2 || got[0].Stage != STT || got[1].Stage != LLM {
+		t.Fatalf("Snapshot = %v, want stt then llm", got)
+	}
+	if got[0].Turns != 10 || got[0].P50 != 300*time.Millisecond {
+		t.Errorf("stt = %v, want 10 turns at 300ms", got[0])
+	}
+	if got[1].P50 != time.Second || got[1].P95 != 1900*time.Millisecond {
+		t.Errorf("llm = %v, want p50=1s p95=1.9s", got[1])
+	}
+	if s := got[1].String(); s != "llm p50=1s p95=1.9s (20 turns)" {
+		t.Errorf("String = %q", s)
+	}
+}
+
+func TestNilRecorderIsDisabled(t *testing.T) {
+	var r *Recorder
+	r.Observe(STT, time.Second)
+	r.Since(LLM, time.Now())
+	r.EndTurn()
+	if r.Snapshot() != nil {
+		t.Error("nil Recorder returned statistics")
+	}
+}
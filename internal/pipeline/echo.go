@@ -0,0 +1,54 @@
+package pipeline
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Self-echo detection thresholds.
+const (
+	// echoMinWords is the shortest transcript considered an echo. Shorter ones
+	// ("yes", "stop") are too likely to be genuine replies that happen to reuse
+	// a word of the response.
+	echoMinWords = 2
+
+	// echoSimilarity is the fraction of transcript words that must appear, in
+	// order, in the reply for the transcript to count as an echo of it.
+	echoSimilarity = 0.8
+)
+
+// echoesReply reports whether transcript closely matches (part of) reply,
+// ignoring case and punctuation. Whisper rarely reproduces an echo word for word,
+// so the words only need to appear in the same order, allowing for a few misses.
+func echoesReply(transcript, reply string) bool {
+	heard := echoWords(transcript)
+	if len(heard) < echoMinWords {
+		return false
+	}
+	common := commonSubsequence(heard, echoWords(reply))
+	return float64(common) >= echoSimilarity*float64(len(heard))
+}
+
+// echoWords splits text into lowercase words, dropping punctuation.
+func echoWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
+
+// commonSubsequence returns the length of the longest common subsequence of a and b.
+func commonSubsequence(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
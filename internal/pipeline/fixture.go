@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+	"github.com/agalue/sherpa-voice-assistant/internal/fixture"
+	"github.com/agalue/sherpa-voice-assistant/internal/stt"
+	"github.com/agalue/sherpa-voice-assistant/internal/tts"
+)
+
+// recordingTranscriber passes every transcribed segment to a fixture recorder.
+type recordingTranscriber struct {
+	stt.Transcriber
+	rec        *fixture.Recorder
+	sampleRate int
+}
+
+func (t recordingTranscriber) TranscribeSegment(samples []float32) string {
+	text := t.Transcriber.TranscribeSegment(samples)
+	if text != "" {
+		t.rec.Input(samples, t.sampleRate, text)
+	}
+	return text
+}
+
+// recordingSynthesizer wraps synth to pass the audio of every synthesis to rec.
+func recordingSynthesizer(synth tts.Synthesizer, rec *fixture.Recorder) tts.Synthesizer {
+	return observedSynthesizer{Synthesizer: synth, observe: func(text string, out *tts.AudioOutput, _ time.Time) {
+		rec.Output(text, audio.AudioBuffer{Samples: out.Samples, SampleRate: out.SampleRate})
+	}}
+}
+
+// ReplayFixtures feeds the input audio of each fixture bundle under path (see
+// [fixture.List]) through the transcriber and the LLM, in order and sharing
+// conversation history like the recorded session did, and logs where the new
+// transcript or reply differs from the recorded one. It returns the number of
+// bundles that differ. Replies are only requested for turns that had one.
+func ReplayFixtures(ctx context.Context, cfg *config.Config, path string) (mismatches int, err error) {
+	dirs, err := fixture.List(path)
+	if err != nil {
+		return 0, err
+	}
+
+	client, err := newLLMClient(cfg, nil, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	loadCtx := ctx
+	if cfg.ModelLoadTimeout > 0 {
+		var cancel context.CancelFunc
+		loadCtx, cancel = context.WithTimeout(ctx, cfg.ModelLoadTimeout)
+		defer cancel()
+	}
+	log.Println("🧠 Loading speech recognition models...")
+	transcriber, err := loadModel(loadCtx, "the speech recognition model", func() (stt.Transcriber, error) {
+		return stt.NewTranscriber(cfg)
+	}, stt.Transcriber.Close)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create STT transcriber: %w", err)
+	}
+	defer transcriber.Close()
+
+	for _, dir := range dirs {
+		recorded, err := fixture.Load(dir)
+		if err != nil {
+			return mismatches, err
+		}
+		input := recorded.Input.Samples
+		if recorded.Input.SampleRate != cfg.SampleRate {
+			input = audio.NewPolyphaseResampler(recorded.Input.SampleRate, cfg.SampleRate).Resample(input)
+		}
+
+		replayed := &fixture.Turn{Transcript: transcriber.TranscribeSegment(input)}
+		if recorded.Response != "" && replayed.Transcript != "" {
+			if replayed.Response, err = client.Chat(ctx, replayed.Transcript); err != nil {
+				return mismatches, fmt.Errorf("%s: %w", dir, err)
+			}
+		}
+
+		name := filepath.Base(dir)
+		diffs := fixture.Diff(recorded, replayed)
+		if len(diffs) == 0 {
+			log.Printf("✅ %s", name)
+			continue
+		}
+		mismatches++
+		for _, d := range diffs {
+			log.Printf("❌ %s: %s", name, d)
+		}
+	}
+	return mismatches, nil
+}
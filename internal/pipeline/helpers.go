@@ -0,0 +1,480 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+	"github.com/agalue/sherpa-voice-assistant/internal/llm"
+	"github.com/agalue/sherpa-voice-assistant/internal/metrics"
+	"github.com/agalue/sherpa-voice-assistant/internal/session"
+	"github.com/agalue/sherpa-voice-assistant/internal/state"
+	"github.com/agalue/sherpa-voice-assistant/internal/stt"
+	"github.com/agalue/sherpa-voice-assistant/internal/tts"
+)
+
+// runReengage speaks cfg.ReengagePrompt once when a conversation goes quiet.
+//
+// A conversation is active from the moment the user is heard until the prompt
+// fires. The silence timer only starts after the assistant has replied to the
+// latest transcript and playback has stopped, so it never fires while the LLM is
+// still thinking, while a response is playing, or when nobody has spoken yet.
+func runReengage(ctx context.Context, cfg *config.Config, lastHeard *atomic.Int64, player *audio.Player, detector stt.VoiceDetector, out chan<- string) {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	var prompted int64 // lastHeard value for which the prompt was already spoken
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		heard := lastHeard.Load()
+		if heard == 0 || heard == prompted {
+			continue // Idle: no conversation, or already prompted for this one
+		}
+
+		replied := player.LastPlayedAt()
+		if replied.UnixNano() <= heard || player.IsPlaying() || detector.IsSpeechDetected() {
+			continue
+		}
+		if time.Since(replied) < cfg.ReengageAfter {
+			continue
+		}
+
+		log.Printf("👋 No response for %s, re-engaging", cfg.ReengageAfter)
+		prompted = heard
+		select {
+		case out <- cfg.ReengagePrompt:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// selectDevice returns the device spec names (see [audio.SelectDevice]) among
+// those list returns; flag names the setting in errors.
+func selectDevice(list func() ([]audio.DeviceInfo, error), spec, flag string) (*audio.DeviceInfo, error) {
+	devices, err := list()
+	if err != nil {
+		return nil, err
+	}
+	device, err := audio.SelectDevice(devices, spec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", flag, err)
+	}
+	return &device, nil
+}
+
+// runDeviceMigration checks every interval whether an output device preferred
+// over the current one has appeared (e.g. a Bluetooth speaker connected after
+// startup) and, while nothing is playing, restarts playback on it.
+func runDeviceMigration(ctx context.Context, interval time.Duration, player *audio.Player) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if player.IsPlaying() || !player.PreferredDeviceAvailable() {
+			continue
+		}
+		restarted, err := player.RestartIfIdle()
+		if err != nil {
+			log.Printf("⚠️ Failed to switch output device: %v", err)
+		} else if restarted {
+			log.Println("🔊 Switched to the preferred output device")
+		}
+	}
+}
+
+// playbackSensitivityHold is the minimum time playback must have been idle
+// before the normal VAD threshold is restored, bridging the short gaps between
+// sentences so the detector is not rebuilt for each one.
+const playbackSensitivityHold = 300 * time.Millisecond
+
+// playbackSensitivity decides when to raise the VAD threshold for playback
+// and when to restore it.
+type playbackSensitivity struct {
+	raised float32       // Threshold while the assistant speaks
+	hold   time.Duration // Idle time after playback before restoring
+	normal float32       // Threshold to restore (valid while active)
+	active bool          // Whether the raised threshold is in effect
+}
+
+// step returns the threshold to switch to, if any, given whether playback is
+// running, whether speech is in progress, the current threshold and how long
+// playback has been idle. Changing the threshold rebuilds the detector, which
+// discards speech in progress, so neither raising nor restoring happens while
+// speech is detected. A threshold changed during playback (by a voice or
+// control command) is kept instead of being restored.
+func (s *playbackSensitivity) step(playing, speech bool, current float32, idle time.Duration) (threshold float32, change bool) {
+	switch {
+	case playing && !s.active && !speech:
+		s.active, s.normal = true, current
+		return s.raised, current != s.raised
+	case !playing && s.active && !speech && idle >= s.hold:
+		s.active = false
+		return s.normal, current == s.raised && current != s.normal
+	}
+	return 0, false
+}
+
+// runPlaybackSensitivity raises the VAD threshold to
+// cfg.VADThresholdDuringPlayback while the assistant speaks, so in 'wait' mode
+// with listening during playback only loud, close speech barges in, and
+// restores the previous threshold once playback has ended.
+func runPlaybackSensitivity(ctx context.Context, cfg *config.Config, player *audio.Player, vad *stt.SileroVAD) {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	s := &playbackSensitivity{
+		raised: cfg.VADThresholdDuringPlayback,
+		hold:   max(time.Duration(cfg.PostPlaybackDelayMs)*time.Millisecond, playbackSensitivityHold),
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		idle := time.Since(player.LastPlayedAt())
+		threshold, change := s.step(player.IsPlaying(), vad.IsSpeechDetected(), vad.Threshold(), idle)
+		if !change {
+			continue
+		}
+		if err := vad.SetThreshold(threshold); err != nil {
+			log.Printf("⚠️ Failed to change VAD threshold for playback: %v", err)
+		}
+	}
+}
+
+// listeningPollInterval is how often runListeningState checks the VAD.
+const listeningPollInterval = 50 * time.Millisecond
+
+// listeningIdleGrace is how long speech must have ended before
+// runListeningState gives up on a segment and reports [state.Idle]; STT takes
+// a segment well within it.
+const listeningIdleGrace = 500 * time.Millisecond
+
+// runListeningState reports [state.Listening] while the detector hears speech
+// and the assistant is otherwise idle. Leaving Listening is up to STT, which
+// moves to [state.Transcribing] once it takes the segment; only when speech
+// ended listeningIdleGrace ago without one does this report [state.Idle]. The
+// VAD runs on the audio callback, so its state is polled rather than reported
+// from there.
+func runListeningState(ctx context.Context, detector stt.VoiceDetector, states *state.Manager) {
+	ticker := time.NewTicker(listeningPollInterval)
+	defer ticker.Stop()
+	var quietSince time.Time // When speech was last seen to have ended (zero = speaking)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if detector.IsSpeechDetected() {
+			quietSince = time.Time{}
+			states.Transition(state.Idle, state.Listening)
+			continue
+		}
+		if quietSince.IsZero() {
+			quietSince = time.Now()
+		}
+		if time.Since(quietSince) >= listeningIdleGrace {
+			states.Transition(state.Listening, state.Idle)
+		}
+	}
+}
+
+// Runtime VAD sensitivity adjustment: each request moves the threshold one step,
+// staying within bounds where the VAD still separates speech from noise.
+const (
+	vadSensitivityStep = 0.1
+	minVADThreshold    = 0.1
+	maxVADThreshold    = 0.9
+)
+
+// adjustSensitivity lowers the VAD threshold by one step when more is true
+// (detect quieter speech) and raises it otherwise, returning the new threshold.
+func adjustSensitivity(vad *stt.SileroVAD, more bool) (float32, error) {
+	current := vad.Threshold()
+	step := vadSensitivityStep
+	if more {
+		step = -step
+	}
+	next := math.Round((float64(current)+step)*100) / 100
+	next = min(max(next, minVADThreshold), maxVADThreshold)
+	// A threshold configured outside the bounds must not jump the wrong way.
+	if (more && float32(next) >= current) || (!more && float32(next) <= current) {
+		return current, fmt.Errorf("VAD threshold already at its limit (%.2f)", current)
+	}
+	if err := vad.SetThreshold(float32(next)); err != nil {
+		return current, err
+	}
+	return float32(next), nil
+}
+
+// speechSpeedStep is how much each faster/slower request changes the speech
+// speed multiplier, within [tts.MinSpeed] and [tts.MaxSpeed].
+const speechSpeedStep = 0.1
+
+// adjustSpeed raises the speech speed by one step when faster is true and
+// lowers it otherwise, returning the new speed.
+func adjustSpeed(synth tts.SpeedController, faster bool) (float32, error) {
+	current := synth.Speed()
+	step := speechSpeedStep
+	if !faster {
+		step = -step
+	}
+	next := math.Round((float64(current)+step)*100) / 100
+	next = min(max(next, tts.MinSpeed), tts.MaxSpeed)
+	// A speed configured outside the bounds must not jump the wrong way.
+	if (faster && float32(next) <= current) || (!faster && float32(next) >= current) {
+		return current, fmt.Errorf("speech speed already at its limit (%.2f)", current)
+	}
+	if err := synth.SetSpeed(float32(next)); err != nil {
+		return current, err
+	}
+	log.Printf("🗣️ Speech speed: %.2f", next)
+	return float32(next), nil
+}
+
+// highRejectionRate is the share of rejected VAD segments above which the
+// shutdown summary suggests raising --vad-threshold.
+const highRejectionRate = 0.3
+
+// logVADStats prints how many VAD segments were transcribed versus rejected,
+// to guide --vad-threshold tuning.
+func logVADStats(s stt.VADStats) {
+	if s.Produced == 0 {
+		return
+	}
+	log.Printf("📊 VAD segments: %d produced, %d transcribed, %d rejected (%.0f%% rejected)",
+		s.Produced, s.Transcribed, s.Rejected, s.RejectionRate()*100)
+	if s.RejectionRate() > highRejectionRate {
+		log.Println("💡 Many segments had no speech; consider raising --vad-threshold")
+	}
+}
+
+// logMemoryStats reports peak audio buffer memory at shutdown when a budget is
+// set, and whether buffers had to be freed to stay within it.
+func logMemoryStats(s audio.MemoryStats) {
+	if s.Limit == 0 {
+		return
+	}
+	log.Printf("📊 Audio memory: peak %.1f MB of %.1f MB budget, %d reclaim(s)",
+		float64(s.Peak)/(1<<20), float64(s.Limit)/(1<<20), s.Reclaims)
+}
+
+// logDropStats reports at shutdown how much audio was lost because the capture
+// or playback ring buffer was full, which suggests the buffers are too small
+// for slow hardware.
+func logDropStats(captureDrops, playbackOverflows uint64) {
+	log.Printf("📊 Audio drops: %d capture chunk(s) dropped, %d playback buffer overflow(s)", captureDrops, playbackOverflows)
+}
+
+// turnLog holds each turn the LLM answered until the TTS processor is done
+// with its reply, so the transcript records the voice it was actually spoken
+// in. Turns are written in the order their replies finish, which is the order
+// they were answered in.
+type turnLog struct {
+	log          *session.Logger
+	defaultVoice string // Recorded when the synthesizer has a fixed voice ("" = none)
+
+	mu      sync.Mutex
+	pending map[string][]session.Turn // By reply text, oldest first
+}
+
+// newTurnLog returns a turnLog writing to l. defaultVoice is recorded for
+// every turn unless synth can switch voices and so reports its own.
+func newTurnLog(l *session.Logger, synth tts.Synthesizer, defaultVoice string) *turnLog {
+	t := &turnLog{log: l, pending: map[string][]session.Turn{}}
+	if _, ok := tts.As[tts.VoiceSwitcher](synth); !ok {
+		t.defaultVoice = defaultVoice
+	}
+	return t
+}
+
+// Log holds turn until its reply has been spoken — satisfies [llm.TurnLogger].
+func (t *turnLog) Log(turn session.Turn) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[turn.Assistant] = append(t.pending[turn.Assistant], turn)
+	return nil
+}
+
+// spoken writes the oldest held turn whose reply is text, recording the voice
+// it was spoken in. Replies of no held turn (e.g. error messages) are ignored,
+// as are calls on a nil turnLog.
+func (t *turnLog) spoken(text, voice string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	turns := t.pending[text]
+	if len(turns) == 0 {
+		return
+	}
+	turn := turns[0]
+	if len(turns) == 1 {
+		delete(t.pending, text)
+	} else {
+		t.pending[text] = turns[1:]
+	}
+
+	turn.Voice = voice
+	if voice == "" {
+		turn.Voice = t.defaultVoice
+	}
+	if err := t.log.Log(turn); err != nil {
+		log.Printf("⚠️ Transcript log: %v", err)
+	}
+}
+
+// newLLMClient creates the LLM client for cfg, selects cfg.Persona and checks
+// that the LLM server is reachable. progress receives tool progress phrases (nil = none),
+// rec the reply times (nil = not recorded) and states the end of prompts that
+// get no reply (nil = not tracked).
+func newLLMClient(cfg *config.Config, progress func(phrase string), rec *metrics.Recorder, states *state.Manager) (*llm.Client, error) {
+	client, err := llm.NewClient(&llm.Config{
+		Backend:      cfg.LLMBackend,
+		Host:         cfg.OllamaURL,
+		APIKey:       cfg.LLMAPIKey,
+		Model:        cfg.OllamaModel,
+		SystemPrompt: cfg.SystemPrompt,
+		Verbose:      cfg.Verbose,
+		LogRequests:  cfg.LogRequests,
+		MaxHistory:   cfg.MaxHistory,
+		Temperature:  cfg.Temperature,
+		SearxngURL:   cfg.SearxngURL,
+		KeepAlive:    cfg.KeepAlive,
+		MaxRetries:   cfg.LLMMaxRetries,
+		RetryBackoff: time.Duration(cfg.LLMRetryBackoffMs) * time.Millisecond,
+		NumPredict:   cfg.LLMNumPredict,
+		NumCtx:       cfg.LLMNumCtx,
+
+		EmptyResponseFallback: cfg.EmptyResponseFallback,
+		Personas:              llmPersonas(cfg.Personas),
+		ToolProgress:          progress,
+		ToolProgressDelay:     cfg.ToolProgressDelay,
+		ToolProgressPhrases:   cfg.ToolProgressPhrases,
+		Metrics:               rec,
+		States:                states,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create LLM client: %w", err)
+	}
+	if cfg.Persona != "" {
+		if err := client.SetPersona(cfg.Persona); err != nil {
+			return nil, err
+		}
+		log.Printf("🎭 Persona: %s", cfg.Persona)
+	}
+
+	server := "Ollama"
+	if strings.EqualFold(cfg.LLMBackend, "openai") {
+		server = "OpenAI-compatible server"
+	}
+	log.Printf("🔗 Checking %s connection at %s...", server, cfg.OllamaURL)
+	if err := client.HealthCheck(context.Background()); err != nil {
+		return nil, fmt.Errorf("LLM connection failed: %w", err)
+	}
+	log.Printf("✅ %s connected (model: %s)", server, cfg.OllamaModel)
+	return client, nil
+}
+
+// llmPersonas converts configured personas for the LLM client.
+func llmPersonas(personas map[string]config.Persona) map[string]llm.Persona {
+	if len(personas) == 0 {
+		return nil
+	}
+	out := make(map[string]llm.Persona, len(personas))
+	for name, p := range personas {
+		out[name] = llm.Persona{Prompt: p.Prompt, Temperature: p.Temperature}
+	}
+	return out
+}
+
+// matchPersona returns the persona named by a switch command: one of phrases
+// followed by the persona's name, e.g. "be my tutor" or "switch to the comedian".
+func matchPersona(text string, phrases, personas []string) (string, bool) {
+	for _, name := range personas {
+		for _, phrase := range phrases {
+			if tts.MatchPhrase(text, []string{phrase + " " + name, phrase + " the " + name}) {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// loadModel runs load (a model constructor, which cannot be interrupted) and
+// waits for it, logging every modelLoadProgressInterval that it is still busy so
+// a slow load does not look like a hang. If ctx ends first, an error naming what
+// was being loaded is returned; the load is left to finish in the background and
+// its result passed to release.
+func loadModel[T any](ctx context.Context, what string, load func() (T, error), release func(T)) (T, error) {
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := load()
+		done <- result{v, err}
+	}()
+
+	start := time.Now()
+	ticker := time.NewTicker(modelLoadProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case r := <-done:
+			return r.v, r.err
+		case <-ticker.C:
+			log.Printf("⏳ Still loading %s (%s so far)...", what, time.Since(start).Round(time.Second))
+		case <-ctx.Done():
+			go func() {
+				if r := <-done; r.err == nil {
+					release(r.v)
+				}
+			}()
+			var zero T
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return zero, fmt.Errorf("timed out loading %s after %s (slow storage? raise --model-load-timeout)", what, time.Since(start).Round(time.Second))
+			}
+			return zero, fmt.Errorf("loading %s: %w", what, ctx.Err())
+		}
+	}
+}
+
+// matchModelAlias returns the model alias named by a switch command: one of
+// phrases followed by the alias and "model", e.g. "switch to the smart model".
+func matchModelAlias(text string, phrases []string, aliases map[string]string) (string, bool) {
+	for alias := range aliases {
+		for _, phrase := range phrases {
+			candidates := []string{phrase + " " + alias + " model", phrase + " the " + alias + " model"}
+			if tts.MatchPhrase(text, candidates) {
+				return alias, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,129 @@
+package pipeline
+
+import (
+	"log"
+	"sync/atomic"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/llm"
+	"github.com/agalue/sherpa-voice-assistant/internal/stt"
+	"github.com/agalue/sherpa-voice-assistant/internal/tts"
+)
+
+// languageMatcher aligns the reply language and the TTS voice with the language
+// detected in each transcript (cfg.MatchResponseLanguage). The reply language
+// is set right away; the voice is picked up by the TTS processor when the next
+// reply starts (see [languageMatcher.Voice]), so a reply never changes voice
+// halfway.
+type languageMatcher struct {
+	detector     stt.LanguageReporter
+	llm          *llm.Client
+	defaultVoice string
+	current      string                 // Language of the last transcript (only used by the STT goroutine)
+	voice        atomic.Pointer[string] // Voice for the language of the last transcript (nil = default)
+}
+
+// newLanguageMatcher returns a languageMatcher, or nil with a warning when the
+// transcriber does not report the language it detects.
+func newLanguageMatcher(transcriber stt.Transcriber, synth tts.Synthesizer, client *llm.Client, defaultVoice string) *languageMatcher {
+	detector, ok := transcriber.(stt.LanguageReporter)
+	if !ok {
+		log.Println("⚠️ The speech recognizer does not report the language it detects; --match-response-language has no effect")
+		return nil
+	}
+	if _, ok := tts.As[tts.VoiceSwitcher](synth); !ok {
+		log.Println("⚠️ The TTS backend cannot switch voices; --match-response-language only sets the reply language")
+	}
+	return &languageMatcher{detector: detector, llm: client, defaultVoice: defaultVoice}
+}
+
+// update switches the reply language and voice when the last transcript was in
+// a different language than the one before. Languages without a voice get the
+// default voice and language.
+func (m *languageMatcher) update() {
+	language := m.detector.LastLanguage()
+	if language == "" || language == m.current {
+		return
+	}
+	m.current = language
+
+	voice, ok := tts.VoiceForLanguage(m.defaultVoice, language)
+	if !ok {
+		log.Printf("🌐 No voice speaks the detected language %q, replying in the default language", language)
+	}
+	replyLanguage := ""
+	if voice != m.defaultVoice {
+		replyLanguage = tts.VoiceLanguage(voice)
+	}
+	m.llm.SetReplyLanguage(replyLanguage)
+	m.voice.Store(&voice)
+
+	if replyLanguage == "" {
+		log.Printf("🌐 Replying in the default language (voice %s)", voice)
+	} else {
+		log.Printf("🌐 Replying in %s (voice %s)", replyLanguage, voice)
+	}
+}
+
+// Voice returns the voice replies should start in: that of the language last
+// detected, or the default voice. It is safe to call from any goroutine and
+// on a nil languageMatcher, which returns "" (no preference).
+func (m *languageMatcher) Voice() string {
+	if m == nil {
+		return ""
+	}
+	if voice := m.voice.Load(); voice != nil {
+		return *voice
+	}
+	return m.defaultVoice
+}
+
+// languageTranscriber updates a languageMatcher after every segment that
+// produced text, before the text moves on to the LLM.
+type languageTranscriber struct {
+	stt.Transcriber
+	match *languageMatcher
+}
+
+func (t languageTranscriber) TranscribeSegment(samples []float32) string {
+	text := t.Transcriber.TranscribeSegment(samples)
+	if text != "" {
+		t.match.update()
+	}
+	return text
+}
+
+// detectingTranscriber gives back what wrappers such as languageTranscriber
+// hide of source, the transcriber they wrap: the language, verbatim text and
+// injection flag of each segment, so [stt.RunProcessor] can attach them to each
+// transcript.
+type detectingTranscriber struct {
+	stt.Transcriber
+	source stt.Transcriber
+}
+
+// LastLanguage satisfies [stt.LanguageReporter]; "" when source does not
+// detect the language.
+func (t detectingTranscriber) LastLanguage() string {
+	if detector, ok := t.source.(stt.LanguageReporter); ok {
+		return detector.LastLanguage()
+	}
+	return ""
+}
+
+// LastVerbatim satisfies [stt.VerbatimReporter]; "" when source does not
+// keep the verbatim text.
+func (t detectingTranscriber) LastVerbatim() string {
+	if reporter, ok := t.source.(stt.VerbatimReporter); ok {
+		return reporter.LastVerbatim()
+	}
+	return ""
+}
+
+// LastInjected satisfies [stt.InjectionReporter]; false when source never
+// makes up a transcript.
+func (t detectingTranscriber) LastInjected() bool {
+	if reporter, ok := t.source.(stt.InjectionReporter); ok {
+		return reporter.LastInjected()
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package pipeline
+
+import (
+	"log"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/metrics"
+	"github.com/agalue/sherpa-voice-assistant/internal/stt"
+	"github.com/agalue/sherpa-voice-assistant/internal/tts"
+)
+
+// timedTranscriber records, for every segment that produced text, how long it
+// took to arrive after the speech ended and how long transcribing it took.
+type timedTranscriber struct {
+	stt.Transcriber
+	vad     *stt.SileroVAD
+	metrics *metrics.Recorder
+}
+
+func (t timedTranscriber) TranscribeSegment(samples []float32) string {
+	start := time.Now()
+	// A segment cut at the maximum speech duration arrives while the speech
+	// goes on, so there is no end of speech to measure from.
+	var wait time.Duration
+	if end := t.vad.SpeechEndedAt(); !end.IsZero() && !t.vad.IsSpeechDetected() {
+		wait = start.Sub(end)
+	}
+
+	text := t.Transcriber.TranscribeSegment(samples)
+	if text != "" {
+		if wait > 0 {
+			t.metrics.Observe(metrics.VAD, wait)
+		}
+		t.metrics.Since(metrics.STT, start)
+	}
+	return text
+}
+
+// timedSynthesizer wraps synth to record how long each synthesis takes. Like
+// any [observedSynthesizer], it keeps every optional interface of synth.
+func timedSynthesizer(synth tts.Synthesizer, rec *metrics.Recorder) tts.Synthesizer {
+	return observedSynthesizer{Synthesizer: synth, observe: func(_ string, _ *tts.AudioOutput, start time.Time) {
+		rec.Since(metrics.TTS, start)
+	}}
+}
+
+// logLatencyStats logs the median and 95th percentile time of each turn stage
+// (--metrics).
+func logLatencyStats(summaries []metrics.Summary) {
+	for _, s := range summaries {
+		log.Printf("📊 Latency %s", s)
+	}
+}
@@ -0,0 +1,1035 @@
+// Package pipeline assembles the voice assistant: microphone capture, voice
+// activity detection, transcription, the LLM, speech synthesis and playback.
+//
+// [New] builds every component from a [config.Config] and [Pipeline.Run] wires
+// them together with the processing goroutines, so the assistant can be embedded
+// in other Go programs as well as run from cmd/assistant:
+//
+//	p, err := pipeline.New(cfg)
+//	if err != nil { ... }
+//	defer p.Close()
+//	err = p.Run(ctx) // Blocks until ctx is cancelled or Stop is called
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+	"github.com/agalue/sherpa-voice-assistant/internal/control"
+	"github.com/agalue/sherpa-voice-assistant/internal/events"
+	"github.com/agalue/sherpa-voice-assistant/internal/fixture"
+	"github.com/agalue/sherpa-voice-assistant/internal/intent"
+	"github.com/agalue/sherpa-voice-assistant/internal/llm"
+	"github.com/agalue/sherpa-voice-assistant/internal/metrics"
+	"github.com/agalue/sherpa-voice-assistant/internal/server"
+	"github.com/agalue/sherpa-voice-assistant/internal/session"
+	"github.com/agalue/sherpa-voice-assistant/internal/state"
+	"github.com/agalue/sherpa-voice-assistant/internal/stt"
+	"github.com/agalue/sherpa-voice-assistant/internal/tts"
+)
+
+// errPipelineStopped is returned by calls that need a running pipeline after
+// [Pipeline.Stop].
+var errPipelineStopped = errors.New("pipeline stopped")
+
+// Pipeline timing constants.
+const (
+	// shutdownTimeout bounds how long Run waits for processing goroutines to exit.
+	shutdownTimeout = 5 * time.Second
+
+	// serverShutdownTimeout bounds the HTTP server's graceful shutdown.
+	serverShutdownTimeout = 2 * time.Second
+
+	// modelLoadProgressInterval is how often a slow model load logs that it is
+	// still running.
+	modelLoadProgressInterval = 5 * time.Second
+
+	// tapBuffer is the capacity of the Transcripts and Responses channels.
+	tapBuffer = 16
+)
+
+// Pipeline owns every component of a running voice assistant.
+type Pipeline struct {
+	cfg         *config.Config
+	llmClient   *llm.Client
+	vad         *stt.SileroVAD
+	transcriber stt.Transcriber
+	synthesizer tts.Synthesizer
+	player      *audio.Player
+	capturer    *audio.Capturer
+	intents     *intent.Matcher
+	memory      *audio.MemoryBudget  // Audio buffer accounting (see cfg.AudioMemoryBudgetMB)
+	ttsCache    *audio.MemoryAccount // Audio the TTS processor caches for replay
+
+	// Optional components (nil when disabled in cfg)
+	statusServer *server.Server
+	ctrl         *control.Server
+	transcript   *session.Logger
+	turns        *turnLog // Turns waiting for their reply to be spoken before they are logged to transcript
+	dumper       *stt.ContextDumper
+	recorder     *fixture.Recorder
+	gate         *audio.DirectionGate
+	language     *languageMatcher
+	terminal     *terminal         // Raw-mode stdin for push-to-talk
+	metrics      *metrics.Recorder // Turn stage latencies (--metrics)
+	states       *state.Manager    // What the assistant is doing, for the status server and embedders
+
+	// Pipeline communication
+	transcriptions chan stt.Transcript    // STT output
+	prompts        chan llm.Prompt        // User text for the LLM
+	replies        chan string            // LLM output, copied to the Responses tap before responses
+	responses      chan string            // LLM replies to speak
+	notices        chan string            // Text to speak that the LLM did not write, kept out of the replay cache
+	commands       chan tts.Command       // Replay/resume requests for the TTS processor
+	announcements  chan tts.Announcement  // Text from Speak, played ahead of responses
+	spoken         chan struct{}          // TTS finished a response (sequential mode only, else nil)
+	transcriptTap  chan string            // Copies of transcriptions for embedders
+	responseTap    chan string            // Copies of spoken text for embedders
+	interrupt      atomic.Bool            // Set by STT when the user speaks over playback
+	lastHeard      atomic.Int64           // Unix nanoseconds of the last user transcript
+	lastTranscript atomic.Pointer[string] // Most recent user transcript, for the status server
+	lastReply      atomic.Pointer[string] // Most recent LLM reply, for self-echo suppression
+	listening      atomic.Bool            // Run has started capture and is not shutting down
+	stop           chan struct{}          // Closed by Stop
+	stopOnce       sync.Once
+	closers        []func() // Resource cleanups, run in reverse by Close
+}
+
+// New creates all pipeline components from cfg: the LLM client (verifying that
+// Ollama is reachable), VAD, transcriber, synthesizer, audio player and capturer,
+// plus the optional status server, control socket and transcript log. Nothing
+// listens or speaks until [Pipeline.Run]. On error, anything already created is
+// released.
+func New(cfg *config.Config) (_ *Pipeline, err error) {
+	p := &Pipeline{
+		cfg:            cfg,
+		transcriptions: make(chan stt.Transcript, 5),
+		prompts:        make(chan llm.Prompt, 5),
+		responses:      make(chan string, 5),
+		notices:        make(chan string, 5),
+		replies:        make(chan string),
+		commands:       make(chan tts.Command, 1),
+		announcements:  make(chan tts.Announcement),
+		transcriptTap:  make(chan string, tapBuffer),
+		responseTap:    make(chan string, tapBuffer),
+		stop:           make(chan struct{}),
+		intents:        intent.NewMatcher(),
+		memory:         audio.NewMemoryBudget(int64(cfg.AudioMemoryBudgetMB) << 20),
+	}
+	// Reclaimable accounts give memory back in registration order: the replay
+	// cache goes before fixture recordings.
+	p.ttsCache = p.memory.Register("tts cache", true)
+	if cfg.PipelineMode == config.PipelineSequential {
+		p.spoken = make(chan struct{}, 1)
+	}
+	defer func() {
+		if err != nil {
+			p.Close()
+		}
+	}()
+
+	// Check the output sink before loading anything slow
+	var sink audio.Sink
+	if cfg.Output != "" {
+		if sink, err = audio.ParseSink(cfg.Output, cfg.OutputFormat); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Metrics {
+		p.metrics = metrics.New()
+	}
+	p.states = state.NewManager(cfg.Verbose)
+	p.closers = append(p.closers, p.states.Close)
+
+	// Create LLM client and verify connection
+	if p.llmClient, err = newLLMClient(cfg, p.announceProgress, p.metrics, p.states); err != nil {
+		return nil, err
+	}
+	if cfg.HistoryFile != "" {
+		switch err := p.llmClient.LoadHistory(cfg.HistoryFile); {
+		case errors.Is(err, os.ErrNotExist):
+			log.Printf("💾 No conversation history at %s yet, starting fresh", cfg.HistoryFile)
+		case err != nil:
+			return nil, err
+		default:
+			log.Printf("💾 Restored conversation history from %s", cfg.HistoryFile)
+		}
+	}
+
+	// Model construction can take a long time on slow storage (SD cards,
+	// network mounts), so it reports progress and is bounded by ModelLoadTimeout.
+	loadCtx := context.Background()
+	if cfg.ModelLoadTimeout > 0 {
+		var cancel context.CancelFunc
+		loadCtx, cancel = context.WithTimeout(loadCtx, cfg.ModelLoadTimeout)
+		defer cancel()
+	}
+
+	// Create Silero VAD (voice activity detection)
+	log.Println("🧠 Loading speech recognition models...")
+	p.vad, err = loadModel(loadCtx, "the VAD model", func() (*stt.SileroVAD, error) {
+		return stt.NewSileroVAD(&stt.SileroConfig{
+			ModelDir:          cfg.ModelDir,
+			Threshold:         cfg.VadThreshold,
+			SilenceDuration:   cfg.VADSilenceDuration,
+			MinSpeechDuration: cfg.VADMinSpeechDuration,
+			MaxSpeechDuration: cfg.VADMaxSpeechDuration,
+			BufferSeconds:     cfg.VADBufferSeconds,
+			PreSpeechPadMs:    cfg.VADPreSpeechPadMs,
+			SampleRate:        cfg.SampleRate,
+			NumThreads:        cfg.VADThreads,
+			Verbose:           cfg.Verbose,
+		})
+	}, (*stt.SileroVAD).Close)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create VAD: %w", err)
+	}
+	p.closers = append(p.closers, p.vad.Close)
+	p.memory.Register("vad buffer", false).Set(audio.SampleBytes(int(cfg.VADBufferSeconds * float32(cfg.SampleRate))))
+
+	// Only listen while toggled on from the keyboard (opt-in)
+	if cfg.PushToTalk {
+		if p.terminal, err = openTerminal(); err != nil {
+			return nil, err
+		}
+		p.closers = append(p.closers, p.terminal.Close)
+		p.vad.SetListening(false)
+	}
+
+	// Create the transcriber (speech-to-text)
+	p.transcriber, err = loadModel(loadCtx, "the speech recognition model", func() (stt.Transcriber, error) {
+		return stt.NewTranscriber(cfg)
+	}, stt.Transcriber.Close)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create STT transcriber: %w", err)
+	}
+	p.closers = append(p.closers, p.transcriber.Close)
+	log.Println("✅ Speech recognition ready")
+
+	// Create the synthesizer (text-to-speech)
+	log.Println("🔊 Loading text-to-speech models...")
+	p.synthesizer, err = loadModel(loadCtx, "the text-to-speech model", func() (tts.Synthesizer, error) {
+		return tts.NewSynthesizer(cfg)
+	}, tts.Synthesizer.Close)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TTS synthesizer: %w", err)
+	}
+	p.closers = append(p.closers, p.synthesizer.Close)
+	if _, ok := tts.As[tts.PhonemeSynthesizer](p.synthesizer); cfg.PhonemeMarkup && !ok {
+		log.Printf("⚠️ The %s TTS backend can't take phoneme input; [phon:...] markup will be spoken as its fallback text", cfg.TTSBackend)
+	}
+	if _, ok := tts.As[tts.VoiceSwitcher](p.synthesizer); cfg.VoiceDirectives && !ok {
+		log.Printf("⚠️ The %s TTS backend can't switch voices; [voice:...] directives will be ignored", cfg.TTSBackend)
+	}
+	if _, ok := tts.As[tts.SpeedSynthesizer](p.synthesizer); cfg.SSMLMarkup && !ok {
+		log.Printf("⚠️ The %s TTS backend can't change speed; <emphasis> tags will be spoken normally", cfg.TTSBackend)
+	}
+	if _, ok := tts.As[tts.StreamingSynthesizer](p.synthesizer); cfg.StreamSynthesis && !ok {
+		log.Printf("⚠️ The %s TTS backend can't stream audio; each sentence will play once it is complete", cfg.TTSBackend)
+	}
+	log.Println("✅ Text-to-speech ready")
+
+	// Reply in the language of each transcript (opt-in)
+	if cfg.MatchResponseLanguage {
+		p.language = newLanguageMatcher(p.transcriber, p.synthesizer, p.llmClient, cfg.TTSVoice)
+	}
+
+	// Create audio player. In 'sentence' mode speech must not cut a sentence
+	// short, so the player ignores it and the TTS processor stops between sentences.
+	playerInterrupt := &p.interrupt
+	if cfg.InterruptMode == config.InterruptSentence {
+		playerInterrupt = nil
+	}
+	// With an output sink and no tee, no output device is opened at all.
+	if cfg.Output != "" && !cfg.OutputTee {
+		p.player = audio.NewSinkPlayer(p.synthesizer.SampleRate(), cfg.AudioBufferMs, sink, playerInterrupt)
+	} else {
+		var outputDevice *audio.DeviceID
+		if cfg.OutputDevice != "" {
+			device, err := selectDevice(audio.ListPlaybackDevices, cfg.OutputDevice, "output-device")
+			if err != nil {
+				return nil, err
+			}
+			outputDevice = &device.ID
+			log.Printf("🔊 Output device: %s", device.Name)
+		}
+		p.player, err = audio.NewPlayer(p.synthesizer.SampleRate(), cfg.AudioBufferMs, cfg.OutputChannels, outputDevice, cfg.OutputDevices, playerInterrupt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create audio player: %w", err)
+		}
+		if cfg.Output != "" {
+			p.player.StreamTo(sink)
+		}
+	}
+	p.closers = append(p.closers, p.player.Close)
+	p.player.SetFade(cfg.FadeMs)
+	if playerInterrupt != nil && cfg.AllowsBargeIn() {
+		p.player.SetBargeInGrace(time.Duration(cfg.BargeInGraceMs) * time.Millisecond)
+	}
+	p.memory.Register("playback ring", false).Set(p.player.RingBytes())
+
+	// Create audio capturer, on the requested microphone if any
+	var inputDevice *audio.DeviceID
+	if cfg.InputDevice != "" {
+		device, err := selectDevice(audio.ListCaptureDevices, cfg.InputDevice, "input-device")
+		if err != nil {
+			return nil, err
+		}
+		inputDevice = &device.ID
+		log.Printf("🎙️ Input device: %s", device.Name)
+	}
+	vad := p.vad
+	accept := vad.AcceptWaveform
+
+	// Boost quiet speech toward a target level (opt-in). It comes last, so the
+	// echo canceller never sees a changing gain and the noise gate measures the
+	// microphone's own level; the gain only rises while the VAD hears speech.
+	if cfg.AGC {
+		agc := audio.NewAGC(float64(cfg.AGCTargetDB))
+		accept = func(samples []float32) { vad.AcceptWaveform(agc.Process(samples, vad.IsSpeechDetected())) }
+		log.Printf("🎚️ Automatic gain control: normalizing input toward %.0f dBFS", cfg.AGCTargetDB)
+	}
+
+	// Silence steady background noise before the VAD hears it (opt-in)
+	if cfg.NoiseFloorDB < 0 {
+		gate := audio.NewNoiseGate(float64(cfg.NoiseFloorDB), cfg.SampleRate)
+		next := accept
+		accept = func(samples []float32) { next(gate.Process(samples)) }
+		log.Printf("🔇 Noise gate: input below %.0f dBFS is silenced", cfg.NoiseFloorDB)
+	}
+	onSamples := accept
+
+	// Remove the assistant's own voice before the VAD hears it (opt-in)
+	if cfg.EchoCancel {
+		canceller := audio.NewEchoCanceller(cfg.SampleRate)
+		canceller.SetDelay(cfg.EchoCancelDelay)
+		p.player.EnableEchoReference(cfg.SampleRate)
+		p.memory.Register("echo reference", false).Set(p.player.RingBytes()) // Same ring size as playback
+		var reference []float32
+		onSamples = func(samples []float32) {
+			reference = slices.Grow(reference[:0], len(samples))[:len(samples)]
+			p.player.ReadEchoReference(reference)
+			accept(canceller.Process(samples, reference))
+		}
+		if !cfg.AllowsBargeIn() {
+			log.Println("⚠️ --echo-cancel has no effect unless the microphone listens during playback (--interrupt-mode always)")
+		} else {
+			log.Println("🔁 Echo cancellation enabled")
+		}
+	}
+	p.capturer, err = audio.NewCapturer(cfg.SampleRate, inputDevice, onSamples)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audio capturer: %w", err)
+	}
+	p.closers = append(p.closers, p.capturer.Close)
+	p.capturer.SetDeadMicWindow(cfg.DeadMicWindow)
+	p.capturer.SetClipThreshold(cfg.ClipThreshold)
+
+	// Only let speech from in front of a two-microphone array barge in (opt-in)
+	if cfg.DirectionGate {
+		p.gate = audio.NewDirectionGate(cfg.DirectionMaxDelay, float64(cfg.DirectionMaxLevelDiff))
+		p.capturer.SetDirectionGate(p.gate)
+		log.Printf("🧭 Direction gate: barge-in only from in front (max delay %s, max level difference %.1f dB)", cfg.DirectionMaxDelay, cfg.DirectionMaxLevelDiff)
+	}
+
+	// Record raw audio around each transcribed turn for debugging (opt-in)
+	if cfg.ContextDumpSeconds > 0 {
+		padding := time.Duration(float64(cfg.ContextDumpSeconds) * float64(time.Second))
+		maxSegment := time.Duration(float64(cfg.VADMaxSpeechDuration)*float64(time.Second)) + time.Duration(cfg.VADPreSpeechPadMs)*time.Millisecond
+		p.dumper, err = stt.NewContextDumper(cfg.ContextDumpDir, cfg.SampleRate, padding, maxSegment)
+		if err != nil {
+			return nil, err
+		}
+		p.capturer.AddTap(p.dumper.Write)
+		p.memory.Register("context dump", false).Set(p.dumper.Bytes())
+		log.Printf("💾 Saving %.1fs of context around each turn to %s", cfg.ContextDumpSeconds, cfg.ContextDumpDir)
+	}
+
+	// Start the optional HTTP status server
+	if cfg.HTTPAddr != "" {
+		p.statusServer = server.New(cfg.HTTPAddr, cfg, p.serverSources())
+		if err := p.statusServer.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start HTTP server: %w", err)
+		}
+		p.closers = append(p.closers, p.shutdownServer)
+		log.Printf("🌐 HTTP status server listening on %s", cfg.HTTPAddr)
+	}
+
+	// Start the optional control socket for external commands (buttons, automation)
+	if cfg.ControlSocket != "" {
+		p.ctrl, err = control.Listen(cfg.ControlSocket, p.controlHandlers(), p.controlArgHandlers())
+		if err != nil {
+			return nil, fmt.Errorf("failed to start control socket: %w", err)
+		}
+		p.closers = append(p.closers, func() { p.ctrl.Close() })
+		log.Printf("🎛️ Control socket listening on %s", cfg.ControlSocket)
+	}
+
+	// Open the optional conversation transcript
+	if cfg.TranscriptLog != "" {
+		p.transcript, err = session.Open(cfg.TranscriptLog, cfg.TranscriptFormat)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open transcript log: %w", err)
+		}
+		p.closers = append(p.closers, func() { p.transcript.Close() })
+		p.turns = newTurnLog(p.transcript, p.synthesizer, cfg.TTSVoice)
+		log.Printf("📝 Logging transcript to %s (%s)", cfg.TranscriptLog, cfg.TranscriptFormat)
+	}
+
+	// Save each turn as a replayable fixture bundle (opt-in)
+	if cfg.RecordFixtures != "" {
+		p.recorder, err = fixture.NewRecorder(cfg.RecordFixtures)
+		if err != nil {
+			return nil, err
+		}
+		p.recorder.SetMemoryAccount(p.memory.Register("fixture recording", true))
+		p.closers = append(p.closers, p.recorder.Close)
+		log.Printf("💾 Recording fixtures to %s", cfg.RecordFixtures)
+	}
+
+	return p, nil
+}
+
+// controlHandlers returns the commands served on the control socket.
+func (p *Pipeline) controlHandlers() map[string]control.Handler {
+	return map[string]control.Handler{
+		"interrupt":      func() error { p.player.Interrupt(); return nil },
+		"mute":           func() error { p.player.SetMuted(true); return nil },
+		"unmute":         func() error { p.player.SetMuted(false); return nil },
+		"reset":          func() error { p.llmClient.ClearHistory(); return nil },
+		"pause":          func() error { p.capturer.SetHold(true); return nil },
+		"resume":         func() error { p.capturer.SetHold(false); return nil },
+		"more-sensitive": func() error { _, err := adjustSensitivity(p.vad, true); return err },
+		"less-sensitive": func() error { _, err := adjustSensitivity(p.vad, false); return err },
+		"faster":         func() error { return p.adjustSpeed(true) },
+		"slower":         func() error { return p.adjustSpeed(false) },
+		"restart-audio":  p.restartAudio,
+	}
+}
+
+// controlArgHandlers returns the control socket commands that take an argument.
+func (p *Pipeline) controlArgHandlers() map[string]control.ArgHandler {
+	return map[string]control.ArgHandler{
+		"say":   func(text string) error { return p.Speak(context.Background(), text) },
+		"model": p.setModel,
+		"silence-duration": func(arg string) error {
+			seconds, err := strconv.ParseFloat(arg, 32)
+			if err != nil {
+				return fmt.Errorf("silence-duration needs a number of seconds, got %q", arg)
+			}
+			return p.vad.SetSilenceDuration(float32(seconds))
+		},
+	}
+}
+
+// adjustSpeed makes speech one step faster or slower, if the TTS backend can
+// change its speed while running.
+func (p *Pipeline) adjustSpeed(faster bool) error {
+	synth, ok := tts.As[tts.SpeedController](p.synthesizer)
+	if !ok {
+		return fmt.Errorf("the TTS backend cannot change its speed while running")
+	}
+	_, err := adjustSpeed(synth, faster)
+	return err
+}
+
+// setModel switches the LLM to name, which may be a --model-aliases alias or an
+// Ollama model name.
+func (p *Pipeline) setModel(name string) error {
+	model := name
+	if m, ok := p.cfg.ModelAliases[strings.ToLower(name)]; ok {
+		model = m
+	}
+	if err := p.llmClient.SetModel(model); err != nil {
+		return err
+	}
+	log.Printf("🧠 LLM model: %s", model)
+	return nil
+}
+
+// restartAudio reopens the microphone and speaker, e.g. after a device was
+// reconnected, adapting to whatever sample rate they come back at.
+func (p *Pipeline) restartAudio() error {
+	if err := p.player.Restart(); err != nil {
+		return err
+	}
+	return p.capturer.Restart()
+}
+
+// Run starts the processing goroutines, plays the greeting, and listens until
+// ctx is cancelled or [Pipeline.Stop] is called, then shuts the goroutines down.
+// It returns an error only if audio capture cannot be started. Run must be
+// called at most once.
+func (p *Pipeline) Run(ctx context.Context) error {
+	defer p.terminal.restoreOnPanic()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer p.Stop() // Unblock Speak callers once Run returns
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	cfg := p.cfg
+	var wg sync.WaitGroup
+
+	// Start STT processing goroutine (interface-based, model-agnostic)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer p.terminal.restoreOnPanic()
+		transcriber := p.transcriber
+		if p.recorder != nil {
+			transcriber = recordingTranscriber{Transcriber: transcriber, rec: p.recorder, sampleRate: cfg.SampleRate}
+		}
+		if p.language != nil {
+			transcriber = languageTranscriber{Transcriber: transcriber, match: p.language}
+		}
+		if p.metrics != nil {
+			transcriber = timedTranscriber{Transcriber: transcriber, vad: p.vad, metrics: p.metrics}
+		}
+		// The wrappers hide the language and verbatim text of each segment
+		transcriber = detectingTranscriber{Transcriber: transcriber, source: p.transcriber}
+		stt.RunProcessor(ctx, p.vad, transcriber, p.transcriptions, p.notices, &p.interrupt, p.gate, p.dumper, p.states, cfg)
+	}()
+
+	// Route transcriptions to TTS commands, VAD adjustments or the LLM
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer p.terminal.restoreOnPanic()
+		defer close(p.prompts)
+		p.route(ctx)
+	}()
+
+	// Start LLM processing goroutine
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer p.terminal.restoreOnPanic()
+		var turns llm.TurnLogger
+		if p.turns != nil {
+			turns = p.turns
+		}
+		p.llmClient.RunProcessor(ctx, p.prompts, p.replies, p.intents, turns, p.spoken)
+	}()
+
+	// Copy LLM replies to the Responses tap on their way to TTS
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer p.terminal.restoreOnPanic()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case text := <-p.replies:
+				p.lastReply.Store(&text)
+				if p.recorder != nil {
+					p.recorder.Response(text)
+				}
+				offer(p.responseTap, text)
+				select {
+				case p.responses <- text:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	// Speak notices like announcements, so they are neither cached for replay
+	// nor taken for the end of an LLM turn
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer p.terminal.restoreOnPanic()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case text := <-p.notices:
+				select {
+				case p.announcements <- tts.Announcement{Text: text}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	// Start TTS and playback goroutine (interface-based, model-agnostic)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer p.terminal.restoreOnPanic()
+		// Keep the LLM's history to what the user actually heard of interrupted replies
+		undelivered := func(full, heard string) {
+			if p.llmClient.ReviseResponse(full, heard) && cfg.Verbose {
+				log.Printf("[LLM] Interrupted reply stored as heard: %q", heard)
+			}
+		}
+		// In sequential mode, tell the LLM each response is done so it can take
+		// the next turn; with --metrics, a finished reply ends the turn (notices,
+		// which are spoken as announcements, never do); with a transcript, the
+		// turn is logged with the voice the reply was spoken in.
+		var finished func(text, voice string)
+		if p.spoken != nil || p.metrics != nil || p.turns != nil {
+			finished = func(text, voice string) {
+				p.turns.spoken(text, voice)
+				p.metrics.EndTurn()
+				if p.spoken == nil {
+					return
+				}
+				select {
+				case p.spoken <- struct{}{}:
+				default:
+				}
+			}
+		}
+		// Start each reply in the voice of the language it answers (opt-in)
+		var voice func() string
+		if p.language != nil {
+			voice = p.language.Voice
+		}
+		synthesizer := p.synthesizer
+		if p.recorder != nil {
+			synthesizer = recordingSynthesizer(synthesizer, p.recorder)
+		}
+		if p.metrics != nil {
+			synthesizer = timedSynthesizer(synthesizer, p.metrics)
+		}
+		tts.RunProcessor(ctx, synthesizer, p.player, p.responses, p.commands, p.announcements, undelivered, finished, voice, &p.interrupt, p.states, cfg, p.capturer, p.ttsCache)
+	}()
+
+	// Report when the user starts speaking
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer p.terminal.restoreOnPanic()
+		runListeningState(ctx, p.vad, p.states)
+	}()
+
+	// Start re-engagement watcher (opt-in)
+	if cfg.ReengageAfter > 0 && cfg.ReengagePrompt != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer p.terminal.restoreOnPanic()
+			runReengage(ctx, cfg, &p.lastHeard, p.player, p.vad, p.notices)
+		}()
+	}
+
+	// Keep listening at reduced sensitivity during playback in 'wait' mode (opt-in)
+	if cfg.ListensDuringPlayback() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer p.terminal.restoreOnPanic()
+			runPlaybackSensitivity(ctx, cfg, p.player, p.vad)
+		}()
+	}
+
+	// Move playback to a preferred output device once it appears (opt-in)
+	if cfg.OutputDeviceMigrate > 0 && len(cfg.OutputDevices) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer p.terminal.restoreOnPanic()
+			runDeviceMigration(ctx, cfg.OutputDeviceMigrate, p.player)
+		}()
+	}
+
+	// A muted greeting plays before capture starts so the mic never hears it,
+	// making the first turn behave the same in every interrupt mode.
+	if cfg.Greeting != "" && cfg.MuteDuringGreeting {
+		log.Printf("👋 Greeting: %s", cfg.Greeting)
+		if err := tts.Speak(ctx, p.synthesizer, p.player, cfg.Greeting); err != nil {
+			log.Printf("⚠️ Greeting failed: %v", err)
+		}
+		time.Sleep(time.Duration(cfg.PostPlaybackDelayMs) * time.Millisecond)
+	}
+
+	// Start audio capture
+	var runErr error
+	if err := p.capturer.Start(); err != nil {
+		runErr = fmt.Errorf("failed to start audio capture: %w", err)
+		cancel()
+	} else {
+		// An unmuted greeting is spoken as a notice, with the usual interrupt handling.
+		if cfg.Greeting != "" && !cfg.MuteDuringGreeting {
+			log.Printf("👋 Greeting: %s", cfg.Greeting)
+			p.notices <- cfg.Greeting
+		}
+
+		p.listening.Store(true)
+		events.Emit(events.Ready, "")
+		if cfg.PushToTalk {
+			// Not waited for: it blocks reading stdin and holds nothing to release.
+			go runPushToTalk(ctx, os.Stdin, p.vad)
+			log.Println("🔇 Push-to-talk: press space to start and stop listening (Ctrl+C to quit)")
+		} else if len(cfg.WakeWord) == 1 {
+			log.Printf("🎙️ Listening for wake word: %q", cfg.WakeWord[0])
+		} else if len(cfg.WakeWord) > 1 {
+			log.Printf("🎙️ Listening for wake words: %q", cfg.WakeWord)
+		} else {
+			log.Println("🎙️ Listening... (speak to interact, Ctrl+C to quit)")
+		}
+	}
+
+	// Wait for shutdown
+	<-ctx.Done()
+	p.listening.Store(false)
+	log.Println("🛑 Shutting down...")
+
+	// Stop capture first
+	p.capturer.Stop()
+
+	if reporter, ok := p.transcriber.(stt.StatsReporter); ok {
+		logVADStats(reporter.VADStats())
+	}
+	logMemoryStats(p.memory.Stats())
+	logDropStats(p.capturer.DroppedChunks(), p.player.BufferOverflows())
+	logLatencyStats(p.metrics.Snapshot())
+
+	p.shutdownServer()
+
+	// Close channels
+	close(p.transcriptions)
+
+	// Wait for goroutines to finish
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("✅ Shutdown complete")
+	case <-time.After(shutdownTimeout):
+		log.Println("⚠️ Shutdown timeout, forcing exit")
+	}
+
+	if cfg.HistoryFile != "" {
+		if err := p.llmClient.SaveHistory(cfg.HistoryFile); err != nil {
+			log.Printf("⚠️ %v", err)
+		} else {
+			log.Printf("💾 Saved conversation history to %s", cfg.HistoryFile)
+		}
+	}
+	return runErr
+}
+
+// route forwards transcriptions: replay/resume phrases go straight to TTS,
+// sensitivity phrases adjust the VAD, speed phrases the speech speed, persona
+// and model phrases switch the LLM's persona or model, and everything else goes
+// to the LLM. Phrases are matched against the command; the status server and
+// transcript subscribers get the transcript as spoken when the wake word is
+// kept (--history-raw-transcript).
+func (p *Pipeline) route(ctx context.Context) {
+	cfg := p.cfg
+	for transcript := range p.transcriptions {
+		text := transcript.Text
+		if p.isSelfEcho(text) {
+			log.Printf("🔇 Ignoring transcript that echoes the last reply: %q", text)
+			p.states.Transition(state.Transcribing, state.Idle)
+			continue
+		}
+		spoken := text
+		if transcript.Verbatim != "" {
+			spoken = transcript.Verbatim
+		}
+		p.lastHeard.Store(time.Now().UnixNano())
+		p.lastTranscript.Store(&spoken)
+		events.Emit(events.Transcript, spoken)
+		offer(p.transcriptTap, spoken)
+
+		cmd, isCommand := tts.Replay, tts.MatchPhrase(text, cfg.ReplayPhrases)
+		if !isCommand && tts.MatchPhrase(text, cfg.ResumePhrases) {
+			cmd, isCommand = tts.Resume, true
+		}
+		if isCommand {
+			select {
+			case p.commands <- cmd:
+			default:
+				// A command is already pending
+			}
+			p.states.Transition(state.Transcribing, state.Idle) // Speaking once the replay plays
+			continue
+		}
+		if more := tts.MatchPhrase(text, cfg.MoreSensitivePhrases); more || tts.MatchPhrase(text, cfg.LessSensitivePhrases) {
+			reply := "Okay, I'll ignore quieter sounds."
+			if more {
+				reply = "Okay, I'll listen more closely."
+			}
+			if _, err := adjustSensitivity(p.vad, more); err != nil {
+				log.Printf("⚠️ %v", err)
+				reply = "I can't change my sensitivity any further."
+			}
+			select {
+			case p.notices <- reply:
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		if faster := tts.MatchPhrase(text, cfg.FasterPhrases); faster || tts.MatchPhrase(text, cfg.SlowerPhrases) {
+			reply := "Okay, I'll speak more slowly."
+			if faster {
+				reply = "Okay, I'll speak faster."
+			}
+			if err := p.adjustSpeed(faster); err != nil {
+				log.Printf("⚠️ %v", err)
+				reply = "I can't change how fast I speak any further."
+			}
+			select {
+			case p.notices <- reply:
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		if name, ok := matchPersona(text, cfg.PersonaPhrases, p.llmClient.AvailablePersonas()); ok {
+			reply := "Okay, I'm your " + name + " now."
+			if err := p.llmClient.SetPersona(name); err != nil {
+				log.Printf("⚠️ %v", err)
+				reply = "Sorry, I couldn't switch to " + name + "."
+			} else {
+				log.Printf("🎭 Persona: %s", name)
+			}
+			select {
+			case p.notices <- reply:
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		if alias, ok := matchModelAlias(text, cfg.ModelPhrases, cfg.ModelAliases); ok {
+			reply := "Okay, switching to the " + alias + " model."
+			if err := p.setModel(alias); err != nil {
+				log.Printf("⚠️ %v", err)
+				reply = "Sorry, I can't use the " + alias + " model."
+			}
+			select {
+			case p.notices <- reply:
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		p.states.Set(state.Thinking)
+		select {
+		case p.prompts <- llm.Prompt{Text: text, Verbatim: transcript.Verbatim, Language: transcript.Language, Injected: transcript.Injected}:
+			if p.statusServer != nil {
+				p.statusServer.RecordInteraction()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// isSelfEcho reports whether text is most likely the microphone picking up the
+// assistant's own last reply: it closely matches that reply and was heard during
+// playback or within cfg.SelfEchoSuppression after it ended.
+func (p *Pipeline) isSelfEcho(text string) bool {
+	window := p.cfg.SelfEchoSuppression
+	reply := p.lastReply.Load()
+	if window <= 0 || reply == nil {
+		return false
+	}
+	if !p.player.IsPlaying() && time.Since(p.player.LastPlayedAt()) > window {
+		return false
+	}
+	return echoesReply(text, *reply)
+}
+
+// Stop asks a running [Pipeline.Run] to shut down. It is safe to call more than once.
+func (p *Pipeline) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+// Close releases every component, in reverse order of creation. Call it after
+// Run has returned.
+func (p *Pipeline) Close() {
+	for i := len(p.closers) - 1; i >= 0; i-- {
+		p.closers[i]()
+	}
+	p.closers = nil
+}
+
+// Speak says text right away with the current voice, bypassing STT and the LLM,
+// e.g. to announce that a timer has finished. Whatever is playing is cut off
+// first, as if interrupted; queued responses are played afterwards, or discarded
+// in the modes that discard them on barge-in. With cfg.PauseForAnnouncements a
+// response being played is paused instead and resumes after the announcement.
+// Speak blocks until the text has been spoken, ctx is done or [Pipeline.Run]
+// returns.
+func (p *Pipeline) Speak(ctx context.Context, text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return errors.New("nothing to speak")
+	}
+
+	done := make(chan error, 1)
+	if p.player.IsPlaying() {
+		if p.cfg.PauseForAnnouncements {
+			log.Printf("📢 Announcement (pausing response): %s", text)
+			go func() { done <- tts.Announce(ctx, p.synthesizer, p.player, text, p.cfg) }()
+			return p.waitSpoken(ctx, done)
+		}
+		p.player.Interrupt()
+	}
+	select {
+	case p.announcements <- tts.Announcement{Text: text, Done: done}:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.stop:
+		return errPipelineStopped
+	}
+	return p.waitSpoken(ctx, done)
+}
+
+// waitSpoken waits for an announcement started by Speak to report on done.
+func (p *Pipeline) waitSpoken(ctx context.Context, done <-chan error) error {
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.stop:
+		return errPipelineStopped
+	}
+}
+
+// announceProgress speaks phrase to show a slow tool call is still running. It
+// must not block the LLM, so the phrase is dropped when TTS is busy (the user
+// is hearing something already).
+func (p *Pipeline) announceProgress(phrase string) {
+	select {
+	case p.announcements <- tts.Announcement{Text: phrase}:
+	default:
+	}
+}
+
+// Transcripts returns a channel receiving a copy of every user transcript.
+// Reading it is optional: copies are dropped when its buffer is full.
+func (p *Pipeline) Transcripts() <-chan string {
+	return p.transcriptTap
+}
+
+// Responses returns a channel receiving a copy of every LLM reply before it is
+// spoken. Reading it is optional: copies are dropped when its buffer is full.
+func (p *Pipeline) Responses() <-chan string {
+	return p.responseTap
+}
+
+// Intents returns the command matcher consulted before each LLM call. Register
+// intents before calling Run, e.g. to handle home automation commands directly.
+func (p *Pipeline) Intents() *intent.Matcher {
+	return p.intents
+}
+
+// LLM returns the LLM client, e.g. to clear or revise its history.
+func (p *Pipeline) LLM() *llm.Client {
+	return p.llmClient
+}
+
+// Player returns the audio player, e.g. to interrupt or mute playback.
+func (p *Pipeline) Player() *audio.Player {
+	return p.player
+}
+
+// States returns the tracker of what the assistant is doing, e.g. to
+// [state.Manager.Subscribe] to its transitions for an LED indicator.
+func (p *Pipeline) States() *state.Manager {
+	return p.states
+}
+
+// AudioMemory returns how much memory the audio buffers use, per account,
+// against cfg.AudioMemoryBudgetMB.
+func (p *Pipeline) AudioMemory() audio.MemoryStats {
+	return p.memory.Stats()
+}
+
+// Capturer returns the microphone capturer, e.g. to pause listening.
+func (p *Pipeline) Capturer() *audio.Capturer {
+	return p.capturer
+}
+
+// serverSources gives the status server access to the pipeline's components.
+func (p *Pipeline) serverSources() server.Sources {
+	src := server.Sources{
+		Ready: func() map[string]bool {
+			return map[string]bool{
+				"pipeline": p.listening.Load(),
+				"capture":  p.capturer.Active(),
+				"llm":      p.llmClient.Reachable(),
+			}
+		},
+		State: p.states.Current,
+		Voice: func() string {
+			if voice := p.language.Voice(); voice != "" {
+				return voice
+			}
+			return p.cfg.TTSVoice
+		},
+		LLMModel: p.llmClient.Model,
+		LastTranscript: func() string {
+			if text := p.lastTranscript.Load(); text != nil {
+				return *text
+			}
+			return ""
+		},
+		Metrics: p.metrics,
+		Speak: func(ctx context.Context, text string) (audio.AudioBuffer, error) {
+			out, err := p.synthesizer.Synthesize(ctx, text)
+			if err != nil {
+				return audio.AudioBuffer{}, err
+			}
+			return audio.AudioBuffer{Samples: out.Samples, SampleRate: out.SampleRate}, nil
+		},
+	}
+	if transcriber, ok := p.transcriber.(stt.AudioTranscriber); ok {
+		src.Transcribe = func(buf audio.AudioBuffer) (string, error) {
+			return transcriber.TranscribeAudio(buf.Samples, buf.SampleRate)
+		}
+	}
+	return src
+}
+
+// shutdownServer gracefully stops the optional status server; later calls are no-ops.
+func (p *Pipeline) shutdownServer() {
+	if p.statusServer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	if err := p.statusServer.Shutdown(ctx); err != nil {
+		log.Printf("⚠️ HTTP server shutdown: %v", err)
+	}
+}
+
+// offer sends v on ch without blocking, dropping it if ch is full.
+func offer[T any](ch chan<- T, v T) {
+	select {
+	case ch <- v:
+	default:
+	}
+}
@@ -0,0 +1,490 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+	"github.com/agalue/sherpa-voice-assistant/internal/llm"
+	"github.com/agalue/sherpa-voice-assistant/internal/metrics"
+	"github.com/agalue/sherpa-voice-assistant/internal/session"
+	"github.com/agalue/sherpa-voice-assistant/internal/state"
+	"github.com/agalue/sherpa-voice-assistant/internal/stt"
+	"github.com/agalue/sherpa-voice-assistant/internal/tts"
+)
+
+func TestOfferDropsWhenFull(t *testing.T) {
+	ch := make(chan string, 1)
+	offer(ch, "first")
+	offer(ch, "second") // Must not block
+
+	if got := <-ch; got != "first" {
+		t.Errorf("got %q, want %q", got, "first")
+	}
+	select {
+	case got := <-ch:
+		t.Errorf("unexpected value %q after a full channel", got)
+	default:
+	}
+}
+
+func TestStopIsIdempotent(t *testing.T) {
+	p := &Pipeline{stop: make(chan struct{})}
+	p.Stop()
+	p.Stop()
+	select {
+	case <-p.stop:
+	default:
+		t.Fatal("stop channel not closed")
+	}
+}
+
+func TestMatchPersona(t *testing.T) {
+	phrases := []string{"be my", "switch to"}
+	personas := []string{"comedian", "tutor"}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Be my tutor!", "tutor"},
+		{"Switch to the comedian.", "comedian"},
+		{"be my teacher", ""},
+		{"can you be my tutor tomorrow", ""},
+	}
+	for _, tt := range tests {
+		got, ok := matchPersona(tt.text, phrases, personas)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("matchPersona(%q) = %q, %v; want %q", tt.text, got, ok, tt.want)
+		}
+	}
+}
+
+func TestEchoesReply(t *testing.T) {
+	reply := "The weather in Paris is sunny, with a high of 24 degrees."
+
+	tests := []struct {
+		transcript string
+		want       bool
+	}{
+		{"with a high of 24 degrees", true},
+		{"With a high of twenty four degrees.", false}, // Too different to be sure
+		{"the weather in paris is sunny", true},
+		{"weather in Paris is so sunny", true}, // One misheard word
+		{"what about London", false},
+		{"Paris", false}, // Too short to tell
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := echoesReply(tt.transcript, reply); got != tt.want {
+			t.Errorf("echoesReply(%q) = %v, want %v", tt.transcript, got, tt.want)
+		}
+	}
+}
+
+func TestLoadModelTimesOutAndReleasesLateResult(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	unblock := make(chan struct{})
+	released := make(chan int, 1)
+	_, err := loadModel(ctx, "the test model", func() (int, error) {
+		<-unblock
+		return 42, nil
+	}, func(v int) { released <- v })
+	if err == nil || !strings.Contains(err.Error(), "timed out loading the test model") {
+		t.Fatalf("err = %v, want a timeout naming the model", err)
+	}
+
+	close(unblock)
+	select {
+	case v := <-released:
+		if v != 42 {
+			t.Errorf("released %d, want the late result 42", v)
+		}
+	case <-time.After(time.Second):
+		t.Error("late result was never released")
+	}
+}
+
+func TestLoadModelReturnsResult(t *testing.T) {
+	v, err := loadModel(context.Background(), "the test model", func() (string, error) {
+		return "ready", nil
+	}, func(string) { t.Error("release called for a result that was returned") })
+	if err != nil || v != "ready" {
+		t.Errorf("loadModel = %q, %v; want ready", v, err)
+	}
+}
+
+func TestMatchModelAlias(t *testing.T) {
+	phrases := []string{"switch to", "use"}
+	aliases := map[string]string{"fast": "qwen2.5:1.5b", "smart": "qwen2.5:7b"}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Switch to the smart model.", "smart"},
+		{"use fast model", "fast"},
+		{"switch to the smart", ""}, // Could be a persona
+		{"use the clever model", ""},
+	}
+	for _, tt := range tests {
+		got, ok := matchModelAlias(tt.text, phrases, aliases)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("matchModelAlias(%q) = %q, %v; want %q", tt.text, got, ok, tt.want)
+		}
+	}
+}
+
+func TestPlaybackSensitivityRaisesAndRestores(t *testing.T) {
+	s := &playbackSensitivity{raised: 0.85, hold: 300 * time.Millisecond}
+
+	if th, change := s.step(true, false, 0.5, 0); !change || th != 0.85 {
+		t.Fatalf("playback start = (%v, %v), want raise to 0.85", th, change)
+	}
+	if _, change := s.step(true, true, 0.85, 0); change {
+		t.Error("changed again during playback")
+	}
+	if _, change := s.step(false, false, 0.85, 100*time.Millisecond); change {
+		t.Error("restored within the hold (gap between sentences)")
+	}
+	if _, change := s.step(false, true, 0.85, time.Second); change {
+		t.Error("restored while speech was in progress")
+	}
+	if th, change := s.step(false, false, 0.85, time.Second); !change || th != 0.5 {
+		t.Errorf("after playback = (%v, %v), want restore to 0.5", th, change)
+	}
+	if _, change := s.step(false, false, 0.5, time.Second); change {
+		t.Error("changed while idle")
+	}
+
+	if _, change := s.step(true, true, 0.5, 0); change {
+		t.Error("raised while speech was in progress")
+	}
+	if _, change := s.step(true, false, 0.5, 0); !change {
+		t.Error("not raised once speech ended")
+	}
+	if _, change := s.step(false, false, 0.6, time.Second); change {
+		t.Error("restored over a threshold changed during playback")
+	}
+}
+
+// fakeLanguage reports a settable detected language.
+type fakeLanguage struct{ language string }
+
+func (f *fakeLanguage) LastLanguage() string { return f.language }
+
+func TestLanguageMatcherPicksVoiceOnLanguageChange(t *testing.T) {
+	client, err := llm.NewClient(&llm.Config{Host: "http://127.0.0.1:1", Model: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	detected := &fakeLanguage{}
+	m := &languageMatcher{detector: detected, llm: client, defaultVoice: "af_bella"}
+
+	var voices []string
+	for _, language := range []string{"en", "es", "es", "", "de", "fr"} {
+		detected.language = language
+		m.update()
+		voices = append(voices, m.Voice())
+	}
+	// English keeps the default voice, unknown and German fall back to it
+	want := []string{"af_bella", "ef_dora", "ef_dora", "ef_dora", "af_bella", "ff_siwis"}
+	if !slices.Equal(voices, want) {
+		t.Errorf("voices = %v, want %v", voices, want)
+	}
+	if got := (*languageMatcher)(nil).Voice(); got != "" {
+		t.Errorf("nil matcher voice = %q, want none", got)
+	}
+}
+
+// fakeGate records push-to-talk toggles.
+type fakeGate struct {
+	listening bool
+	toggles   []bool
+}
+
+func (g *fakeGate) Listening() bool { return g.listening }
+
+func (g *fakeGate) SetListening(on bool) {
+	g.listening = on
+	g.toggles = append(g.toggles, on)
+}
+
+func TestPushToTalkTogglesOnSpace(t *testing.T) {
+	g := &fakeGate{}
+	runPushToTalk(context.Background(), strings.NewReader(" x \n "), g)
+	if want := []bool{true, false, true}; !slices.Equal(g.toggles, want) {
+		t.Errorf("toggles = %v, want %v", g.toggles, want)
+	}
+}
+
+func TestRestoreOnPanicLetsThePanicGoOn(t *testing.T) {
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recovered %v, want the original panic", r)
+		}
+	}()
+	func() {
+		var term *terminal // Push-to-talk off
+		defer term.restoreOnPanic()
+		panic("boom")
+	}()
+}
+
+// fakeSynth records the texts it is asked to speak.
+type fakeSynth struct {
+	tts.Synthesizer
+	texts []string
+}
+
+func (f *fakeSynth) Synthesize(_ context.Context, text string) (*tts.AudioOutput, error) {
+	f.texts = append(f.texts, text)
+	return &tts.AudioOutput{Samples: make([]float32, 10), SampleRate: 24000}, nil
+}
+
+func (f *fakeSynth) SampleRate() int { return 24000 }
+
+func TestTimedSynthesizerKeepsPhonemeFallback(t *testing.T) {
+	inner := &fakeSynth{}
+	rec := metrics.New()
+	synth := timedSynthesizer(inner, rec)
+
+	if _, err := tts.SynthesizeMarked(context.Background(), synth, "Say [phon:həˈloʊ|hello] now."); err != nil {
+		t.Fatal(err)
+	}
+	if last := inner.texts[len(inner.texts)-1]; last != "Say hello now." {
+		t.Errorf("synthesized %q, want the markup replaced by its fallback text", inner.texts)
+	}
+	rec.EndTurn()
+	if got := rec.Snapshot(); len(got) != 1 || got[0].Stage != metrics.TTS {
+		t.Errorf("Snapshot = %v, want the synthesis time recorded", got)
+	}
+}
+
+// fakeVoiceSynth additionally takes phonemes, changes speed and switches voices.
+type fakeVoiceSynth struct {
+	fakeSynth
+	voice    string
+	phonemes []string
+}
+
+func (f *fakeVoiceSynth) SynthesizePhonemes(ctx context.Context, phonemes string) (*tts.AudioOutput, error) {
+	f.phonemes = append(f.phonemes, phonemes)
+	return f.Synthesize(ctx, "")
+}
+
+func (f *fakeVoiceSynth) SynthesizeAtSpeed(ctx context.Context, text string, _ float32) (*tts.AudioOutput, error) {
+	return f.Synthesize(ctx, text)
+}
+
+func (f *fakeVoiceSynth) Voice() string { return f.voice }
+
+func (f *fakeVoiceSynth) SetVoice(name string) error {
+	f.voice = name
+	return nil
+}
+
+func TestObservedSynthesizerForwardsCapabilities(t *testing.T) {
+	var observed []string
+	observe := func(text string, _ *tts.AudioOutput, _ time.Time) { observed = append(observed, text) }
+
+	inner := &fakeVoiceSynth{voice: "af_bella"}
+	synth := observedSynthesizer{Synthesizer: observedSynthesizer{Synthesizer: inner, observe: observe}, observe: observe}
+	if _, ok := tts.As[tts.SpeedSynthesizer](synth); !ok {
+		t.Error("wrapping hid SpeedSynthesizer")
+	}
+	vs, ok := tts.As[tts.VoiceSwitcher](synth)
+	if !ok {
+		t.Fatal("wrapping hid VoiceSwitcher")
+	}
+	if err := vs.SetVoice("bf_emma"); err != nil || inner.voice != "bf_emma" {
+		t.Errorf("SetVoice = %v, inner voice %q; want bf_emma", err, inner.voice)
+	}
+	if _, err := tts.SynthesizeMarked(context.Background(), synth, "[phon:həˈloʊ|hello]"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(inner.phonemes, []string{"həˈloʊ"}) || len(observed) != 2 {
+		t.Errorf("phonemes = %q, observed %q; want the phonemes synthesized once, seen by both wrappers", inner.phonemes, observed)
+	}
+
+	plain := observedSynthesizer{Synthesizer: &fakeSynth{}, observe: observe}
+	if _, ok := tts.As[tts.PhonemeSynthesizer](plain); ok {
+		t.Error("wrapper claims phonemes its synthesizer lacks")
+	}
+	if _, ok := tts.As[tts.VoiceSwitcher](plain); ok {
+		t.Error("wrapper claims voices its synthesizer lacks")
+	}
+	if _, ok := tts.As[tts.SpeedController](plain); ok {
+		t.Error("wrapper claims speed control its synthesizer lacks")
+	}
+}
+
+// fakeSpeed is a synthesizer whose speed can be changed.
+type fakeSpeed struct {
+	fakeSynth
+	speed float32
+}
+
+func (f *fakeSpeed) Speed() float32 { return f.speed }
+
+func (f *fakeSpeed) SetSpeed(speed float32) error {
+	f.speed = speed
+	return nil
+}
+
+func TestAdjustSpeedThroughWrappers(t *testing.T) {
+	inner := &fakeSpeed{speed: 1}
+	p := &Pipeline{synthesizer: observedSynthesizer{Synthesizer: inner, observe: func(string, *tts.AudioOutput, time.Time) {}}}
+	if err := p.adjustSpeed(true); err != nil || inner.speed <= 1 {
+		t.Errorf("adjustSpeed = %v, inner speed %v; want it raised through the wrapper", err, inner.speed)
+	}
+}
+
+func TestAdjustSpeedStepsWithinBounds(t *testing.T) {
+	synth := &fakeSpeed{speed: 0.93}
+	if got, err := adjustSpeed(synth, false); err != nil || got != 0.83 {
+		t.Errorf("slower from 0.93 = %v, %v, want 0.83", got, err)
+	}
+	if got, err := adjustSpeed(synth, true); err != nil || got != 0.93 {
+		t.Errorf("faster from 0.83 = %v, %v, want 0.93", got, err)
+	}
+
+	synth.speed = tts.MaxSpeed
+	if got, err := adjustSpeed(synth, true); err == nil || got != tts.MaxSpeed {
+		t.Errorf("faster at the limit = %v, %v, want an error", got, err)
+	}
+	// A startup speed beyond the bounds is brought back within them, never
+	// further out.
+	synth.speed = 2.5
+	if _, err := adjustSpeed(synth, true); err == nil || synth.speed != 2.5 {
+		t.Errorf("faster from 2.5 changed the speed to %v (err %v)", synth.speed, err)
+	}
+	if got, err := adjustSpeed(synth, false); err != nil || got != tts.MaxSpeed {
+		t.Errorf("slower from 2.5 = %v, %v, want %v", got, err, tts.MaxSpeed)
+	}
+}
+
+// fakeDetector is a VoiceDetector whose speech detection the test controls.
+type fakeDetector struct{ speech atomic.Bool }
+
+func (d *fakeDetector) AcceptWaveform([]float32)                {}
+func (d *fakeDetector) SegmentChannel() <-chan stt.AudioSegment { return nil }
+func (d *fakeDetector) IsSpeechDetected() bool                  { return d.speech.Load() }
+func (d *fakeDetector) Clear()                                  {}
+func (d *fakeDetector) Close()                                  {}
+
+func TestListeningStateFollowsSpeechWhenIdle(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	detector := &fakeDetector{}
+	states := state.NewManager(false)
+	ch := states.Subscribe()
+	<-ch // Idle
+	go runListeningState(ctx, detector, states)
+
+	next := func() state.State {
+		select {
+		case s := <-ch:
+			return s
+		case <-time.After(time.Second):
+			t.Fatal("no state transition")
+			return 0
+		}
+	}
+	detector.speech.Store(true)
+	if got := next(); got != state.Listening {
+		t.Errorf("state with speech = %s, want listening", got)
+	}
+	detector.speech.Store(false)
+	if got := next(); got != state.Idle {
+		t.Errorf("state after speech without a segment = %s, want idle", got)
+	}
+
+	// Once STT takes the segment, the state goes straight on to Transcribing.
+	detector.speech.Store(true)
+	next()
+	detector.speech.Store(false)
+	states.Transition(state.Listening, state.Transcribing)
+	next()
+	time.Sleep(listeningIdleGrace + 2*listeningPollInterval)
+	if got := states.Current(); got != state.Transcribing {
+		t.Errorf("state after the segment was taken = %s, want transcribing", got)
+	}
+	states.Set(state.Idle)
+	next()
+
+	// Speech over a reply does not replace Speaking.
+	states.Set(state.Speaking)
+	next()
+	detector.speech.Store(true)
+	time.Sleep(3 * listeningPollInterval)
+	if got := states.Current(); got != state.Speaking {
+		t.Errorf("state with speech during playback = %s, want speaking", got)
+	}
+}
+
+func TestRouteSpeaksAcksAsNotices(t *testing.T) {
+	p := &Pipeline{
+		cfg:            config.DefaultConfig(),
+		synthesizer:    &fakeSynth{}, // Can't change speed
+		transcriptions: make(chan stt.Transcript, 1),
+		notices:        make(chan string, 1),
+	}
+	p.transcriptions <- stt.Transcript{Text: "speak faster"}
+	close(p.transcriptions)
+	p.route(context.Background())
+
+	select {
+	case ack := <-p.notices:
+		if !strings.Contains(ack, "can't change how fast") {
+			t.Errorf("ack = %q, want the speed change refused", ack)
+		}
+	default:
+		t.Error("no acknowledgement sent as a notice")
+	}
+}
+
+func TestTurnLogRecordsVoiceOnceSpoken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.jsonl")
+	l, err := session.Open(path, session.FormatJSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	turns := newTurnLog(l, &fakeVoiceSynth{}, "af_bella")
+	turns.Log(session.Turn{User: "hola", Assistant: "¡Hola!", Language: "es"})
+	turns.Log(session.Turn{User: "hi", Assistant: "Hello!", Language: "en"})
+
+	turns.spoken("I'm sorry, I encountered an error.", "af_bella") // Not a held turn
+	turns.spoken("¡Hola!", "ef_dora")
+	turns.spoken("Hello!", "") // Discarded unheard
+	l.Close()
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logged %d turns, want 2", len(lines))
+	}
+	want := []session.Turn{
+		{User: "hola", Assistant: "¡Hola!", Language: "es", Voice: "ef_dora"},
+		{User: "hi", Assistant: "Hello!", Language: "en"},
+	}
+	for i, line := range lines {
+		var got session.Turn
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatal(err)
+		}
+		got.Time = time.Time{}
+		if got != want[i] {
+			t.Errorf("turn %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
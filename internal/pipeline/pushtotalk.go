@@ -0,0 +1,96 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// pushToTalkKey toggles listening in push-to-talk mode.
+const pushToTalkKey = ' '
+
+// listenGate is the part of the VAD that push-to-talk switches on and off.
+type listenGate interface {
+	Listening() bool
+	SetListening(on bool)
+}
+
+// terminal is stdin switched to raw (non-canonical, no echo) mode so single
+// key presses are read as they happen.
+type terminal struct {
+	fd    int
+	saved unix.Termios // Settings to restore on close
+}
+
+// openTerminal switches stdin to raw mode. Output processing and signals are
+// left alone, so log lines still end in a newline and Ctrl+C still quits.
+func openTerminal() (*terminal, error) {
+	fd := int(os.Stdin.Fd())
+	saved, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, errors.New("push-to-talk needs stdin to be a terminal")
+	}
+	raw := *saved
+	raw.Lflag &^= unix.ICANON | unix.ECHO
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, fmt.Errorf("failed to set up the terminal for push-to-talk: %w", err)
+	}
+	return &terminal{fd: fd, saved: *saved}, nil
+}
+
+// Close restores the terminal settings.
+func (t *terminal) Close() {
+	unix.IoctlSetTermios(t.fd, ioctlSetTermios, &t.saved)
+}
+
+// restoreOnPanic restores the terminal settings if the calling goroutine is
+// panicking and lets the panic go on. The pipeline's goroutines defer it so a
+// crash does not leave the shell without echo. A nil terminal does nothing.
+func (t *terminal) restoreOnPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if t != nil {
+		t.Close()
+	}
+	panic(r)
+}
+
+// runPushToTalk toggles gate each time the push-to-talk key is read from keys.
+// Reading blocks, so this returns only when keys ends or on the first key after
+// ctx is cancelled; it is not waited for on shutdown.
+func runPushToTalk(ctx context.Context, keys io.Reader, gate listenGate) {
+	buf := make([]byte, 16)
+	for {
+		n, err := keys.Read(buf)
+		if ctx.Err() != nil {
+			return
+		}
+		for _, key := range buf[:n] {
+			if key != pushToTalkKey {
+				continue
+			}
+			on := !gate.Listening()
+			gate.SetListening(on)
+			if on {
+				log.Println("🎙️ Listening (press space to stop)")
+			} else {
+				log.Println("🔇 Not listening (press space to talk)")
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("⚠️ Push-to-talk stopped reading keys: %v", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build darwin
+
+package pipeline
+
+import "golang.org/x/sys/unix"
+
+// Terminal ioctl requests for push-to-talk raw mode.
+const (
+	ioctlGetTermios = unix.TIOCGETA
+	ioctlSetTermios = unix.TIOCSETA
+)
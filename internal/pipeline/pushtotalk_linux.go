@@ -0,0 +1,11 @@
+//go:build linux
+
+package pipeline
+
+import "golang.org/x/sys/unix"
+
+// Terminal ioctl requests for push-to-talk raw mode.
+const (
+	ioctlGetTermios = unix.TCGETS
+	ioctlSetTermios = unix.TCSETS
+)
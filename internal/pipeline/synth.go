@@ -0,0 +1,106 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/tts"
+)
+
+// errNotSupported is returned by observedSynthesizer for an optional interface
+// the wrapped synthesizer lacks. Callers check with [tts.As] first, so it is
+// only seen when they don't.
+var errNotSupported = errors.New("not supported by the TTS backend")
+
+// observedSynthesizer wraps a synthesizer to pass each successful synthesis to
+// observe, with the time it started, e.g. to time or record it. It forwards
+// every optional interface of the tts package, so wrapping never changes what
+// is spoken (see [tts.Wrapper]). Streamed audio is observed once the whole text
+// has been synthesized.
+type observedSynthesizer struct {
+	tts.Synthesizer
+	observe func(text string, out *tts.AudioOutput, start time.Time)
+}
+
+// Unwrap returns the wrapped synthesizer — satisfies [tts.Wrapper].
+func (s observedSynthesizer) Unwrap() tts.Synthesizer {
+	return s.Synthesizer
+}
+
+func (s observedSynthesizer) Synthesize(ctx context.Context, text string) (*tts.AudioOutput, error) {
+	start := time.Now()
+	out, err := s.Synthesizer.Synthesize(ctx, text)
+	return s.done(text, start, out, err)
+}
+
+func (s observedSynthesizer) SynthesizePhonemes(ctx context.Context, phonemes string) (*tts.AudioOutput, error) {
+	ps, ok := tts.As[tts.PhonemeSynthesizer](s.Synthesizer)
+	if !ok {
+		return nil, tts.ErrPhonemesUnsupported
+	}
+	start := time.Now()
+	out, err := ps.SynthesizePhonemes(ctx, phonemes)
+	return s.done(phonemes, start, out, err)
+}
+
+func (s observedSynthesizer) SynthesizeAtSpeed(ctx context.Context, text string, factor float32) (*tts.AudioOutput, error) {
+	ss, ok := tts.As[tts.SpeedSynthesizer](s.Synthesizer)
+	if !ok {
+		return nil, errNotSupported
+	}
+	start := time.Now()
+	out, err := ss.SynthesizeAtSpeed(ctx, text, factor)
+	return s.done(text, start, out, err)
+}
+
+func (s observedSynthesizer) SynthesizeToCallback(ctx context.Context, text string, onChunk func(tts.AudioOutput) error) error {
+	start := time.Now()
+	out := &tts.AudioOutput{}
+	err := tts.SynthesizeChunks(ctx, s.Synthesizer, text, func(chunk tts.AudioOutput) error {
+		out.Samples = append(out.Samples, chunk.Samples...)
+		out.SampleRate = chunk.SampleRate
+		return onChunk(chunk)
+	})
+	_, err = s.done(text, start, out, err)
+	return err
+}
+
+func (s observedSynthesizer) Voice() string {
+	if vs, ok := tts.As[tts.VoiceSwitcher](s.Synthesizer); ok {
+		return vs.Voice()
+	}
+	return ""
+}
+
+func (s observedSynthesizer) SetVoice(name string) error {
+	vs, ok := tts.As[tts.VoiceSwitcher](s.Synthesizer)
+	if !ok {
+		return errNotSupported
+	}
+	return vs.SetVoice(name)
+}
+
+func (s observedSynthesizer) Speed() float32 {
+	if sc, ok := tts.As[tts.SpeedController](s.Synthesizer); ok {
+		return sc.Speed()
+	}
+	return 0
+}
+
+func (s observedSynthesizer) SetSpeed(speed float32) error {
+	sc, ok := tts.As[tts.SpeedController](s.Synthesizer)
+	if !ok {
+		return errNotSupported
+	}
+	return sc.SetSpeed(speed)
+}
+
+// done observes the synthesis of text begun at start unless it failed, and
+// returns its result.
+func (s observedSynthesizer) done(text string, start time.Time, out *tts.AudioOutput, err error) (*tts.AudioOutput, error) {
+	if err == nil {
+		s.observe(text, out, start)
+	}
+	return out, err
+}
@@ -0,0 +1,374 @@
+// Package server provides an optional embedded HTTP server for remote monitoring
+// of the voice assistant.
+//
+// The status endpoints are read-only and safe to call concurrently with the
+// running pipeline; they only read the resolved [config.Config] (never mutated
+// after startup), atomic counters and the [Sources] the pipeline provides. The
+// inference endpoints (POST /transcribe and /speak) share the pipeline's
+// engines, so each engine admits at most [config.Config.MaxConcurrentRequests]
+// of them at a time and rejects the rest with 429 Too Many Requests.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+	"github.com/agalue/sherpa-voice-assistant/internal/metrics"
+	"github.com/agalue/sherpa-voice-assistant/internal/state"
+)
+
+// Sources gives the server read access to the running assistant's components.
+// Each function must be safe to call from any goroutine; any field may be nil,
+// leaving its part of the reports empty.
+type Sources struct {
+	// Ready reports whether each component (e.g. "pipeline", "capture", "llm")
+	// is working, for GET /healthz.
+	Ready func() map[string]bool
+
+	// State returns what the assistant is doing.
+	State func() state.State
+
+	// Voice returns the voice replies are spoken in, which may change at
+	// runtime; nil reports the configured voice.
+	Voice func() string
+
+	// LLMModel returns the model LLM requests are sent to, which may change at
+	// runtime; nil reports the configured model.
+	LLMModel func() string
+
+	// LastTranscript returns the most recent user transcript, or "".
+	LastTranscript func() string
+
+	// Metrics holds the turn stage latencies for GET /metrics (nil unless
+	// --metrics is set).
+	Metrics *metrics.Recorder
+
+	// Transcribe converts posted audio to text for POST /transcribe; nil
+	// leaves the endpoint out.
+	Transcribe func(buf audio.AudioBuffer) (string, error)
+
+	// Speak synthesizes text for POST /speak; nil leaves the endpoint out.
+	Speak func(ctx context.Context, text string) (audio.AudioBuffer, error)
+}
+
+// maxAudioUpload bounds the WAV body accepted by POST /transcribe: a minute of
+// 16-bit stereo audio at 48 kHz.
+const maxAudioUpload = 60 * 48000 * 2 * 2
+
+// maxSpeakRequest bounds the JSON body accepted by POST /speak.
+const maxSpeakRequest = 64 << 10
+
+// Transcription is the JSON document returned by POST /transcribe.
+type Transcription struct {
+	Text string `json:"text"`
+}
+
+// SpeakRequest is the JSON document POST /speak expects.
+type SpeakRequest struct {
+	Text string `json:"text"`
+}
+
+// Status is the JSON document returned by GET /status.
+type Status struct {
+	Providers      ProviderStatus `json:"providers"`
+	Threads        ThreadStatus   `json:"threads"`
+	Voice          string         `json:"voice"`
+	SpeakerID      int            `json:"speaker_id"`
+	Models         ModelStatus    `json:"models"`
+	LLMModel       string         `json:"llm_model"`
+	InterruptMode  string         `json:"interrupt_mode"`
+	UptimeSeconds  float64        `json:"uptime_seconds"`
+	Interactions   uint64         `json:"interactions"`
+	State          string         `json:"state,omitempty"`
+	LastTranscript string         `json:"last_transcript,omitempty"`
+}
+
+// Health is the JSON document returned by GET /healthz.
+type Health struct {
+	OK         bool            `json:"ok"`
+	Components map[string]bool `json:"components"`
+}
+
+// StageLatency is the aggregate latency of one turn stage, as returned by GET
+// /metrics.
+type StageLatency struct {
+	Stage string  `json:"stage"`
+	Turns int     `json:"turns"`
+	P50Ms float64 `json:"p50_ms"`
+	P95Ms float64 `json:"p95_ms"`
+}
+
+// ProviderStatus reports the hardware acceleration providers in use.
+type ProviderStatus struct {
+	STT string `json:"stt"`
+	TTS string `json:"tts"`
+}
+
+// ThreadStatus reports the resolved per-model thread counts.
+type ThreadStatus struct {
+	VAD int `json:"vad"`
+	STT int `json:"stt"`
+	TTS int `json:"tts"`
+}
+
+// ModelStatus reports where models are loaded from and which backends use them.
+type ModelStatus struct {
+	Dir        string `json:"dir"`
+	STTBackend string `json:"stt_backend"`
+	STTModel   string `json:"stt_model"`
+	TTSBackend string `json:"tts_backend"`
+}
+
+// Server is the embedded HTTP status server.
+type Server struct {
+	cfg          *config.Config // Resolved configuration (read-only)
+	src          Sources        // Running components reported on
+	started      time.Time      // Process start time for uptime reporting
+	interactions atomic.Uint64  // Number of user turns forwarded to the LLM
+	sttLimit     limiter        // Concurrent POST /transcribe requests
+	ttsLimit     limiter        // Concurrent POST /speak requests
+	srv          *http.Server
+	shutdown     sync.Once
+}
+
+// New creates a status server bound to addr (e.g. ":8080") that reports on
+// src. Call [Server.Start] to begin serving.
+func New(addr string, cfg *config.Config, src Sources) *Server {
+	s := &Server{
+		cfg:      cfg,
+		src:      src,
+		started:  time.Now(),
+		sttLimit: newLimiter("STT", cfg.MaxConcurrentRequests),
+		ttsLimit: newLimiter("TTS", cfg.MaxConcurrentRequests),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	if src.Transcribe != nil {
+		mux.Handle("POST /transcribe", s.sttLimit.wrap(http.HandlerFunc(s.handleTranscribe)))
+	}
+	if src.Speak != nil {
+		mux.Handle("POST /speak", s.ttsLimit.wrap(http.HandlerFunc(s.handleSpeak)))
+	}
+
+	s.srv = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// RecordInteraction increments the interaction counter reported by /status.
+func (s *Server) RecordInteraction() {
+	s.interactions.Add(1)
+}
+
+// Start binds the listening socket and serves requests on a background goroutine.
+// Binding errors (e.g. address already in use) are returned synchronously.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.srv.Addr, err)
+	}
+
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("❌ HTTP server error: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Shutdown gracefully stops the server, waiting for in-flight requests until ctx
+// expires. It is safe to call more than once; later calls do nothing and return nil.
+func (s *Server) Shutdown(ctx context.Context) (err error) {
+	s.shutdown.Do(func() { err = s.srv.Shutdown(ctx) })
+	return err
+}
+
+// Status returns a snapshot of the current status.
+func (s *Server) Status() Status {
+	status := Status{
+		Providers: ProviderStatus{
+			STT: s.cfg.STTProvider,
+			TTS: s.cfg.TTSProvider,
+		},
+		Threads: ThreadStatus{
+			VAD: s.cfg.VADThreads,
+			STT: s.cfg.STTThreads,
+			TTS: s.cfg.TTSThreads,
+		},
+		Voice:     s.cfg.TTSVoice,
+		SpeakerID: s.cfg.TTSSpeakerID,
+		Models: ModelStatus{
+			Dir:        s.cfg.ModelDir,
+			STTBackend: s.cfg.STTBackend,
+			STTModel:   s.cfg.STTModel,
+			TTSBackend: s.cfg.TTSBackend,
+		},
+		LLMModel:      s.cfg.OllamaModel,
+		InterruptMode: s.cfg.InterruptMode.String(),
+		UptimeSeconds: time.Since(s.started).Seconds(),
+		Interactions:  s.interactions.Load(),
+	}
+	if s.src.State != nil {
+		status.State = s.src.State().String()
+	}
+	if s.src.Voice != nil {
+		status.Voice = s.src.Voice()
+	}
+	if s.src.LLMModel != nil {
+		status.LLMModel = s.src.LLMModel()
+	}
+	if s.src.LastTranscript != nil {
+		status.LastTranscript = s.src.LastTranscript()
+	}
+	return status
+}
+
+// Health reports whether every component is working. With no
+// [Sources.Ready], the server being up is all there is to report.
+func (s *Server) Health() Health {
+	h := Health{OK: true, Components: map[string]bool{}}
+	if s.src.Ready != nil {
+		h.Components = s.src.Ready()
+	}
+	for _, ready := range h.Components {
+		h.OK = h.OK && ready
+	}
+	return h
+}
+
+// Latencies returns the median and 95th percentile time of each turn stage so
+// far; it is empty unless [Sources.Metrics] is set.
+func (s *Server) Latencies() []StageLatency {
+	out := []StageLatency{}
+	for _, sum := range s.src.Metrics.Snapshot() {
+		out = append(out, StageLatency{
+			Stage: sum.Stage.String(),
+			Turns: sum.Turns,
+			P50Ms: milliseconds(sum.P50),
+			P95Ms: milliseconds(sum.P95),
+		})
+	}
+	return out
+}
+
+// handleStatus serves GET /status.
+func (s *Server) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.Status())
+}
+
+// handleHealth serves GET /healthz: 200 when every component is initialized,
+// 503 otherwise.
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	h := s.Health()
+	code := http.StatusOK
+	if !h.OK {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, h)
+}
+
+// handleMetrics serves GET /metrics.
+func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.Latencies())
+}
+
+// handleTranscribe serves POST /transcribe: the body is a WAV file (16-bit PCM
+// or 32-bit float, any rate, downmixed to mono), the reply its transcript.
+func (s *Server) handleTranscribe(w http.ResponseWriter, r *http.Request) {
+	buf, err := audio.DecodeWAV(http.MaxBytesReader(w, r.Body, maxAudioUpload))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid WAV body: %w", err))
+		return
+	}
+	text, err := s.src.Transcribe(buf)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Transcription{Text: text})
+}
+
+// handleSpeak serves POST /speak: the body is a [SpeakRequest], the reply the
+// synthesized speech as a 16-bit PCM WAV file.
+func (s *Server) handleSpeak(w http.ResponseWriter, r *http.Request) {
+	var req SpeakRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSpeakRequest)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON body: %w", err))
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("text is empty"))
+		return
+	}
+	buf, err := s.src.Speak(r.Context(), req.Text)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "audio/wav")
+	if err := audio.EncodeWAV(w, buf); err != nil {
+		log.Printf("⚠️ Failed to write HTTP audio response: %v", err)
+	}
+}
+
+// limiter bounds the concurrent requests to one engine.
+type limiter struct {
+	engine string        // Engine name used in the 429 response
+	slots  chan struct{} // Holds one token per request in progress
+}
+
+// newLimiter returns a limiter admitting n concurrent requests (at least one).
+func newLimiter(engine string, n int) limiter {
+	return limiter{engine: engine, slots: make(chan struct{}, max(n, 1))}
+}
+
+// wrap admits requests to h while a slot is free and rejects the rest with 429
+// Too Many Requests rather than queuing them on the engine.
+func (l limiter) wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case l.slots <- struct{}{}:
+			defer func() { <-l.slots }()
+			h.ServeHTTP(w, r)
+		default:
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusTooManyRequests, fmt.Errorf("%s engine is busy (%d request(s) in progress)", l.engine, cap(l.slots)))
+		}
+	})
+}
+
+// milliseconds converts d to fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// writeError replies with err as a JSON {"error": ...} document.
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// writeJSON encodes v as the JSON response body with the given status code.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("⚠️ Failed to encode HTTP response: %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+	"github.com/agalue/sherpa-voice-assistant/internal/metrics"
+	"github.com/agalue/sherpa-voice-assistant/internal/state"
+)
+
+func TestStatusReportsResolvedConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.STTProvider = "cuda"
+	cfg.TTSProvider = "cpu"
+	cfg.STTThreads = 2
+
+	s := New("127.0.0.1:0", cfg, Sources{})
+	s.RecordInteraction()
+	s.RecordInteraction()
+
+	rec := httptest.NewRecorder()
+	s.srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want 200", rec.Code)
+	}
+	var got Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.Providers.STT != "cuda" || got.Providers.TTS != "cpu" {
+		t.Errorf("providers = %+v, want stt=cuda tts=cpu", got.Providers)
+	}
+	if got.Threads.STT != 2 {
+		t.Errorf("stt threads = %d, want 2", got.Threads.STT)
+	}
+	if got.Interactions != 2 {
+		t.Errorf("interactions = %d, want 2", got.Interactions)
+	}
+}
+
+func TestStatusReportsStateAndLastTranscript(t *testing.T) {
+	s := New("127.0.0.1:0", config.DefaultConfig(), Sources{
+		State:          func() state.State { return state.Speaking },
+		Voice:          func() string { return "ef_dora" },
+		LLMModel:       func() string { return "qwen2.5:7b" },
+		LastTranscript: func() string { return "what time is it" },
+	})
+
+	rec := httptest.NewRecorder()
+	s.srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	var got Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.State != "speaking" || got.LastTranscript != "what time is it" {
+		t.Errorf("state = %q, last transcript = %q; want %q, %q", got.State, got.LastTranscript, "speaking", "what time is it")
+	}
+	if got.Voice != "ef_dora" {
+		t.Errorf("voice = %q, want the voice in use", got.Voice)
+	}
+	if got.LLMModel != "qwen2.5:7b" {
+		t.Errorf("llm model = %q, want the model in use", got.LLMModel)
+	}
+}
+
+func TestHealthzReportsUninitializedComponents(t *testing.T) {
+	ready := map[string]bool{"llm": true, "stt": true, "tts": false}
+	s := New("127.0.0.1:0", config.DefaultConfig(), Sources{Ready: func() map[string]bool { return ready }})
+
+	rec := httptest.NewRecorder()
+	s.srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/healthz code with TTS down = %d, want 503", rec.Code)
+	}
+	var got Health
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.OK || got.Components["tts"] {
+		t.Errorf("health = %+v, want not ok with tts=false", got)
+	}
+
+	ready["tts"] = true
+	rec = httptest.NewRecorder()
+	s.srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/healthz code = %d, want 200", rec.Code)
+	}
+}
+
+func TestMetricsReportsStageLatencies(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New("127.0.0.1:0", config.DefaultConfig(), Sources{}).srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if body := rec.Body.String(); body != "[]\n" {
+		t.Errorf("/metrics without --metrics = %q, want an empty list", body)
+	}
+
+	m := metrics.New()
+	m.Observe(metrics.STT, 300*time.Millisecond)
+	m.Observe(metrics.LLM, 1500*time.Millisecond)
+	m.EndTurn()
+
+	rec = httptest.NewRecorder()
+	New("127.0.0.1:0", config.DefaultConfig(), Sources{Metrics: m}).srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	var got []StageLatency
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	want := []StageLatency{{Stage: "stt", Turns: 1, P50Ms: 300, P95Ms: 300}, {Stage: "llm", Turns: 1, P50Ms: 1500, P95Ms: 1500}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("/metrics = %+v, want %+v", got, want)
+	}
+}
+
+func TestStatusRejectsWrites(t *testing.T) {
+	s := New("127.0.0.1:0", config.DefaultConfig(), Sources{})
+
+	rec := httptest.NewRecorder()
+	s.srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/status", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /status code = %d, want 405", rec.Code)
+	}
+}
+
+func TestTranscribeAndSpeak(t *testing.T) {
+	s := New("127.0.0.1:0", config.DefaultConfig(), Sources{
+		Transcribe: func(buf audio.AudioBuffer) (string, error) {
+			return fmt.Sprintf("%d samples at %d Hz", len(buf.Samples), buf.SampleRate), nil
+		},
+		Speak: func(_ context.Context, text string) (audio.AudioBuffer, error) {
+			return audio.AudioBuffer{Samples: make([]float32, len(text)), SampleRate: 24000}, nil
+		},
+	})
+
+	var wav bytes.Buffer
+	if err := audio.EncodeWAV(&wav, audio.AudioBuffer{Samples: make([]float32, 160), SampleRate: 16000}); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	s.srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/transcribe", &wav))
+	var got Transcription
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("/transcribe = %d %q", rec.Code, rec.Body.String())
+	}
+	if got.Text != "160 samples at 16000 Hz" {
+		t.Errorf("transcript = %q, want the decoded WAV", got.Text)
+	}
+
+	rec = httptest.NewRecorder()
+	s.srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/speak", strings.NewReader(`{"text":"hello"}`)))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "audio/wav" {
+		t.Fatalf("/speak = %d, %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	buf, err := audio.DecodeWAV(rec.Body)
+	if err != nil || len(buf.Samples) != 5 || buf.SampleRate != 24000 {
+		t.Errorf("/speak WAV = %d samples at %d Hz (%v), want 5 at 24000 Hz", len(buf.Samples), buf.SampleRate, err)
+	}
+
+	for path, body := range map[string]string{"/transcribe": "not audio", "/speak": `{"text":" "}`} {
+		rec = httptest.NewRecorder()
+		s.srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s with %q = %d, want 400", path, body, rec.Code)
+		}
+	}
+}
+
+func TestInferenceEndpointsOnlyWithSources(t *testing.T) {
+	s := New("127.0.0.1:0", config.DefaultConfig(), Sources{})
+	for _, path := range []string{"/transcribe", "/speak"} {
+		rec := httptest.NewRecorder()
+		s.srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("POST %s without a source = %d, want 404", path, rec.Code)
+		}
+	}
+}
+
+func TestInferenceEndpointsLimitConcurrencyPerEngine(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.MaxConcurrentRequests = 1
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	s := New("127.0.0.1:0", cfg, Sources{
+		Speak: func(context.Context, string) (audio.AudioBuffer, error) {
+			entered <- struct{}{}
+			<-release
+			return audio.AudioBuffer{SampleRate: 16000}, nil
+		},
+		Transcribe: func(audio.AudioBuffer) (string, error) { return "ok", nil },
+	})
+	speak := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/speak", strings.NewReader(`{"text":"hi"}`)))
+		return rec
+	}
+
+	// Occupy the only TTS slot.
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- speak() }()
+	<-entered
+
+	if rec := speak(); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("second /speak = %d (Retry-After %q), want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// STT has its own limit and is unaffected by the busy TTS engine.
+	var wav bytes.Buffer
+	if err := audio.EncodeWAV(&wav, audio.AudioBuffer{Samples: make([]float32, 16), SampleRate: 16000}); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	s.srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/transcribe", &wav))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/transcribe while TTS is busy = %d, want 200", rec.Code)
+	}
+
+	close(release)
+	if first := <-done; first.Code != http.StatusOK {
+		t.Errorf("first /speak = %d, want 200", first.Code)
+	}
+	// The slot is free again once the first request finishes.
+	go func() { <-entered }()
+	if rec := speak(); rec.Code != http.StatusOK {
+		t.Errorf("/speak after release = %d, want 200", rec.Code)
+	}
+}
+
+func TestShutdownTwice(t *testing.T) {
+	s := New("127.0.0.1:0", config.DefaultConfig(), Sources{})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("first Shutdown: %v", err)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown: %v", err)
+	}
+}
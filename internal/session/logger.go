@@ -0,0 +1,134 @@
+// Package session records conversation transcripts to disk.
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transcript formats accepted by [Open].
+const (
+	FormatJSONL    = "jsonl"    // One JSON object per line, for machines
+	FormatText     = "text"     // Human-readable plain text
+	FormatMarkdown = "markdown" // Markdown with role headers, for sharing
+)
+
+// Turn is one request/response exchange. Every format serializes the same data.
+type Turn struct {
+	Time      time.Time `json:"time"`
+	User      string    `json:"user"`
+	Assistant string    `json:"assistant"`
+	Language  string    `json:"language,omitempty"` // Language speech recognition detected (e.g. "en")
+	Voice     string    `json:"voice,omitempty"`    // TTS voice the reply was spoken with
+	Injected  bool      `json:"injected,omitempty"` // User text was made up, not heard (e.g. for a bare wake word)
+}
+
+// details returns the language and voice of t for the text formats, e.g.
+// " (language en, voice af_bella)", or "" when neither is known. Injected turns
+// are marked " (injected)".
+func (t Turn) details() string {
+	var parts []string
+	if t.Injected {
+		parts = append(parts, "injected")
+	}
+	if t.Language != "" {
+		parts = append(parts, "language "+t.Language)
+	}
+	if t.Voice != "" {
+		parts = append(parts, "voice "+t.Voice)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// formatter writes a single turn in a specific transcript format.
+type formatter interface {
+	writeTurn(w io.Writer, t Turn) error
+}
+
+// newFormatter returns the formatter for format.
+func newFormatter(format string) (formatter, error) {
+	switch format {
+	case FormatJSONL:
+		return jsonlFormatter{}, nil
+	case FormatText:
+		return textFormatter{}, nil
+	case FormatMarkdown:
+		return markdownFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("invalid transcript format: %q (must be %q, %q or %q)", format, FormatJSONL, FormatText, FormatMarkdown)
+	}
+}
+
+type jsonlFormatter struct{}
+
+func (jsonlFormatter) writeTurn(w io.Writer, t Turn) error {
+	// Encode appends the trailing newline
+	return json.NewEncoder(w).Encode(t)
+}
+
+type textFormatter struct{}
+
+func (textFormatter) writeTurn(w io.Writer, t Turn) error {
+	_, err := fmt.Fprintf(w, "[%s]%s\nUser: %s\nAssistant: %s\n\n", t.Time.Format(time.RFC3339), t.details(), t.User, t.Assistant)
+	return err
+}
+
+type markdownFormatter struct{}
+
+func (markdownFormatter) writeTurn(w io.Writer, t Turn) error {
+	_, err := fmt.Fprintf(w, "## %s%s\n\n**User:** %s\n\n**Assistant:** %s\n\n", t.Time.Format(time.RFC3339), t.details(), t.User, t.Assistant)
+	return err
+}
+
+// Logger appends conversation turns to a transcript file. It is safe for
+// concurrent use.
+type Logger struct {
+	mu   sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+	fmt  formatter
+}
+
+// Open opens (or creates) the transcript at path for appending, serializing
+// turns in the given format.
+func Open(path, format string) (*Logger, error) {
+	f, err := newFormatter(format)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript log: %w", err)
+	}
+	return &Logger{file: file, w: bufio.NewWriter(file), fmt: f}, nil
+}
+
+// Log appends a turn and flushes it, so a crash loses at most the turn in flight.
+func (l *Logger) Log(t Turn) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.fmt.writeTurn(l.w, t); err != nil {
+		return err
+	}
+	return l.w.Flush()
+}
+
+// Close flushes and closes the transcript file.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	flushErr := l.w.Flush()
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	return flushErr
+}
@@ -0,0 +1,149 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func logTurns(t *testing.T, format string, turns ...Turn) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "transcript")
+	l, err := Open(path, format)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for _, turn := range turns {
+		if err := l.Log(turn); err != nil {
+			t.Fatalf("Log: %v", err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+var testTurn = Turn{
+	Time:      time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC),
+	User:      "what time is it",
+	Assistant: "It is half past nine.",
+}
+
+func TestLoggerJSONL(t *testing.T) {
+	out := logTurns(t, FormatJSONL, testTurn, testTurn)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), out)
+	}
+	var got Turn
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", lines[0], err)
+	}
+	if !got.Time.Equal(testTurn.Time) || got.User != testTurn.User || got.Assistant != testTurn.Assistant {
+		t.Errorf("round trip = %+v, want %+v", got, testTurn)
+	}
+}
+
+func TestLoggerText(t *testing.T) {
+	out := logTurns(t, FormatText, testTurn)
+	want := "[2025-03-01T09:30:00Z]\nUser: what time is it\nAssistant: It is half past nine.\n\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestLoggerMarkdown(t *testing.T) {
+	out := logTurns(t, FormatMarkdown, testTurn)
+	want := "## 2025-03-01T09:30:00Z\n\n**User:** what time is it\n\n**Assistant:** It is half past nine.\n\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestLoggerAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.jsonl")
+	for range 2 {
+		l, err := Open(path, FormatJSONL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Log(testTurn); err != nil {
+			t.Fatal(err)
+		}
+		l.Close()
+	}
+	data, _ := os.ReadFile(path)
+	if n := strings.Count(string(data), "\n"); n != 2 {
+		t.Errorf("got %d lines after reopening, want 2", n)
+	}
+}
+
+func TestOpenInvalidFormat(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "x"), "yaml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestLoggerRecordsLanguageAndVoice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.jsonl")
+	l, err := Open(path, FormatJSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{{"es", "ef_dora"}, {"", "af_bella"}, {"", ""}}
+	for _, details := range want {
+		turn := testTurn
+		turn.Language, turn.Voice = details[0], details[1]
+		if err := l.Log(turn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("logged %d turns, want %d", len(lines), len(want))
+	}
+	for i, line := range lines {
+		var got Turn
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		if got.Language != want[i][0] || got.Voice != want[i][1] {
+			t.Errorf("turn %d: language %q, voice %q, want %q, %q", i, got.Language, got.Voice, want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestLoggerTextIncludesDetails(t *testing.T) {
+	turn := testTurn
+	turn.Language, turn.Voice = "en", "af_bella"
+	out := logTurns(t, FormatText, turn)
+	want := "[2025-03-01T09:30:00Z] (language en, voice af_bella)\nUser: what time is it\nAssistant: It is half past nine.\n\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestLoggerMarksInjectedTurns(t *testing.T) {
+	turn := testTurn
+	turn.User, turn.Language, turn.Injected = "Hello", "en", true
+	if out := logTurns(t, FormatText, turn); !strings.HasPrefix(out, "[2025-03-01T09:30:00Z] (injected, language en)\nUser: Hello\n") {
+		t.Errorf("text = %q, want the turn marked as injected", out)
+	}
+	if out := logTurns(t, FormatJSONL, turn); !strings.Contains(out, `"injected":true`) {
+		t.Errorf("jsonl = %q, want the injected flag", out)
+	}
+	if out := logTurns(t, FormatJSONL, testTurn); strings.Contains(out, "injected") {
+		t.Errorf("jsonl = %q, want no flag on a heard turn", out)
+	}
+}
@@ -0,0 +1,145 @@
+// Package state tracks what the voice assistant is doing (listening, thinking,
+// speaking, ...) so status endpoints, LED indicators or UIs can follow it.
+//
+// A nil *Manager is valid and tracks nothing, so processors can report
+// transitions unconditionally.
+package state
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// State is what the assistant is doing. The pipeline overlaps its stages, so
+// the state is that of the most recent transition: e.g. speech the user
+// starts while a reply plays is reported once playback has stopped.
+type State int
+
+// States, in the order a turn goes through them.
+const (
+	Idle         State = iota // Waiting for speech
+	Listening                 // The user is speaking
+	Transcribing              // Turning the user's speech into text
+	Thinking                  // Waiting for the LLM's reply
+	Speaking                  // Playing a response
+)
+
+// String returns the state's name as used in logs and the status server ("idle").
+func (s State) String() string {
+	switch s {
+	case Idle:
+		return "idle"
+	case Listening:
+		return "listening"
+	case Transcribing:
+		return "transcribing"
+	case Thinking:
+		return "thinking"
+	case Speaking:
+		return "speaking"
+	default:
+		return fmt.Sprintf("state(%d)", int(s))
+	}
+}
+
+// Manager holds the current state and publishes every transition to its
+// subscribers. It is safe for concurrent use.
+type Manager struct {
+	mu      sync.Mutex
+	current State
+	subs    []chan State
+	verbose bool // Log each transition
+}
+
+// NewManager returns a Manager in the Idle state that logs transitions when
+// verbose is set.
+func NewManager(verbose bool) *Manager {
+	return &Manager{verbose: verbose}
+}
+
+// Current returns the current state; Idle for a nil Manager.
+func (m *Manager) Current() State {
+	if m == nil {
+		return Idle
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.current
+}
+
+// Set moves to s, publishing the transition unless s is the current state.
+func (m *Manager) Set(s State) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.move(s)
+}
+
+// Transition moves from one state to another, reporting whether the
+// assistant was in from. It is for leaving a state only if nothing else has
+// happened since it was entered, e.g. going back to Idle once playback ends
+// unless the user has started speaking.
+func (m *Manager) Transition(from, to State) bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current != from {
+		return false
+	}
+	m.move(to)
+	return true
+}
+
+// Subscribe returns a channel that receives the current state and then every
+// transition. A subscriber that falls behind skips to the latest state rather
+// than blocking the assistant. The channel is closed by [Manager.Close]; a nil
+// Manager returns a nil channel.
+func (m *Manager) Subscribe() <-chan State {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ch := make(chan State, 1)
+	ch <- m.current
+	m.subs = append(m.subs, ch)
+	return ch
+}
+
+// Close closes the channels of all subscribers. Transitions after Close are
+// still tracked but no longer published.
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, ch := range m.subs {
+		close(ch)
+	}
+	m.subs = nil
+}
+
+// move sets the current state to s and publishes it. m.mu must be held.
+func (m *Manager) move(s State) {
+	if s == m.current {
+		return
+	}
+	if m.verbose {
+		log.Printf("[State] %s → %s", m.current, s)
+	}
+	m.current = s
+	for _, ch := range m.subs {
+		// Replace a state the subscriber has not received yet.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- s
+	}
+}
@@ -0,0 +1,55 @@
+package state
+
+import "testing"
+
+func TestManagerPublishesTransitions(t *testing.T) {
+	m := NewManager(false)
+	ch := m.Subscribe()
+	if got := <-ch; got != Idle {
+		t.Fatalf("first state = %s, want idle", got)
+	}
+
+	m.Set(Listening)
+	if got := <-ch; got != Listening {
+		t.Errorf("state = %s, want listening", got)
+	}
+
+	// A subscriber that falls behind gets the latest state only.
+	m.Set(Transcribing)
+	m.Set(Thinking)
+	if got := <-ch; got != Thinking {
+		t.Errorf("state after falling behind = %s, want thinking", got)
+	}
+
+	m.Set(Thinking) // Not a transition: nothing published
+	select {
+	case got := <-ch:
+		t.Errorf("setting the current state again published %s", got)
+	default:
+	}
+
+	m.Close()
+	if _, ok := <-ch; ok {
+		t.Error("channel still open after Close")
+	}
+}
+
+func TestManagerTransitionOnlyFromState(t *testing.T) {
+	m := NewManager(false)
+	m.Set(Speaking)
+	if m.Transition(Thinking, Idle) || m.Current() != Speaking {
+		t.Errorf("Transition from thinking while speaking moved to %s", m.Current())
+	}
+	if !m.Transition(Speaking, Idle) || m.Current() != Idle {
+		t.Errorf("Transition from speaking left the state at %s, want idle", m.Current())
+	}
+}
+
+func TestNilManager(t *testing.T) {
+	var m *Manager
+	m.Set(Speaking)
+	if m.Transition(Idle, Speaking) || m.Current() != Idle || m.Subscribe() != nil {
+		t.Error("nil Manager should track nothing")
+	}
+	m.Close()
+}
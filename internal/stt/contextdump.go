@@ -0,0 +1,111 @@
+package stt
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+)
+
+// contextDumpSlack is extra history kept beyond the largest dump window.
+const contextDumpSlack = 2 * time.Second
+
+// ContextDumper saves each transcribed turn to a WAV file together with the raw
+// audio around it: padding seconds before the VAD segment, the segment, and
+// padding seconds after it. Comparing the dump with the transcript shows whether
+// the VAD clipped the onset or the microphone itself was the problem.
+//
+// Feed [ContextDumper.Write] from a [audio.Capturer.AddTap]. A nil ContextDumper
+// is valid and does nothing.
+type ContextDumper struct {
+	history    *audio.History
+	dir        string
+	sampleRate int
+	padding    int // Samples of context on each side of a segment
+}
+
+// NewContextDumper creates dir if needed and returns a dumper adding padding of
+// context around segments of up to maxSegment.
+func NewContextDumper(dir string, sampleRate int, padding, maxSegment time.Duration) (*ContextDumper, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create context dump directory: %w", err)
+	}
+	pad := int(padding.Seconds() * float64(sampleRate))
+	retain := maxSegment + contextDumpSlack
+	return &ContextDumper{
+		history:    audio.NewHistory(int(retain.Seconds()*float64(sampleRate)) + 2*pad),
+		dir:        dir,
+		sampleRate: sampleRate,
+		padding:    pad,
+	}, nil
+}
+
+// Bytes returns the memory held by the rolling history.
+func (d *ContextDumper) Bytes() int64 {
+	if d == nil {
+		return 0
+	}
+	return d.history.Bytes()
+}
+
+// Write records captured samples (at sampleRate) into the rolling history.
+func (d *ContextDumper) Write(samples []float32) {
+	d.history.Write(samples)
+}
+
+// Mark returns the history position at which a VAD segment was received. The
+// segment's speech lies just before it (followed by the VAD's trailing silence).
+func (d *ContextDumper) Mark() uint64 {
+	if d == nil {
+		return 0
+	}
+	return d.history.Position()
+}
+
+// Dump writes the segment of segmentLen samples ending at mark, with its context,
+// once the trailing padding has been captured. It returns immediately; the file
+// is written in the background and text is logged alongside its path.
+func (d *ContextDumper) Dump(mark uint64, segmentLen int, text string) {
+	if d == nil {
+		return
+	}
+	from := mark - min(mark, uint64(segmentLen+d.padding))
+	to := mark + uint64(d.padding)
+	go func() {
+		// Wait for the trailing context; give up on it if capture is paused.
+		wait := time.Duration(d.padding)*time.Second/time.Duration(d.sampleRate) + time.Second
+		deadline := time.Now().Add(wait)
+		for d.history.Position() < to && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		path := filepath.Join(d.dir, "turn-"+time.Now().Format("20060102-150405.000")+".wav")
+		if err := d.save(path, d.history.Range(from, to)); err != nil {
+			log.Printf("⚠️ Context dump failed: %v", err)
+			return
+		}
+		log.Printf("💾 Context dump: %s (%q)", path, text)
+	}()
+}
+
+// save writes samples to path as a WAV file. The file is written under a
+// temporary name first so a partially written dump never appears at path.
+func (d *ContextDumper) save(path string, samples []float32) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = audio.EncodeWAV(f, audio.AudioBuffer{Samples: samples, SampleRate: d.sampleRate})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
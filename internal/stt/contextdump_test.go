@@ -0,0 +1,59 @@
+package stt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+)
+
+func TestContextDumperSavesSegmentWithContext(t *testing.T) {
+	const rate = 100 // 1 sample = 10ms keeps the arithmetic readable
+	dir := t.TempDir()
+	d, err := NewContextDumper(dir, rate, 100*time.Millisecond, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make([]float32, 50)
+	for i := range samples {
+		samples[i] = float32(i) / 100
+	}
+	d.Write(samples[:40])
+	mark := d.Mark()
+	d.Dump(mark, 20, "hello") // Segment is samples 20..39
+	d.Write(samples[40:])     // Trailing context arrives after the segment fires
+
+	var files []string
+	for deadline := time.Now().Add(3 * time.Second); len(files) == 0 && time.Now().Before(deadline); {
+		time.Sleep(20 * time.Millisecond)
+		files, _ = filepath.Glob(filepath.Join(dir, "*.wav"))
+	}
+	if len(files) != 1 {
+		t.Fatalf("found %d dump(s), want 1", len(files))
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	buf, err := audio.DecodeWAV(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10 samples of pre-roll, the 20-sample segment and 10 samples of trailing audio
+	if len(buf.Samples) != 40 || buf.SampleRate != rate {
+		t.Fatalf("dump has %d samples at %d Hz, want 40 at %d Hz", len(buf.Samples), buf.SampleRate, rate)
+	}
+	if first := buf.Samples[0]; first < 0.095 || first > 0.105 {
+		t.Errorf("first sample = %v, want ~0.10 (sample 10)", first)
+	}
+}
+
+func TestNilContextDumperIsNoop(t *testing.T) {
+	var d *ContextDumper
+	d.Dump(d.Mark(), 100, "ignored")
+}
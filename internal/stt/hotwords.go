@@ -0,0 +1,101 @@
+package stt
+
+import (
+	"log"
+	"strings"
+	"unicode"
+)
+
+// hotwordTolerance is the largest edit distance, as a fraction of a phrase's
+// length, at which a transcript is taken to be a misrecognition of the phrase.
+const hotwordTolerance = 0.2
+
+// hotwordBias snaps transcripts that nearly match an expected phrase to that
+// phrase. sherpa-onnx offers no hotword or initial-prompt biasing for Whisper,
+// so the bias is applied to the decoded text instead: "light on" becomes
+// "lights on" when that is an expected phrase.
+type hotwordBias struct {
+	phrases []string // Phrases as written, returned on a match
+	keys    []string // Normalized phrases, compared against transcripts
+}
+
+// newHotwordBias returns nil when phrases is empty (no biasing).
+func newHotwordBias(phrases []string) *hotwordBias {
+	b := &hotwordBias{}
+	for _, p := range phrases {
+		if key := normalizePhrase(p); key != "" {
+			b.phrases = append(b.phrases, strings.TrimSpace(p))
+			b.keys = append(b.keys, key)
+		}
+	}
+	if len(b.phrases) == 0 {
+		return nil
+	}
+	return b
+}
+
+// apply returns the expected phrase closest to text when it is within
+// hotwordTolerance of it, and text unchanged otherwise (including when it
+// already matches a phrase up to case and punctuation). Ties go to the phrase
+// listed first. A nil bias passes text through unchanged.
+func (b *hotwordBias) apply(text string) string {
+	if b == nil || text == "" {
+		return text
+	}
+	key := normalizePhrase(text)
+	best, bestDist := -1, 0
+	for i, k := range b.keys {
+		d := editDistance(key, k)
+		if float64(d) > hotwordTolerance*float64(len([]rune(k))) {
+			continue
+		}
+		if best < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	if best < 0 || bestDist == 0 {
+		return text // No expected phrase, or already recognized correctly
+	}
+	log.Printf("🎯 Taken as expected phrase: %s", b.phrases[best])
+	return b.phrases[best]
+}
+
+// normalizePhrase lowercases s, drops punctuation and collapses whitespace, so
+// "Lights on!" and "lights  on" compare equal.
+func normalizePhrase(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return unicode.ToLower(r)
+		case unicode.IsSpace(r):
+			return ' '
+		case r == '\'':
+			return -1
+		default:
+			return ' '
+		}
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// editDistance returns the Levenshtein distance between a and b in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,49 @@
+package stt
+
+import "testing"
+
+func TestHotwordBias(t *testing.T) {
+	b := newHotwordBias([]string{"lights on", "lights off", "Play music", "  "})
+	tests := []struct {
+		text, want string
+	}{
+		{"light on", "lights on"},      // Misrecognition within tolerance
+		{"Lights on!", "Lights on!"},   // Already correct: left as spoken
+		{"lights offf", "lights off"},  // Not confused with "lights on"
+		{"play music.", "play music."}, // Case and punctuation ignored
+		{"Play musik", "Play music"},   // Phrase returned as written
+		{"what's the weather", "what's the weather"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := b.apply(tt.text); got != tt.want {
+			t.Errorf("apply(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	if newHotwordBias([]string{"", " ? "}) != nil {
+		t.Error("phrases without words should disable biasing")
+	}
+	var none *hotwordBias
+	if got := none.apply("light on"); got != "light on" {
+		t.Errorf("nil bias changed text to %q", got)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"lights on", "lights off", 2},
+		{"café", "cafe", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package stt
+
+// onsetSlackSeconds is extra history kept beyond the pre-speech pad to cover the
+// delay between the true speech onset and the VAD reporting speech.
+const onsetSlackSeconds = 1.0
+
+// onsetBuffer keeps a short history of the audio fed to the VAD so that audio
+// just before a segment's start can be prepended to it. The VAD only reports a
+// segment once speech has ended, long after that audio has scrolled out of a
+// small ring, so the ring is snapshotted when speech is first detected.
+//
+// Positions are absolute sample indices since the detector was created, matching
+// the Start offsets the VAD reports for its segments. Not safe for concurrent use.
+type onsetBuffer struct {
+	pad      int       // Samples to prepend to each segment
+	ring     []float32 // Most recent audio (pad + slack samples)
+	pos      int       // Next write index in ring
+	fed      int       // Total samples written
+	onset    []float32 // Chronological copy of ring taken at speech start
+	onsetEnd int       // Absolute index just after the last sample in onset
+}
+
+// newOnsetBuffer returns a buffer that prepends padSamples of lookback, keeping
+// slackSamples of extra history for VAD detection latency.
+func newOnsetBuffer(padSamples, slackSamples int) *onsetBuffer {
+	size := padSamples + slackSamples
+	return &onsetBuffer{
+		pad:   padSamples,
+		ring:  make([]float32, size),
+		onset: make([]float32, 0, size),
+	}
+}
+
+// write appends samples to the history.
+func (b *onsetBuffer) write(samples []float32) {
+	for _, s := range samples {
+		b.ring[b.pos] = s
+		b.pos = (b.pos + 1) % len(b.ring)
+	}
+	b.fed += len(samples)
+}
+
+// snapshot preserves the current history; call it when speech starts.
+func (b *onsetBuffer) snapshot() {
+	n := min(b.fed, len(b.ring))
+	start := (b.pos - n + len(b.ring)) % len(b.ring)
+	b.onset = b.onset[:0]
+	if start+n <= len(b.ring) {
+		b.onset = append(b.onset, b.ring[start:start+n]...)
+	} else {
+		b.onset = append(b.onset, b.ring[start:]...)
+		b.onset = append(b.onset, b.ring[:b.pos]...)
+	}
+	b.onsetEnd = b.fed
+}
+
+// reset discards all history, restarting absolute positions at zero to match a
+// newly created detector.
+func (b *onsetBuffer) reset() {
+	clear(b.ring)
+	b.pos, b.fed = 0, 0
+	b.onset = b.onset[:0]
+	b.onsetEnd = 0
+}
+
+// before returns up to pad samples that immediately precede absolute position
+// start, or nil if the last snapshot does not cover them. The result aliases
+// the snapshot and must be copied before the next call to snapshot.
+func (b *onsetBuffer) before(start int) []float32 {
+	onsetStart := b.onsetEnd - len(b.onset)
+	from := max(start-b.pad, onsetStart)
+	if start > b.onsetEnd || from >= start {
+		return nil
+	}
+	return b.onset[from-onsetStart : start-onsetStart]
+}
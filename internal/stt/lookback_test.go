@@ -0,0 +1,65 @@
+package stt
+
+import (
+	"slices"
+	"testing"
+)
+
+// ramp returns n samples whose values equal their absolute index, starting at from.
+func ramp(from, n int) []float32 {
+	s := make([]float32, n)
+	for i := range s {
+		s[i] = float32(from + i)
+	}
+	return s
+}
+
+func TestOnsetBufferBefore(t *testing.T) {
+	b := newOnsetBuffer(3, 5) // ring of 8 samples
+	b.write(ramp(0, 10))
+	b.write(ramp(10, 10)) // wraps several times; ring now holds 12..19
+	b.snapshot()
+	b.write(ramp(20, 50)) // later audio must not affect the snapshot
+
+	if got, want := b.before(15), ramp(12, 3); !slices.Equal(got, want) {
+		t.Errorf("before(15) = %v, want %v", got, want)
+	}
+	// Only part of the pad is available before the oldest retained sample.
+	if got, want := b.before(13), ramp(12, 1); !slices.Equal(got, want) {
+		t.Errorf("before(13) = %v, want %v", got, want)
+	}
+	// Segment start after the snapshot (e.g. a long utterance split by the VAD).
+	if got := b.before(30); got != nil {
+		t.Errorf("before(30) = %v, want nil", got)
+	}
+}
+
+func TestOnsetBufferShortHistory(t *testing.T) {
+	b := newOnsetBuffer(4, 4)
+	b.write(ramp(0, 3))
+	b.snapshot()
+
+	if got, want := b.before(2), ramp(0, 2); !slices.Equal(got, want) {
+		t.Errorf("before(2) = %v, want %v", got, want)
+	}
+	if got := b.before(0); got != nil {
+		t.Errorf("before(0) = %v, want nil", got)
+	}
+}
+
+func TestOnsetBufferReset(t *testing.T) {
+	b := newOnsetBuffer(3, 5)
+	b.write(ramp(0, 20))
+	b.snapshot()
+	b.reset()
+
+	// Positions restart at zero, as they do in a freshly built detector.
+	if got := b.before(15); got != nil {
+		t.Errorf("before(15) after reset = %v, want nil", got)
+	}
+	b.write(ramp(100, 6))
+	b.snapshot()
+	if got, want := b.before(4), ramp(101, 3); !slices.Equal(got, want) {
+		t.Errorf("before(4) = %v, want %v", got, want)
+	}
+}
@@ -4,8 +4,55 @@ import (
 	"context"
 	"log"
 	"sync/atomic"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+	"github.com/agalue/sherpa-voice-assistant/internal/state"
 )
 
+// turnGap is the silence between consecutive segments after which a new turn starts.
+// It is longer than the VAD silence duration so pauses within a monologue still
+// count toward the same turn.
+const turnGap = 2 * time.Second
+
+// tooLongReply is spoken when a turn exceeds [config.Config.MaxTurnAudioSeconds].
+const tooLongReply = "That was a bit long, could you be briefer?"
+
+// turnTracker accumulates speech duration across the segments of a single turn.
+type turnTracker struct {
+	maxSeconds float64   // Limit per turn (0 = unlimited)
+	seconds    float64   // Speech accumulated in the current turn
+	lastEnd    time.Time // Arrival time of the previous segment
+	warned     bool      // Whether the user was already told about the current turn
+}
+
+// add records a segment of the given duration that arrived at now. It returns
+// exceeded=true when the turn is over the limit (the segment should be rejected)
+// and warn=true the first time that happens within a turn.
+func (t *turnTracker) add(seconds float64, now time.Time) (exceeded, warn bool) {
+	if t.maxSeconds <= 0 {
+		return false, false
+	}
+
+	// Segments arrive once speech has ended, so the silence before this one is
+	// the time since the previous arrival minus this segment's own duration.
+	start := now.Add(-time.Duration(seconds * float64(time.Second)))
+	if t.lastEnd.IsZero() || start.Sub(t.lastEnd) > turnGap {
+		t.seconds = 0
+		t.warned = false
+	}
+	t.lastEnd = now
+	t.seconds += seconds
+
+	if t.seconds <= t.maxSeconds {
+		return false, false
+	}
+	warn = !t.warned
+	t.warned = true
+	return true, warn
+}
+
 // RunProcessor receives speech segments from the VAD channel and sends transcriptions.
 // It accepts the [VoiceDetector] and [Transcriber] interfaces so it is not coupled to
 // any specific STT implementation. It is intended to run as a goroutine and returns
@@ -14,7 +61,28 @@ import (
 // interrupt is set to true when speech is detected (to stop any in-progress playback)
 // and cleared to false after a transcription is successfully forwarded to out, so the
 // next response is not immediately interrupted.
-func RunProcessor(ctx context.Context, detector VoiceDetector, transcriber Transcriber, out chan<- string, interrupt *atomic.Bool, verbose bool) {
+//
+// When cfg.AutoPunctuate is set, transcripts are passed through [Punctuate]
+// unless cfg.HistoryRawTranscript asks for them verbatim. When transcriber is a
+// [LanguageReporter], each transcript carries the language of its segment, and
+// when it is a [VerbatimReporter], the segment as spoken.
+//
+// When gate is non-nil, speech only sets interrupt if the gate reports that it
+// came from in front of the microphone array (see [audio.DirectionGate]).
+//
+// When dumper is non-nil, every transcribed segment is saved with its surrounding
+// audio (see [ContextDumper]).
+//
+// states (nil = not tracked) moves to [state.Transcribing] while a segment is
+// transcribed, and back to [state.Idle] when it holds no speech; once a
+// transcript is forwarded, whoever handles it reports the next state.
+//
+// When cfg.MaxTurnAudioSeconds is set, segments that push the current turn over the
+// limit are dropped and a short request to be briefer is sent to notices, which
+// should feed the TTS processor directly (bypassing the LLM).
+func RunProcessor(ctx context.Context, detector VoiceDetector, transcriber Transcriber, out chan<- Transcript, notices chan<- string, interrupt *atomic.Bool, gate *audio.DirectionGate, dumper *ContextDumper, states *state.Manager, cfg *config.Config) {
+	turn := turnTracker{maxSeconds: float64(cfg.MaxTurnAudioSeconds)}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -23,27 +91,64 @@ func RunProcessor(ctx context.Context, detector VoiceDetector, transcriber Trans
 			if !ok {
 				return
 			}
+			mark := dumper.Mark()
 
 			// Set interrupt flag when new speech arrives to stop any active playback.
 			if detector.IsSpeechDetected() {
-				interrupt.Store(true)
+				if gate.Allows() {
+					interrupt.Store(true)
+				} else if cfg.Verbose {
+					log.Println("[STT] Speech did not come from in front, not interrupting")
+				}
+			}
+
+			duration := float64(len(samples)) / float64(cfg.SampleRate)
+			if exceeded, warn := turn.add(duration, time.Now()); exceeded {
+				log.Printf("⚠️ Turn exceeds %.0fs of speech, dropping %.1fs segment", turn.maxSeconds, duration)
+				// Nothing is forwarded, so clear the interrupt here as below.
+				interrupt.Store(false)
+				if warn {
+					select {
+					case notices <- tooLongReply:
+					case <-ctx.Done():
+						return
+					}
+				}
+				continue
 			}
 
+			states.Set(state.Transcribing)
 			text := transcriber.TranscribeSegment(samples)
 			if text == "" {
+				states.Transition(state.Transcribing, state.Idle)
 				continue
 			}
+			if cfg.AutoPunctuate {
+				text = Punctuate(text) // The verbatim text, if kept, stays as recognized
+			}
+			dumper.Dump(mark, len(samples), text)
 
-			if verbose {
+			if cfg.Verbose {
 				log.Printf("[STT] Transcription received (%d chars)", len(text))
 			}
 
+			transcript := Transcript{Text: text}
+			if detector, ok := transcriber.(LanguageReporter); ok {
+				transcript.Language = detector.LastLanguage()
+			}
+			if reporter, ok := transcriber.(VerbatimReporter); ok {
+				transcript.Verbatim = reporter.LastVerbatim()
+			}
+			if reporter, ok := transcriber.(InjectionReporter); ok {
+				transcript.Injected = reporter.LastInjected()
+			}
+
 			select {
-			case out <- text:
+			case out <- transcript:
 				// Clear interrupt after forwarding so the next response is not
 				// immediately interrupted before it even starts playing.
 				interrupt.Store(false)
-				if verbose {
+				if cfg.Verbose {
 					log.Println("[STT] Transcription sent to LLM processor")
 				}
 			case <-ctx.Done():
@@ -0,0 +1,126 @@
+package stt
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+)
+
+func TestTurnTrackerAccumulatesAcrossShortPauses(t *testing.T) {
+	tr := turnTracker{maxSeconds: 10}
+	now := time.Unix(0, 0)
+
+	// Three 4s segments separated by 1s pauses form one 12s turn.
+	for i, want := range []bool{false, false, true} {
+		now = now.Add(5 * time.Second)
+		exceeded, _ := tr.add(4, now)
+		if exceeded != want {
+			t.Errorf("segment %d: exceeded = %v, want %v", i+1, exceeded, want)
+		}
+	}
+}
+
+func TestTurnTrackerWarnsOncePerTurn(t *testing.T) {
+	tr := turnTracker{maxSeconds: 5}
+	now := time.Unix(0, 0)
+
+	now = now.Add(6 * time.Second)
+	if exceeded, warn := tr.add(6, now); !exceeded || !warn {
+		t.Fatalf("first oversized segment: exceeded=%v warn=%v, want true/true", exceeded, warn)
+	}
+	now = now.Add(2 * time.Second)
+	if exceeded, warn := tr.add(1, now); !exceeded || warn {
+		t.Errorf("follow-up segment: exceeded=%v warn=%v, want true/false", exceeded, warn)
+	}
+}
+
+func TestTurnTrackerResetsAfterLongSilence(t *testing.T) {
+	tr := turnTracker{maxSeconds: 5}
+	now := time.Unix(0, 0)
+
+	now = now.Add(6 * time.Second)
+	tr.add(6, now)
+
+	// A 3s segment after 10s of silence starts a fresh turn.
+	now = now.Add(13 * time.Second)
+	if exceeded, _ := tr.add(3, now); exceeded {
+		t.Error("segment after a long silence should start a new turn")
+	}
+}
+
+func TestTurnTrackerDisabledByDefault(t *testing.T) {
+	var tr turnTracker
+	if exceeded, warn := tr.add(120, time.Now()); exceeded || warn {
+		t.Errorf("zero limit should never reject, got exceeded=%v warn=%v", exceeded, warn)
+	}
+}
+
+// segmentDetector delivers the segments the test sends, with speech detected.
+type segmentDetector struct{ segments chan AudioSegment }
+
+func (d segmentDetector) AcceptWaveform([]float32)            {}
+func (d segmentDetector) SegmentChannel() <-chan AudioSegment { return d.segments }
+func (d segmentDetector) IsSpeechDetected() bool              { return true }
+func (d segmentDetector) Clear()                              {}
+func (d segmentDetector) Close()                              {}
+
+func TestRunProcessorClearsInterruptOnDroppedSegment(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.MaxTurnAudioSeconds = 1
+	detector := segmentDetector{segments: make(chan AudioSegment)}
+	notices := make(chan string, 1)
+	var interrupt atomic.Bool
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go RunProcessor(ctx, detector, nil, make(chan Transcript), notices, &interrupt, nil, nil, nil, cfg)
+
+	detector.segments <- make(AudioSegment, 2*cfg.SampleRate)
+	select {
+	case notice := <-notices:
+		if notice != tooLongReply {
+			t.Errorf("notice = %q, want %q", notice, tooLongReply)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no notice for a segment over the turn limit")
+	}
+	if interrupt.Load() {
+		t.Error("interrupt still set after the segment was dropped")
+	}
+}
+
+// rawTranscriber transcribes every segment as command, reporting verbatim as
+// the text spoken.
+type rawTranscriber struct{ command, verbatim string }
+
+func (r rawTranscriber) TranscribeSegment([]float32) string { return r.command }
+func (r rawTranscriber) LastVerbatim() string               { return r.verbatim }
+func (r rawTranscriber) Close()                             {}
+
+func TestRunProcessorPunctuatesOnlyTheCommandInRawMode(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AutoPunctuate = true
+	cfg.HistoryRawTranscript = true
+	detector := segmentDetector{segments: make(chan AudioSegment)}
+	transcriber := rawTranscriber{command: "what time is it", verbatim: "sherpa what time is it"}
+	out := make(chan Transcript, 1)
+	var interrupt atomic.Bool
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go RunProcessor(ctx, detector, transcriber, out, make(chan string), &interrupt, nil, nil, nil, cfg)
+
+	detector.segments <- make(AudioSegment, cfg.SampleRate/2)
+	select {
+	case got := <-out:
+		want := Transcript{Text: "What time is it?", Verbatim: "sherpa what time is it"}
+		if got != want {
+			t.Errorf("transcript = %+v, want %+v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no transcript for the segment")
+	}
+}
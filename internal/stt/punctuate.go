@@ -0,0 +1,57 @@
+package stt
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// questionWords are leading words that make an unpunctuated English utterance a
+// question ("what time is it", "can you help"). "Don't" is left out because it
+// mostly starts commands ("don't forget").
+var questionWords = map[string]bool{
+	"what": true, "who": true, "whom": true, "whose": true, "where": true,
+	"when": true, "why": true, "how": true, "which": true,
+	"is": true, "are": true, "am": true, "was": true, "were": true,
+	"do": true, "does": true, "did": true, "have": true, "has": true, "had": true,
+	"can": true, "could": true, "will": true, "would": true, "should": true,
+	"shall": true, "may": true, "might": true,
+	"isn't": true, "aren't": true, "wasn't": true, "weren't": true,
+	"doesn't": true, "didn't": true, "can't": true,
+	"won't": true, "wouldn't": true, "shouldn't": true, "couldn't": true,
+}
+
+// Punctuate capitalizes the first letter of a transcript and, when it lacks
+// terminal punctuation, appends a question mark if it starts with an English
+// question word and a period otherwise. Trailing commas, colons and semicolons
+// are replaced. Text that already ends a sentence, or has no words, is left as
+// is.
+func Punctuate(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return text
+	}
+
+	first, size := utf8.DecodeRuneInString(text)
+	text = string(unicode.ToUpper(first)) + text[size:]
+
+	last, _ := utf8.DecodeLastRuneInString(text)
+	switch last {
+	case '.', '!', '?', '…', '。', '！', '？':
+		return text
+	}
+
+	// Punctuation-only text (",", "; :") has no sentence to end.
+	if !strings.ContainsFunc(text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+		return text
+	}
+	text = strings.TrimRight(text, ",;: ")
+
+	firstWord := strings.ToLower(strings.FieldsFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ','
+	})[0])
+	if questionWords[firstWord] {
+		return text + "?"
+	}
+	return text + "."
+}
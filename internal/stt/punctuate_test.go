@@ -0,0 +1,30 @@
+package stt
+
+import "testing"
+
+func TestPunctuate(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"  turn on the lights ", "Turn on the lights."},
+		{"what time is it", "What time is it?"},
+		{"Can you help me", "Can you help me?"},
+		{"don't stop", "Don't stop."},
+		{"doesn't it work", "Doesn't it work?"},
+		{"how, exactly", "How, exactly?"},
+		{"hello there,", "Hello there."},
+		{"already done.", "Already done."},
+		{"really!", "Really!"},
+		{"is it raining?", "Is it raining?"},
+		{"whatever you think", "Whatever you think."},
+		{"élan vital", "Élan vital."},
+		{"...", "..."},
+		{"?!", "?!"},
+		{",", ","},
+		{" ; : ", "; :"},
+	}
+	for _, tt := range tests {
+		if got := Punctuate(tt.in); got != tt.want {
+			t.Errorf("Punctuate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package stt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/sherpa"
+)
+
+// SpeechSegment is a span of speech found by [SegmentAudio].
+type SpeechSegment struct {
+	Start   time.Duration // Offset of the first sample within the audio
+	Samples AudioSegment
+}
+
+// SegmentAudio splits recorded audio at cfg.SampleRate into speech segments
+// with the Silero VAD, as live microphone input would be split, including the
+// pre-speech padding. Speech still running at the end of samples is returned
+// as a final segment. It is meant for offline transcription of audio files.
+func SegmentAudio(cfg *SileroConfig, samples []float32) ([]SpeechSegment, error) {
+	// The whole file may be one long stretch of speech, so the buffer must
+	// hold all of it at once.
+	_, maxSpeech := cfg.speechDurations()
+	seconds := max(float32(len(samples))/float32(cfg.SampleRate)+1, maxSpeech)
+	vad := sherpa.NewVoiceActivityDetector(sileroModelConfig(cfg), seconds)
+	if vad == nil {
+		return nil, fmt.Errorf("failed to create Silero VAD")
+	}
+	defer sherpa.DeleteVoiceActivityDetector(vad)
+
+	pad := cfg.PreSpeechPadMs * cfg.SampleRate / 1000
+	var segments []SpeechSegment
+	collect := func() {
+		for !vad.IsEmpty() {
+			segment := vad.Front()
+			vad.Pop()
+			start := max(0, segment.Start-pad)
+			padded := append(samples[start:segment.Start:segment.Start], segment.Samples...)
+			segments = append(segments, SpeechSegment{
+				Start:   time.Duration(start) * time.Second / time.Duration(cfg.SampleRate),
+				Samples: padded,
+			})
+		}
+	}
+
+	for i := 0; i < len(samples); i += VADWindowSize {
+		vad.AcceptWaveform(samples[i:min(i+VADWindowSize, len(samples))])
+		collect()
+	}
+	vad.Flush()
+	collect()
+	return segments, nil
+}
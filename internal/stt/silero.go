@@ -16,20 +16,29 @@ import (
 
 // VAD configuration constants.
 const (
-	// VADMinSpeechDuration is the minimum speech duration (in seconds) required to
-	// trigger segment delivery. 0.1 s captures short utterances like "yes" or "no".
+	// VADMinSpeechDuration is the default minimum speech duration (in seconds)
+	// required to trigger segment delivery. 0.1 s captures short utterances like
+	// "yes" or "no".
 	VADMinSpeechDuration = 0.1
 
-	// VADMaxSpeechDuration is the maximum continuous speech duration (in seconds).
-	// Prevents unbounded audio accumulation and forces segmentation of long utterances.
+	// VADMaxSpeechDuration is the default maximum continuous speech duration (in
+	// seconds). Prevents unbounded audio accumulation and forces segmentation of
+	// long utterances.
 	VADMaxSpeechDuration = 30.0
 
 	// VADWindowSize is the VAD processing window in samples.
 	// At 16 kHz: 512 samples = 32 ms (standard Silero VAD frame size).
 	VADWindowSize = 512
 
-	// VADBufferSize is the VAD audio buffer depth in seconds.
+	// VADBufferSize is the default VAD audio buffer depth in seconds.
+	// The buffer holds seconds × sample rate float32 samples: 60 s at 16 kHz ≈ 3.8 MB.
 	VADBufferSize = 60.0
+
+	// VADMinSilenceDuration and VADMaxSilenceDuration bound the silence (in
+	// seconds) accepted by [SileroVAD.SetSilenceDuration]: shorter cuts words
+	// apart, longer makes every reply wait noticeably.
+	VADMinSilenceDuration = 0.1
+	VADMaxSilenceDuration = 5.0
 )
 
 // Compile-time interface compliance check.
@@ -44,34 +53,94 @@ type SileroVAD struct {
 	mu         sync.Mutex                    // Protects VAD access
 	sampleRate int
 
+	// Construction parameters, kept to rebuild the engine in SetThreshold and
+	// SetSilenceDuration; protected by mu. reconfigMu is held by each rebuild
+	// from reading the parameters to swapping in the new engine, so concurrent
+	// changes are applied one after the other instead of undoing each other.
+	vadConfig     sherpa.VadModelConfig
+	bufferSeconds float32
+	reconfigMu    sync.Mutex
+
 	// Atomic speech-detection state — lock-free on the hot path.
 	wasSpeaking atomic.Bool
 	speechStart atomic.Int64 // Unix nanoseconds; 0 if not currently in speech
+	speechEnd   atomic.Int64 // Unix nanoseconds of the last end of speech; 0 if none yet
 
 	// Event-driven segment delivery.
 	segmentChan chan []float32
+
+	// Push-to-talk gate: AcceptWaveform discards audio while false.
+	listening atomic.Bool
+
+	// Pre-speech lookback (nil when disabled); protected by mu.
+	onset    *onsetBuffer
+	inSpeech bool // VAD speech state as of the previous AcceptWaveform
 }
 
 // SileroConfig holds configuration for [SileroVAD].
 type SileroConfig struct {
-	ModelDir        string  // Base model directory (silero_vad.onnx is resolved automatically)
-	Threshold       float32 // VAD confidence threshold (0.0–1.0)
-	SilenceDuration float32 // Silence duration in seconds before speech is considered ended
-	SampleRate      int
-	NumThreads      int
-	Verbose         bool
+	ModelDir          string  // Base model directory (silero_vad.onnx is resolved automatically)
+	Threshold         float32 // VAD confidence threshold (0.0–1.0)
+	SilenceDuration   float32 // Silence duration in seconds before speech is considered ended
+	MinSpeechDuration float32 // Shorter speech is ignored, in seconds (0 = VADMinSpeechDuration)
+	MaxSpeechDuration float32 // Longer speech is split, in seconds (0 = VADMaxSpeechDuration)
+	BufferSeconds     float32 // VAD buffer depth in seconds (0 = VADBufferSize; must be >= the maximum speech duration)
+	PreSpeechPadMs    int     // Audio before the detected onset prepended to each segment (0 disables)
+	SampleRate        int
+	NumThreads        int
+	Verbose           bool
+}
+
+// speechDurations returns the minimum and maximum speech durations in
+// seconds, with defaults applied.
+func (cfg *SileroConfig) speechDurations() (minSeconds, maxSeconds float32) {
+	minSeconds, maxSeconds = cfg.MinSpeechDuration, cfg.MaxSpeechDuration
+	if minSeconds <= 0 {
+		minSeconds = VADMinSpeechDuration
+	}
+	if maxSeconds <= 0 {
+		maxSeconds = VADMaxSpeechDuration
+	}
+	return minSeconds, maxSeconds
 }
 
 // NewSileroVAD creates a [SileroVAD] that satisfies [VoiceDetector].
 func NewSileroVAD(cfg *SileroConfig) (*SileroVAD, error) {
-	modelPath := filepath.Join(cfg.ModelDir, "silero_vad.onnx")
+	bufferSeconds := cfg.BufferSeconds
+	if bufferSeconds == 0 {
+		bufferSeconds = VADBufferSize
+	}
+	if _, maxSpeech := cfg.speechDurations(); bufferSeconds < maxSpeech {
+		return nil, fmt.Errorf("VAD buffer of %.1fs is shorter than the maximum speech duration (%.1fs)", bufferSeconds, maxSpeech)
+	}
 
+	vadConfig := sileroModelConfig(cfg)
+	vad := sherpa.NewVoiceActivityDetector(vadConfig, bufferSeconds)
+	if vad == nil {
+		return nil, fmt.Errorf("failed to create Silero VAD")
+	}
+
+	v := &SileroVAD{
+		vad:           vad,
+		sampleRate:    cfg.SampleRate,
+		vadConfig:     *vadConfig,
+		bufferSeconds: bufferSeconds,
+		segmentChan:   make(chan []float32, 5),
+	}
+	if cfg.PreSpeechPadMs > 0 {
+		v.onset = newOnsetBuffer(cfg.PreSpeechPadMs*cfg.SampleRate/1000, int(onsetSlackSeconds*float64(cfg.SampleRate)))
+	}
+	v.listening.Store(true)
+	return v, nil
+}
+
+// sileroModelConfig returns the sherpa-onnx configuration for cfg.
+func sileroModelConfig(cfg *SileroConfig) *sherpa.VadModelConfig {
 	vadConfig := &sherpa.VadModelConfig{}
-	vadConfig.SileroVad.Model = modelPath
+	vadConfig.SileroVad.Model = filepath.Join(cfg.ModelDir, "silero_vad.onnx")
 	vadConfig.SileroVad.Threshold = cfg.Threshold
 	vadConfig.SileroVad.MinSilenceDuration = cfg.SilenceDuration
-	vadConfig.SileroVad.MinSpeechDuration = VADMinSpeechDuration
-	vadConfig.SileroVad.MaxSpeechDuration = VADMaxSpeechDuration
+	vadConfig.SileroVad.MinSpeechDuration, vadConfig.SileroVad.MaxSpeechDuration = cfg.speechDurations()
 	vadConfig.SileroVad.WindowSize = VADWindowSize
 	vadConfig.SampleRate = cfg.SampleRate
 	vadConfig.NumThreads = cfg.NumThreads
@@ -79,36 +148,46 @@ func NewSileroVAD(cfg *SileroConfig) (*SileroVAD, error) {
 	if cfg.Verbose {
 		vadConfig.Debug = 1
 	}
-
-	vad := sherpa.NewVoiceActivityDetector(vadConfig, VADBufferSize)
-	if vad == nil {
-		return nil, fmt.Errorf("failed to create Silero VAD")
-	}
-
-	return &SileroVAD{
-		vad:         vad,
-		sampleRate:  cfg.SampleRate,
-		segmentChan: make(chan []float32, 5),
-	}, nil
+	return vadConfig
 }
 
 // AcceptWaveform feeds audio samples into the VAD and delivers completed speech
 // segments immediately via [SileroVAD.SegmentChannel].
 //
-// Must never block; called on the real-time audio callback thread.
+// Must never block; called on the real-time audio callback thread. Audio is
+// discarded while listening is disabled (see [SileroVAD.SetListening]).
 func (v *SileroVAD) AcceptWaveform(samples []float32) {
 	v.mu.Lock()
+	// Checked under the lock so no audio gets in after SetListening(false) reset the detector.
+	if !v.listening.Load() {
+		v.mu.Unlock()
+		return
+	}
 	v.vad.AcceptWaveform(samples)
 	isSpeech := v.vad.IsSpeech()
 
+	// Keep recent audio so the onset the VAD detected late can be restored.
+	if v.onset != nil {
+		v.onset.write(samples)
+		if isSpeech && !v.inSpeech {
+			v.onset.snapshot()
+		}
+	}
+	v.inSpeech = isSpeech
+
 	// EVENT-DRIVEN: send completed segments without holding the VAD lock.
 	if !v.vad.IsEmpty() {
 		segment := v.vad.Front()
 		v.vad.Pop()
 
 		if len(segment.Samples) > 0 {
-			samplesCopy := make([]float32, len(segment.Samples))
-			copy(samplesCopy, segment.Samples)
+			var pad []float32
+			if v.onset != nil {
+				pad = v.onset.before(segment.Start)
+			}
+			samplesCopy := make([]float32, len(pad)+len(segment.Samples))
+			copy(samplesCopy, pad)
+			copy(samplesCopy[len(pad):], segment.Samples)
 			v.mu.Unlock()
 
 			// Non-blocking send — never block the audio callback thread.
@@ -130,6 +209,7 @@ func (v *SileroVAD) AcceptWaveform(samples []float32) {
 		v.speechStart.Store(time.Now().UnixNano())
 		v.wasSpeaking.Store(true)
 	} else if !isSpeech && wasSpk {
+		v.speechEnd.Store(time.Now().UnixNano())
 		if startNano := v.speechStart.Load(); startNano > 0 {
 			duration := float64(time.Now().UnixNano()-startNano) / 1e9
 			log.Printf("🎤 Speech ended (%.1fs)", duration)
@@ -150,6 +230,16 @@ func (v *SileroVAD) IsSpeechDetected() bool {
 	return !v.vad.IsEmpty() || v.vad.IsSpeech()
 }
 
+// SpeechEndedAt returns when the VAD last saw speech stop, or the zero time if
+// it has not yet. The segment of that speech is delivered once the configured
+// silence duration has passed.
+func (v *SileroVAD) SpeechEndedAt() time.Time {
+	if nano := v.speechEnd.Load(); nano > 0 {
+		return time.Unix(0, nano)
+	}
+	return time.Time{}
+}
+
 // Clear resets the internal VAD state.
 func (v *SileroVAD) Clear() {
 	v.mu.Lock()
@@ -157,6 +247,116 @@ func (v *SileroVAD) Clear() {
 	v.vad.Clear()
 }
 
+// Listening reports whether audio is passed to the detector.
+func (v *SileroVAD) Listening() bool {
+	return v.listening.Load()
+}
+
+// SetListening enables or disables speech detection, e.g. for push-to-talk.
+// While disabled, AcceptWaveform discards the audio; disabling also drops any
+// speech in progress so stale audio is not transcribed once listening resumes.
+// Safe to call from any goroutine while the audio callback runs.
+func (v *SileroVAD) SetListening(on bool) {
+	if v.listening.Swap(on) == on || on {
+		return
+	}
+	v.mu.Lock()
+	if v.vad != nil {
+		v.vad.Reset()
+	}
+	v.inSpeech = false
+	if v.onset != nil {
+		v.onset.reset() // Segment offsets restart at zero after a reset
+	}
+	v.mu.Unlock()
+	v.wasSpeaking.Store(false)
+	v.speechStart.Store(0)
+}
+
+// Threshold returns the current speech confidence threshold.
+func (v *SileroVAD) Threshold() float32 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.vadConfig.SileroVad.Threshold
+}
+
+// SetThreshold changes the speech confidence threshold (0.0–1.0, exclusive) while
+// running. sherpa-onnx fixes the threshold when the detector is created, so a new
+// detector is built and swapped in under the lock. Speech in progress is
+// discarded; the segment channel is kept, so consumers are unaffected.
+func (v *SileroVAD) SetThreshold(threshold float32) error {
+	if threshold <= 0 || threshold >= 1 {
+		return fmt.Errorf("VAD threshold must be between 0.0 and 1.0, got %.2f", threshold)
+	}
+	if err := v.reconfigure(func(c *sherpa.VadModelConfig) { c.SileroVad.Threshold = threshold }); err != nil {
+		return err
+	}
+	log.Printf("🎚️ VAD threshold set to %.2f", threshold)
+	return nil
+}
+
+// SilenceDuration returns how many seconds of silence end a speech segment.
+func (v *SileroVAD) SilenceDuration() float32 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.vadConfig.SileroVad.MinSilenceDuration
+}
+
+// SetSilenceDuration changes how many seconds of silence end a speech segment
+// (between [VADMinSilenceDuration] and [VADMaxSilenceDuration]) while running,
+// e.g. short for quick commands and long for dictation. Like
+// [SileroVAD.SetThreshold], it swaps in a new detector and discards speech in
+// progress.
+func (v *SileroVAD) SetSilenceDuration(seconds float32) error {
+	if seconds < VADMinSilenceDuration || seconds > VADMaxSilenceDuration {
+		return fmt.Errorf("VAD silence duration must be between %.1fs and %.1fs, got %.2fs", VADMinSilenceDuration, VADMaxSilenceDuration, seconds)
+	}
+	if err := v.reconfigure(func(c *sherpa.VadModelConfig) { c.SileroVad.MinSilenceDuration = seconds }); err != nil {
+		return err
+	}
+	log.Printf("🎚️ VAD silence duration set to %.2fs", seconds)
+	return nil
+}
+
+// reconfigure builds a detector from the current configuration as changed by
+// update and swaps it in under the lock, since sherpa-onnx cannot change a
+// detector's settings after creation. Rebuilds are serialized by reconfigMu.
+func (v *SileroVAD) reconfigure(update func(*sherpa.VadModelConfig)) error {
+	v.reconfigMu.Lock()
+	defer v.reconfigMu.Unlock()
+
+	// Build outside mu: loading the model takes far longer than the audio
+	// callback can wait.
+	v.mu.Lock()
+	vadConfig := v.vadConfig
+	v.mu.Unlock()
+	update(&vadConfig)
+	vad := sherpa.NewVoiceActivityDetector(&vadConfig, v.bufferSeconds)
+	if vad == nil {
+		return fmt.Errorf("failed to recreate Silero VAD")
+	}
+
+	v.mu.Lock()
+	old := v.vad
+	if old == nil {
+		v.mu.Unlock()
+		sherpa.DeleteVoiceActivityDetector(vad)
+		return fmt.Errorf("VAD is closed")
+	}
+	v.vad = vad
+	v.vadConfig = vadConfig
+	v.inSpeech = false
+	if v.onset != nil {
+		v.onset.reset() // Segment offsets restart at zero in the new detector
+	}
+	v.mu.Unlock()
+
+	sherpa.DeleteVoiceActivityDetector(old)
+	v.wasSpeaking.Store(false)
+	v.speechStart.Store(0)
+	return nil
+}
+
 // Close releases all resources held by the detector.
 func (v *SileroVAD) Close() {
 	v.mu.Lock()
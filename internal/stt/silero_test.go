@@ -0,0 +1,60 @@
+package stt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewSileroVADRejectsShortBuffer(t *testing.T) {
+	_, err := NewSileroVAD(&SileroConfig{
+		ModelDir:      t.TempDir(),
+		SampleRate:    16000,
+		BufferSeconds: VADMaxSpeechDuration - 1,
+	})
+	if err == nil {
+		t.Fatal("expected error for a buffer shorter than VADMaxSpeechDuration")
+	}
+}
+
+func TestNewSileroVADRejectsBufferShorterThanMaxSpeech(t *testing.T) {
+	_, err := NewSileroVAD(&SileroConfig{
+		ModelDir:          t.TempDir(),
+		SampleRate:        16000,
+		MaxSpeechDuration: 45,
+		BufferSeconds:     40,
+	})
+	if err == nil {
+		t.Fatal("expected error for a buffer shorter than the configured maximum speech duration")
+	}
+}
+
+func TestSetSilenceDurationRejectsOutOfRange(t *testing.T) {
+	v := &SileroVAD{} // Validation happens before the detector is touched
+	for _, seconds := range []float32{0, -1, VADMinSilenceDuration / 2, VADMaxSilenceDuration + 1} {
+		if err := v.SetSilenceDuration(seconds); err == nil {
+			t.Errorf("SetSilenceDuration(%v) succeeded, want a range error", seconds)
+		}
+	}
+}
+
+func TestAcceptWaveformDiscardsAudioWhileNotListening(t *testing.T) {
+	// A detector part-way through speech, minus the engine: audio that got
+	// past the gate would panic on the nil engine.
+	v := &SileroVAD{onset: newOnsetBuffer(160, 1600), inSpeech: true}
+	v.listening.Store(true)
+	v.onset.write(make([]float32, VADWindowSize))
+	v.wasSpeaking.Store(true)
+	v.speechStart.Store(time.Now().UnixNano())
+
+	v.SetListening(false)
+	if v.Listening() {
+		t.Error("listening after SetListening(false)")
+	}
+	if v.inSpeech || v.wasSpeaking.Load() || v.speechStart.Load() != 0 || v.onset.fed != 0 {
+		t.Error("speech in progress kept after SetListening(false)")
+	}
+	v.AcceptWaveform(make([]float32, VADWindowSize))
+	if v.onset.fed != 0 {
+		t.Error("audio reached the detector while not listening")
+	}
+}
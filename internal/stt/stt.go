@@ -60,6 +60,88 @@ type Transcriber interface {
 	Close()
 }
 
+// VADStats counts how the speech segments delivered by the VAD were used. A high
+// share of rejected segments means the VAD is triggering on noise and
+// VadThreshold should be raised.
+type VADStats struct {
+	Produced    uint64 // Segments handed to the transcriber
+	Transcribed uint64 // Segments that produced text
+	Rejected    uint64 // Segments that produced no usable text
+}
+
+// RejectionRate returns the fraction of produced segments that were rejected
+// (0 when none were produced).
+func (s VADStats) RejectionRate() float64 {
+	if s.Produced == 0 {
+		return 0
+	}
+	return float64(s.Rejected) / float64(s.Produced)
+}
+
+// StatsReporter is implemented by transcribers that track [VADStats].
+type StatsReporter interface {
+	VADStats() VADStats
+}
+
+// WordTiming is when a word was spoken, in seconds from the start of the
+// speech segment it was transcribed from.
+type WordTiming struct {
+	Word  string
+	Start float32
+	End   float32
+}
+
+// TimedTranscriber is implemented by transcribers that can report word
+// timings, e.g. for subtitles.
+type TimedTranscriber interface {
+	// TranscribeSegmentTimed is TranscribeSegment that also returns the timing
+	// of each word, or no timings when the model does not provide them.
+	TranscribeSegmentTimed(samples []float32) (string, []WordTiming)
+}
+
+// AudioTranscriber is implemented by transcribers that can also convert
+// recordings made outside the live pipeline, e.g. audio posted to the HTTP
+// server.
+type AudioTranscriber interface {
+	// TranscribeAudio converts samples recorded at sampleRate to text, without
+	// the wake word filter or the statistics of TranscribeSegment. Whisper
+	// models only hear the first 30 seconds.
+	TranscribeAudio(samples []float32, sampleRate int) (string, error)
+}
+
+// LanguageReporter is implemented by transcribers that detect the spoken
+// language, e.g. Whisper with --stt-language auto.
+type LanguageReporter interface {
+	// LastLanguage returns the code (e.g. "es") of the language detected in the
+	// most recent segment that produced text, or "" when unknown.
+	LastLanguage() string
+}
+
+// VerbatimReporter is implemented by transcribers that can report the text of
+// a segment as spoken, before the wake word is removed from it, for
+// --history-raw-transcript.
+type VerbatimReporter interface {
+	// LastVerbatim returns the most recent transcript as spoken, or "" when it
+	// is not kept.
+	LastVerbatim() string
+}
+
+// InjectionReporter is implemented by transcribers that can stand in words the
+// user did not say, such as [WakeWordPlaceholder] for a bare wake word.
+type InjectionReporter interface {
+	// LastInjected reports whether the most recent transcript was made up
+	// rather than heard.
+	LastInjected() bool
+}
+
+// Transcript is the text of a speech segment, as sent by [RunProcessor].
+type Transcript struct {
+	Text     string // The command, wake word removed
+	Verbatim string // The segment as spoken, when kept (see [VerbatimReporter]); "" = Text
+	Language string // Language detected in the segment (see [LanguageReporter]), or ""
+	Injected bool   // Text was made up, not heard (see [InjectionReporter])
+}
+
 // ModelProvider manages the lifecycle of model files required by an STT backend.
 //
 // Every STT implementation must implement this interface so that the binary can
@@ -86,14 +168,20 @@ func NewTranscriber(cfg *config.Config) (Transcriber, error) {
 	switch strings.ToLower(cfg.STTBackend) {
 	case "whisper":
 		return NewWhisperRecognizer(&WhisperConfig{
-			ModelDir:   cfg.ModelDir,
-			ModelSize:  cfg.STTModel,
-			SampleRate: cfg.SampleRate,
-			WakeWord:   cfg.WakeWord,
-			Provider:   cfg.STTProvider,
-			Language:   cfg.STTLanguage,
-			Verbose:    cfg.Verbose,
-			NumThreads: cfg.STTThreads,
+			ModelDir:      cfg.ModelDir,
+			ModelSize:     cfg.STTModel,
+			SampleRate:    cfg.SampleRate,
+			WakeWords:     cfg.WakeWord,
+			WakeTolerance: cfg.WakeWordTolerance,
+			WakeGrace:     cfg.WakeWordGrace,
+			WakeRaw:       cfg.HistoryRawTranscript,
+			RetryEmpty:    cfg.RetryEmptyTranscript,
+			Hotwords:      cfg.Hotwords,
+			MaxNoSpeech:   cfg.MaxNoSpeechProb,
+			Provider:      cfg.STTProvider,
+			Language:      cfg.STTLanguage,
+			Verbose:       cfg.Verbose,
+			NumThreads:    cfg.STTThreads,
 		})
 	default:
 		return nil, fmt.Errorf("unknown STT backend %q (available: whisper)", cfg.STTBackend)
@@ -0,0 +1,108 @@
+package stt
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestVADStatsRejectionRate(t *testing.T) {
+	if got := (VADStats{}).RejectionRate(); got != 0 {
+		t.Errorf("empty stats: got %v, want 0", got)
+	}
+	s := VADStats{Produced: 8, Transcribed: 6, Rejected: 2}
+	if got := s.RejectionRate(); got != 0.25 {
+		t.Errorf("got %v, want 0.25", got)
+	}
+}
+
+func TestDecodeWithRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		results    []string // Successive decode results; "!" fails
+		retryEmpty bool
+		want       string
+		calls      int
+	}{
+		{"success", []string{"hello"}, true, "hello", 1},
+		{"empty without retry", []string{"", "hello"}, false, "", 1},
+		{"empty with retry", []string{"", "hello"}, true, "hello", 2},
+		{"failure always retried", []string{"!", "hello"}, false, "hello", 2},
+		{"retried once only", []string{"!", "!", "hello"}, true, "", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			decode := func() (decoded, error) {
+				r := tt.results[calls]
+				calls++
+				if r == "!" {
+					return decoded{}, errors.New("no stream")
+				}
+				return decoded{text: r}, nil
+			}
+			if got := decodeWithRetry(decode, tt.retryEmpty, false).text; got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if calls != tt.calls {
+				t.Errorf("decode called %d times, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestNoSpeechProb(t *testing.T) {
+	if got := noSpeechProb(nil); got != -1 {
+		t.Errorf("no token probabilities: got %v, want -1 (unknown)", got)
+	}
+	// Tokens decoded with probability 0.9 and 0.1: mean log probability ln(0.3)
+	logProbs := []float32{float32(math.Log(0.9)), float32(math.Log(0.1))}
+	if got := noSpeechProb(logProbs); math.Abs(float64(got)-0.7) > 1e-6 {
+		t.Errorf("got %v, want 0.7", got)
+	}
+	if got := noSpeechProb([]float32{0, 0}); got != 0 {
+		t.Errorf("certain tokens: got %v, want 0", got)
+	}
+}
+
+func TestLikelyNoSpeech(t *testing.T) {
+	r := &WhisperRecognizer{maxNoSpeech: 0.6}
+	for _, tt := range []struct {
+		noSpeech float32
+		want     bool
+	}{{0.2, false}, {0.6, false}, {0.8, true}, {-1, false}} {
+		if got := r.likelyNoSpeech(decoded{text: "thanks", noSpeech: tt.noSpeech}); got != tt.want {
+			t.Errorf("noSpeech %v: got %v, want %v", tt.noSpeech, got, tt.want)
+		}
+	}
+	if (&WhisperRecognizer{}).likelyNoSpeech(decoded{text: "thanks", noSpeech: 0.99}) {
+		t.Error("dropped a transcript with the threshold disabled")
+	}
+}
+
+func TestWordTimings(t *testing.T) {
+	tokens := []string{"<|en|>", " Turn", " on", " the", " kit", "chen", " lights", "."}
+	starts := []float32{0, 0.2, 0.5, 0.7, 0.9, 1.1, 1.4, 1.8}
+
+	got := wordTimings(tokens, starts, nil, 2.5)
+	want := []WordTiming{
+		{"Turn", 0.2, 0.5},
+		{"on", 0.5, 0.7},
+		{"the", 0.7, 0.9},
+		{"kitchen", 0.9, 1.4},
+		{"lights.", 1.4, 2.5}, // No durations: the last word ends with the segment
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("wordTimings = %+v, want %+v", got, want)
+	}
+
+	durations := []float32{0, 0.3, 0.2, 0.2, 0.2, 0.3, 0.4, 0.1}
+	if got := wordTimings(tokens, starts, durations, 2.5); got[len(got)-1].End != 1.9 {
+		t.Errorf("last word ends at %v, want 1.9 (its last token's end)", got[len(got)-1].End)
+	}
+
+	if got := wordTimings(tokens, nil, nil, 2.5); got != nil {
+		t.Errorf("wordTimings without timestamps = %+v, want nil", got)
+	}
+}
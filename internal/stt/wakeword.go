@@ -0,0 +1,214 @@
+package stt
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// WakeWordPlaceholder is sent in place of a command when the wake word is
+// spoken on its own with no grace period. The transcript is flagged as injected
+// (see [InjectionReporter]), so logs don't show it as the user's words.
+const WakeWordPlaceholder = "Hello"
+
+// wakeWordFilter gates transcripts on any of its wake words. After a wake word
+// is heard on its own ("Sherpa..."), it stays armed for a grace period so a
+// command that the VAD split into the next segment ("...what's the weather") is
+// accepted without repeating the wake word.
+type wakeWordFilter struct {
+	wakeWords []string      // Lowercase wake words
+	tolerance int           // Edits allowed between a wake word and what was heard (0 = exact)
+	grace     time.Duration // Armed window after a bare wake word (0 = reply with WakeWordPlaceholder instead)
+	raw       bool          // Also report accepted segments verbatim, wake word included
+	verbose   bool
+
+	mu         sync.Mutex
+	armedUntil time.Time
+	pending    string // Raw bare wake word segment awaiting its command (raw mode)
+}
+
+// newWakeWordFilter returns nil when wakeWords has no non-empty word (no
+// gating). A wake word also matches words within tolerance edits of it (see
+// [matchWakeWord]). With raw set, accepted segments are also reported as
+// spoken, wake word included (see [wakeWordFilter.apply]).
+func newWakeWordFilter(wakeWords []string, tolerance int, grace time.Duration, raw, verbose bool) *wakeWordFilter {
+	var words []string
+	for _, w := range wakeWords {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			words = append(words, w)
+		}
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	return &wakeWordFilter{wakeWords: words, tolerance: tolerance, grace: grace, raw: raw, verbose: verbose}
+}
+
+// match returns the wake word found in text and the command left once it is
+// removed, or false when there is none. Exact (case-insensitive) occurrences
+// come first: when several occur, the earliest wins, and the longest among
+// those starting at the same place ("hey sherpa" over "hey"). Failing that,
+// the wake word heard most closely at the start of text within the tolerance
+// is taken, the first listed on a tie.
+func (f *wakeWordFilter) match(text string) (word, command string, found bool) {
+	lower := strings.ToLower(text)
+	best, bestIdx := "", -1
+	for _, w := range f.wakeWords {
+		idx := strings.Index(lower, w)
+		if idx == -1 {
+			continue
+		}
+		if bestIdx == -1 || idx < bestIdx || idx == bestIdx && len(w) > len(best) {
+			best, bestIdx = w, idx
+		}
+	}
+	if bestIdx != -1 {
+		return best, removeWakeWord(text, best), true
+	}
+	bestDist := -1
+	for _, w := range f.wakeWords {
+		if dist, remainder := matchWakeWord(text, w, f.tolerance); dist >= 0 && (bestDist == -1 || dist < bestDist) {
+			word, command, bestDist = w, remainder, dist
+		}
+	}
+	if bestDist == -1 {
+		return "", "", false
+	}
+	if f.verbose {
+		log.Printf("[STT] Wake word %q matched approximately in %q", word, text)
+	}
+	return word, command, true
+}
+
+// apply returns the command in text with the matched wake word removed, or ""
+// when the segment should be ignored. start and end bound the segment's speech:
+// a command is accepted without the wake word if it started within the armed
+// window, which opens when a bare wake word ends. In raw mode it also returns
+// the accepted text verbatim, prefixed by the bare wake word segment that armed
+// the filter, if any; otherwise, and for [WakeWordPlaceholder], verbatim is "".
+// A nil filter passes text through unchanged.
+func (f *wakeWordFilter) apply(text string, start, end time.Time) (command, verbatim string) {
+	if f == nil {
+		log.Printf("🗣️ You: %s", text)
+		return text, ""
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	word, command, found := f.match(text)
+	if !found {
+		if start.Before(f.armedUntil) {
+			f.armedUntil = time.Time{}
+			log.Printf("🗣️ You (after wake word): %s", text)
+			if f.raw {
+				verbatim = f.pending + " " + text
+			}
+			f.pending = ""
+			return text, verbatim
+		}
+		if f.verbose {
+			log.Printf("[STT] No wake word %q found in %q, ignoring", f.wakeWords, text)
+		}
+		return "", ""
+	}
+
+	f.armedUntil = time.Time{}
+	f.pending = ""
+	if f.raw {
+		verbatim = text
+	}
+	if command != "" {
+		log.Printf("🗣️ You (wake word %q detected): %s", word, command)
+		return command, verbatim
+	}
+
+	// Only the wake word was spoken: wait for the command, or greet right away
+	if f.grace > 0 {
+		f.armedUntil = end.Add(f.grace)
+		f.pending = text
+		log.Printf("🗣️ Wake word %q detected, listening for %s", word, f.grace)
+		return "", ""
+	}
+	log.Printf("🗣️ Wake word %q detected", word)
+	return WakeWordPlaceholder, ""
+}
+
+// removeWakeWord removes the wake word from text, case-insensitively.
+func removeWakeWord(text, wakeWord string) string {
+	lowerText := strings.ToLower(text)
+	idx := strings.Index(lowerText, strings.ToLower(wakeWord))
+	if idx == -1 {
+		return text
+	}
+	return trimCommand(text[:idx] + text[idx+len(wakeWord):])
+}
+
+// trimCommand strips the spaces and punctuation a removed wake word leaves at
+// the start of a command (", what time" -> "what time") and collapses the
+// spaces left where it was removed from the middle.
+func trimCommand(s string) string {
+	return strings.Join(strings.Fields(strings.TrimLeft(s, " ,.!?;:-'\"")), " ")
+}
+
+// matchWakeWord reports how closely text starts with wake: 0 when it contains
+// wake exactly (case-insensitively), else the edit distance of the closest run
+// of its first words once case, punctuation and spacing are ignored ("hey
+// sharpa.", "heysherpa"). It also returns text with the match removed, or -1
+// and text when there is no match within maxDist. Runs from one word fewer to
+// one word more than wake are tried, so a word that speech recognition split or
+// merged still matches. maxDist is capped at a quarter of the length of wake,
+// so short wake words must be heard exactly, and a run that is only wake cut
+// short is not taken, as that is usually another word ("compute" for
+// "computer").
+func matchWakeWord(text, wake string, maxDist int) (dist int, remainder string) {
+	if strings.Contains(strings.ToLower(text), strings.ToLower(wake)) {
+		return 0, removeWakeWord(text, wake)
+	}
+	phrase := normalizePhrase(wake)
+	key := strings.ReplaceAll(phrase, " ", "")
+	maxDist = min(maxDist, len([]rune(key))/4)
+	if maxDist <= 0 {
+		return -1, text
+	}
+
+	words := wordSpans(text)
+	n := len(strings.Fields(phrase))
+	bestEnd, bestDist := -1, maxDist+1
+	for size := max(1, n-1); size <= n+1 && size <= len(words); size++ {
+		end := words[size-1][1]
+		run := strings.ReplaceAll(normalizePhrase(text[:end]), " ", "")
+		if run != key && strings.HasPrefix(key, run) {
+			continue
+		}
+		if d := editDistance(run, key); d < bestDist {
+			bestEnd, bestDist = end, d
+		}
+	}
+	if bestEnd == -1 {
+		return -1, text
+	}
+	return bestDist, trimCommand(text[bestEnd:])
+}
+
+// wordSpans returns the byte offsets [start, end) of the space-separated words
+// in s, punctuation included.
+func wordSpans(s string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range s {
+		switch {
+		case unicode.IsSpace(r) && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		case !unicode.IsSpace(r) && start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(s)})
+	}
+	return spans
+}
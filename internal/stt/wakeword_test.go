@@ -0,0 +1,148 @@
+package stt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWakeWordFilterArmsAfterBareWakeWord(t *testing.T) {
+	f := newWakeWordFilter([]string{"Sherpa"}, 0, 4*time.Second, false, false)
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(sec float64) time.Time { return t0.Add(time.Duration(sec * float64(time.Second))) }
+
+	if got, _ := f.apply("Sherpa.", at(0), at(1)); got != "" {
+		t.Fatalf("bare wake word = %q, want it swallowed while armed", got)
+	}
+	if got, _ := f.apply("What's the weather?", at(3), at(5)); got != "What's the weather?" {
+		t.Errorf("command within grace = %q, want it accepted", got)
+	}
+	// The window is used up by the accepted command.
+	if got, _ := f.apply("And tomorrow?", at(6), at(7)); got != "" {
+		t.Errorf("second command = %q, want it ignored", got)
+	}
+
+	f.apply("Sherpa", at(10), at(11))
+	if got, _ := f.apply("Too late", at(16), at(17)); got != "" {
+		t.Errorf("command after grace = %q, want it ignored", got)
+	}
+}
+
+func TestWakeWordFilterInlineCommand(t *testing.T) {
+	f := newWakeWordFilter([]string{"hey sherpa"}, 0, time.Second, false, false)
+	now := time.Now()
+	if got, _ := f.apply("Hey Sherpa, turn on the lights", now, now); got != "turn on the lights" {
+		t.Errorf("apply = %q, want the command without the wake word", got)
+	}
+}
+
+func TestWakeWordFilterWithoutGraceGreets(t *testing.T) {
+	f := newWakeWordFilter([]string{"sherpa"}, 0, 0, false, false)
+	now := time.Now()
+	if got, _ := f.apply("Sherpa!", now, now); got != WakeWordPlaceholder {
+		t.Errorf("apply = %q, want %q", got, WakeWordPlaceholder)
+	}
+	if got, _ := f.apply("what time is it", now, now); got != "" {
+		t.Errorf("apply = %q, want it ignored (no armed window)", got)
+	}
+}
+
+func TestNilWakeWordFilterPassesThrough(t *testing.T) {
+	var f *wakeWordFilter
+	if got, _ := f.apply("anything", time.Now(), time.Now()); got != "anything" {
+		t.Errorf("apply = %q, want the text unchanged", got)
+	}
+}
+
+func TestWakeWordFilterRawKeepsWakeWord(t *testing.T) {
+	f := newWakeWordFilter([]string{"Sherpa"}, 0, 4*time.Second, true, false)
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(sec float64) time.Time { return t0.Add(time.Duration(sec * float64(time.Second))) }
+
+	// The command is still stripped, so it can be matched against phrases.
+	if command, verbatim := f.apply("Sherpa, repeat that", at(0), at(1)); command != "repeat that" || verbatim != "Sherpa, repeat that" {
+		t.Errorf("inline command = %q, %q, want it stripped and verbatim", command, verbatim)
+	}
+	f.apply("Sherpa.", at(2), at(3))
+	if command, verbatim := f.apply("What's the weather?", at(4), at(5)); command != "What's the weather?" || verbatim != "Sherpa. What's the weather?" {
+		t.Errorf("command after bare wake word = %q, %q, want both segments verbatim", command, verbatim)
+	}
+
+	f = newWakeWordFilter([]string{"Sherpa"}, 0, 0, true, false)
+	if command, verbatim := f.apply("Sherpa!", at(0), at(1)); command != WakeWordPlaceholder || verbatim != "" {
+		t.Errorf("bare wake word = %q, %q, want only the placeholder", command, verbatim)
+	}
+}
+
+func TestWakeWordFilterAcceptsAnyWakeWord(t *testing.T) {
+	f := newWakeWordFilter([]string{"sherpa", "Computer", " ", "hey sherpa"}, 0, 0, false, false)
+	now := time.Now()
+	for text, want := range map[string]string{
+		"Computer, lights off":         "lights off",
+		"Hey Sherpa, what time is it?": "what time is it?",
+		"Sherpa play some music":       "play some music",
+		"Hey there, how are you?":      "",
+		"COMPUTER":                     WakeWordPlaceholder,
+	} {
+		if got, _ := f.apply(text, now, now); got != want {
+			t.Errorf("apply(%q) = %q, want %q", text, got, want)
+		}
+	}
+	if newWakeWordFilter([]string{"", " "}, 0, 0, false, false) != nil {
+		t.Error("filter without non-empty wake words is not nil")
+	}
+}
+
+func TestMatchWakeWordToleratesMisrecognitions(t *testing.T) {
+	tests := []struct {
+		text      string
+		maxDist   int
+		dist      int
+		remainder string
+	}{
+		{"Hey Sherpa, lights on", 0, 0, "lights on"},
+		{"Hey sharpa, lights on", 0, -1, "Hey sharpa, lights on"},
+		{"Hey sharpa, lights on", 2, 1, "lights on"},
+		{"hey sherpa. What time is it?", 2, 0, "What time is it?"},
+		{"Hey  Sherpa!", 2, 0, ""},
+		{"Heysherpa play music", 2, 0, "play music"},
+		{"Hey, Sher pa, play music", 2, 0, "play music"},
+		{"A sherbet please", 2, -1, "A sherbet please"},
+		{"Hey there, play music", 2, -1, "Hey there, play music"},
+		{"Play music, hey sharpa", 2, -1, "Play music, hey sharpa"}, // Only at the start
+	}
+	for _, tt := range tests {
+		dist, remainder := matchWakeWord(tt.text, "hey sherpa", tt.maxDist)
+		if dist != tt.dist || remainder != tt.remainder {
+			t.Errorf("matchWakeWord(%q, %d) = %d, %q, want %d, %q",
+				tt.text, tt.maxDist, dist, remainder, tt.dist, tt.remainder)
+		}
+	}
+
+	// Short wake words must be heard exactly, and a wake word cut short is
+	// another word.
+	if dist, _ := matchWakeWord("What's the weather", "hey", 2); dist != -1 {
+		t.Error(`"hey" matched "the"`)
+	}
+	if dist, _ := matchWakeWord("Compute the total for me", "computer", 2); dist != -1 {
+		t.Error(`"computer" matched "compute"`)
+	}
+	if dist, remainder := matchWakeWord("Komputer, lights on", "computer", 2); dist != 1 || remainder != "lights on" {
+		t.Errorf(`"Komputer" = %d, %q, want 1, "lights on"`, dist, remainder)
+	}
+}
+
+func TestWakeWordFilterAcceptsNearMiss(t *testing.T) {
+	f := newWakeWordFilter([]string{"hey sherpa"}, 2, 0, false, false)
+	now := time.Now()
+	if got, _ := f.apply("Hey Sharpa, turn on the lights", now, now); got != "turn on the lights" {
+		t.Errorf("apply = %q, want the command without the misheard wake word", got)
+	}
+}
+
+func TestWakeWordFilterPrefersClosestNearMiss(t *testing.T) {
+	f := newWakeWordFilter([]string{"hey sherry", "hey sherpa"}, 2, 0, false, false)
+	word, command, found := f.match("Hey sherpo, what time is it")
+	if !found || word != "hey sherpa" || command != "what time is it" {
+		t.Errorf("match = %q, %q, %v, want the closest wake word", word, command, found)
+	}
+}
@@ -3,18 +3,30 @@
 package stt
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/agalue/sherpa-voice-assistant/internal/setup"
 	"github.com/agalue/sherpa-voice-assistant/internal/sherpa"
 )
 
-// Compile-time interface compliance check.
-var _ Transcriber = (*WhisperRecognizer)(nil)
+// Compile-time interface compliance checks.
+var (
+	_ Transcriber      = (*WhisperRecognizer)(nil)
+	_ StatsReporter    = (*WhisperRecognizer)(nil)
+	_ LanguageReporter = (*WhisperRecognizer)(nil)
+	_ VerbatimReporter = (*WhisperRecognizer)(nil)
+	_ TimedTranscriber = (*WhisperRecognizer)(nil)
+	_ AudioTranscriber = (*WhisperRecognizer)(nil)
+)
 
 // WhisperRecognizer implements [Transcriber] using OpenAI Whisper via sherpa-onnx.
 //
@@ -22,21 +34,42 @@ var _ Transcriber = (*WhisperRecognizer)(nil)
 // callback thread. Pair with [SileroVAD] to form a complete STT pipeline.
 type WhisperRecognizer struct {
 	recognizer *sherpa.OfflineRecognizer
-	wakeWord   string
+	wakeWord   *wakeWordFilter // nil when no wake word is configured
+	hotwords   *hotwordBias    // nil when no expected phrases are configured
+	retryEmpty bool            // Decode a segment a second time when it yields no text
 	verbose    bool
 	sampleRate int
+
+	// Transcripts whose estimated no-speech probability is higher are dropped (0 disables)
+	maxNoSpeech     float32
+	noSpeechUnknown sync.Once // Warns once when the model reports no token probabilities
+
+	lastLanguage atomic.Pointer[string] // Language of the last transcribed segment
+	lastVerbatim atomic.Pointer[string] // Verbatim text of the last transcript ("" = same as the transcript)
+	lastInjected atomic.Bool            // The last transcript was WakeWordPlaceholder
+
+	// Segment outcome counters (see [WhisperRecognizer.VADStats])
+	produced    atomic.Uint64
+	transcribed atomic.Uint64
+	rejected    atomic.Uint64
 }
 
 // WhisperConfig holds configuration for [WhisperRecognizer].
 type WhisperConfig struct {
-	ModelDir   string // Base model directory (Whisper files resolved automatically)
-	ModelSize  string // Model variant (e.g. "tiny", "base", "small")
-	SampleRate int
-	WakeWord   string
-	Provider   string // Hardware acceleration provider (cpu, cuda, coreml)
-	Language   string // Recognition language (e.g. "en", "es", "auto")
-	Verbose    bool
-	NumThreads int
+	ModelDir      string // Base model directory (Whisper files resolved automatically)
+	ModelSize     string // Model variant (e.g. "tiny", "base", "small")
+	SampleRate    int
+	WakeWords     []string      // Any of these activates the assistant (empty = no wake word)
+	WakeTolerance int           // Edits allowed when matching a wake word (0 = exact)
+	WakeGrace     time.Duration // How long a bare wake word waits for the command (0 = reply with WakeWordPlaceholder)
+	WakeRaw       bool          // Also report transcripts verbatim, wake word included (see [VerbatimReporter])
+	RetryEmpty    bool          // Decode a segment a second time when it yields no text
+	Hotwords      []string      // Expected phrases that near-miss transcripts are snapped to
+	MaxNoSpeech   float32       // Drop transcripts more likely than this to be noise (0 disables)
+	Provider      string        // Hardware acceleration provider (cpu, cuda, coreml)
+	Language      string        // Recognition language (e.g. "en", "es", "auto")
+	Verbose       bool
+	NumThreads    int
 }
 
 // NewWhisperRecognizer creates a [WhisperRecognizer] that satisfies [Transcriber].
@@ -72,65 +105,245 @@ func NewWhisperRecognizer(cfg *WhisperConfig) (*WhisperRecognizer, error) {
 	}
 
 	return &WhisperRecognizer{
-		recognizer: recognizer,
-		wakeWord:   strings.ToLower(cfg.WakeWord),
-		verbose:    cfg.Verbose,
-		sampleRate: cfg.SampleRate,
+		recognizer:  recognizer,
+		wakeWord:    newWakeWordFilter(cfg.WakeWords, cfg.WakeTolerance, cfg.WakeGrace, cfg.WakeRaw, cfg.Verbose),
+		hotwords:    newHotwordBias(cfg.Hotwords),
+		retryEmpty:  cfg.RetryEmpty,
+		maxNoSpeech: cfg.MaxNoSpeech,
+		verbose:     cfg.Verbose,
+		sampleRate:  cfg.SampleRate,
 	}, nil
 }
 
 // TranscribeSegment converts a completed speech segment to text using Whisper.
 // Returns an empty string when the segment contains no recognisable speech.
 func (r *WhisperRecognizer) TranscribeSegment(samples []float32) string {
+	text, _ := r.transcribe(samples)
+	return text
+}
+
+// TranscribeSegmentTimed is [WhisperRecognizer.TranscribeSegment] that also
+// returns when each word was spoken — satisfies [TimedTranscriber]. The timings
+// describe the words as decoded, before the wake word is removed or the text is
+// snapped to an expected phrase. They are nil when the model reports no token
+// timestamps or the segment produced no text.
+func (r *WhisperRecognizer) TranscribeSegmentTimed(samples []float32) (string, []WordTiming) {
+	text, result := r.transcribe(samples)
+	if text == "" {
+		return "", nil
+	}
+	return text, result.words
+}
+
+// transcribe implements TranscribeSegment, also returning the decoding result.
+func (r *WhisperRecognizer) transcribe(samples []float32) (string, decoded) {
 	if len(samples) == 0 {
-		return ""
+		return "", decoded{}
 	}
+	r.produced.Add(1)
 
 	if r.verbose {
 		duration := float32(len(samples)) / float32(r.sampleRate)
 		log.Printf("[STT] Processing speech segment: %.2fs", duration)
 	}
 
+	result := decodeWithRetry(func() (decoded, error) { return r.decode(samples, r.sampleRate) }, r.retryEmpty, r.verbose)
+	text := result.text
+	if text == "" || r.likelyNoSpeech(result) {
+		r.rejected.Add(1)
+		return "", result
+	}
+	r.transcribed.Add(1)
+	r.lastLanguage.Store(&result.language)
+
+	// The segment has just ended (give or take the VAD's trailing silence)
+	end := time.Now()
+	start := end.Add(-time.Duration(len(samples)) * time.Second / time.Duration(r.sampleRate))
+	text, verbatim := r.wakeWord.apply(text, start, end)
+	r.lastVerbatim.Store(&verbatim)
+	injected := r.wakeWord != nil && text == WakeWordPlaceholder
+	r.lastInjected.Store(injected)
+	if injected {
+		return text, result
+	}
+	return r.hotwords.apply(text), result
+}
+
+// decoded is the outcome of one decoding pass.
+type decoded struct {
+	text     string
+	noSpeech float32 // Estimated probability that the segment holds no speech (-1 = unknown)
+	language string  // Language the segment was decoded as (e.g. "es"; "" = unknown)
+	words    []WordTiming
+}
+
+// decode runs the recognizer once over samples recorded at sampleRate.
+func (r *WhisperRecognizer) decode(samples []float32, sampleRate int) (decoded, error) {
 	stream := sherpa.NewOfflineStream(r.recognizer)
 	if stream == nil {
-		log.Println("[STT] Failed to create offline stream")
-		return ""
+		return decoded{}, errors.New("failed to create offline stream")
 	}
 	defer sherpa.DeleteOfflineStream(stream)
 
-	stream.AcceptWaveform(r.sampleRate, samples)
+	stream.AcceptWaveform(sampleRate, samples)
 	r.recognizer.Decode(stream)
+	result := stream.GetResult()
+	if result == nil { // No tokens decoded
+		return decoded{noSpeech: -1}, nil
+	}
+	return decoded{
+		text:     strings.TrimSpace(result.Text),
+		noSpeech: noSpeechProb(result.YsLogProbs),
+		language: strings.Trim(result.Lang, "<|>"), // Whisper reports language tokens as "<|es|>"
+		words:    wordTimings(result.Tokens, result.Timestamps, result.Durations, float32(len(samples))/float32(sampleRate)),
+	}, nil
+}
 
-	text := strings.TrimSpace(stream.GetResult().Text)
-	if text == "" {
-		return ""
+// TranscribeAudio converts a recording at sampleRate to text — satisfies
+// [AudioTranscriber]. Decoding failures are returned rather than retried, and
+// the wake word, hotwords and segment statistics are left out.
+func (r *WhisperRecognizer) TranscribeAudio(samples []float32, sampleRate int) (string, error) {
+	if len(samples) == 0 {
+		return "", nil
 	}
+	result, err := r.decode(samples, sampleRate)
+	return result.text, err
+}
+
+// likelyNoSpeech reports whether result should be dropped because its
+// estimated no-speech probability exceeds the configured maximum.
+func (r *WhisperRecognizer) likelyNoSpeech(result decoded) bool {
+	if r.maxNoSpeech <= 0 {
+		return false
+	}
+	if result.noSpeech < 0 {
+		r.noSpeechUnknown.Do(func() {
+			log.Println("⚠️ The speech recognition model does not report token probabilities; --max-no-speech-prob has no effect")
+		})
+		return false
+	}
+	if result.noSpeech <= r.maxNoSpeech {
+		return false
+	}
+	if r.verbose {
+		log.Printf("[STT] Skipping %q: no-speech probability %.2f exceeds %.2f", result.text, result.noSpeech, r.maxNoSpeech)
+	}
+	return true
+}
 
-	// Check wake word if configured
-	if r.wakeWord != "" {
-		lowerText := strings.ToLower(text)
-		if !strings.Contains(lowerText, r.wakeWord) {
-			if r.verbose {
-				log.Printf("[STT] Wake word %q not found in %q, ignoring", r.wakeWord, text)
+// noSpeechProb estimates how likely a transcript is to be hallucinated from
+// noise as one minus the mean probability of its tokens, given their log
+// probabilities. sherpa-onnx does not expose Whisper's own no-speech
+// probability, but noise decodes to low-confidence tokens all the same. It
+// returns -1 when the model reports no token probabilities.
+func noSpeechProb(logProbs []float32) float32 {
+	if len(logProbs) == 0 {
+		return -1
+	}
+	var sum float64
+	for _, lp := range logProbs {
+		sum += float64(lp)
+	}
+	return float32(1 - math.Exp(sum/float64(len(logProbs))))
+}
+
+// wordTimings groups tokens into words, a token starting with a space starting
+// a new word, and times each word from its first token's start to the next
+// word's start. The last word ends after its last token's duration when known,
+// otherwise at segmentEnd. Special tokens such as "<|en|>" are skipped. It
+// returns nil when there are no timestamps for the tokens.
+func wordTimings(tokens []string, starts, durations []float32, segmentEnd float32) []WordTiming {
+	if len(tokens) == 0 || len(starts) != len(tokens) {
+		return nil
+	}
+	var words []WordTiming
+	last := -1 // Index of the last token added to a word
+	for i, token := range tokens {
+		if strings.HasPrefix(token, "<|") || strings.TrimSpace(token) == "" {
+			continue
+		}
+		if len(words) == 0 || strings.HasPrefix(token, " ") {
+			if len(words) > 0 {
+				words[len(words)-1].End = starts[i]
 			}
-			return ""
+			words = append(words, WordTiming{Start: starts[i]})
 		}
-		// Remove wake word from text
-		text = removeWakeWord(text, r.wakeWord)
-		text = strings.TrimSpace(text)
-
-		// If only wake word was spoken (no query), prompt for hello
-		if text == "" {
-			log.Printf("🗣️ Wake word %q detected", r.wakeWord)
-			text = "Hello"
-		} else {
-			log.Printf("🗣️ You (wake word detected): %s", text)
+		words[len(words)-1].Word += token
+		last = i
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	end := segmentEnd
+	if len(durations) == len(tokens) && durations[last] > 0 {
+		end = starts[last] + durations[last]
+	}
+	words[len(words)-1].End = max(end, words[len(words)-1].Start)
+	for i := range words {
+		words[i].Word = strings.TrimSpace(words[i].Word)
+	}
+	return words
+}
+
+// decodeWithRetry calls decode, calling it once more if it fails (transient
+// engine errors such as a stream that could not be created) or, when
+// retryEmpty is set, if it yields no text. Errors are logged, not returned:
+// a segment that cannot be decoded is treated as having no speech.
+func decodeWithRetry(decode func() (decoded, error), retryEmpty, verbose bool) decoded {
+	result, err := decode()
+	switch {
+	case err != nil:
+		log.Printf("[STT] Decoding failed, retrying: %v", err)
+	case result.text == "" && retryEmpty:
+		if verbose {
+			log.Println("[STT] Empty transcript, decoding once more")
 		}
-		return text
+	default:
+		return result
+	}
+	if result, err = decode(); err != nil {
+		log.Printf("[STT] Decoding failed: %v", err)
 	}
+	return result
+}
 
-	log.Printf("🗣️ You: %s", text)
-	return text
+// LastLanguage returns the language detected in the most recent segment that
+// produced text — satisfies [LanguageReporter]. With a fixed --stt-language it
+// is that language.
+func (r *WhisperRecognizer) LastLanguage() string {
+	if lang := r.lastLanguage.Load(); lang != nil {
+		return *lang
+	}
+	return ""
+}
+
+// LastVerbatim returns the most recent transcript as spoken, wake word
+// included, when the wake word filter keeps it (WhisperConfig.WakeRaw) —
+// satisfies [VerbatimReporter]. It is "" otherwise.
+func (r *WhisperRecognizer) LastVerbatim() string {
+	if text := r.lastVerbatim.Load(); text != nil {
+		return *text
+	}
+	return ""
+}
+
+// LastInjected reports whether the most recent transcript was
+// [WakeWordPlaceholder], standing in for a bare wake word — satisfies
+// [InjectionReporter].
+func (r *WhisperRecognizer) LastInjected() bool {
+	return r.lastInjected.Load()
+}
+
+// VADStats returns how many segments were transcribed or rejected — satisfies
+// [StatsReporter]. Segments without the wake word still count as transcribed;
+// segments that fail to decode, decode to no text even after the retry, or are
+// dropped as likely non-speech (see [WhisperConfig] MaxNoSpeech) are rejected.
+func (r *WhisperRecognizer) VADStats() VADStats {
+	return VADStats{
+		Produced:    r.produced.Load(),
+		Transcribed: r.transcribed.Load(),
+		Rejected:    r.rejected.Load(),
+	}
 }
 
 // Close releases all resources held by the recognizer.
@@ -141,17 +354,6 @@ func (r *WhisperRecognizer) Close() {
 	}
 }
 
-// removeWakeWord removes the wake word from text, case-insensitively.
-func removeWakeWord(text, wakeWord string) string {
-	lowerText := strings.ToLower(text)
-	idx := strings.Index(lowerText, strings.ToLower(wakeWord))
-	if idx == -1 {
-		return text
-	}
-	result := text[:idx] + text[idx+len(wakeWord):]
-	return strings.TrimSpace(strings.TrimLeft(result, " ,.!?;:-'\""))
-}
-
 // ---------------------------------------------------------------------------
 // WhisperModelProvider
 // ---------------------------------------------------------------------------
@@ -0,0 +1,119 @@
+package tts
+
+import (
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+)
+
+// voiceDirective matches an inline voice directive: [voice:NAME].
+var voiceDirective = regexp.MustCompile(`\[voice:\s*([^\]\s]*)\s*\]`)
+
+// defaultVoiceDirective names the voice in use when a response began, so
+// [voice:default] returns to it (e.g. the narrator after a character's line).
+const defaultVoiceDirective = "default"
+
+// splitVoiceDirectives splits text into sentences like [SplitSentencesWith],
+// taking out [voice:NAME] directives, and returns the catalog voice to speak
+// each sentence in ("" = the voice in use when the response began). A directive
+// switches the voice for the text after it, up to the next directive, and
+// always starts a new sentence, so short sentences are never merged across a
+// change of voice; [voice:default] switches back. Unknown voices are reported
+// and ignored, keeping the current one. voices is nil when text has no
+// directives.
+func splitVoiceDirectives(text string, split SentenceSplitConfig) (sentences, voices []string) {
+	matches := voiceDirective.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return SplitSentencesWith(text, split), nil
+	}
+
+	current, pos := "", 0
+	add := func(part string) {
+		for _, sentence := range SplitSentencesWith(part, split) {
+			sentences = append(sentences, sentence)
+			voices = append(voices, current)
+		}
+	}
+	for _, m := range matches {
+		add(text[pos:m[0]])
+		pos = m[1]
+		switch name := text[m[2]:m[3]]; {
+		case strings.EqualFold(name, defaultVoiceDirective):
+			current = ""
+		case config.GetVoice(name) == nil:
+			log.Printf("⚠️ Unknown voice %q in [voice:...] directive, keeping the current voice", name)
+		default:
+			current = name
+		}
+	}
+	add(text[pos:])
+	return sentences, voices
+}
+
+// voiceSequence switches a synthesizer to the voice of each sentence of a
+// response (see splitVoiceDirectives), or to its base voice for sentences
+// without a directive.
+type voiceSequence struct {
+	synth  VoiceSwitcher // nil when there is nothing to switch
+	voices []string
+	base   string
+}
+
+// newVoiceSequence returns a voiceSequence for voices whose base is base, or
+// the voice in use now when base is "". It does nothing when synth cannot
+// switch voices, or when voices is nil and base is "" (every sentence is
+// spoken in the voice in use).
+//
+// Switching to a voice of another language reloads the Kokoro engine, which
+// takes about as long as startup, so directives that do are reported.
+func newVoiceSequence(synth Synthesizer, voices []string, base string) *voiceSequence {
+	vs, ok := As[VoiceSwitcher](synth)
+	if !ok || (voices == nil && base == "") {
+		return &voiceSequence{}
+	}
+	if base == "" {
+		base = vs.Voice()
+	}
+	if from := config.GetVoice(base); from != nil {
+		for _, name := range slices.Compact(slices.Sorted(slices.Values(voices))) {
+			if to := config.GetVoice(name); to != nil && to.EspeakCode != from.EspeakCode {
+				log.Printf("⚠️ [voice:%s] speaks %s, unlike %s; switching reloads the TTS engine and delays the reply", name, to.Language, base)
+			}
+		}
+	}
+	return &voiceSequence{synth: vs, voices: voices, base: base}
+}
+
+// apply switches to the voice of sentence i before it is synthesized.
+func (s *voiceSequence) apply(i int) {
+	if s.synth == nil {
+		return
+	}
+	voice := s.base
+	if s.voices != nil && s.voices[i] != "" {
+		voice = s.voices[i]
+	}
+	s.use(voice)
+}
+
+// restore switches back to the base voice. Responses spoken by the TTS
+// processor leave this to the next one, which skips the switch when it starts
+// in the same voice.
+func (s *voiceSequence) restore() {
+	if s.synth != nil {
+		s.use(s.base)
+	}
+}
+
+// use switches to voice unless it is already in use.
+func (s *voiceSequence) use(voice string) {
+	if s.synth.Voice() == voice {
+		return
+	}
+	if err := s.synth.SetVoice(voice); err != nil {
+		log.Printf("⚠️ Failed to switch to voice %s: %v", voice, err)
+	}
+}
@@ -0,0 +1,84 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+)
+
+// voiceSynth additionally switches voices, recording the voice of each text.
+type voiceSynth struct {
+	recordingSynth
+	voice    string
+	spoken   []string // "voice: text"
+	switches []string
+}
+
+func (s *voiceSynth) Synthesize(ctx context.Context, text string) (*AudioOutput, error) {
+	s.spoken = append(s.spoken, s.voice+": "+text)
+	return s.recordingSynth.Synthesize(ctx, text)
+}
+
+func (s *voiceSynth) Voice() string { return s.voice }
+
+func (s *voiceSynth) SetVoice(name string) error {
+	if config.GetVoice(name) == nil {
+		return fmt.Errorf("unknown voice %q", name)
+	}
+	s.voice = name
+	s.switches = append(s.switches, name)
+	return nil
+}
+
+func TestSplitVoiceDirectives(t *testing.T) {
+	sentences, voices := splitVoiceDirectives("Once upon a time. [voice:bf_emma] Hello there! Who are you?"+
+		"[voice:am_adam]I am a bear. [voice:nobody] Still a bear. [voice:Default] The end.", SentenceSplitConfig{})
+	wantSentences := []string{"Once upon a time.", "Hello there!", "Who are you?", "I am a bear.", "Still a bear.", "The end."}
+	wantVoices := []string{"", "bf_emma", "bf_emma", "am_adam", "am_adam", ""}
+	if !slices.Equal(sentences, wantSentences) || !slices.Equal(voices, wantVoices) {
+		t.Errorf("splitVoiceDirectives =\n%q %q\nwant\n%q %q", sentences, voices, wantSentences, wantVoices)
+	}
+
+	if sentences, voices := splitVoiceDirectives("No directives here.", SentenceSplitConfig{}); len(sentences) != 1 || voices != nil {
+		t.Errorf("splitVoiceDirectives without directives = %q, %q, want one sentence and nil", sentences, voices)
+	}
+}
+
+func TestSynthesizeTextSwitchesVoicesAndRestores(t *testing.T) {
+	synth := &voiceSynth{voice: "af_bella"}
+	cfg := config.DefaultConfig()
+	cfg.VoiceDirectives = true
+
+	if _, err := SynthesizeText(context.Background(), synth, `The fox spoke. [voice:bm_george] "Good day." [voice:default] Then it left.`, cfg); err != nil {
+		t.Fatalf("SynthesizeText: %v", err)
+	}
+	want := []string{"af_bella: The fox spoke.", `bm_george: "Good day."`, "af_bella: Then it left."}
+	if !slices.Equal(synth.spoken, want) {
+		t.Errorf("spoken =\n%q\nwant\n%q", synth.spoken, want)
+	}
+
+	synth = &voiceSynth{voice: "af_bella"}
+	if _, err := SynthesizeText(context.Background(), synth, "[voice:bf_emma] Hello. Goodbye.", cfg); err != nil {
+		t.Fatalf("SynthesizeText: %v", err)
+	}
+	if synth.voice != "af_bella" {
+		t.Errorf("voice after synthesis = %q, want the initial voice restored", synth.voice)
+	}
+}
+
+func TestVoiceSequenceLeavesSwitchBackToNextResponse(t *testing.T) {
+	synth := &voiceSynth{voice: "af_bella"}
+	for _, voices := range [][]string{{"bf_emma"}, {"bf_emma", ""}, nil} {
+		seq := newVoiceSequence(synth, voices, "af_bella")
+		for i := range max(len(voices), 1) {
+			seq.apply(i)
+		}
+	}
+	// The second response starts in the voice the first ended in
+	if want := []string{"bf_emma", "af_bella"}; !slices.Equal(synth.switches, want) {
+		t.Errorf("switches = %q, want %q", synth.switches, want)
+	}
+}
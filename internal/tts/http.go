@@ -0,0 +1,282 @@
+// Package tts provides text-to-speech functionality.
+// This file contains the remote HTTP TTS implementation.
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+)
+
+// Compile-time interface compliance check.
+var (
+	_ SpeedSynthesizer = (*HTTPSynthesizer)(nil)
+	_ SpeedController  = (*HTTPSynthesizer)(nil)
+)
+
+const (
+	// httpDefaultSampleRate is reported by SampleRate until the server has answered.
+	httpDefaultSampleRate = 24000
+
+	// httpErrorPhrase is fetched at startup and played when the server is unreachable.
+	httpErrorPhrase = "Sorry, I can't reach the speech server right now."
+)
+
+// SpeakRequest is the JSON body POSTed to a remote TTS server. The server
+// replies with a WAV file (16-bit PCM or 32-bit float).
+type SpeakRequest struct {
+	Text  string  `json:"text"`
+	Voice string  `json:"voice,omitempty"`
+	Speed float32 `json:"speed,omitempty"`
+}
+
+// HTTPSynthesizer implements [Synthesizer] by delegating to a remote TTS server,
+// so synthesis can run on a more powerful machine than the rest of the pipeline.
+//
+// If the server becomes unreachable, Synthesize returns a cached recording of an
+// apology phrase (fetched at startup) instead of failing, so the user hears why
+// the assistant went quiet.
+type HTTPSynthesizer struct {
+	url        string
+	voice      string
+	speed      float32
+	verbose    bool
+	client     *http.Client
+	mu         sync.Mutex   // Protects speed, sampleRate and fallback
+	sampleRate int          // Rate of the most recent response
+	fallback   *AudioOutput // Cached error phrase (nil if unavailable)
+	caching    atomic.Bool  // A background fetch of the error phrase is running
+	noFallback bool         // Never fetch or return the error phrase
+}
+
+// HTTPConfig holds configuration for the remote HTTP TTS synthesizer.
+type HTTPConfig struct {
+	URL     string        // Endpoint that accepts a [SpeakRequest] and returns WAV audio
+	Voice   string        // Voice name forwarded to the server
+	Speed   float32       // Speech speed forwarded to the server
+	Timeout time.Duration // Per-request timeout (0 = 30s)
+	Verbose bool
+
+	// NoFallback makes failed requests return their error instead of the error
+	// phrase, e.g. for offline synthesis, where the phrase would end up in the
+	// output as if it had been asked for.
+	NoFallback bool
+}
+
+// NewHTTPSynthesizer creates an [HTTPSynthesizer] that satisfies [Synthesizer].
+// Unless cfg.NoFallback is set, it tries to fetch the error phrase up front; an
+// unreachable server at startup is logged but not fatal.
+func NewHTTPSynthesizer(cfg *HTTPConfig) (*HTTPSynthesizer, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("the http TTS backend requires --tts-url")
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	s := &HTTPSynthesizer{
+		url:        cfg.URL,
+		voice:      cfg.Voice,
+		speed:      cfg.Speed,
+		verbose:    cfg.Verbose,
+		client:     &http.Client{Timeout: timeout},
+		sampleRate: httpDefaultSampleRate,
+		noFallback: cfg.NoFallback,
+	}
+	if s.noFallback {
+		return s, nil
+	}
+
+	if out, err := s.fetch(context.Background(), httpErrorPhrase, s.speed); err != nil {
+		log.Printf("⚠️ Remote TTS server %s not reachable yet: %v", cfg.URL, err)
+	} else {
+		s.fallback = out
+		log.Printf("🌐 Remote TTS server ready: %s (%d Hz)", cfg.URL, out.SampleRate)
+	}
+	return s, nil
+}
+
+// Synthesize converts text to audio on the remote server — satisfies [Synthesizer].
+// On network or server errors it returns the cached error phrase when available
+// (see [HTTPConfig.NoFallback]).
+func (s *HTTPSynthesizer) Synthesize(ctx context.Context, text string) (*AudioOutput, error) {
+	return s.synthesize(ctx, text, s.currentSpeed())
+}
+
+// SynthesizeAtSpeed is like Synthesize at factor times the configured speed
+// (or the server's default speed when none is configured) — satisfies
+// [SpeedSynthesizer].
+func (s *HTTPSynthesizer) SynthesizeAtSpeed(ctx context.Context, text string, factor float32) (*AudioOutput, error) {
+	return s.synthesize(ctx, text, s.Speed()*factor)
+}
+
+// Speed returns the speech speed multiplier in use, 1 when the server's default
+// speed is used — satisfies [SpeedController].
+func (s *HTTPSynthesizer) Speed() float32 {
+	if speed := s.currentSpeed(); speed != 0 {
+		return speed
+	}
+	return 1
+}
+
+// SetSpeed changes the speed requested for subsequent sentences — satisfies
+// [SpeedController].
+func (s *HTTPSynthesizer) SetSpeed(speed float32) error {
+	if err := checkSpeed(speed); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.speed = speed
+	return nil
+}
+
+// currentSpeed returns the speed to request (0 = the server's default).
+func (s *HTTPSynthesizer) currentSpeed() float32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.speed
+}
+
+// synthesize requests text at speed (0 = the server's default), falling back
+// to the cached error phrase on failure. A request cancelled through ctx
+// returns ctx's error instead.
+func (s *HTTPSynthesizer) synthesize(ctx context.Context, text string, speed float32) (*AudioOutput, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("empty text")
+	}
+
+	if s.verbose {
+		log.Printf("[TTS] Requesting remote synthesis: %q", text)
+	}
+
+	out, err := s.fetch(ctx, text, speed)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+
+	s.mu.Lock()
+	fallback := s.fallback
+	s.mu.Unlock()
+
+	if err == nil {
+		// The server came up after startup: cache the error phrase in the background.
+		if fallback == nil && !s.noFallback && s.caching.CompareAndSwap(false, true) {
+			go s.cacheFallback()
+		}
+		log.Printf("🎵 Generated speech (%d samples)", len(out.Samples))
+		return out, nil
+	}
+	if fallback == nil {
+		return nil, err
+	}
+	log.Printf("⚠️ Remote TTS failed, playing fallback phrase: %v", err)
+	return fallback, nil
+}
+
+// fetch POSTs text to the server and decodes the WAV response.
+func (s *HTTPSynthesizer) fetch(ctx context.Context, text string, speed float32) (*AudioOutput, error) {
+	body, err := json.Marshal(SpeakRequest{Text: text, Voice: s.voice, Speed: speed})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("remote TTS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("remote TTS returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	buf, err := audio.DecodeWAV(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote TTS audio: %w", err)
+	}
+	if len(buf.Samples) == 0 {
+		return nil, fmt.Errorf("remote TTS returned no audio")
+	}
+
+	s.mu.Lock()
+	s.sampleRate = buf.SampleRate
+	s.mu.Unlock()
+
+	return &AudioOutput{Samples: buf.Samples, SampleRate: buf.SampleRate}, nil
+}
+
+// cacheFallback fetches the error phrase if it was not available at startup.
+func (s *HTTPSynthesizer) cacheFallback() {
+	defer s.caching.Store(false)
+	out, err := s.fetch(context.Background(), httpErrorPhrase, s.currentSpeed())
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	if s.fallback == nil {
+		s.fallback = out
+	}
+	s.mu.Unlock()
+}
+
+// SampleRate returns the sample rate of the most recent response — satisfies
+// [Synthesizer]. Playback resamples per buffer, so a server that changes rate
+// is handled transparently.
+func (s *HTTPSynthesizer) SampleRate() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sampleRate
+}
+
+// Close releases idle connections — satisfies [Synthesizer].
+func (s *HTTPSynthesizer) Close() {
+	s.client.CloseIdleConnections()
+}
+
+// HTTPModelProvider implements [ModelProvider] for the remote HTTP backend.
+// Models live on the server, so there is nothing to download or verify locally.
+type HTTPModelProvider struct{}
+
+// Name returns the human-readable name of this TTS implementation.
+func (p *HTTPModelProvider) Name() string {
+	return "Remote HTTP"
+}
+
+// EnsureModels is a no-op; the remote server owns its models.
+func (p *HTTPModelProvider) EnsureModels(modelDir string, force bool) error {
+	return nil
+}
+
+// VerifyModels always reports no missing files.
+func (p *HTTPModelProvider) VerifyModels(modelDir string) []string {
+	return nil
+}
+
+// PrintVoices explains that voices are defined by the remote server — satisfies [ModelProvider].
+func (p *HTTPModelProvider) PrintVoices() {
+	fmt.Println("Voices are provided by the remote TTS server; pass one with --tts-voice.")
+}
+
+// PrintVoiceInfo is not supported for remote voices — satisfies [ModelProvider].
+func (p *HTTPModelProvider) PrintVoiceInfo(name string) error {
+	return fmt.Errorf("voice details for %q are only available on the remote TTS server", name)
+}
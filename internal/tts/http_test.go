@@ -0,0 +1,192 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// wavBytes encodes samples as a mono 32-bit float WAV file.
+func wavBytes(samples []float32, rate int) []byte {
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+len(samples)*4))
+	b.WriteString("WAVEfmt ")
+	for _, v := range []any{uint32(16), uint16(3), uint16(1), uint32(rate), uint32(rate * 4), uint16(4), uint16(32)} {
+		binary.Write(&b, binary.LittleEndian, v)
+	}
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(len(samples)*4))
+	for _, s := range samples {
+		binary.Write(&b, binary.LittleEndian, math.Float32bits(s))
+	}
+	return b.Bytes()
+}
+
+func TestHTTPSynthesizerSynthesize(t *testing.T) {
+	var got SpeakRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(wavBytes([]float32{0.1, 0.2, 0.3}, 22050))
+	}))
+	defer srv.Close()
+
+	s, err := NewHTTPSynthesizer(&HTTPConfig{URL: srv.URL, Voice: "af_bella", Speed: 1.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	out, err := s.Synthesize(context.Background(), "Hello there.")
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+	if len(out.Samples) != 3 || out.SampleRate != 22050 {
+		t.Errorf("got %d samples at %d Hz, want 3 at 22050", len(out.Samples), out.SampleRate)
+	}
+	if got.Text != "Hello there." || got.Voice != "af_bella" || got.Speed != 1.1 {
+		t.Errorf("request = %+v", got)
+	}
+	if s.SampleRate() != 22050 {
+		t.Errorf("SampleRate() = %d, want 22050", s.SampleRate())
+	}
+}
+
+func TestHTTPSynthesizerSetSpeed(t *testing.T) {
+	var got SpeakRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write(wavBytes([]float32{0.1}, 22050))
+	}))
+	defer srv.Close()
+
+	s, err := NewHTTPSynthesizer(&HTTPConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Speed() != 1 {
+		t.Errorf("Speed() with the server's default = %v, want 1", s.Speed())
+	}
+
+	for _, bad := range []float32{0.4, 2.1} {
+		if err := s.SetSpeed(bad); err == nil {
+			t.Errorf("SetSpeed(%v) succeeded, want an out-of-range error", bad)
+		}
+	}
+	if err := s.SetSpeed(0.8); err != nil {
+		t.Fatalf("SetSpeed(0.8): %v", err)
+	}
+	if _, err := s.Synthesize(context.Background(), "Slowly now."); err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+	if got.Speed != 0.8 || s.Speed() != 0.8 {
+		t.Errorf("requested speed = %v, Speed() = %v, want 0.8", got.Speed, s.Speed())
+	}
+}
+
+func TestHTTPSynthesizerFallsBackToErrorPhrase(t *testing.T) {
+	var fail atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(wavBytes([]float32{0.5}, 24000))
+	}))
+	defer srv.Close()
+
+	s, err := NewHTTPSynthesizer(&HTTPConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fail.Store(true)
+	out, err := s.Synthesize(context.Background(), "What's the weather?")
+	if err != nil {
+		t.Fatalf("expected fallback audio, got error: %v", err)
+	}
+	if len(out.Samples) != 1 || out.Samples[0] != 0.5 {
+		t.Errorf("got %v, want cached error phrase", out.Samples)
+	}
+}
+
+func TestHTTPSynthesizerNoFallbackReturnsError(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) > 1 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(wavBytes([]float32{0.5}, 24000))
+	}))
+	defer srv.Close()
+
+	s, err := NewHTTPSynthesizer(&HTTPConfig{URL: srv.URL, NoFallback: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Synthesize(context.Background(), "Hello."); err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+	if _, err := s.Synthesize(context.Background(), "What's the weather?"); err == nil {
+		t.Error("Synthesize returned audio for a failed request, want the error")
+	}
+}
+
+func TestHTTPSynthesizerCancelSkipsFallback(t *testing.T) {
+	var block atomic.Bool
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if block.Load() {
+			<-release // A slow sentence, abandoned by the client
+			return
+		}
+		w.Write(wavBytes([]float32{0.5}, 24000))
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	s, err := NewHTTPSynthesizer(&HTTPConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block.Store(true)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if out, err := s.Synthesize(ctx, "A very long sentence."); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Synthesize after cancel = %v, %v; want the context error, not the fallback phrase", out, err)
+	}
+}
+
+func TestHTTPSynthesizerUnreachableWithoutFallback(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close() // Nothing listening
+
+	s, err := NewHTTPSynthesizer(&HTTPConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatalf("startup should tolerate an unreachable server: %v", err)
+	}
+	if _, err := s.Synthesize(context.Background(), "Hello."); err == nil {
+		t.Error("expected error when the server is down and no fallback is cached")
+	}
+}
+
+func TestNewHTTPSynthesizerRequiresURL(t *testing.T) {
+	if _, err := NewHTTPSynthesizer(&HTTPConfig{}); err == nil {
+		t.Error("expected error without a URL")
+	}
+}
@@ -3,137 +3,61 @@
 package tts
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"sync"
 
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
 	"github.com/agalue/sherpa-voice-assistant/internal/setup"
 	"github.com/agalue/sherpa-voice-assistant/internal/sherpa"
 )
 
-// Compile-time interface compliance check.
-var _ Synthesizer = (*KokoroSynthesizer)(nil)
+// Compile-time interface compliance checks.
+var (
+	_ VoiceSwitcher    = (*KokoroSynthesizer)(nil)
+	_ SpeedSynthesizer = (*KokoroSynthesizer)(nil)
+	_ SpeedController  = (*KokoroSynthesizer)(nil)
+)
 
 // ---------------------------------------------------------------------------
 // Kokoro voice catalog (53 voices across 9 languages)
 // ---------------------------------------------------------------------------
 
-// kokoroVoice contains essential runtime data for a single Kokoro TTS voice.
-type kokoroVoice struct {
-	speakerID  int
-	espeakCode string // Language code for espeak-ng
-	language   string // Human-readable language name
-}
-
-// kokoroVoices maps voice names to metadata for all 53 Kokoro v1.0 voices.
-var kokoroVoices = map[string]kokoroVoice{
-	// American English (20 voices)
-	"af_alloy":   {speakerID: 0, espeakCode: "en-us", language: "American English"},
-	"af_aoede":   {speakerID: 1, espeakCode: "en-us", language: "American English"},
-	"af_bella":   {speakerID: 2, espeakCode: "en-us", language: "American English"},
-	"af_heart":   {speakerID: 3, espeakCode: "en-us", language: "American English"},
-	"af_jessica": {speakerID: 4, espeakCode: "en-us", language: "American English"},
-	"af_kore":    {speakerID: 5, espeakCode: "en-us", language: "American English"},
-	"af_nicole":  {speakerID: 6, espeakCode: "en-us", language: "American English"},
-	"af_nova":    {speakerID: 7, espeakCode: "en-us", language: "American English"},
-	"af_river":   {speakerID: 8, espeakCode: "en-us", language: "American English"},
-	"af_sarah":   {speakerID: 9, espeakCode: "en-us", language: "American English"},
-	"af_sky":     {speakerID: 10, espeakCode: "en-us", language: "American English"},
-	"am_adam":    {speakerID: 11, espeakCode: "en-us", language: "American English"},
-	"am_echo":    {speakerID: 12, espeakCode: "en-us", language: "American English"},
-	"am_eric":    {speakerID: 13, espeakCode: "en-us", language: "American English"},
-	"am_fenrir":  {speakerID: 14, espeakCode: "en-us", language: "American English"},
-	"am_liam":    {speakerID: 15, espeakCode: "en-us", language: "American English"},
-	"am_michael": {speakerID: 16, espeakCode: "en-us", language: "American English"},
-	"am_onyx":    {speakerID: 17, espeakCode: "en-us", language: "American English"},
-	"am_puck":    {speakerID: 18, espeakCode: "en-us", language: "American English"},
-	"am_santa":   {speakerID: 19, espeakCode: "en-us", language: "American English"},
-
-	// British English (8 voices)
-	"bf_alice":    {speakerID: 20, espeakCode: "en-gb", language: "British English"},
-	"bf_emma":     {speakerID: 21, espeakCode: "en-gb", language: "British English"},
-	"bf_isabella": {speakerID: 22, espeakCode: "en-gb", language: "British English"},
-	"bf_lily":     {speakerID: 23, espeakCode: "en-gb", language: "British English"},
-	"bm_daniel":   {speakerID: 24, espeakCode: "en-gb", language: "British English"},
-	"bm_fable":    {speakerID: 25, espeakCode: "en-gb", language: "British English"},
-	"bm_george":   {speakerID: 26, espeakCode: "en-gb", language: "British English"},
-	"bm_lewis":    {speakerID: 27, espeakCode: "en-gb", language: "British English"},
-
-	// Spanish (2 voices)
-	"ef_dora": {speakerID: 28, espeakCode: "es", language: "Spanish"},
-	"em_alex": {speakerID: 29, espeakCode: "es", language: "Spanish"},
-
-	// French (1 voice)
-	"ff_siwis": {speakerID: 30, espeakCode: "fr-fr", language: "French"},
-
-	// Hindi (4 voices)
-	"hf_alpha": {speakerID: 31, espeakCode: "hi", language: "Hindi"},
-	"hf_beta":  {speakerID: 32, espeakCode: "hi", language: "Hindi"},
-	"hm_omega": {speakerID: 33, espeakCode: "hi", language: "Hindi"},
-	"hm_psi":   {speakerID: 34, espeakCode: "hi", language: "Hindi"},
-
-	// Italian (2 voices)
-	"if_sara":   {speakerID: 35, espeakCode: "it", language: "Italian"},
-	"im_nicola": {speakerID: 36, espeakCode: "it", language: "Italian"},
-
-	// Japanese (5 voices)
-	"jf_alpha":      {speakerID: 37, espeakCode: "ja", language: "Japanese"},
-	"jf_gongitsune": {speakerID: 38, espeakCode: "ja", language: "Japanese"},
-	"jf_nezumi":     {speakerID: 39, espeakCode: "ja", language: "Japanese"},
-	"jf_tebukuro":   {speakerID: 40, espeakCode: "ja", language: "Japanese"},
-	"jm_kumo":       {speakerID: 41, espeakCode: "ja", language: "Japanese"},
-
-	// Portuguese BR (3 voices)
-	"pf_dora":  {speakerID: 42, espeakCode: "pt-br", language: "Portuguese BR"},
-	"pm_alex":  {speakerID: 43, espeakCode: "pt-br", language: "Portuguese BR"},
-	"pm_santa": {speakerID: 44, espeakCode: "pt-br", language: "Portuguese BR"},
-
-	// Mandarin Chinese (8 voices)
-	"zf_xiaobei":  {speakerID: 45, espeakCode: "cmn", language: "Mandarin Chinese"},
-	"zf_xiaoni":   {speakerID: 46, espeakCode: "cmn", language: "Mandarin Chinese"},
-	"zf_xiaoxiao": {speakerID: 47, espeakCode: "cmn", language: "Mandarin Chinese"},
-	"zf_xiaoyi":   {speakerID: 48, espeakCode: "cmn", language: "Mandarin Chinese"},
-	"zm_yunjian":  {speakerID: 49, espeakCode: "cmn", language: "Mandarin Chinese"},
-	"zm_yunxi":    {speakerID: 50, espeakCode: "cmn", language: "Mandarin Chinese"},
-	"zm_yunxia":   {speakerID: 51, espeakCode: "cmn", language: "Mandarin Chinese"},
-	"zm_yunyang":  {speakerID: 52, espeakCode: "cmn", language: "Mandarin Chinese"},
-}
-
-// getKokoroVoice returns voice data for a given voice name, or nil if unknown.
-func getKokoroVoice(name string) *kokoroVoice {
-	if v, ok := kokoroVoices[name]; ok {
-		return &v
-	}
-	return nil
-}
-
 // KokoroSynthesizer implements [Synthesizer] using the Kokoro multi-lingual TTS
 // model via sherpa-onnx. It is safe for concurrent use; a mutex serialises calls
 // to the underlying ONNX runtime.
 type KokoroSynthesizer struct {
 	tts        *sherpa.OfflineTts // Kokoro TTS engine
+	cfg        KokoroConfig       // Configuration the engine was created from (Voice is the startup voice)
+	voice      string             // Voice in use
 	sampleRate int                // Output sample rate (24kHz for Kokoro)
 	speakerID  int                // Speaker/voice identifier
-	speed      float32            // Speech speed multiplier
+	speed      float32            // Speech speed multiplier (see SetSpeed)
 	verbose    bool               // Enable verbose logging
-	mu         sync.Mutex         // Protects TTS engine access
+	mu         sync.Mutex         // Protects TTS engine access, voice and speed
 }
 
 // KokoroConfig holds configuration for the Kokoro TTS synthesizer.
 // Only generic configuration is required — all model-specific paths (model.onnx,
 // voices.bin, tokens.txt, espeak-ng-data, lexicon) are derived from ModelDir.
 type KokoroConfig struct {
-	ModelDir   string // Base model directory (Kokoro files resolved automatically)
-	Voice      string // Voice name (e.g. "af_bella"); looked up in the Voices catalog
-	SpeakerID  int
-	Speed      float32
-	Provider   string // Hardware acceleration provider (cpu, cuda, coreml)
-	Verbose    bool
-	NumThreads int
+	ModelDir    string // Base model directory (Kokoro files resolved automatically)
+	Voice       string // Voice name (e.g. "af_bella"); looked up in the Voices catalog
+	UserLexicon string // Optional lexicon file whose entries override the built-in lexicon
+	SpeakerID   int
+	Speed       float32
+	Provider    string // Hardware acceleration provider (cpu, cuda, coreml)
+	Verbose     bool
+	NumThreads  int
+
+	// MaxNumSentences is how many sentences the engine may synthesize per batch
+	// (0 = 1). Current Kokoro models only support 1; larger values are passed to
+	// sherpa-onnx as-is with a warning.
+	MaxNumSentences int
 }
 
 // AudioOutput type is defined in tts.go.
@@ -141,11 +65,30 @@ type KokoroConfig struct {
 // NewKokoroSynthesizer creates a [KokoroSynthesizer] that satisfies [Synthesizer].
 //
 // All file paths are derived from cfg.ModelDir. The voice's language and optional
-// lexicon are determined automatically from the [Voices] catalog.
+// lexicon are determined automatically from the [Voices] catalog; cfg.UserLexicon
+// is merged ahead of that lexicon when the language supports one.
 func NewKokoroSynthesizer(cfg *KokoroConfig) (*KokoroSynthesizer, error) {
-	voice := getKokoroVoice(cfg.Voice)
+	tts, err := newKokoroEngine(cfg, cfg.Voice)
+	if err != nil {
+		return nil, err
+	}
+	return &KokoroSynthesizer{
+		tts:        tts,
+		cfg:        *cfg,
+		voice:      cfg.Voice,
+		sampleRate: 24000, // Kokoro default sample rate
+		speakerID:  cfg.SpeakerID,
+		speed:      cfg.Speed,
+		verbose:    cfg.Verbose,
+	}, nil
+}
+
+// newKokoroEngine creates the sherpa-onnx engine for cfg, set up for the
+// language and lexicon of voiceName.
+func newKokoroEngine(cfg *KokoroConfig, voiceName string) (*sherpa.OfflineTts, error) {
+	voice := config.GetVoice(voiceName)
 	if voice == nil {
-		return nil, fmt.Errorf("unknown TTS voice %q; run with --list-voices to see available voices", cfg.Voice)
+		return nil, fmt.Errorf("unknown TTS voice %q; run with --list-voices to see available voices", voiceName)
 	}
 
 	kokoroDir := filepath.Join(cfg.ModelDir, "tts", "kokoro-multi-lang-v1_0")
@@ -153,7 +96,7 @@ func NewKokoroSynthesizer(cfg *KokoroConfig) (*KokoroSynthesizer, error) {
 	voicesPath := filepath.Join(kokoroDir, "voices.bin")
 	tokensPath := filepath.Join(kokoroDir, "tokens.txt")
 	dataDir := filepath.Join(kokoroDir, "espeak-ng-data")
-	lexicon := lexiconForVoice(kokoroDir, cfg.Voice)
+	lexicon := withUserLexicon(lexiconForVoice(kokoroDir, voiceName), cfg.UserLexicon, voice)
 
 	ttsConfig := &sherpa.OfflineTtsConfig{}
 
@@ -163,11 +106,11 @@ func NewKokoroSynthesizer(cfg *KokoroConfig) (*KokoroSynthesizer, error) {
 	ttsConfig.Model.Kokoro.Tokens = tokensPath
 	ttsConfig.Model.Kokoro.DataDir = dataDir
 	ttsConfig.Model.Kokoro.Lexicon = lexicon
-	ttsConfig.Model.Kokoro.Lang = voice.espeakCode       // Derived from voice catalog
-	ttsConfig.Model.Kokoro.LengthScale = 1.0 / cfg.Speed // Inverse for speed control
+	ttsConfig.Model.Kokoro.Lang = voice.EspeakCode // Derived from voice catalog
+	ttsConfig.Model.Kokoro.LengthScale = 1.0       // Speed is passed with each call, so it can change at runtime
 	ttsConfig.Model.NumThreads = cfg.NumThreads
 	ttsConfig.Model.Provider = cfg.Provider // Hardware acceleration (cpu, cuda, coreml)
-	ttsConfig.MaxNumSentences = 1           // Kokoro TTS only supports 1
+	ttsConfig.MaxNumSentences = kokoroMaxNumSentences(cfg.MaxNumSentences)
 	ttsConfig.Model.Debug = 0
 	if cfg.Verbose {
 		ttsConfig.Model.Debug = 1
@@ -177,20 +120,49 @@ func NewKokoroSynthesizer(cfg *KokoroConfig) (*KokoroSynthesizer, error) {
 	if tts == nil {
 		return nil, fmt.Errorf("failed to create Kokoro TTS synthesizer (model dir: %s); verify model files are valid or re-download with --setup", kokoroDir)
 	}
+	return tts, nil
+}
 
-	return &KokoroSynthesizer{
-		tts:        tts,
-		sampleRate: 24000, // Kokoro default sample rate
-		speakerID:  cfg.SpeakerID,
-		speed:      cfg.Speed,
-		verbose:    cfg.Verbose,
-	}, nil
+// kokoroMaxNumSentences resolves [KokoroConfig.MaxNumSentences], defaulting to
+// the single sentence per batch that Kokoro supports.
+func kokoroMaxNumSentences(n int) int {
+	if n <= 0 {
+		return 1
+	}
+	if n > 1 {
+		log.Printf("⚠️ Kokoro only supports 1 sentence per batch; using %d as requested", n)
+	}
+	return n
 }
 
 // Synthesize converts text to audio — satisfies [Synthesizer].
-func (s *KokoroSynthesizer) Synthesize(text string) (*AudioOutput, error) {
+func (s *KokoroSynthesizer) Synthesize(ctx context.Context, text string) (*AudioOutput, error) {
+	return s.synthesize(ctx, text, 1, nil)
+}
+
+// SynthesizeToCallback converts text to audio, passing each chunk to onChunk
+// as the engine generates it — satisfies [StreamingSynthesizer].
+func (s *KokoroSynthesizer) SynthesizeToCallback(ctx context.Context, text string, onChunk func(AudioOutput) error) error {
+	_, err := s.synthesize(ctx, text, 1, onChunk)
+	return err
+}
+
+// SynthesizeAtSpeed converts text to audio at factor times the configured
+// speed — satisfies [SpeedSynthesizer].
+func (s *KokoroSynthesizer) SynthesizeAtSpeed(ctx context.Context, text string, factor float32) (*AudioOutput, error) {
+	return s.synthesize(ctx, text, factor, nil)
+}
+
+// synthesize converts text to audio at factor times the configured speed. The
+// engine reports progress as it generates, and generation stops at the next
+// report once ctx is done. Each chunk reported is also passed to onChunk, if
+// not nil, and an error from it stops generation the same way.
+func (s *KokoroSynthesizer) synthesize(ctx context.Context, text string, factor float32, onChunk func(AudioOutput) error) (*AudioOutput, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err // Cancelled while waiting for the engine
+	}
 
 	text = strings.TrimSpace(text)
 	if text == "" {
@@ -204,9 +176,24 @@ func (s *KokoroSynthesizer) Synthesize(text string) (*AudioOutput, error) {
 	// Generate audio
 	cfg := &sherpa.GenerationConfig{
 		Sid:   s.speakerID,
-		Speed: s.speed,
+		Speed: s.speed * factor,
+	}
+	var chunkErr error
+	audio := s.tts.GenerateWithConfig(text, cfg, func(samples []float32, _ float32) bool {
+		if onChunk != nil && ctx.Err() == nil {
+			chunkErr = onChunk(AudioOutput{Samples: samples, SampleRate: s.sampleRate})
+		}
+		return chunkErr == nil && ctx.Err() == nil // false stops generation
+	})
+	if err := ctx.Err(); err != nil {
+		if s.verbose {
+			log.Printf("[TTS] Synthesis cancelled: %q", text)
+		}
+		return nil, err
+	}
+	if chunkErr != nil {
+		return nil, chunkErr
 	}
-	audio := s.tts.GenerateWithConfig(text, cfg, nil)
 	if audio == nil || len(audio.Samples) == 0 {
 		return nil, fmt.Errorf("TTS generation failed")
 	}
@@ -219,6 +206,65 @@ func (s *KokoroSynthesizer) Synthesize(text string) (*AudioOutput, error) {
 	}, nil
 }
 
+// Speed returns the speech speed multiplier in use — satisfies [SpeedController].
+func (s *KokoroSynthesizer) Speed() float32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.speed
+}
+
+// SetSpeed changes the speech speed multiplier of subsequent sentences —
+// satisfies [SpeedController].
+func (s *KokoroSynthesizer) SetSpeed(speed float32) error {
+	if err := checkSpeed(speed); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.speed = speed
+	return nil
+}
+
+// Voice returns the name of the voice in use — satisfies [VoiceSwitcher].
+func (s *KokoroSynthesizer) Voice() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.voice
+}
+
+// SetVoice switches to the named catalog voice — satisfies [VoiceSwitcher].
+// Voices of the same language only change the speaker; a voice of another
+// language reloads the engine for that language, which takes about as long as
+// startup did. Switching back to the startup voice restores its speaker ID.
+func (s *KokoroSynthesizer) SetVoice(name string) error {
+	voice := config.GetVoice(name)
+	if voice == nil {
+		return fmt.Errorf("unknown TTS voice %q; run with --list-voices to see available voices", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name == s.voice {
+		return nil
+	}
+	if current := config.GetVoice(s.voice); current == nil || current.EspeakCode != voice.EspeakCode {
+		tts, err := newKokoroEngine(&s.cfg, name)
+		if err != nil {
+			return err
+		}
+		if s.tts != nil {
+			sherpa.DeleteOfflineTts(s.tts)
+		}
+		s.tts = tts
+	}
+	s.voice = name
+	s.speakerID = voice.SpeakerID
+	if name == s.cfg.Voice {
+		s.speakerID = s.cfg.SpeakerID
+	}
+	return nil
+}
+
 // SampleRate returns the output sample rate — satisfies [Synthesizer].
 func (s *KokoroSynthesizer) SampleRate() int {
 	return s.sampleRate
@@ -244,11 +290,11 @@ func (s *KokoroSynthesizer) Close() {
 // field. Returns an empty string when there is no matching lexicon, which is the
 // expected case for most languages.
 func lexiconForVoice(kokoroDir, voiceName string) string {
-	v := getKokoroVoice(voiceName)
+	v := config.GetVoice(voiceName)
 	if v == nil {
 		return ""
 	}
-	switch v.espeakCode {
+	switch v.EspeakCode {
 	case "en-us":
 		lexPath := filepath.Join(kokoroDir, "lexicon-us-en.txt")
 		if _, err := os.Stat(lexPath); err != nil {
@@ -278,6 +324,27 @@ func lexiconForVoice(kokoroDir, voiceName string) string {
 	}
 }
 
+// withUserLexicon merges the user lexicon into base for the sherpa-onnx lexicon
+// field. The user file is listed first because sherpa-onnx keeps the first
+// entry for a duplicated word, so user pronunciations override built-in ones.
+// Languages pronounced purely through espeak-ng ignore lexicons; for those the
+// user lexicon is dropped with a warning.
+func withUserLexicon(base, user string, v *config.Voice) string {
+	if user == "" {
+		return base
+	}
+	switch v.EspeakCode {
+	case "en-us", "en-gb", "cmn":
+	default:
+		log.Printf("⚠️  %s voices don't support lexicon pronunciation overrides; ignoring %s", v.Language, user)
+		return base
+	}
+	if base == "" {
+		return user
+	}
+	return user + "," + base
+}
+
 // ---------------------------------------------------------------------------
 // ModelProvider implementation
 // ---------------------------------------------------------------------------
@@ -365,20 +432,19 @@ func (p *KokoroModelProvider) PrintVoices() {
 
 	for _, lang := range languages {
 		var voiceNames []string
-		for name, voice := range kokoroVoices {
-			if voice.language == lang {
+		for _, name := range config.VoiceNames() {
+			if config.GetVoice(name).Language == lang {
 				voiceNames = append(voiceNames, name)
 			}
 		}
-		sort.Strings(voiceNames)
 
 		fmt.Printf("\n── %s (%d voices) ──\n", lang, len(voiceNames))
 		fmt.Printf("%-15s %-4s %s\n", "VOICE", "ID", "ESPEAK")
 		fmt.Println(strings.Repeat("─", 50))
 
 		for _, name := range voiceNames {
-			voice := kokoroVoices[name]
-			fmt.Printf("%-15s %-4d %s\n", name, voice.speakerID, voice.espeakCode)
+			voice := config.GetVoice(name)
+			fmt.Printf("%-15s %-4d %s\n", name, voice.SpeakerID, voice.EspeakCode)
 		}
 	}
 
@@ -398,7 +464,7 @@ func (p *KokoroModelProvider) PrintVoices() {
 
 // PrintVoiceInfo prints detailed information about a specific Kokoro voice — satisfies [ModelProvider].
 func (p *KokoroModelProvider) PrintVoiceInfo(name string) error {
-	voice := getKokoroVoice(name)
+	voice := config.GetVoice(name)
 	if voice == nil {
 		return fmt.Errorf("voice '%s' not found. Run with --list-voices to see available voices", name)
 	}
@@ -406,12 +472,12 @@ func (p *KokoroModelProvider) PrintVoiceInfo(name string) error {
 	fmt.Println()
 	fmt.Printf("Voice: %s\n", name)
 	fmt.Println(strings.Repeat("─", 40))
-	fmt.Printf("Speaker ID:  %d\n", voice.speakerID)
-	fmt.Printf("Language:    %s\n", voice.language)
-	fmt.Printf("Espeak code: %s\n", voice.espeakCode)
+	fmt.Printf("Speaker ID:  %d\n", voice.SpeakerID)
+	fmt.Printf("Language:    %s\n", voice.Language)
+	fmt.Printf("Espeak code: %s\n", voice.EspeakCode)
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Printf("  ./voice-assistant --tts-voice %s --tts-speaker-id %d\n", name, voice.speakerID)
+	fmt.Printf("  ./voice-assistant --tts-voice %s --tts-speaker-id %d\n", name, voice.SpeakerID)
 	fmt.Println()
 
 	return nil
@@ -0,0 +1,39 @@
+package tts
+
+import (
+	"testing"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+)
+
+func TestWithUserLexicon(t *testing.T) {
+	english := config.GetVoice("af_bella")
+	spanish := config.GetVoice("ef_dora")
+
+	tests := []struct {
+		name       string
+		base, user string
+		voice      *config.Voice
+		want       string
+	}{
+		{"no user lexicon", "base.txt", "", english, "base.txt"},
+		{"user first to take precedence", "en.txt,zh.txt", "me.txt", english, "me.txt,en.txt,zh.txt"},
+		{"no base lexicon", "", "me.txt", english, "me.txt"},
+		{"unsupported language", "", "me.txt", spanish, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withUserLexicon(tt.base, tt.user, tt.voice); got != tt.want {
+				t.Errorf("withUserLexicon(%q, %q) = %q, want %q", tt.base, tt.user, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKokoroMaxNumSentencesDefaultsToOne(t *testing.T) {
+	for in, want := range map[int]int{0: 1, -2: 1, 1: 1, 3: 3} {
+		if got := kokoroMaxNumSentences(in); got != want {
+			t.Errorf("kokoroMaxNumSentences(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
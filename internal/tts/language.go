@@ -0,0 +1,72 @@
+package tts
+
+import (
+	"strings"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+)
+
+// VoiceSwitcher is implemented by synthesizers whose voice can be changed
+// while running.
+type VoiceSwitcher interface {
+	Synthesizer
+
+	// Voice returns the name of the voice in use.
+	Voice() string
+
+	// SetVoice switches subsequent synthesis to the named voice.
+	SetVoice(name string) error
+}
+
+// voiceLanguageCode returns the language code, as Whisper reports it (e.g.
+// "pt"), of a catalog voice's espeak-ng code (e.g. "pt-br").
+func voiceLanguageCode(espeakCode string) string {
+	if espeakCode == "cmn" {
+		return "zh"
+	}
+	code, _, _ := strings.Cut(espeakCode, "-")
+	return code
+}
+
+// VoiceForLanguage returns the catalog voice to reply in language, a code as
+// the speech recognizer reports it (e.g. "es"). defaultVoice is kept when it
+// already speaks language; otherwise the first voice by name that does is
+// picked, preferring one of the same gender as defaultVoice. ok is false, and
+// defaultVoice returned, when no voice speaks language.
+func VoiceForLanguage(defaultVoice, language string) (voice string, ok bool) {
+	language = strings.ToLower(language)
+	if language == "" {
+		return defaultVoice, false
+	}
+	if v := config.GetVoice(defaultVoice); v != nil && voiceLanguageCode(v.EspeakCode) == language {
+		return defaultVoice, true
+	}
+
+	var candidates []string
+	for _, name := range config.VoiceNames() {
+		if voiceLanguageCode(config.GetVoice(name).EspeakCode) == language {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 0 {
+		return defaultVoice, false
+	}
+	// The second letter of a voice name is its gender (af_bella, em_alex).
+	if len(defaultVoice) > 1 {
+		for _, name := range candidates {
+			if name[1] == defaultVoice[1] {
+				return name, true
+			}
+		}
+	}
+	return candidates[0], true
+}
+
+// VoiceLanguage returns the language a catalog voice speaks (e.g. "Spanish"),
+// or "" for an unknown voice.
+func VoiceLanguage(voice string) string {
+	if v := config.GetVoice(voice); v != nil {
+		return v.Language
+	}
+	return ""
+}
@@ -0,0 +1,27 @@
+package tts
+
+import "testing"
+
+func TestVoiceForLanguage(t *testing.T) {
+	tests := []struct {
+		defaultVoice, language string
+		want                   string
+		wantOK                 bool
+	}{
+		{"af_bella", "en", "af_bella", true},   // Default voice already speaks it
+		{"bf_emma", "en", "bf_emma", true},     // British voices are English too
+		{"af_bella", "es", "ef_dora", true},    // Same gender preferred
+		{"am_adam", "es", "em_alex", true},     // Same gender preferred
+		{"af_bella", "zh", "zf_xiaobei", true}, // Whisper "zh" is espeak "cmn"
+		{"af_bella", "pt", "pf_dora", true},
+		{"am_adam", "fr", "ff_siwis", true},   // Only a female French voice
+		{"af_bella", "de", "af_bella", false}, // No German voices
+		{"af_bella", "", "af_bella", false},
+	}
+	for _, tt := range tests {
+		got, ok := VoiceForLanguage(tt.defaultVoice, tt.language)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("VoiceForLanguage(%q, %q) = %q, %v; want %q, %v", tt.defaultVoice, tt.language, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
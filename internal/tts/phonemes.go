@@ -0,0 +1,92 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrPhonemesUnsupported is returned by [PhonemeSynthesizer.SynthesizePhonemes]
+// when the loaded model cannot take phoneme input.
+var ErrPhonemesUnsupported = errors.New("phoneme input is not supported by this TTS model")
+
+// PhonemeSynthesizer is implemented by synthesizers that can speak phonemes
+// directly, bypassing the lexicon and grapheme-to-phoneme conversion.
+type PhonemeSynthesizer interface {
+	Synthesizer
+
+	// SynthesizePhonemes converts a phoneme string, in the notation of the
+	// model's token set, to audio. It returns [ErrPhonemesUnsupported] when the
+	// loaded model cannot take phoneme input.
+	SynthesizePhonemes(ctx context.Context, phonemes string) (*AudioOutput, error)
+}
+
+// phonemeMarkup matches inline phoneme markup: [phon:PHONEMES] or
+// [phon:PHONEMES|TEXT], where TEXT is spoken instead when phonemes can't be.
+var phonemeMarkup = regexp.MustCompile(`\[phon:([^\]|]*)(?:\|([^\]]*))?\]`)
+
+// spaceBeforePunct matches whitespace left before punctuation by dropped markup.
+var spaceBeforePunct = regexp.MustCompile(`\s+([.,!?;:])`)
+
+// HasPhonemeMarkup reports whether text contains [phon:...] markup.
+func HasPhonemeMarkup(text string) bool {
+	return phonemeMarkup.MatchString(text)
+}
+
+// StripPhonemeMarkup replaces each [phon:PHONEMES|TEXT] in text with TEXT,
+// dropping markup without a fallback, so it can be spoken by any synthesizer.
+func StripPhonemeMarkup(text string) string {
+	stripped := phonemeMarkup.ReplaceAllStringFunc(text, func(m string) string {
+		return phonemeMarkup.FindStringSubmatch(m)[2]
+	})
+	return spaceBeforePunct.ReplaceAllString(strings.Join(strings.Fields(stripped), " "), "$1")
+}
+
+// SynthesizeMarked synthesizes text that may contain [phon:...] markup. Plain
+// runs go through Synthesize and phoneme runs through SynthesizePhonemes, and
+// the audio is concatenated. When synth cannot take phonemes, the whole text is
+// spoken with each markup replaced by its fallback text (see
+// [StripPhonemeMarkup]).
+func SynthesizeMarked(ctx context.Context, synth Synthesizer, text string) (*AudioOutput, error) {
+	if !HasPhonemeMarkup(text) {
+		return synth.Synthesize(ctx, text)
+	}
+	ps, ok := As[PhonemeSynthesizer](synth)
+	if !ok {
+		return synth.Synthesize(ctx, StripPhonemeMarkup(text))
+	}
+
+	out := &AudioOutput{SampleRate: synth.SampleRate()}
+	appendRun := func(chunk *AudioOutput, err error) error {
+		if err != nil {
+			return err
+		}
+		out.Samples = append(out.Samples, chunk.Samples...)
+		return nil
+	}
+
+	pos := 0
+	for _, m := range phonemeMarkup.FindAllStringSubmatchIndex(text, -1) {
+		if plain := strings.TrimSpace(text[pos:m[0]]); plain != "" {
+			if err := appendRun(ps.Synthesize(ctx, plain)); err != nil {
+				return nil, err
+			}
+		}
+		phonemes := strings.TrimSpace(text[m[2]:m[3]])
+		if err := appendRun(ps.SynthesizePhonemes(ctx, phonemes)); err != nil {
+			if errors.Is(err, ErrPhonemesUnsupported) {
+				return synth.Synthesize(ctx, StripPhonemeMarkup(text))
+			}
+			return nil, fmt.Errorf("synthesizing phonemes %q: %w", phonemes, err)
+		}
+		pos = m[1]
+	}
+	if plain := strings.TrimSpace(text[pos:]); plain != "" {
+		if err := appendRun(ps.Synthesize(ctx, plain)); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,94 @@
+package tts
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// recordingSynth returns one sample per call and records what it was asked to say.
+type recordingSynth struct {
+	texts, phonemes []string
+	phonemeErr      error
+}
+
+func (s *recordingSynth) Synthesize(_ context.Context, text string) (*AudioOutput, error) {
+	s.texts = append(s.texts, text)
+	return &AudioOutput{Samples: []float32{0.1}, SampleRate: 24000}, nil
+}
+
+func (s *recordingSynth) SampleRate() int { return 24000 }
+func (s *recordingSynth) Close()          {}
+
+// phonemeSynth additionally takes phoneme input.
+type phonemeSynth struct{ recordingSynth }
+
+func (s *phonemeSynth) SynthesizePhonemes(_ context.Context, phonemes string) (*AudioOutput, error) {
+	if s.phonemeErr != nil {
+		return nil, s.phonemeErr
+	}
+	s.phonemes = append(s.phonemes, phonemes)
+	return &AudioOutput{Samples: []float32{0.2}, SampleRate: 24000}, nil
+}
+
+func TestStripPhonemeMarkup(t *testing.T) {
+	got := StripPhonemeMarkup("Take [phon:ˈæs.pɹɪn|aspirin] with water [phon:wɔːtə].")
+	if want := "Take aspirin with water."; got != want {
+		t.Errorf("StripPhonemeMarkup = %q, want %q", got, want)
+	}
+}
+
+func TestSynthesizeMarkedUsesPhonemesWhenSupported(t *testing.T) {
+	s := &phonemeSynth{}
+	out, err := SynthesizeMarked(context.Background(), s, "Call sign [phon:ˈælfə|Alpha] one.")
+	if err != nil {
+		t.Fatalf("SynthesizeMarked: %v", err)
+	}
+	if !slices.Equal(s.texts, []string{"Call sign", "one."}) || !slices.Equal(s.phonemes, []string{"ˈælfə"}) {
+		t.Errorf("texts = %q, phonemes = %q", s.texts, s.phonemes)
+	}
+	if !slices.Equal(out.Samples, []float32{0.1, 0.2, 0.1}) {
+		t.Errorf("samples = %v, want the three runs in order", out.Samples)
+	}
+}
+
+func TestSynthesizeMarkedFallsBackToText(t *testing.T) {
+	plain := &recordingSynth{}
+	if _, err := SynthesizeMarked(context.Background(), plain, "Call sign [phon:ˈælfə|Alpha] one."); err != nil {
+		t.Fatalf("SynthesizeMarked: %v", err)
+	}
+	if !slices.Equal(plain.texts, []string{"Call sign Alpha one."}) {
+		t.Errorf("texts = %q, want the fallback text inline", plain.texts)
+	}
+
+	unsupported := &phonemeSynth{recordingSynth{phonemeErr: ErrPhonemesUnsupported}}
+	if _, err := SynthesizeMarked(context.Background(), unsupported, "Call sign [phon:ˈælfə|Alpha] one."); err != nil {
+		t.Fatalf("SynthesizeMarked: %v", err)
+	}
+	if got := unsupported.texts[len(unsupported.texts)-1]; got != "Call sign Alpha one." {
+		t.Errorf("last text = %q, want the whole sentence with the fallback", got)
+	}
+}
+
+// wrapper claims phoneme input whatever the synthesizer it wraps supports.
+type wrapper struct {
+	phonemeSynth
+	inner Synthesizer
+}
+
+func (w *wrapper) Unwrap() Synthesizer { return w.inner }
+
+func TestAsLooksThroughWrappers(t *testing.T) {
+	if _, ok := As[PhonemeSynthesizer](&phonemeSynth{}); !ok {
+		t.Error("As missed a PhonemeSynthesizer")
+	}
+	if _, ok := As[PhonemeSynthesizer](&recordingSynth{}); ok {
+		t.Error("As found phonemes on a plain synthesizer")
+	}
+	if _, ok := As[PhonemeSynthesizer](&wrapper{inner: &phonemeSynth{}}); !ok {
+		t.Error("As missed phonemes supported all the way down")
+	}
+	if _, ok := As[PhonemeSynthesizer](&wrapper{inner: &recordingSynth{}}); ok {
+		t.Error("As trusted a wrapper over a synthesizer without phonemes")
+	}
+}
@@ -2,19 +2,120 @@ package tts
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"slices"
+	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/agalue/sherpa-voice-assistant/internal/audio"
 	"github.com/agalue/sherpa-voice-assistant/internal/config"
+	"github.com/agalue/sherpa-voice-assistant/internal/events"
+	"github.com/agalue/sherpa-voice-assistant/internal/state"
 )
 
+// Command asks the TTS processor to act on the last response without querying the LLM.
+type Command int
+
+const (
+	// Replay plays the whole last response again.
+	Replay Command = iota
+	// Resume continues the last response from the sentence where it was interrupted.
+	Resume
+)
+
+// Announcement is text to speak right away, ahead of queued responses and
+// without involving the LLM (e.g. a timer or alert notification).
+type Announcement struct {
+	Text string
+	Done chan<- error // Receives nil once playback has ended; must be buffered (nil = not notified)
+}
+
+// lastResponse caches the most recent response so it can be replayed or resumed
+// without re-invoking the LLM. audio is aligned with sentences; an entry with nil
+// Samples was never synthesized (e.g. synthesis stopped on interruption).
+type lastResponse struct {
+	sentences []string
+	audio     []audio.AudioBuffer
+	voices    []string // Voice of each sentence from [voice:...] directives (nil = none)
+	base      string   // Voice of sentences without a directive ("" = the voice in use)
+	next      int      // Index of the first sentence not yet fully played
+	played    float64  // Fraction of sentence next played before an interruption
+}
+
+// newResponse splits text into the sentences of a response, taking out
+// [voice:...] directives when cfg.VoiceDirectives is set.
+func newResponse(text string, split SentenceSplitConfig, cfg *config.Config) lastResponse {
+	var resp lastResponse
+	if cfg.VoiceDirectives {
+		resp.sentences, resp.voices = splitVoiceDirectives(text, split)
+	} else {
+		resp.sentences = SplitSentencesWith(text, split)
+	}
+	resp.audio = make([]audio.AudioBuffer, len(resp.sentences))
+	return resp
+}
+
+// account reports the audio cached in r to cache and drops it if cache asks
+// for memory back.
+func (r *lastResponse) account(cache *audio.MemoryAccount) {
+	var n int
+	for _, buf := range r.audio {
+		n += len(buf.Samples)
+	}
+	cache.Set(audio.SampleBytes(n))
+	if cache.Reclaim() {
+		clear(r.audio)
+		cache.Set(0)
+		log.Println("⚠️  Audio memory budget exceeded, dropped the cached audio of the last response")
+	}
+}
+
 // RunProcessor handles TTS synthesis and audio playback for incoming LLM responses.
 // It accepts the [Synthesizer] interface so it is not coupled to any specific TTS
-// implementation. It reads complete responses from in, splits them into sentences,
-// and runs a pipelined synthesis+playback loop where sentence N+1 is synthesised
-// concurrently with playback of sentence N to minimise perceived latency.
+// implementation. It reads complete LLM responses from in, splits them into sentences
+// (as tuned by the cfg.Sentence* settings, see [SentenceSplitConfig]), and runs a
+// pipelined synthesis+playback loop where sentence N+1 is synthesised concurrently
+// with playback of sentence N to minimise perceived latency.
+//
+// Values received on commands act on the last response from the cached audio:
+// [Replay] plays it again in full, and [Resume] continues from the sentence that
+// was cut off by an interruption. Sentences that were never synthesized because
+// playback was interrupted are synthesized on demand.
+//
+// Announcements are spoken before any queued response or command. They are not
+// cached for replay, never reported as undelivered and never passed to
+// finished, since the LLM did not produce them; text such as notices and
+// command acknowledgements belongs there rather than on in.
+//
+// When cfg.ResponseChime is set, the chime plays right before the first sentence of
+// each new response (not for commands) and is skipped like speech on interruption.
+//
+// When a new response is interrupted or discarded unheard, undelivered (if non-nil)
+// is called with the full response text and the part that was actually heard, so
+// the caller can align the LLM history with what the user heard. finished (if
+// non-nil) is called once for every response read from in, after it has been
+// played to the end, interrupted or discarded, and for nothing else. It gets
+// the response text and the voice it was spoken in ("" when none of it was, or
+// when the synthesizer has a fixed voice).
+//
+// voice (if non-nil) returns the voice each response from in is spoken in
+// outside [voice:...] directives, or "" to keep the current one. When the
+// synthesizer can switch voices, the switch is made before the first sentence
+// is synthesized, so it never happens halfway through a response.
+//
+// The audio cached for replay is reported to cache (nil = not accounted) after
+// each response or command, and dropped when cache asks for memory back; it is
+// then synthesized again if the response is replayed.
+//
+// states (nil = not tracked) moves to [state.Speaking] when the first audio of
+// each response plays, and back to [state.Idle] when it ends unless something
+// else has happened since; a response with nothing to speak also ends
+// [state.Thinking]. An announcement made while the LLM is thinking returns to
+// Thinking.
 //
 // Microphone pause/resume and playback interruption behaviour are controlled by
 // cfg.InterruptMode. This function is intended to be run as a goroutine and returns
@@ -24,160 +125,577 @@ func RunProcessor(
 	synth Synthesizer,
 	player *audio.Player,
 	in <-chan string,
+	commands <-chan Command,
+	announcements <-chan Announcement,
+	undelivered func(full, heard string),
+	finished func(text, voice string),
+	voice func() string,
 	interrupt *atomic.Bool,
+	states *state.Manager,
 	cfg *config.Config,
 	capturer *audio.Capturer,
+	cache *audio.MemoryAccount,
 ) {
+	var last lastResponse
+	defer cache.Set(0)
+
+	// finish reports that the response text from in, spoken in voice, is done with.
+	finish := func(text, voice string) {
+		states.Transition(state.Thinking, state.Idle)
+		if finished != nil {
+			finished(text, voice)
+		}
+	}
+
+	// discard reports a response that was dropped before any of it was played.
+	discard := func(text string) {
+		if undelivered != nil {
+			undelivered(text, "")
+		}
+		finish(text, "")
+	}
+
+	// base is the voice responses are spoken in outside [voice:...]
+	// directives ("" when synth has a fixed voice). A response that ends in
+	// another voice is left in it, and the next one switches back only if it
+	// doesn't start in that voice too.
+	var base string
+	if voices, ok := As[VoiceSwitcher](synth); ok {
+		base = voices.Voice()
+	}
+	// startVoice picks the base voice of the next response.
+	startVoice := func() {
+		if voice == nil || base == "" {
+			return
+		}
+		if name := voice(); name != "" {
+			base = name
+		}
+	}
+
+	split := sentenceSplitConfig(cfg)
+
+	chime, err := loadChime(cfg.ResponseChime)
+	if err != nil {
+		log.Printf("⚠️  Response chime disabled: %v", err)
+	}
+
+	// announce speaks a, reporting whether it was interrupted. It may play
+	// while the LLM is still thinking (e.g. a tool progress phrase), so that
+	// state is restored afterwards; Listening and Transcribing are left to the
+	// processors that report them.
+	announce := func(a Announcement) bool {
+		resp := newResponse(a.Text, split, cfg)
+		resp.base = base
+		log.Printf("📢 Announcement: %s", a.Text)
+		after := state.Idle
+		if states.Current() == state.Thinking {
+			after = state.Thinking
+		}
+		interrupted := playResponse(ctx, synth, player, &resp, 0, chime, interrupt, states, after, cfg, capturer)
+		if a.Done != nil {
+			a.Done <- nil
+		}
+		return interrupted
+	}
+
 	for {
+		var wasInterrupted bool
+
+		// Announcements take priority over anything already queued.
 		select {
-		case <-ctx.Done():
-			return
-		case text, ok := <-in:
-			if !ok {
+		case a := <-announcements:
+			wasInterrupted = announce(a)
+		default:
+			select {
+			case <-ctx.Done():
 				return
-			}
+			case a := <-announcements:
+				wasInterrupted = announce(a)
+			case cmd := <-commands:
+				start := 0
+				switch cmd {
+				case Replay:
+					if len(last.sentences) == 0 {
+						log.Println("🔁 Nothing to replay yet")
+						continue
+					}
+					log.Printf("🔁 Replaying last response (%d sentence(s))", len(last.sentences))
+					events.Emit(events.Replay, strings.Join(last.sentences, " "))
+				case Resume:
+					if last.next >= len(last.sentences) {
+						log.Println("▶️  Nothing to resume")
+						continue
+					}
+					start = last.next
+					log.Printf("▶️  Resuming last response at sentence %d/%d", start+1, len(last.sentences))
+					events.Emit(events.Resume, strings.Join(last.sentences[start:], " "))
+				}
+				wasInterrupted = playResponse(ctx, synth, player, &last, start, nil, interrupt, states, state.Idle, cfg, capturer)
+				last.account(cache)
+			case text, ok := <-in:
+				if !ok {
+					return
+				}
 
-			// In 'always' mode, skip the entire response if the user is already speaking.
-			if cfg.InterruptMode == config.InterruptAlways && interrupt.Load() {
-				discarded := drainChannel(in)
-				log.Printf("🗑️  Discarded %d queued LLM response(s) due to interruption", discarded+1)
-				continue
-			}
+				// In 'always' and 'sentence' modes, skip the entire response if the user is already speaking.
+				if cfg.AllowsBargeIn() && interrupt.Load() {
+					discard(text)
+					discarded := drainChannel(in, discard)
+					log.Printf("🗑️  Discarded %d queued LLM response(s) due to interruption", discarded+1)
+					continue
+				}
 
-			// In 'wait' mode, pause the microphone for the duration of playback.
-			if cfg.InterruptMode == config.InterruptWait {
-				capturer.Pause()
-				if cfg.Verbose {
-					log.Println("[TTS] Microphone paused for playback")
+				resp := newResponse(text, split, cfg)
+				if !slices.ContainsFunc(resp.sentences, isSpeakable) {
+					if cfg.EmptyAfterFilterFallback == "" {
+						log.Println("⚠️  No sentences to synthesize")
+						finish(text, "")
+						continue
+					}
+					log.Printf("⚠️  Response has nothing to speak, saying %q instead", cfg.EmptyAfterFilterFallback)
+					resp = newResponse(cfg.EmptyAfterFilterFallback, split, cfg)
 				}
-			}
 
-			// Pipeline synthesis and playback concurrently for lower latency.
-			// Synthesis of sentence N+1 overlaps with playback of sentence N.
-			sentences := SplitSentences(text)
-			if len(sentences) == 0 {
-				log.Println("⚠️  No sentences to synthesize")
-				if cfg.InterruptMode == config.InterruptWait {
-					time.Sleep(time.Duration(cfg.PostPlaybackDelayMs) * time.Millisecond)
-					capturer.Resume()
+				events.Emit(events.Response, text)
+				startVoice()
+				resp.base = base
+				last = resp
+				wasInterrupted = playResponse(ctx, synth, player, &last, 0, chime, interrupt, states, state.Idle, cfg, capturer)
+				last.account(cache)
+				if wasInterrupted && undelivered != nil {
+					undelivered(text, HeardText(last.sentences, last.next, last.played))
 				}
+				finish(text, base)
+			}
+		}
+
+		// If interrupted by speech, drain any remaining queued responses.
+		if wasInterrupted && cfg.AllowsBargeIn() {
+			if discarded := drainChannel(in, discard); discarded > 0 {
+				log.Printf("🗑️  Discarded %d queued TTS response(s)", discarded)
+			}
+		}
+	}
+}
+
+// playResponse plays resp sentence by sentence starting at index start, reusing cached
+// audio where available and synthesizing the rest. Newly synthesized audio is stored
+// back into resp so it can be replayed later, and resp.next records where playback
+// stopped so it can be resumed, with resp.played holding how much of that sentence
+// was heard. A non-nil chime is played just before the first
+// sentence. states moves to [state.Speaking] when the first sentence plays and
+// on to after once playback ends, unless something else has happened since.
+// Returns true if playback was interrupted.
+//
+// Pipeline synthesis and playback run concurrently for lower latency: synthesis of
+// sentence N+1 overlaps with playback of sentence N. Synthesis runs at most
+// cfg.MaxSynthLookahead sentences ahead of playback, bounding the audio that is
+// rendered only to be thrown away when the response is interrupted.
+func playResponse(
+	ctx context.Context,
+	synth Synthesizer,
+	player *audio.Player,
+	resp *lastResponse,
+	start int,
+	chime *audio.AudioBuffer,
+	interrupt *atomic.Bool,
+	states *state.Manager,
+	after state.State,
+	cfg *config.Config,
+	capturer *audio.Capturer,
+) bool {
+	// In 'wait' mode, pause the microphone for the duration of playback, unless
+	// it keeps listening at reduced sensitivity.
+	pauseMic := cfg.InterruptMode == config.InterruptWait && !cfg.ListensDuringPlayback()
+	if pauseMic {
+		capturer.Pause()
+		if cfg.Verbose {
+			log.Println("[TTS] Microphone paused for playback")
+		}
+	}
+
+	sentences := resp.sentences
+	wasInterrupted := false
+	// synthExitedEarly is set by the synthesis goroutine when it exits due to
+	// an interrupt before sending any audio, so the playback loop's normal
+	// channel-close exit can still trigger the response drain.
+	var synthExitedEarly atomic.Bool
+
+	synthCtx, synthCancel := context.WithCancel(ctx)
+	// Barging in cancels the sentence being synthesized right away, rather than
+	// once it is complete. While a sentence plays, the player decides whether
+	// the flag stops it (see [audio.Player.SetBargeInGrace]) and the playback
+	// loop below cancels synthesis itself.
+	if cfg.AllowsBargeIn() {
+		go cancelOnInterrupt(synthCtx, func() bool { return interrupt.Load() && !player.IsPlaying() }, synthCancel)
+	}
+	// One sentence is synthesized while the previous one plays; the queue holds
+	// the rest of the allowed lookahead.
+	audioQueue := make(chan queuedSentence, max(cfg.MaxSynthLookahead, 1)-1)
+	synthDone := make(chan struct{})
+
+	go func() {
+		defer close(synthDone)
+		defer close(audioQueue)
+		voices := newVoiceSequence(synth, resp.voices, resp.base)
+		for i := start; i < len(sentences); i++ {
+			sentence := sentences[i]
+			if !isSpeakable(sentence) {
 				continue
 			}
 
-			wasInterrupted := false
-			// synthExitedEarly is set by the synthesis goroutine when it exits due to
-			// an interrupt before sending any audio, so the playback loop's normal
-			// channel-close exit can still trigger the response drain.
-			var synthExitedEarly atomic.Bool
+			// Stop if the playback side cancelled (interruption or error).
+			select {
+			case <-synthCtx.Done():
+				return
+			default:
+			}
 
-			synthCtx, synthCancel := context.WithCancel(ctx)
-			audioQueue := make(chan *AudioOutput, 1) // 1-slot buffer: prefetch next sentence
+			if cfg.AllowsBargeIn() && interrupt.Load() {
+				synthExitedEarly.Store(true)
+				return
+			}
 
-			go func() {
-				defer close(audioQueue)
-				for i, sentence := range sentences {
-					if sentence == "" {
-						continue
-					}
+			buf := resp.audio[i]
+			if buf.Samples == nil {
+				if cfg.Verbose {
+					log.Printf("[TTS] Synthesizing sentence %d/%d: %q", i+1, len(sentences), sentence)
+				}
 
-					// Stop if the playback side cancelled (interruption or error).
+				voices.apply(i)
+				var err error
+				streamed := false
+				if ss, ok := streamingSynth(synth, sentence, cfg); ok {
+					// Queue the sentence first, so it starts playing with its
+					// first chunk.
+					stream := &sentenceStream{chunks: make(chan audio.AudioBuffer, streamChunkBuffer)}
 					select {
+					case audioQueue <- queuedSentence{index: i, stream: stream}:
 					case <-synthCtx.Done():
 						return
-					default:
 					}
-
-					if cfg.InterruptMode == config.InterruptAlways && interrupt.Load() {
-						synthExitedEarly.Store(true)
-						return
+					buf, err = stream.synthesize(synthCtx, ss, sentence)
+					streamed = true
+				} else {
+					var chunk *AudioOutput
+					if chunk, err = synthesizeSentence(synthCtx, synth, sentence, cfg); err == nil {
+						buf = audio.AudioBuffer{Samples: chunk.Samples, SampleRate: chunk.SampleRate}
 					}
-
+				}
+				if err != nil && synthCtx.Err() != nil {
+					// Cancelled mid-sentence by an interruption.
 					if cfg.Verbose {
-						log.Printf("[TTS] Synthesizing sentence %d/%d: %q", i+1, len(sentences), sentence)
+						log.Printf("[TTS] Synthesis of sentence %d cancelled", i+1)
 					}
-
-					chunk, err := synth.Synthesize(sentence)
-					if err != nil {
-						log.Printf("❌ TTS error for sentence %d: %v", i+1, err)
-						continue
-					}
-
-					// Send to playback; abort if cancelled while waiting.
-					select {
-					case audioQueue <- chunk:
-					case <-synthCtx.Done():
-						return
+					if cfg.AllowsBargeIn() && interrupt.Load() {
+						synthExitedEarly.Store(true)
 					}
+					return
 				}
-			}()
-
-			sentNum := 0
-			for chunk := range audioQueue {
-				// Pre-play interrupt check: a chunk may have been queued before the
-				// user started speaking; avoid playing it over them.
-				if cfg.InterruptMode == config.InterruptAlways && interrupt.Load() {
-					log.Println("⏸️  Playback interrupted by speech (pre-play)")
-					synthCancel()
-					wasInterrupted = true
-					break
+				if err != nil {
+					log.Printf("❌ TTS error for sentence %d: %v", i+1, err)
+					continue
 				}
-
-				sentNum++
-				log.Printf("🔊 Playing sentence %d/%d (%d samples)", sentNum, len(sentences), len(chunk.Samples))
-
-				if err := player.Play(audio.AudioBuffer{
-					Samples:    chunk.Samples,
-					SampleRate: chunk.SampleRate,
-				}); err != nil {
-					log.Printf("❌ Playback error: %v", err)
-					synthCancel()
-					wasInterrupted = true
-					break
+				resp.audio[i] = buf
+				if streamed {
+					continue // Already queued, playing as it is synthesized
 				}
+			}
 
-				if cfg.InterruptMode == config.InterruptAlways && interrupt.Load() {
-					log.Println("⏸️  Playback interrupted by speech")
-					synthCancel()
-					wasInterrupted = true
-					break
-				}
+			// Send to playback; abort if cancelled while waiting.
+			select {
+			case audioQueue <- queuedSentence{index: i, buf: buf}:
+			case <-synthCtx.Done():
+				return
 			}
+		}
+	}()
 
-			synthCancel() // No-op if already called; ensures goroutine exits.
+	// resp.next points at the sentence being played and moves past each one
+	// that finishes without interruption.
+	resp.next = start
+	resp.played = 0
+	for q := range audioQueue {
+		// Pre-play interrupt check: a chunk may have been queued before the
+		// user started speaking; avoid playing it over them.
+		if cfg.AllowsBargeIn() && interrupt.Load() {
+			log.Println("⏸️  Playback interrupted by speech (pre-play)")
+			events.Emit(events.Interrupt, "")
+			synthCancel()
+			wasInterrupted = true
+			break
+		}
+
+		resp.next = q.index
+		states.Set(state.Speaking)
 
-			// Propagate an interruption that occurred entirely inside the synthesis
-			// goroutine (before any audio reached the playback loop), so the drain
-			// below still runs when appropriate.
-			if !wasInterrupted && synthExitedEarly.Load() {
+		// Chime once the first sentence is ready, so it leads straight into speech.
+		if chime != nil {
+			err := player.Play(*chime)
+			chime = nil
+			if errors.Is(err, audio.ErrInterrupted) || (cfg.AllowsBargeIn() && interrupt.Load()) {
+				log.Println("⏹️  Response chime interrupted")
+				events.Emit(events.Interrupt, "")
+				synthCancel()
 				wasInterrupted = true
+				break
 			}
+		}
 
-			// Resume microphone after playback in 'wait' mode.
-			if cfg.InterruptMode == config.InterruptWait {
-				// Delay before resuming to avoid capturing the playback tail.
-				time.Sleep(time.Duration(cfg.PostPlaybackDelayMs) * time.Millisecond)
-				capturer.Resume()
-				if cfg.Verbose {
-					log.Println("[TTS] Microphone resumed after playback")
-				}
-			}
+		var err error
+		if q.stream != nil {
+			log.Printf("🔊 Playing sentence %d/%d as it is synthesized", q.index+1, len(sentences))
+			err = player.PlayStream(q.stream.chunks)
+			resp.played = q.stream.playedFraction(player.Position())
+		} else {
+			log.Printf("🔊 Playing sentence %d/%d (%d samples)", q.index+1, len(sentences), len(q.buf.Samples))
+			err = player.Play(q.buf)
+			resp.played = playedFraction(player.Position(), q.buf)
+		}
+		if errors.Is(err, audio.ErrInterrupted) {
+			log.Println("⏹️  Playback stopped")
+			events.Emit(events.Interrupt, "")
+			synthCancel()
+			wasInterrupted = true
+			break
+		} else if err != nil {
+			log.Printf("❌ Playback error: %v", err)
+			synthCancel()
+			wasInterrupted = true
+			break
+		}
 
-			// If interrupted in 'always' mode, drain any remaining queued responses.
-			if wasInterrupted && cfg.InterruptMode == config.InterruptAlways {
-				if discarded := drainChannel(in); discarded > 0 {
-					log.Printf("🗑️  Discarded %d queued TTS response(s)", discarded)
-				}
+		if cfg.AllowsBargeIn() && interrupt.Load() {
+			log.Println("⏸️  Playback interrupted by speech")
+			events.Emit(events.Interrupt, "")
+			synthCancel()
+			wasInterrupted = true
+			break
+		}
+		resp.next = q.index + 1
+		resp.played = 0
+	}
+
+	synthCancel() // No-op if already called; ensures goroutine exits.
+	<-synthDone   // resp.audio is written by the goroutine; wait before handing it back.
+	states.Transition(state.Speaking, after)
+
+	if !wasInterrupted && !synthExitedEarly.Load() {
+		resp.next = len(sentences)
+	}
+
+	// Propagate an interruption that occurred entirely inside the synthesis
+	// goroutine (before any audio reached the playback loop), so the caller's
+	// drain still runs when appropriate.
+	if !wasInterrupted && synthExitedEarly.Load() {
+		wasInterrupted = true
+	}
+
+	// Resume microphone after playback in 'wait' mode.
+	if pauseMic {
+		// Delay before resuming to avoid capturing the playback tail.
+		time.Sleep(time.Duration(cfg.PostPlaybackDelayMs) * time.Millisecond)
+		capturer.Resume()
+		if cfg.Verbose {
+			log.Println("[TTS] Microphone resumed after playback")
+		}
+	}
+
+	return wasInterrupted
+}
+
+// chimeSampleRate is the rate at which the built-in response chime is generated.
+const chimeSampleRate = 24000
+
+// loadChime resolves a [config.Config.ResponseChime] value: nil when empty, the
+// generated tone for [config.ChimeTone], and otherwise the decoded WAV file.
+func loadChime(spec string) (*audio.AudioBuffer, error) {
+	switch spec {
+	case "":
+		return nil, nil
+	case config.ChimeTone:
+		return &audio.AudioBuffer{Samples: audio.GenerateChime(chimeSampleRate), SampleRate: chimeSampleRate}, nil
+	}
+
+	f, err := os.Open(spec)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf, err := audio.DecodeWAV(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", spec, err)
+	}
+	return &buf, nil
+}
+
+// playedFraction returns the share of buf covered by position, clamped to [0, 1].
+func playedFraction(position time.Duration, buf audio.AudioBuffer) float64 {
+	if len(buf.Samples) == 0 || buf.SampleRate == 0 {
+		return 0
+	}
+	total := float64(len(buf.Samples)) / float64(buf.SampleRate)
+	return min(position.Seconds()/total, 1)
+}
+
+// interruptPollInterval is how often cancelOnInterrupt checks for an interruption.
+const interruptPollInterval = 20 * time.Millisecond
+
+// cancelOnInterrupt calls cancel as soon as interrupted reports true, returning
+// without calling it once ctx is done.
+func cancelOnInterrupt(ctx context.Context, interrupted func() bool, cancel context.CancelFunc) {
+	ticker := time.NewTicker(interruptPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if interrupted() {
+				cancel()
+				return
 			}
 		}
 	}
 }
 
-// drainChannel removes all pending messages from ch and returns the count.
-func drainChannel[T any](ch <-chan T) int {
+// queuedSentence is synthesized audio handed from the synthesis goroutine to playback.
+type queuedSentence struct {
+	index  int // Position in the response's sentence list
+	buf    audio.AudioBuffer
+	stream *sentenceStream // Audio still being synthesized, instead of buf (see config.Config.StreamSynthesis)
+}
+
+// Speak synthesizes text and plays it sentence by sentence, blocking until playback
+// completes or is stopped with [audio.Player.Interrupt]. Unlike [RunProcessor] it
+// does not pipeline synthesis or manage the microphone; it is meant for one-off
+// announcements such as the startup greeting.
+func Speak(ctx context.Context, synth Synthesizer, player *audio.Player, text string) error {
+	for _, sentence := range SplitSentences(text) {
+		chunk, err := synth.Synthesize(ctx, sentence)
+		if err != nil {
+			return fmt.Errorf("synthesizing %q: %w", sentence, err)
+		}
+		err = player.Play(audio.AudioBuffer{Samples: chunk.Samples, SampleRate: chunk.SampleRate})
+		if errors.Is(err, audio.ErrInterrupted) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("playing %q: %w", sentence, err)
+		}
+	}
+	return nil
+}
+
+// Announce synthesizes all of text and then plays it in one piece at
+// [audio.PriorityAnnouncement]: a response being played is paused and resumes
+// once the announcement has been spoken (see [audio.Player.PlayPriority]).
+// Sentences are synthesized as a reply's would be (markup and silence trimming
+// follow cfg), except that voice directives are dropped rather than applied:
+// the response being paused shares the synthesizer and must keep its voice.
+// Like [Speak], it blocks until playback completes or is interrupted. Text that
+// yields no audio is not played at all.
+func Announce(ctx context.Context, synth Synthesizer, player *audio.Player, text string, cfg *config.Config) error {
+	buf := audio.AudioBuffer{SampleRate: synth.SampleRate()}
+	for _, sentence := range newResponse(text, sentenceSplitConfig(cfg), cfg).sentences {
+		if !isSpeakable(sentence) {
+			continue
+		}
+		chunk, err := synthesizeSentence(ctx, synth, sentence, cfg)
+		if err != nil {
+			return fmt.Errorf("synthesizing %q: %w", sentence, err)
+		}
+		buf.Samples = append(buf.Samples, chunk.Samples...)
+		buf.SampleRate = chunk.SampleRate
+	}
+	if len(buf.Samples) == 0 {
+		return nil // Nothing speakable: don't pause the response for silence
+	}
+	err := player.PlayPriority(buf, audio.PriorityAnnouncement)
+	if err != nil && !errors.Is(err, audio.ErrInterrupted) {
+		return fmt.Errorf("playing announcement: %w", err)
+	}
+	return nil
+}
+
+// SynthesizeText synthesizes all of text sentence by sentence, as a reply
+// would be spoken (markup and silence trimming follow cfg), and returns the
+// audio in one piece. It is used for offline synthesis to a file.
+func SynthesizeText(ctx context.Context, synth Synthesizer, text string, cfg *config.Config) (audio.AudioBuffer, error) {
+	buf := audio.AudioBuffer{SampleRate: synth.SampleRate()}
+	resp := newResponse(text, sentenceSplitConfig(cfg), cfg)
+	if !slices.ContainsFunc(resp.sentences, isSpeakable) {
+		return buf, fmt.Errorf("no text to synthesize")
+	}
+	voices := newVoiceSequence(synth, resp.voices, "")
+	defer voices.restore()
+	for i, sentence := range resp.sentences {
+		if !isSpeakable(sentence) {
+			continue
+		}
+		voices.apply(i)
+		chunk, err := synthesizeSentence(ctx, synth, sentence, cfg)
+		if err != nil {
+			return buf, fmt.Errorf("synthesizing %q: %w", sentence, err)
+		}
+		buf.Samples = append(buf.Samples, chunk.Samples...)
+		buf.SampleRate = chunk.SampleRate
+	}
+	return buf, nil
+}
+
+// sentenceSplitConfig returns the sentence splitting settings in cfg.
+func sentenceSplitConfig(cfg *config.Config) SentenceSplitConfig {
+	return SentenceSplitConfig{
+		MinChars:       cfg.SentenceMinChars,
+		SoftBoundaries: cfg.SentenceSoftBoundaries,
+		SoftMinChars:   cfg.SentenceSoftMinChars,
+		MaxChars:       cfg.MaxSentenceChars,
+	}
+}
+
+// synthesizeSentence synthesizes one sentence, honoring <break/> and
+// <emphasis> tags when cfg.SSMLMarkup is set, [phon:...] markup when
+// cfg.PhonemeMarkup is set, and trimming silence at either end of each spoken
+// run when cfg.TrimSilence is set (so breaks are kept).
+func synthesizeSentence(ctx context.Context, synth Synthesizer, sentence string, cfg *config.Config) (*AudioOutput, error) {
+	if cfg.SSMLMarkup {
+		return SynthesizeSSML(synth, sentence, func(s Synthesizer, text string) (*AudioOutput, error) {
+			return synthesizeRun(ctx, s, text, cfg)
+		})
+	}
+	return synthesizeRun(ctx, synth, sentence, cfg)
+}
+
+// synthesizeRun synthesizes a run of text without SSML tags for
+// synthesizeSentence.
+func synthesizeRun(ctx context.Context, synth Synthesizer, sentence string, cfg *config.Config) (*AudioOutput, error) {
+	var out *AudioOutput
+	var err error
+	if cfg.PhonemeMarkup {
+		out, err = SynthesizeMarked(ctx, synth, sentence)
+	} else {
+		out, err = synth.Synthesize(ctx, sentence)
+	}
+	if err != nil || cfg.TrimSilence <= 0 {
+		return out, err
+	}
+	out.Samples = audio.TrimSilence(out.Samples, out.SampleRate, cfg.TrimSilence)
+	return out, nil
+}
+
+// drainChannel removes all pending messages from ch, passing each to discard
+// when it is non-nil, and returns the count.
+func drainChannel[T any](ch <-chan T, discard func(T)) int {
 	discarded := 0
 	for {
 		select {
-		case <-ch:
+		case v := <-ch:
+			if discard != nil {
+				discard(v)
+			}
 			discarded++
 		default:
 			return discarded
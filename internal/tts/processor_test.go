@@ -0,0 +1,98 @@
+package tts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+)
+
+func TestLoadChime(t *testing.T) {
+	if buf, err := loadChime(""); buf != nil || err != nil {
+		t.Errorf("loadChime(\"\") = %v, %v; want nil, nil", buf, err)
+	}
+
+	tone, err := loadChime(config.ChimeTone)
+	if err != nil || tone == nil || len(tone.Samples) == 0 || tone.SampleRate != chimeSampleRate {
+		t.Fatalf("loadChime(tone) = %+v, %v; want generated chime", tone, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "chime.wav")
+	if err := os.WriteFile(path, wavBytes([]float32{0.25, -0.25, 0.5}, 16000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := loadChime(path)
+	if err != nil || file == nil || len(file.Samples) != 3 || file.SampleRate != 16000 {
+		t.Errorf("loadChime(wav) = %+v, %v; want 3 samples at 16000 Hz", file, err)
+	}
+
+	if _, err := loadChime(filepath.Join(t.TempDir(), "missing.wav")); err == nil {
+		t.Error("loadChime(missing) should fail")
+	}
+}
+
+func TestSynthesizeText(t *testing.T) {
+	synth := &recordingSynth{}
+	buf, err := SynthesizeText(context.Background(), synth, "Hello there. How are you? 🙂", config.DefaultConfig())
+	if err != nil {
+		t.Fatalf("SynthesizeText: %v", err)
+	}
+	if len(synth.texts) != 2 || len(buf.Samples) != 2 || buf.SampleRate != 24000 {
+		t.Errorf("spoke %q into %d samples at %d Hz, want two sentences", synth.texts, len(buf.Samples), buf.SampleRate)
+	}
+	if _, err := SynthesizeText(context.Background(), synth, "  ", config.DefaultConfig()); err == nil {
+		t.Error("SynthesizeText accepted empty text")
+	}
+}
+
+func TestAnnounceSkipsPlaybackWithoutAudio(t *testing.T) {
+	synth := &recordingSynth{}
+	// A nil player would panic if anything were played.
+	for _, text := range []string{"", "🙂", "..."} {
+		if err := Announce(context.Background(), synth, nil, text, config.DefaultConfig()); err != nil {
+			t.Errorf("Announce(%q) = %v, want nil", text, err)
+		}
+	}
+	if len(synth.texts) != 0 {
+		t.Errorf("synthesized %q, want nothing", synth.texts)
+	}
+	if err := Announce(context.Background(), &silentSynth{}, nil, "Timer done.", config.DefaultConfig()); err != nil {
+		t.Errorf("Announce with no samples = %v, want nil", err)
+	}
+}
+
+// silentSynth synthesizes every text to no samples.
+type silentSynth struct{ recordingSynth }
+
+func (*silentSynth) Synthesize(context.Context, string) (*AudioOutput, error) {
+	return &AudioOutput{SampleRate: 24000}, nil
+}
+
+func TestCancelOnInterrupt(t *testing.T) {
+	var interrupt atomic.Bool
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		cancelOnInterrupt(ctx, interrupt.Load, cancel)
+		close(done)
+	}()
+
+	time.Sleep(3 * interruptPollInterval)
+	if ctx.Err() != nil {
+		t.Fatal("cancelled without an interruption")
+	}
+	interrupt.Store(true)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cancelOnInterrupt did not return after the interruption")
+	}
+	if ctx.Err() == nil {
+		t.Error("context not cancelled by the interruption")
+	}
+}
@@ -0,0 +1,31 @@
+package tts
+
+import "fmt"
+
+// Bounds for [SpeedController.SetSpeed]: beyond them Kokoro's speech becomes
+// hard to follow.
+const (
+	MinSpeed = 0.5
+	MaxSpeed = 2.0
+)
+
+// SpeedController is implemented by synthesizers whose speech speed can be
+// changed while running, e.g. when the user asks the assistant to speak slower.
+type SpeedController interface {
+	Synthesizer
+
+	// Speed returns the speech speed multiplier in use.
+	Speed() float32
+
+	// SetSpeed changes the speech speed multiplier of subsequent synthesis; it
+	// must be within [MinSpeed] and [MaxSpeed].
+	SetSpeed(speed float32) error
+}
+
+// checkSpeed reports whether speed is within [MinSpeed] and [MaxSpeed].
+func checkSpeed(speed float32) error {
+	if speed < MinSpeed || speed > MaxSpeed {
+		return fmt.Errorf("speech speed must be between %.1f and %.1f, got %.2f", MinSpeed, MaxSpeed, speed)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SpeedSynthesizer is implemented by synthesizers that can speak a piece of
+// text faster or slower than configured, for <emphasis> markup.
+type SpeedSynthesizer interface {
+	Synthesizer
+
+	// SynthesizeAtSpeed is like Synthesize with the configured speech speed
+	// multiplied by factor (below 1 is slower).
+	SynthesizeAtSpeed(ctx context.Context, text string, factor float32) (*AudioOutput, error)
+}
+
+// SSML-lite limits and defaults.
+const (
+	// maxSSMLBreak bounds a single <break/>, so a bad value cannot stall a reply.
+	maxSSMLBreak = 5 * time.Second
+
+	// defaultSSMLBreak is the pause of a <break/> without time or strength
+	// (SSML's "medium").
+	defaultSSMLBreak = 400 * time.Millisecond
+)
+
+// ssmlBreakStrengths maps the strength attribute of <break/> to a pause.
+var ssmlBreakStrengths = map[string]time.Duration{
+	"none":     0,
+	"x-weak":   100 * time.Millisecond,
+	"weak":     200 * time.Millisecond,
+	"medium":   defaultSSMLBreak,
+	"strong":   700 * time.Millisecond,
+	"x-strong": time.Second,
+}
+
+// ssmlEmphasisSpeeds maps the level attribute of <emphasis> to a speed
+// factor: emphasized words are spoken more slowly, "reduced" ones faster.
+var ssmlEmphasisSpeeds = map[string]float32{
+	"strong":   0.75,
+	"moderate": 0.85,
+	"none":     1,
+	"reduced":  1.1,
+}
+
+// ssmlTag matches an opening, closing or self-closing tag of an SSML element,
+// with attributes written as name="value"; ssmlAttr matches one attribute
+// within it. Anything else in angle brackets, such as "a<b and c>d", is text.
+var (
+	ssmlTag  = regexp.MustCompile(`<\s*(/?)\s*((?i:speak|p|s|break|emphasis|prosody|say-as|sub|voice|lang|phoneme|audio|mark))((?:\s+[\w:-]+\s*=\s*(?:"[^"]*"|'[^']*'))*)\s*(/?)\s*>`)
+	ssmlAttr = regexp.MustCompile(`([\w:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// ssmlPart is a piece of SSML-lite text: words to speak at a speed factor, or
+// a pause.
+type ssmlPart struct {
+	text  string        // Words to speak (empty for a pause)
+	speed float32       // Speed factor for text
+	pause time.Duration // Silence to insert
+}
+
+// HasSSML reports whether text contains anything that looks like an SSML tag.
+func HasSSML(text string) bool {
+	return ssmlTag.MatchString(text)
+}
+
+// parseSSML splits text into parts at its tags. <break time="500ms"/> (or
+// strength="strong") becomes a pause and <emphasis level="...">...</emphasis>
+// slows its words down; any other SSML tag is dropped, keeping the words inside
+// it.
+// An <emphasis> left open lasts to the end of text, and stray closing tags are
+// ignored, so a sentence split inside a span still parses.
+func parseSSML(text string) []ssmlPart {
+	var parts []ssmlPart
+	var emphasis []float32 // Speed factors of the open <emphasis> tags
+
+	addText := func(s string) {
+		s = strings.Join(strings.Fields(s), " ")
+		if s == "" {
+			return
+		}
+		speed := float32(1)
+		if len(emphasis) > 0 {
+			speed = emphasis[len(emphasis)-1]
+		}
+		parts = append(parts, ssmlPart{text: s, speed: speed})
+	}
+
+	pos := 0
+	for _, m := range ssmlTag.FindAllStringSubmatchIndex(text, -1) {
+		addText(text[pos:m[0]])
+		pos = m[1]
+
+		closing := m[3] > m[2]
+		name := strings.ToLower(text[m[4]:m[5]])
+		attrs := ssmlAttrs(text[m[6]:m[7]])
+		switch {
+		case name == "break" && !closing:
+			parts = append(parts, ssmlPart{pause: ssmlBreak(attrs)})
+		case name == "emphasis" && closing:
+			if len(emphasis) > 0 {
+				emphasis = emphasis[:len(emphasis)-1]
+			}
+		case name == "emphasis" && m[9] == m[8]: // Not self-closing
+			speed, ok := ssmlEmphasisSpeeds[strings.ToLower(attrs["level"])]
+			if !ok {
+				speed = ssmlEmphasisSpeeds["moderate"]
+			}
+			emphasis = append(emphasis, speed)
+		}
+	}
+	addText(text[pos:])
+	return parts
+}
+
+// ssmlAttrs returns the attributes in the attribute section of a tag, with
+// lowercase names.
+func ssmlAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range ssmlAttr.FindAllStringSubmatch(s, -1) {
+		attrs[strings.ToLower(m[1])] = m[2] + m[3]
+	}
+	return attrs
+}
+
+// ssmlBreak returns the pause of a <break/> with attrs: its time ("500ms",
+// "1.5s"), else its strength, else the default, capped at maxSSMLBreak.
+func ssmlBreak(attrs map[string]string) time.Duration {
+	if t, ok := attrs["time"]; ok {
+		if d, err := time.ParseDuration(strings.TrimSpace(t)); err == nil {
+			return min(max(d, 0), maxSSMLBreak)
+		}
+	}
+	if d, ok := ssmlBreakStrengths[strings.ToLower(attrs["strength"])]; ok {
+		return d
+	}
+	return defaultSSMLBreak
+}
+
+// StripSSML returns text with its tags removed, as it would be spoken by a
+// synthesizer that ignores pauses and emphasis.
+func StripSSML(text string) string {
+	var words []string
+	for _, part := range parseSSML(text) {
+		if part.text != "" {
+			words = append(words, part.text)
+		}
+	}
+	return spaceBeforePunct.ReplaceAllString(strings.Join(words, " "), "$1")
+}
+
+// SynthesizeSSML synthesizes text that may contain SSML-lite markup (see
+// parseSSML): each run of words is passed to speak, emphasized runs with a
+// synthesizer that speaks at the emphasis speed when synth is a
+// [SpeedSynthesizer] (and at normal speed otherwise), and breaks become
+// silence between the runs' audio. Text without tags goes straight to speak.
+func SynthesizeSSML(synth Synthesizer, text string, speak func(Synthesizer, string) (*AudioOutput, error)) (*AudioOutput, error) {
+	if !HasSSML(text) {
+		return speak(synth, text)
+	}
+	parts := parseSSML(text)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("empty text")
+	}
+
+	chunks := make([]*AudioOutput, len(parts))
+	rate := 0
+	for i, part := range parts {
+		if part.text == "" {
+			continue
+		}
+		s := synth
+		if ss, ok := As[SpeedSynthesizer](synth); ok && part.speed != 1 {
+			s = atSpeed{SpeedSynthesizer: ss, factor: part.speed}
+		}
+		chunk, err := speak(s, part.text)
+		if err != nil {
+			return nil, err
+		}
+		chunks[i] = chunk
+		if rate == 0 {
+			rate = chunk.SampleRate
+		}
+	}
+	if rate == 0 {
+		rate = synth.SampleRate() // Only breaks
+	}
+
+	out := &AudioOutput{SampleRate: rate}
+	for i, part := range parts {
+		if chunks[i] != nil {
+			out.Samples = append(out.Samples, chunks[i].Samples...)
+		} else {
+			out.Samples = append(out.Samples, make([]float32, int(part.pause.Seconds()*float64(rate)))...)
+		}
+	}
+	return out, nil
+}
+
+// atSpeed is a [Synthesizer] that speaks at a fixed speed factor.
+type atSpeed struct {
+	SpeedSynthesizer
+	factor float32
+}
+
+// Unwrap returns the wrapped synthesizer — satisfies [Wrapper].
+func (s atSpeed) Unwrap() Synthesizer {
+	return s.SpeedSynthesizer
+}
+
+func (s atSpeed) Synthesize(ctx context.Context, text string) (*AudioOutput, error) {
+	return s.SynthesizeAtSpeed(ctx, text, s.factor)
+}
+
+// SynthesizePhonemes passes phonemes to the wrapped synthesizer, so [phon:...]
+// markup inside <emphasis> keeps its pronunciation. Phonemes take no speed
+// factor: they are spoken at the normal speed.
+func (s atSpeed) SynthesizePhonemes(ctx context.Context, phonemes string) (*AudioOutput, error) {
+	ps, ok := As[PhonemeSynthesizer](s.SpeedSynthesizer)
+	if !ok {
+		return nil, ErrPhonemesUnsupported
+	}
+	return ps.SynthesizePhonemes(ctx, phonemes)
+}
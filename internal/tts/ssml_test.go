@@ -0,0 +1,133 @@
+package tts
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// speedSynth additionally speaks at a speed factor, recording each factor.
+type speedSynth struct {
+	recordingSynth
+	speeds []float32
+}
+
+func (s *speedSynth) SynthesizeAtSpeed(ctx context.Context, text string, factor float32) (*AudioOutput, error) {
+	s.speeds = append(s.speeds, factor)
+	return s.Synthesize(ctx, text)
+}
+
+func TestParseSSML(t *testing.T) {
+	got := parseSSML(`Take <emphasis level="strong">two</emphasis> pills<break time="1.5s"/> then <prosody rate="slow">rest</prosody><break strength='weak'/><break time="1h"/>`)
+	want := []ssmlPart{
+		{text: "Take", speed: 1},
+		{text: "two", speed: 0.75},
+		{text: "pills", speed: 1},
+		{pause: 1500 * time.Millisecond},
+		{text: "then", speed: 1},
+		{text: "rest", speed: 1},
+		{pause: 200 * time.Millisecond},
+		{pause: maxSSMLBreak},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseSSML =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseSSMLUnbalancedEmphasis(t *testing.T) {
+	got := parseSSML(`Yes</emphasis> <emphasis>really`)
+	want := []ssmlPart{{text: "Yes", speed: 1}, {text: "really", speed: 0.85}}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseSSML = %+v, want %+v", got, want)
+	}
+}
+
+func TestStripSSML(t *testing.T) {
+	got := StripSSML(`Hello <break time="300ms"/> <emphasis>world</emphasis> <voice name="x">again</voice> .`)
+	if want := "Hello world again."; got != want {
+		t.Errorf("StripSSML = %q, want %q", got, want)
+	}
+}
+
+func TestSSMLLeavesOtherAngleBracketsAlone(t *testing.T) {
+	for _, text := range []string{"a<b and c>d", "1 < 2 and 3 > 2", "Use <T> for any type.", `<break time=500ms>`} {
+		if HasSSML(text) {
+			t.Errorf("HasSSML(%q) = true, want false", text)
+		}
+		if got := StripSSML(text); got != strings.Join(strings.Fields(text), " ") {
+			t.Errorf("StripSSML(%q) = %q, want the text kept", text, got)
+		}
+	}
+}
+
+func TestSynthesizeSSMLInsertsBreaksAndEmphasis(t *testing.T) {
+	synth := &speedSynth{}
+	speak := func(s Synthesizer, text string) (*AudioOutput, error) {
+		return s.Synthesize(context.Background(), text)
+	}
+
+	out, err := SynthesizeSSML(synth, `Wait<break time="10ms"/>for <emphasis>it</emphasis>.`, speak)
+	if err != nil {
+		t.Fatalf("SynthesizeSSML: %v", err)
+	}
+	if want := []string{"Wait", "for", "it", "."}; !slices.Equal(synth.texts, want) {
+		t.Errorf("spoken runs = %q, want %q", synth.texts, want)
+	}
+	if want := []float32{0.85}; !slices.Equal(synth.speeds, want) {
+		t.Errorf("speeds = %v, want %v", synth.speeds, want)
+	}
+	// One sample per run plus 10ms of silence at 24kHz.
+	if len(out.Samples) != 4+240 || out.Samples[0] != 0.1 || out.Samples[1] != 0 || out.Samples[241] != 0.1 {
+		t.Errorf("samples = %d, want the runs with 240 silent samples after the first", len(out.Samples))
+	}
+}
+
+func TestSynthesizeSSMLWithoutSpeedControl(t *testing.T) {
+	synth := &recordingSynth{}
+	speak := func(s Synthesizer, text string) (*AudioOutput, error) {
+		return s.Synthesize(context.Background(), text)
+	}
+
+	if _, err := SynthesizeSSML(synth, `Say <emphasis>this</emphasis>`, speak); err != nil {
+		t.Fatalf("SynthesizeSSML: %v", err)
+	}
+	if want := []string{"Say", "this"}; !slices.Equal(synth.texts, want) {
+		t.Errorf("spoken runs = %q, want %q", synth.texts, want)
+	}
+	if _, err := SynthesizeSSML(synth, `<p></p>`, speak); err == nil {
+		t.Error("SynthesizeSSML accepted text with nothing to say")
+	}
+}
+
+// speedPhonemeSynth speaks at a speed factor and takes phoneme input.
+type speedPhonemeSynth struct {
+	phonemeSynth
+	speeds []float32
+}
+
+func (s *speedPhonemeSynth) SynthesizeAtSpeed(ctx context.Context, text string, factor float32) (*AudioOutput, error) {
+	s.speeds = append(s.speeds, factor)
+	return s.Synthesize(ctx, text)
+}
+
+func TestSynthesizeSSMLKeepsPhonemesInEmphasis(t *testing.T) {
+	synth := &speedPhonemeSynth{}
+	speak := func(s Synthesizer, text string) (*AudioOutput, error) {
+		return SynthesizeMarked(context.Background(), s, text)
+	}
+
+	if _, err := SynthesizeSSML(synth, `Take <emphasis>the [phon:ˈæs.pɹɪn|aspirin]</emphasis>.`, speak); err != nil {
+		t.Fatalf("SynthesizeSSML: %v", err)
+	}
+	if want := []string{"ˈæs.pɹɪn"}; !slices.Equal(synth.phonemes, want) {
+		t.Errorf("phonemes = %q, want %q", synth.phonemes, want)
+	}
+	if want := []string{"Take", "the", "."}; !slices.Equal(synth.texts, want) {
+		t.Errorf("spoken runs = %q, want %q", synth.texts, want)
+	}
+	if want := []float32{0.85}; !slices.Equal(synth.speeds, want) {
+		t.Errorf("speeds = %v, want %v", synth.speeds, want)
+	}
+}
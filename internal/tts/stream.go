@@ -0,0 +1,92 @@
+package tts
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+)
+
+// StreamingSynthesizer is implemented by synthesizers that can hand over the
+// audio of a sentence in chunks while generating it, so playback of a long
+// sentence can start before it is complete.
+type StreamingSynthesizer interface {
+	Synthesizer
+
+	// SynthesizeToCallback synthesizes text like Synthesize, passing each chunk
+	// of audio to onChunk as soon as it is generated. An error from onChunk
+	// stops generation and is returned.
+	SynthesizeToCallback(ctx context.Context, text string, onChunk func(AudioOutput) error) error
+}
+
+// SynthesizeChunks synthesizes text chunk by chunk when synth is a
+// [StreamingSynthesizer], and otherwise passes all of its audio to onChunk at
+// once, so wrappers around a synthesizer can offer streaming either way.
+func SynthesizeChunks(ctx context.Context, synth Synthesizer, text string, onChunk func(AudioOutput) error) error {
+	if ss, ok := As[StreamingSynthesizer](synth); ok {
+		return ss.SynthesizeToCallback(ctx, text, onChunk)
+	}
+	out, err := synth.Synthesize(ctx, text)
+	if err != nil {
+		return err
+	}
+	return onChunk(*out)
+}
+
+// streamChunkBuffer is how many chunks of a streamed sentence may wait for
+// playback, so synthesis can run ahead (see config.Config.MaxSynthLookahead)
+// without blocking on the sentence before it.
+const streamChunkBuffer = 64
+
+// sentenceStream is a sentence whose audio plays while it is being synthesized.
+type sentenceStream struct {
+	chunks      chan audio.AudioBuffer
+	synthesized atomic.Int64 // Duration of the audio synthesized so far
+}
+
+// streamingSynth returns synth as a [StreamingSynthesizer] when
+// cfg.StreamSynthesis is set and sentence needs no processing of its whole
+// audio: silence trimming, SSML or phoneme markup.
+func streamingSynth(synth Synthesizer, sentence string, cfg *config.Config) (StreamingSynthesizer, bool) {
+	ss, ok := As[StreamingSynthesizer](synth)
+	if !ok || !cfg.StreamSynthesis || cfg.TrimSilence > 0 {
+		return nil, false
+	}
+	if (cfg.SSMLMarkup && HasSSML(sentence)) || (cfg.PhonemeMarkup && HasPhonemeMarkup(sentence)) {
+		return nil, false
+	}
+	return ss, true
+}
+
+// synthesize streams sentence from synth into s.chunks, closing it when done,
+// and returns the whole audio. Chunks are dropped once ctx is done.
+func (s *sentenceStream) synthesize(ctx context.Context, synth StreamingSynthesizer, sentence string) (audio.AudioBuffer, error) {
+	defer close(s.chunks)
+	var buf audio.AudioBuffer
+	err := synth.SynthesizeToCallback(ctx, sentence, func(chunk AudioOutput) error {
+		buf.Samples = append(buf.Samples, chunk.Samples...)
+		buf.SampleRate = chunk.SampleRate
+		if chunk.SampleRate > 0 {
+			s.synthesized.Store(int64(time.Duration(len(buf.Samples)) * time.Second / time.Duration(chunk.SampleRate)))
+		}
+		select {
+		case s.chunks <- audio.AudioBuffer{Samples: chunk.Samples, SampleRate: chunk.SampleRate}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	return buf, err
+}
+
+// playedFraction returns the share of the audio synthesized so far covered by
+// position, clamped to [0, 1].
+func (s *sentenceStream) playedFraction(position time.Duration) float64 {
+	total := time.Duration(s.synthesized.Load())
+	if total <= 0 {
+		return 0
+	}
+	return min(position.Seconds()/total.Seconds(), 1)
+}
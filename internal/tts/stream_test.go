@@ -0,0 +1,101 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agalue/sherpa-voice-assistant/internal/audio"
+	"github.com/agalue/sherpa-voice-assistant/internal/config"
+)
+
+// streamSynth additionally streams each word of the text as a chunk of one
+// sample.
+type streamSynth struct{ recordingSynth }
+
+func (s *streamSynth) SynthesizeToCallback(ctx context.Context, text string, onChunk func(AudioOutput) error) error {
+	for i := range strings.Fields(text) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := onChunk(AudioOutput{Samples: []float32{float32(i + 1)}, SampleRate: 4}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestStreamingSynthOnlyForPlainSentences(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.StreamSynthesis = true
+	cfg.TrimSilence = 0
+	cfg.SSMLMarkup = true
+	cfg.PhonemeMarkup = true
+
+	synth := &streamSynth{}
+	if _, ok := streamingSynth(synth, "A long plain sentence.", cfg); !ok {
+		t.Error("plain sentence not streamed")
+	}
+	for _, sentence := range []string{"Say it <emphasis>now</emphasis>.", "Take [phon:ˈæs.pɹɪn|aspirin]."} {
+		if _, ok := streamingSynth(synth, sentence, cfg); ok {
+			t.Errorf("%q streamed despite its markup", sentence)
+		}
+	}
+	if _, ok := streamingSynth(&recordingSynth{}, "A long plain sentence.", cfg); ok {
+		t.Error("streamed with a synthesizer that can't stream")
+	}
+
+	cfg.TrimSilence = 0.01
+	if _, ok := streamingSynth(synth, "A long plain sentence.", cfg); ok {
+		t.Error("streamed with silence trimming on")
+	}
+}
+
+func TestSentenceStreamForwardsChunks(t *testing.T) {
+	stream := &sentenceStream{chunks: make(chan audio.AudioBuffer, streamChunkBuffer)}
+	buf, err := stream.synthesize(context.Background(), &streamSynth{}, "one two three four")
+	if err != nil {
+		t.Fatalf("synthesize: %v", err)
+	}
+	if want := []float32{1, 2, 3, 4}; !slices.Equal(buf.Samples, want) || buf.SampleRate != 4 {
+		t.Errorf("synthesized %v at %d Hz, want %v at 4 Hz", buf.Samples, buf.SampleRate, want)
+	}
+
+	var chunks int
+	for chunk := range stream.chunks { // Closed once synthesis is done
+		if chunk.SampleRate != 4 {
+			t.Errorf("chunk %d sample rate = %d, want 4", chunks, chunk.SampleRate)
+		}
+		chunks++
+	}
+	if chunks != 4 {
+		t.Errorf("forwarded %d chunks, want 4", chunks)
+	}
+	if got := stream.playedFraction(500 * time.Millisecond); got != 0.5 {
+		t.Errorf("playedFraction(500ms) of 1s = %v, want 0.5", got)
+	}
+}
+
+func TestSentenceStreamStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &sentenceStream{chunks: make(chan audio.AudioBuffer, 1)}
+	done := make(chan error)
+	go func() {
+		_, err := stream.synthesize(ctx, &streamSynth{}, "nobody is listening to these words")
+		done <- err
+	}()
+
+	<-stream.chunks // Playback takes one chunk, then stops listening
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("synthesize = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("synthesis still blocked on a full channel after cancellation")
+	}
+}
@@ -2,23 +2,76 @@
 // This file contains shared text processing utilities for TTS implementations.
 package tts
 
-import "strings"
+import (
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SentenceSplitConfig tunes where [SplitSentencesWith] breaks text into pieces
+// for synthesis, trading latency (small pieces reach the speaker sooner) against
+// prosody (each piece is spoken with its own intonation). The zero value splits
+// at every sentence boundary, like [SplitSentences].
+type SentenceSplitConfig struct {
+	// MinChars is the shortest piece a sentence boundary may end; shorter
+	// sentences are joined with the next one instead of being spoken alone.
+	MinChars int
+
+	// SoftBoundaries are extra characters (e.g. ",;:") that also end a piece when
+	// followed by a space, but only once the piece is at least SoftMinChars long,
+	// so long sentences start playing before they are complete.
+	SoftBoundaries string
+	SoftMinChars   int
+
+	// MaxChars caps the length of a piece (0 = no limit). Longer sentences, such
+	// as run-ons without punctuation, are cut at a word boundary, preferably
+	// after a comma, so no single synthesis call blocks interruption for long.
+	MaxChars int
+}
 
 // SplitSentences splits text into sentences for streaming synthesis.
 //
 // It splits on sentence boundaries (. ! ? \n) while avoiding:
-//   - Decimal numbers (e.g., "10.5°C")
+//   - Decimal numbers (e.g., "10.5°C", "$3.50")
 //   - Single-letter abbreviations (e.g., the letters in "U.S.")
+//   - Common abbreviations (e.g., "Dr.", "Mrs.", "e.g."; see sentenceAbbreviations)
 //   - Periods not followed by a space + uppercase start
 func SplitSentences(text string) []string {
+	return SplitSentencesWith(text, SentenceSplitConfig{})
+}
+
+// SplitSentencesWith splits text like [SplitSentences], applying cfg's minimum
+// and maximum piece lengths and soft boundaries.
+func SplitSentencesWith(text string, cfg SentenceSplitConfig) []string {
 	var sentences []string
 	var current strings.Builder
 
+	// flush ends the current piece if it is at least minChars long.
+	flush := func(minChars int) {
+		trimmed := strings.TrimSpace(current.String())
+		if utf8.RuneCountInString(trimmed) < minChars {
+			return
+		}
+		if trimmed != "" {
+			sentences = append(sentences, trimmed)
+		}
+		current.Reset()
+	}
+
 	runes := []rune(text)
 	for i := 0; i < len(runes); i++ {
 		c := runes[i]
 		current.WriteRune(c)
 
+		if cfg.SoftBoundaries != "" && strings.ContainsRune(cfg.SoftBoundaries, c) &&
+			(i+1 == len(runes) || unicode.IsSpace(runes[i+1])) {
+			flush(cfg.SoftMinChars)
+			continue
+		}
+
 		if c == '.' || c == '!' || c == '?' || c == '\n' {
 			if c == '.' {
 				prevIsDigit := i > 0 && isDigit(runes[i-1])
@@ -41,6 +94,11 @@ func SplitSentences(text string) []string {
 					}
 				}
 
+				// Common abbreviation ("Dr. Smith", "e.g. Paris"), don't split.
+				if sentenceAbbreviations[wordBefore(runes, i)] {
+					continue
+				}
+
 				// Look ahead: proper sentence boundary has space + uppercase after period.
 				if i+1 < len(runes) {
 					next := runes[i+1]
@@ -55,11 +113,7 @@ func SplitSentences(text string) []string {
 				}
 			}
 
-			trimmed := strings.TrimSpace(current.String())
-			if trimmed != "" {
-				sentences = append(sentences, trimmed)
-			}
-			current.Reset()
+			flush(cfg.MinChars)
 		}
 	}
 
@@ -68,7 +122,112 @@ func SplitSentences(text string) []string {
 		sentences = append(sentences, trimmed)
 	}
 
-	return sentences
+	if cfg.MaxChars <= 0 {
+		return sentences
+	}
+	var pieces []string
+	for _, sentence := range sentences {
+		pieces = append(pieces, chunkSentence(sentence, cfg.MaxChars)...)
+	}
+	return pieces
+}
+
+// sentenceAbbreviations are the lowercased words, without their final period,
+// that [SplitSentences] never treats as the end of a sentence. They are titles
+// and Latin abbreviations that are almost always followed by more of the same
+// sentence; ones that often end a sentence, such as "Inc.", "etc.", "Jr." or
+// "St." (street), are left out.
+var sentenceAbbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "mt": true,
+	"vs": true, "e.g": true, "i.e": true, "cf": true, "approx": true,
+}
+
+// wordBefore returns the lowercased letters and periods immediately before
+// runes[i], e.g. "e.g" for the last period of "see e.g.".
+func wordBefore(runes []rune, i int) string {
+	start := i
+	for start > 0 && (isLetter(runes[start-1]) || runes[start-1] == '.') {
+		start--
+	}
+	return strings.ToLower(string(runes[start:i]))
+}
+
+// chunkSentence cuts sentence into pieces of at most maxChars characters at word
+// boundaries. A cut after a clause mark (, ; :) is preferred when one falls in
+// the second half of the allowed length, so chunks sound like natural phrases.
+// A single word longer than maxChars is kept whole rather than split, and so
+// are [phon:...] markup and SSML tags, which would no longer parse if cut.
+func chunkSentence(sentence string, maxChars int) []string {
+	var pieces []string
+	runes := []rune(sentence)
+	for len(runes) > maxChars {
+		markup := markupMask(runes)
+		cut, clause := -1, -1
+		for i := 1; i <= maxChars && i < len(runes); i++ {
+			if runes[i] != ' ' || markup[i] {
+				continue
+			}
+			cut = i
+			if strings.ContainsRune(",;:", runes[i-1]) && i >= maxChars/2 {
+				clause = i
+			}
+		}
+		if clause > 0 {
+			cut = clause
+		}
+		if cut < 0 {
+			// No space within the limit: keep the overlong word or markup whole.
+			for i := maxChars; i < len(runes); i++ {
+				if runes[i] == ' ' && !markup[i] {
+					cut = i
+					break
+				}
+			}
+			if cut < 0 {
+				break
+			}
+		}
+		pieces = append(pieces, strings.TrimSpace(string(runes[:cut])))
+		runes = []rune(strings.TrimSpace(string(runes[cut:])))
+	}
+	if rest := strings.TrimSpace(string(runes)); rest != "" {
+		pieces = append(pieces, rest)
+	}
+	return pieces
+}
+
+// markupMask reports for each of runes whether it lies within [phon:...]
+// markup or an SSML tag.
+func markupMask(runes []rune) []bool {
+	text := string(runes)
+	mask := make([]bool, len(runes))
+	for _, re := range []*regexp.Regexp{phonemeMarkup, ssmlTag} {
+		for _, m := range re.FindAllStringIndex(text, -1) {
+			start := utf8.RuneCountInString(text[:m[0]])
+			end := start + utf8.RuneCountInString(text[m[0]:m[1]])
+			for i := start; i < end; i++ {
+				mask[i] = true
+			}
+		}
+	}
+	return mask
+}
+
+// HeardText returns the part of a response the listener heard when playback
+// stopped during sentences[next], after playing the fraction played (0–1) of its
+// audio. Earlier sentences are included whole; the cut-off sentence is truncated
+// to the same fraction of its words, which tracks speech closely enough to keep
+// the conversation history honest.
+func HeardText(sentences []string, next int, played float64) string {
+	next = min(max(next, 0), len(sentences))
+	heard := slices.Clone(sentences[:next])
+	if next < len(sentences) && played > 0 {
+		words := strings.Fields(sentences[next])
+		if n := int(math.Round(float64(len(words)) * min(played, 1))); n > 0 {
+			heard = append(heard, strings.Join(words[:n], " "))
+		}
+	}
+	return strings.Join(heard, " ")
 }
 
 // isDigit reports whether r is an ASCII decimal digit.
@@ -79,3 +238,39 @@ func isLetter(r rune) bool { return (r >= 'a' && r <= 'z') || (r >= 'A' && r <=
 
 // isUpper reports whether r is an ASCII uppercase letter.
 func isUpper(r rune) bool { return r >= 'A' && r <= 'Z' }
+
+// isSpeakable reports whether text has anything a synthesizer can say, i.e. at
+// least one letter or digit. Pieces made only of symbols, emoji or leftover
+// markdown ("**", "🙂") produce no speech.
+func isSpeakable(text string) bool {
+	return strings.IndexFunc(text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0
+}
+
+// MatchPhrase reports whether text, once normalized, equals one of phrases.
+//
+// Normalization lowercases the text, drops punctuation, and collapses whitespace,
+// so "Say that again?" matches the phrase "say that again". The whole utterance
+// must match; a phrase embedded in a longer request does not count.
+func MatchPhrase(text string, phrases []string) bool {
+	normalized := normalizePhrase(text)
+	if normalized == "" {
+		return false
+	}
+	for _, p := range phrases {
+		if normalizePhrase(p) == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePhrase lowercases s, removes punctuation, and collapses runs of whitespace.
+func normalizePhrase(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || r == '\'' {
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
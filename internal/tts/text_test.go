@@ -4,6 +4,7 @@ package tts
 
 import (
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -41,8 +42,6 @@ func TestSplitSentencesBasic(t *testing.T) {
 }
 
 func TestSplitSentencesAbbreviations(t *testing.T) {
-	// Note: Multi-letter abbreviations like "Dr." are challenging to detect reliably.
-	// For voice assistants, we prioritize decimal numbers (10.5°C) over all abbreviations.
 	text := "I live in the U.S. and it's great. The temperature is 72.5°F!"
 	sentences := SplitSentences(text)
 
@@ -63,6 +62,33 @@ func TestSplitSentencesAbbreviations(t *testing.T) {
 	}
 }
 
+func TestSplitSentencesTrickyPeriods(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Dr. Smith owes $3.50 to Mrs. Jones.", []string{"Dr. Smith owes $3.50 to Mrs. Jones."}},
+		{"Ask Mr. Brown. He knows.", []string{"Ask Mr. Brown.", "He knows."}},
+		{"Bring fruit, e.g. Apples or pears. Thanks!", []string{"Bring fruit, e.g. Apples or pears.", "Thanks!"}},
+		{"Use a tool (i.e. A hammer) today.", []string{"Use a tool (i.e. A hammer) today."}},
+		{"It was Prof. Lee vs. Dr. Kim on Mt. Hood.", []string{"It was Prof. Lee vs. Dr. Kim on Mt. Hood."}},
+		{"We need eggs, milk, etc. Then we bake.", []string{"We need eggs, milk, etc.", "Then we bake."}},
+		{"It was signed by John Smith Jr. He left.", []string{"It was signed by John Smith Jr.", "He left."}},
+		{"Turn left on Main St. It is the second house.", []string{"Turn left on Main St.", "It is the second house."}},
+		{"Pi is about 3.14159. That is enough.", []string{"Pi is about 3.14159.", "That is enough."}},
+		{"It rose 0.5 points. Then 2.25 more.", []string{"It rose 0.5 points.", "Then 2.25 more."}},
+		{"Version 2.0. Released today.", []string{"Version 2.0.", "Released today."}},
+		{"I met Ms. Park. DR. WHO was there.", []string{"I met Ms. Park.", "DR. WHO was there."}},
+		{"She drove a car. Dr. Ruiz did not.", []string{"She drove a car.", "Dr. Ruiz did not."}},
+		{"The score was 10. Eleven is next.", []string{"The score was 10.", "Eleven is next."}},
+	}
+	for _, tt := range tests {
+		if got := SplitSentences(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("SplitSentences(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
 func TestSplitSentencesNoSpaceAfterPeriod(t *testing.T) {
 	text := "Visit example.com for more info. Thank you!"
 	sentences := SplitSentences(text)
@@ -92,3 +118,132 @@ func TestSplitSentencesWeatherFormat(t *testing.T) {
 		t.Errorf("Expected first sentence to contain '7.2°C', got: %q", sentences[0])
 	}
 }
+
+func TestMatchPhraseIgnoresCaseAndPunctuation(t *testing.T) {
+	phrases := []string{"say that again", "repeat"}
+
+	for _, text := range []string{"Say that again?", "say that, again.", "  REPEAT!  "} {
+		if !MatchPhrase(text, phrases) {
+			t.Errorf("MatchPhrase(%q) = false, want true", text)
+		}
+	}
+}
+
+func TestMatchPhraseRequiresWholeUtterance(t *testing.T) {
+	phrases := []string{"repeat"}
+
+	for _, text := range []string{"repeat after me hello", "", "?!"} {
+		if MatchPhrase(text, phrases) {
+			t.Errorf("MatchPhrase(%q) = true, want false", text)
+		}
+	}
+}
+
+func TestIsSpeakable(t *testing.T) {
+	for text, want := range map[string]bool{
+		"Hello.":  true,
+		"42":      true,
+		"¿Qué?":   true,
+		"**":      false,
+		"🙂 👍":     false,
+		"- * _ `": false,
+		"":        false,
+	} {
+		if got := isSpeakable(text); got != want {
+			t.Errorf("isSpeakable(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestHeardText(t *testing.T) {
+	sentences := []string{"It is sunny.", "Tomorrow will bring heavy rain and wind.", "Take an umbrella."}
+	tests := []struct {
+		next   int
+		played float64
+		want   string
+	}{
+		{0, 0, ""},
+		{0, 0.3, "It"},
+		{1, 0, "It is sunny."},
+		{1, 0.5, "It is sunny. Tomorrow will bring heavy"},
+		{3, 0, "It is sunny. Tomorrow will bring heavy rain and wind. Take an umbrella."},
+	}
+	for _, tt := range tests {
+		if got := HeardText(sentences, tt.next, tt.played); got != tt.want {
+			t.Errorf("HeardText(next=%d, played=%.1f) = %q, want %q", tt.next, tt.played, got, tt.want)
+		}
+	}
+}
+
+// TestSplitSentencesWithMinChars validates that short sentences are joined with
+// the next one instead of being synthesized alone.
+func TestSplitSentencesWithMinChars(t *testing.T) {
+	got := SplitSentencesWith("Sure! The meeting is at noon. Okay.", SentenceSplitConfig{MinChars: 10})
+	want := []string{"Sure! The meeting is at noon.", "Okay."}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// TestSplitSentencesWithSoftBoundaries validates that soft boundaries split only
+// pieces that are already long, and never inside numbers like "1,000".
+func TestSplitSentencesWithSoftBoundaries(t *testing.T) {
+	cfg := SentenceSplitConfig{SoftBoundaries: ",;", SoftMinChars: 20}
+	text := "Yes, it costs 1,000 dollars in total, which includes shipping; taxes are extra."
+	got := SplitSentencesWith(text, cfg)
+	want := []string{
+		"Yes, it costs 1,000 dollars in total,",
+		"which includes shipping;",
+		"taxes are extra.",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got := SplitSentencesWith(text, SentenceSplitConfig{}); len(got) != 1 {
+		t.Errorf("zero config split into %q, want one sentence", got)
+	}
+}
+
+// TestSplitSentencesWithMaxChars validates that run-on sentences are cut at word
+// boundaries, preferring clause marks, and that no chunk splits a word.
+func TestSplitSentencesWithMaxChars(t *testing.T) {
+	text := "so we walked along the river, past the old mill and the bakery and then up the hill to the castle where we stayed"
+	got := SplitSentencesWith(text, SentenceSplitConfig{MaxChars: 40})
+
+	if strings.Join(got, " ") != text {
+		t.Fatalf("chunks %q do not rejoin to the original text", got)
+	}
+	if got[0] != "so we walked along the river," {
+		t.Errorf("first chunk = %q, want a cut after the comma", got[0])
+	}
+	words := strings.Fields(text)
+	for _, chunk := range got {
+		if len([]rune(chunk)) > 40 {
+			t.Errorf("chunk %q is longer than 40 characters", chunk)
+		}
+		for _, w := range strings.Fields(chunk) {
+			if !slices.Contains(words, w) {
+				t.Errorf("chunk %q splits a word (%q)", chunk, w)
+			}
+		}
+	}
+}
+
+// TestChunkSentenceKeepsOverlongWordWhole validates that a word longer than the
+// limit is not split.
+func TestChunkSentenceKeepsOverlongWordWhole(t *testing.T) {
+	got := chunkSentence("see https://example.com/a/very/long/path/to/somewhere now", 10)
+	want := []string{"see", "https://example.com/a/very/long/path/to/somewhere", "now"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestChunkSentenceKeepsMarkupWhole(t *testing.T) {
+	got := chunkSentence(`Take the [phon:ˈæs pɹɪn|aspirin] pill <break time="300ms"/> with water now`, 20)
+	want := []string{"Take the", "[phon:ˈæs pɹɪn|aspirin]", `pill`, `<break time="300ms"/>`, "with water now"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -2,7 +2,8 @@
 //
 // The package defines [Synthesizer] — the primary interface — that decouples the rest
 // of the voice assistant from any specific TTS implementation. The current implementation
-// uses Kokoro (via sherpa-onnx); see [KokoroSynthesizer].
+// uses Kokoro (via sherpa-onnx); see [KokoroSynthesizer]. [HTTPSynthesizer] delegates
+// synthesis to a remote server instead.
 //
 // To add a new TTS backend:
 //  1. Implement [Synthesizer] in a new file.
@@ -12,6 +13,7 @@
 package tts
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -30,8 +32,10 @@ type AudioOutput struct {
 // dedicated goroutine, not the audio callback thread.
 type Synthesizer interface {
 	// Synthesize converts a single sentence of text to audio.
-	// Returns an error if synthesis fails (e.g., model error, empty input).
-	Synthesize(text string) (*AudioOutput, error)
+	// Returns an error if synthesis fails (e.g., model error, empty input), and
+	// ctx's error when ctx is done before the audio is complete, so a barge-in
+	// does not wait for a long sentence to finish synthesizing.
+	Synthesize(ctx context.Context, text string) (*AudioOutput, error)
 
 	// SampleRate returns the sample rate (in Hz) of audio produced by this synthesizer.
 	SampleRate() int
@@ -40,6 +44,33 @@ type Synthesizer interface {
 	Close()
 }
 
+// Wrapper is implemented by synthesizers that wrap another one, e.g. to time
+// or record it. A wrapper implements every optional interface (such as
+// [PhonemeSynthesizer]) by forwarding to the synthesizer it wraps; [As] looks
+// through it to tell which ones that synthesizer really has.
+type Wrapper interface {
+	Synthesizer
+
+	// Unwrap returns the wrapped synthesizer.
+	Unwrap() Synthesizer
+}
+
+// As returns synth as a T (an optional interface such as [SpeedSynthesizer])
+// when synth and every synthesizer it wraps (see [Wrapper]) implement T.
+func As[T any](synth Synthesizer) (T, bool) {
+	t, ok := synth.(T)
+	if !ok {
+		return t, false
+	}
+	if w, ok := synth.(Wrapper); ok {
+		if _, ok := As[T](w.Unwrap()); !ok {
+			var zero T
+			return zero, false
+		}
+	}
+	return t, true
+}
+
 // ModelProvider manages the lifecycle of model files required by a TTS backend.
 //
 // Every TTS implementation must implement this interface so that the binary can
@@ -75,16 +106,27 @@ func NewSynthesizer(cfg *config.Config) (Synthesizer, error) {
 	switch strings.ToLower(cfg.TTSBackend) {
 	case "kokoro":
 		return NewKokoroSynthesizer(&KokoroConfig{
-			ModelDir:   cfg.ModelDir,
+			ModelDir:    cfg.ModelDir,
+			Voice:       cfg.TTSVoice,
+			UserLexicon: cfg.UserLexicon,
+			SpeakerID:   cfg.TTSSpeakerID,
+			Speed:       cfg.TTSSpeed,
+			Provider:    cfg.TTSProvider,
+			Verbose:     cfg.Verbose,
+			NumThreads:  cfg.TTSThreads,
+
+			MaxNumSentences: cfg.TTSMaxNumSentences,
+		})
+	case "http":
+		return NewHTTPSynthesizer(&HTTPConfig{
+			URL:        cfg.TTSURL,
 			Voice:      cfg.TTSVoice,
-			SpeakerID:  cfg.TTSSpeakerID,
 			Speed:      cfg.TTSSpeed,
-			Provider:   cfg.TTSProvider,
 			Verbose:    cfg.Verbose,
-			NumThreads: cfg.TTSThreads,
+			NoFallback: cfg.SynthesizeTo != "", // An apology must not end up in the file
 		})
 	default:
-		return nil, fmt.Errorf("unknown TTS backend %q (available: kokoro)", cfg.TTSBackend)
+		return nil, fmt.Errorf("unknown TTS backend %q (available: kokoro, http)", cfg.TTSBackend)
 	}
 }
 
@@ -96,7 +138,9 @@ func NewModelProvider(cfg *config.Config) (ModelProvider, error) {
 	switch strings.ToLower(cfg.TTSBackend) {
 	case "kokoro":
 		return &KokoroModelProvider{}, nil
+	case "http":
+		return &HTTPModelProvider{}, nil
 	default:
-		return nil, fmt.Errorf("unknown TTS backend %q (available: kokoro)", cfg.TTSBackend)
+		return nil, fmt.Errorf("unknown TTS backend %q (available: kokoro, http)", cfg.TTSBackend)
 	}
 }